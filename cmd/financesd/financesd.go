@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -23,9 +26,17 @@ import (
 	"github.com/go-akka/configuration"
 	_ "github.com/go-sql-driver/mysql" // register the driver
 	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
 	"github.com/graphql-go/handler"
+	"github.com/jonestimd/financesd/internal/attachment"
+	"github.com/jonestimd/financesd/internal/auth"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/jonestimd/financesd/internal/migration"
 	"github.com/jonestimd/financesd/internal/schema"
 	"github.com/jonestimd/financesd/internal/server"
+	_ "github.com/lib/pq"           // register the driver
+	_ "github.com/mattn/go-sqlite3" // register the driver
 )
 
 var httpHandle = http.Handle
@@ -33,22 +44,78 @@ var netListen = net.Listen
 var signalNotify = signal.Notify
 var logAndQuit = log.Fatal
 var sqlOpen = sql.Open
+var setDialect = database.SetDialect
+var setSlowStatementThreshold = database.SetSlowStatementThreshold
 var newSchema = schema.New
 var getwd = os.Getwd
 var newHandler = handler.New
+var getPermissions = domain.GetPermissions
+var newChangeEvent = domain.NewChangeEvent
+var newTokenVerifier = auth.NewTokenVerifier
+var newAuthHandler = auth.NewHandler
+var beginChangeSet = database.BeginChangeSet
+var endChangeSet = database.EndChangeSet
+var beginRequest = database.BeginRequest
+var endRequest = database.EndRequest
+var newAttachmentStore = attachment.NewStore
+var loadPersistedQueries = schema.LoadPersistedQueries
+var pendingMigrations = migration.Pending
+var checkSchema = migration.CheckSchema
+var migrateUp = migration.Up
+var migrationStatus = migration.Status
+var migrationBaseline = migration.Baseline
+var initDatabase = migration.Init
+var addAdmin = migration.AddAdmin
+var stdout io.Writer = os.Stdout
+
+const migrateUsage = "usage: financesd migrate up|status [config file] or financesd migrate baseline version [config file]"
+const initUsage = "usage: financesd init [-categories] [-currency code] [config file]"
+const adminUsage = "usage: financesd admin name [config file]"
 
 func main() {
+	command, args := "", os.Args[1:]
+	var initOptions migration.Options
+	var adminName string
+	var baselineVersion int
+	if len(args) > 0 && args[0] == "migrate" {
+		if len(args) < 2 || (args[1] != "up" && args[1] != "status" && args[1] != "baseline") {
+			logAndQuit(migrateUsage)
+		}
+		command, args = "migrate "+args[1], args[2:]
+		if command == "migrate baseline" {
+			var err error
+			if len(args) == 0 {
+				logAndQuit(migrateUsage)
+			} else if baselineVersion, err = strconv.Atoi(args[0]); err != nil {
+				logAndQuit(migrateUsage)
+			}
+			args = args[1:]
+		}
+	} else if len(args) > 0 && args[0] == "init" {
+		flags := flag.NewFlagSet("init", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		flags.BoolVar(&initOptions.Categories, "categories", false, "add a starter category tree")
+		flags.StringVar(&initOptions.Currency, "currency", "", "add the base currency")
+		if err := flags.Parse(args[1:]); err != nil {
+			logAndQuit(initUsage)
+		}
+		command, args = "init", flags.Args()
+	} else if len(args) > 0 && args[0] == "admin" {
+		if len(args) < 2 {
+			logAndQuit(adminUsage)
+		}
+		command, adminName, args = "admin", args[1], args[2:]
+	}
 	configPath := fmt.Sprintf("%s/.finances/connection.conf", os.Getenv("HOME"))
-	if len(os.Args) > 1 {
-		configPath = os.Args[1]
+	if len(args) > 0 {
+		configPath = args[0]
 	}
 	config := configuration.LoadConfig(configPath)
-	driver := strings.ToLower(config.GetString("connection.default.driver"))
-	user := config.GetString("connection.default.user")
-	password := config.GetString("connection.default.password")
-	host := config.GetString("connection.default.host")
-	schema := config.GetString("connection.default.schema")
-	db, err := sqlOpen(driver, fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", user, password, host, schema))
+	driver := strings.ToLower(config.GetString("connection.default.driver", "mysql"))
+	if err := setDialect(driver); err != nil {
+		logAndQuit(err)
+	}
+	db, err := sqlOpen(driver, getDataSource(driver, config.GetConfig("connection.default")))
 	if err != nil {
 		logAndQuit(err)
 	}
@@ -56,9 +123,41 @@ func main() {
 	db.SetConnMaxLifetime(config.GetTimeDuration("connection.maxLifetime", 0))
 	db.SetMaxIdleConns(int(config.GetInt32("connection.maxIdleConnections", 10)))
 	db.SetMaxOpenConns(int(config.GetInt32("connection.maxOpenConnections", 10)))
+	setSlowStatementThreshold(config.GetTimeDuration("connection.slowStatementThreshold", time.Second))
 	if err := db.Ping(); err != nil {
 		logAndQuit(err)
 	}
+	switch command {
+	case "migrate up":
+		runMigrations(db)
+		return
+	case "migrate status":
+		printMigrationStatus(db)
+		return
+	case "migrate baseline":
+		markMigrationsApplied(db, baselineVersion)
+		return
+	case "init":
+		user := getOSUser()
+		if err := initDatabase(db, initOptions, user); err != nil {
+			logAndQuit(err)
+		}
+		log.Printf("Database initialized with admin user %s", user)
+		return
+	case "admin":
+		if err := addAdmin(db, adminName, getOSUser()); err != nil {
+			logAndQuit(err)
+		}
+		log.Printf("Admin user %s added", adminName)
+		return
+	}
+	if pending, err := pendingMigrations(db); err != nil {
+		logAndQuit(err)
+	} else if len(pending) > 0 {
+		logAndQuit(fmt.Sprintf("database schema is behind by %d migration(s), run: financesd migrate up", len(pending)))
+	} else if err := checkSchema(db); err != nil {
+		logAndQuit(err)
+	}
 
 	graphqlSchema, err := newSchema()
 	if err != nil {
@@ -74,7 +173,47 @@ func main() {
 		logAndQuit("can't get current directory")
 	} else {
 		network, address := getListenConfig(config.GetConfig("listen"))
-		httpHandle("/finances/api/v1/graphql", &graphqlHandler{db: db, defaultUser: config.GetString("oauth.user"), handler: gqlHandler})
+		store, err := newAttachmentStore(config.GetString("attachments.directory", filepath.Join(os.Getenv("HOME"), ".finances", "attachments")))
+		if err != nil {
+			logAndQuit(err)
+		}
+		verifier, trustedProxies := getTokenVerifier(config), getTrustedProxies(config)
+		queries, err := loadPersistedQueries(config.GetString("graphql.persistedQueries.manifest",
+			filepath.Join(cwd, "web", "dist", "persisted-queries.json")), config.GetBoolean("graphql.persistedQueries.strict", false))
+		if err != nil {
+			logAndQuit(err)
+		}
+		log.Printf("Loaded %d persisted queries", queries.Len())
+		limits := schema.QueryLimits{
+			MaxDepth: int(config.GetInt32("graphql.maxDepth", 10)),
+			MaxCost:  int(config.GetInt32("graphql.maxCost", 25000)),
+		}
+		broker := server.NewBroker()
+		authHandler, err := newAuthHandler(&persistedQueryHandler{queries: queries, next: &queryCostHandler{
+			schema: &graphqlSchema,
+			limits: limits,
+			next: &graphqlHandler{
+				db:      db,
+				handler: gqlHandler,
+				store:   store,
+				broker:  broker,
+				timeout: config.GetTimeDuration("connection.statementTimeout", 0),
+			},
+		}}, db, verifier, trustedProxies)
+		if err != nil {
+			logAndQuit(err)
+		}
+		subscriptionHandler, err := newAuthHandler(server.NewSubscriptionHandler(db, &graphqlSchema, broker), db, verifier, trustedProxies)
+		if err != nil {
+			logAndQuit(err)
+		}
+		attachmentHandler, err := newAuthHandler(attachment.NewHandler(db, store), db, verifier, trustedProxies)
+		if err != nil {
+			logAndQuit(err)
+		}
+		httpHandle("/finances/api/v1/graphql", authHandler)
+		httpHandle("/finances/api/v1/subscriptions", subscriptionHandler)
+		httpHandle("/finances/api/v1/attachments/", http.StripPrefix("/finances/api/v1/attachments/", attachmentHandler))
 		httpHandle("/finances/scripts/", http.StripPrefix("/finances/scripts/", http.FileServer(http.Dir(filepath.Join(cwd, "web", "dist")))))
 		httpHandle("/finances/", newIndexHandler(cwd, network, address))
 		umask, err := strconv.ParseInt(config.GetString("listen.umask", "0117"), 8, 32)
@@ -88,16 +227,8 @@ func main() {
 			log.Fatalf("Error listening: %v", err)
 		}
 		defer listener.Close()
-		idSupplier := server.NewIDSupplier()
-		go func() {
-			router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				requestID := idSupplier.Next()
-				snoop := httpsnoop.CaptureMetrics(http.DefaultServeMux, w, r.WithContext(context.WithValue(r.Context(), requestIdKey, requestID)))
-				log.Printf("[%s] %d %-5s %s %s %d %v %s\n", requestID, snoop.Code, r.Method, r.URL.Path, r.Host, snoop.Written,
-					snoop.Duration.Truncate(time.Millisecond), r.UserAgent())
-			})
-			serve(listener, router)
-		}()
+		router := accessLog(server.NewIDSupplier(), http.DefaultServeMux)
+		go serve(listener, router)
 
 		sigc := make(chan os.Signal, 1)
 		signalNotify(sigc, os.Interrupt, syscall.SIGTERM)
@@ -106,73 +237,338 @@ func main() {
 	}
 }
 
+// getOSUser returns the name of the user running the command for the audit columns of the starter data.
+func getOSUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "financesd"
+}
+
+// runMigrations applies the pending schema migrations.
+func runMigrations(db *sql.DB) {
+	applied, err := migrateUp(db)
+	for _, m := range applied {
+		log.Printf("Applied migration %s\n", m)
+	}
+	if err != nil {
+		logAndQuit(err)
+	}
+	if len(applied) == 0 {
+		log.Print("Database schema is up to date")
+	}
+}
+
+// markMigrationsApplied records the pending migrations up to the version as applied without running them.
+func markMigrationsApplied(db *sql.DB, version int) {
+	baseline, err := migrationBaseline(db, version)
+	if err != nil {
+		logAndQuit(err)
+	}
+	for _, m := range baseline {
+		log.Printf("Marked migration %s as applied\n", m)
+	}
+	if len(baseline) == 0 {
+		log.Print("No pending migrations to mark as applied")
+	}
+}
+
+// printMigrationStatus lists the migrations with the date that each one was applied.
+func printMigrationStatus(db *sql.DB) {
+	migrations, err := migrationStatus(db)
+	if err != nil {
+		logAndQuit(err)
+	}
+	for _, m := range migrations {
+		status := "pending"
+		if m.ApplyDate != nil {
+			status = m.ApplyDate.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(stdout, "%-40s %s\n", m, status)
+	}
+}
+
+// accessLog assigns an ID to each request and logs the response, including the totals of the request's SQL statements.
+func accessLog(idSupplier *server.IDSupplier, handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := idSupplier.Next()
+		stats := &database.RequestStats{ID: requestID}
+		ctx := context.WithValue(context.WithValue(r.Context(), requestIdKey, requestID), sqlStatsKey, stats)
+		snoop := httpsnoop.CaptureMetrics(handler, w, r.WithContext(ctx))
+		log.Printf("[%s] %d %-5s %s %s %d %v (%v) %s\n", requestID, snoop.Code, r.Method, r.URL.Path, r.Host, snoop.Written,
+			snoop.Duration.Truncate(time.Millisecond), stats, r.UserAgent())
+	}
+}
+
 var serve = func(listener net.Listener, router http.HandlerFunc) {
 	server := &http.Server{Handler: router}
 	log.Fatal(server.Serve(listener))
 }
 
+// getDataSource returns the data source name for the database driver. For SQLite, the file setting is the path of
+// the database file. For PostgreSQL, the sslMode setting defaults to "require".
+func getDataSource(driver string, config *configuration.Config) string {
+	if driver == "sqlite3" {
+		file := config.GetString("file", filepath.Join(os.Getenv("HOME"), ".finances", "finances.db"))
+		return fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate", file)
+	}
+	if driver == "postgres" {
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(config.GetString("user"), config.GetString("password")),
+			Host:     config.GetString("host"),
+			Path:     "/" + config.GetString("schema"),
+			RawQuery: url.Values{"sslmode": {config.GetString("sslMode", "require")}}.Encode(),
+		}
+		return dsn.String()
+	}
+	user := config.GetString("user")
+	password := config.GetString("password")
+	host := config.GetString("host")
+	schema := config.GetString("schema")
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", user, password, host, schema)
+}
+
 func getListenConfig(config *configuration.Config) (network string, address string) {
 	network = config.GetString("network", "tcp")
 	address = config.GetString("address", "localhost:8080")
 	return
 }
 
+// getTokenVerifier returns the verifier for OIDC bearer tokens or nil if OIDC is not configured.
+func getTokenVerifier(config *configuration.Config) *auth.TokenVerifier {
+	if !config.HasPath("auth.oidc.issuer") {
+		return nil
+	}
+	verifier, err := newTokenVerifier(config.GetString("auth.oidc.issuer"), config.GetString("auth.oidc.jwks"),
+		config.GetString("auth.oidc.audience"), config.GetString("auth.oidc.userClaim"))
+	if err != nil {
+		logAndQuit(err)
+	}
+	return verifier
+}
+
+// getTrustedProxies returns the addresses that are allowed to set the X-User header.
+func getTrustedProxies(config *configuration.Config) []string {
+	if !config.HasPath("auth.trustedProxies") {
+		return nil
+	}
+	return config.GetStringList("auth.trustedProxies")
+}
+
 type graphqlHandler struct {
-	db          *sql.DB
-	defaultUser string
-	handler     http.Handler
+	db      *sql.DB
+	handler http.Handler
+	store   schema.FileStore
+	// broker publishes the changes of committed requests to subscriptions. Changes aren't published if it is nil.
+	broker *server.Broker
+	// timeout limits the time that the statements of a request can run. No limit if it is 0.
+	timeout time.Duration
 }
 
 type reqContextKey string
 
 const requestIdKey = reqContextKey("requestID")
 const hasErrorKey = reqContextKey("hasError")
+const sqlStatsKey = reqContextKey("sqlStats")
+const queryCostKey = reqContextKey("queryCost")
 
 var compressSpaces = regexp.MustCompile(`\s+`)
 
 func resultCallback(ctx context.Context, params *graphql.Params, result *graphql.Result, responseBody []byte) {
 	requestID := ctx.Value(requestIdKey)
-	log.Printf("[%s] query: %s", requestID, compressSpaces.ReplaceAllString(params.RequestString, " "))
+	query := compressSpaces.ReplaceAllString(params.RequestString, " ")
+	if cost, ok := ctx.Value(queryCostKey).(*schema.QueryCost); ok {
+		log.Printf("[%s] query (%v): %s", requestID, cost, query)
+	} else {
+		log.Printf("[%s] query: %s", requestID, query)
+	}
 	log.Printf("[%s] variables: %v", requestID, params.VariableValues)
 	hasError := ctx.Value(hasErrorKey).(*bool)
 	*hasError = result.HasErrors()
 }
 
+// queryCostHandler rejects GraphQL operations that exceed the depth or cost limits before starting a transaction. The
+// cost of an accepted operation is added to the request context for logging.
+type queryCostHandler struct {
+	schema *graphql.Schema
+	limits schema.QueryLimits
+	next   http.Handler
+}
+
+func (h *queryCostHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	opts := readRequestOptions(r)
+	cost := schema.GetQueryCost(h.schema, opts.Query, opts.OperationName, opts.Variables)
+	if cost == nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if err := h.limits.Check(cost); err != nil {
+		log.Printf("[%s] query rejected (%v): %s", r.Context().Value(requestIdKey), cost,
+			compressSpaces.ReplaceAllString(opts.Query, " "))
+		writeError(w, err)
+		return
+	}
+	h.next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), queryCostKey, cost)))
+}
+
+// persistedQueryHandler replaces the hash of a persisted query with the query text. Requests that include the query
+// and its hash register the query, unless strict mode is enabled.
+type persistedQueryHandler struct {
+	queries *schema.PersistedQueries
+	next    http.Handler
+}
+
+func (h *persistedQueryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	opts := readRequestOptions(r)
+	query, err := h.queries.Resolve(readPersistedQueryHash(r), opts.Query)
+	if err != nil {
+		log.Printf("[%s] persisted query rejected: %v", r.Context().Value(requestIdKey), err)
+		writeError(w, err)
+		return
+	}
+	if query != opts.Query {
+		opts.Query = query
+		r = r.Clone(r.Context())
+		if r.Method == http.MethodPost {
+			body, _ := json.Marshal(opts)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.ContentLength = int64(len(body))
+			r.Header.Set("Content-Type", handler.ContentTypeJSON)
+		} else {
+			values := r.URL.Query()
+			values.Set("query", query)
+			r.URL.RawQuery = values.Encode()
+		}
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// persistedQueryExtensions is the "extensions" parameter of a request for a persisted query.
+type persistedQueryExtensions struct {
+	PersistedQuery struct {
+		Sha256Hash string `json:"sha256Hash"`
+	} `json:"persistedQuery"`
+}
+
+// readPersistedQueryHash returns the hash of the persisted query from the URL parameters or the JSON body of the
+// request. Returns a blank string if the request doesn't contain a hash.
+func readPersistedQueryHash(r *http.Request) string {
+	var extensions persistedQueryExtensions
+	if value := r.URL.Query().Get("extensions"); value != "" {
+		json.Unmarshal([]byte(value), &extensions)
+	} else if r.Method == http.MethodPost {
+		var body struct {
+			Extensions persistedQueryExtensions `json:"extensions"`
+		}
+		json.Unmarshal(readRequestBody(r), &body)
+		extensions = body.Extensions
+	}
+	return extensions.PersistedQuery.Sha256Hash
+}
+
+// readRequestOptions parses the GraphQL request and restores the body so that it can be read again by the handler.
+func readRequestOptions(r *http.Request) *handler.RequestOptions {
+	body := readRequestBody(r)
+	opts := handler.NewRequestOptions(r)
+	if body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return opts
+}
+
+// readRequestBody returns the body of the request and replaces it with a new reader so that it can be read again.
+func readRequestBody(r *http.Request) []byte {
+	if r.Body == nil {
+		return nil
+	}
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// writeError writes a GraphQL response containing the error.
+func writeError(w http.ResponseWriter, err error) {
+	// wrap the error so that the response includes its extensions
+	gqlError := gqlerrors.NewError(err.Error(), nil, "", nil, nil, err)
+	result := &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(gqlError)}}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(result)
+}
+
 func (h *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var hasError bool
 	ctx := context.WithValue(r.Context(), hasErrorKey, &hasError)
-	if user := r.Header.Get("X-User"); user != "" {
-		ctx = context.WithValue(ctx, schema.UserKey, user)
-	} else if h.defaultUser != "" {
-		ctx = context.WithValue(ctx, schema.UserKey, h.defaultUser)
-	} else {
+	user := auth.User(r.Context())
+	if user == "" {
 		http.Error(w, "Unknown user", http.StatusBadRequest)
 		return
 	}
-	// start transaction
-	tx, err := h.db.Begin()
+	ctx = context.WithValue(ctx, schema.UserKey, user)
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	// start transaction, which is rolled back if the request is cancelled or times out
+	sqlTx, err := h.db.BeginTx(ctx, nil)
 	if err != nil {
 		http.Error(w, "Error starting transaction", http.StatusInternalServerError)
 		return
 	}
 	defer func() {
 		if r := recover(); r != nil {
-			tx.Rollback()
+			sqlTx.Rollback()
 			panic(r)
 		}
 	}()
+	stats, _ := r.Context().Value(sqlStatsKey).(*database.RequestStats)
+	tx := beginRequest(sqlTx, ctx, stats)
+	defer endRequest(tx)
+	// record the changes made by the request so that they can be undone
+	if requestID, ok := r.Context().Value(requestIdKey).(string); ok {
+		beginChangeSet(tx, requestID, user)
+		defer endChangeSet(tx)
+	}
+	afterCommit := make([]func(), 0)
 	ctx = context.WithValue(ctx, schema.DbContextKey, tx)
+	ctx = context.WithValue(ctx, schema.PermissionsKey, getPermissions(tx, user))
+	ctx = context.WithValue(ctx, schema.AfterCommitKey, &afterCommit)
+	if h.store != nil {
+		ctx = context.WithValue(ctx, schema.AttachmentStoreKey, h.store)
+	}
 	h.handler.ServeHTTP(w, r.WithContext(ctx))
 	// end transaction
 	requestID := ctx.Value(requestIdKey)
-	if hasError {
+	var event *domain.ChangeEvent
+	if h.broker != nil && !hasError && ctx.Err() == nil {
+		// load the accounts of the changes before the transaction is committed
+		event = newChangeEvent(tx, fmt.Sprint(requestID), user)
+	}
+	if err := ctx.Err(); err != nil {
+		// the response has already been written, so the cancellation can only be logged
+		log.Printf("[%s] Request cancelled, rolling back: %v", requestID, err)
+		rollback(tx, requestID)
+	} else if hasError {
 		log.Printf("[%s] Query errors, rolling back", requestID)
-		if err := tx.Rollback(); err != nil {
-			log.Printf("[%s] Rollback failed: %v", requestID, err)
-		}
+		rollback(tx, requestID)
 	} else if err := tx.Commit(); err != nil {
 		log.Printf("[%s] Commit failed: %v", requestID, err)
 		http.Error(w, fmt.Sprintf("Commit failed: %v", err), http.StatusInternalServerError)
+	} else {
+		for _, fn := range afterCommit {
+			fn()
+		}
+		if event != nil {
+			h.broker.Publish(event)
+		}
+	}
+}
+
+// rollback ends the transaction. The transaction has already been rolled back if the request was cancelled.
+func rollback(tx *database.Tx, requestID interface{}) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		log.Printf("[%s] Rollback failed: %v", requestID, err)
 	}
 }
 
@@ -10,7 +10,10 @@ require (
 	github.com/felixge/httpsnoop v1.0.1
 	github.com/go-akka/configuration v0.0.0-20190919102339-a31c845c4b1b
 	github.com/go-sql-driver/mysql v1.5.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/graphql-go/graphql v0.7.9
 	github.com/graphql-go/handler v0.2.3
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/stretchr/testify v1.7.0
 )
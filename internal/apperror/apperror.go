@@ -0,0 +1,275 @@
+// Package apperror defines the errors that are reported to API clients. The errors implement
+// gqlerrors.ExtendedError so that GraphQL responses include the error code and details in the extensions.
+package apperror
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Error codes used in the "code" extension of GraphQL errors.
+const (
+	CodeNotFound                 = "NOT_FOUND"
+	CodeVersionConflict          = "VERSION_CONFLICT"
+	CodeValidation               = "VALIDATION"
+	CodeConstraint               = "CONSTRAINT"
+	CodeForbidden                = "FORBIDDEN"
+	CodeQueryLimit               = "QUERY_LIMIT"
+	CodePersistedQueryNotFound   = "PERSISTED_QUERY_NOT_FOUND"
+	CodePersistedQueryNotAllowed = "PERSISTED_QUERY_NOT_ALLOWED"
+)
+
+// entityName converts a table name to the name used in messages.
+func entityName(entity string) string {
+	return strings.ReplaceAll(entity, "_", " ")
+}
+
+// NotFoundError is returned when rows referenced by a request don't exist.
+type NotFoundError struct {
+	Entity  string
+	IDs     []interface{}
+	message string
+}
+
+// NotFound returns an error for missing rows of a table.
+func NotFound(entity string, ids ...interface{}) *NotFoundError {
+	var message string
+	switch len(ids) {
+	case 0:
+		message = fmt.Sprintf("%s(s) not found", entityName(entity))
+	case 1:
+		message = fmt.Sprintf("%s not found (%v)", entityName(entity), ids[0])
+	default:
+		message = fmt.Sprintf("%s(s) not found %v", entityName(entity), ids)
+	}
+	return &NotFoundError{Entity: entity, IDs: ids, message: message}
+}
+
+// NotFoundf returns an error for missing rows with a custom message.
+func NotFoundf(entity string, format string, args ...interface{}) *NotFoundError {
+	return &NotFoundError{Entity: entity, message: fmt.Sprintf(format, args...)}
+}
+
+func (e *NotFoundError) Error() string {
+	return e.message
+}
+
+// Extensions returns the error details for GraphQL responses.
+func (e *NotFoundError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": CodeNotFound, "entity": e.Entity}
+	if len(e.IDs) > 0 {
+		extensions["ids"] = e.IDs
+	}
+	return extensions
+}
+
+// Reasons for a version conflict, used in the "reason" extension.
+const (
+	ReasonDeleted = "DELETED"
+	ReasonChanged = "CHANGED"
+)
+
+// VersionConflictError is returned when an update or delete doesn't match the current version of a row. Reason and
+// Current are set when the current state of the row is known.
+type VersionConflictError struct {
+	Entity  string
+	ID      interface{}
+	Version interface{}
+	Reason  string
+	Current interface{}
+	message string
+}
+
+// VersionConflict returns an error for a stale version of a row.
+func VersionConflict(entity string, id int64, version int64) *VersionConflictError {
+	message := fmt.Sprintf("%s not found (%d @ %d)", entityName(entity), id, version)
+	return &VersionConflictError{Entity: entity, ID: id, Version: version, message: message}
+}
+
+// VersionConflictf returns an error for a row that has been changed, with a custom message.
+func VersionConflictf(entity string, id interface{}, format string, args ...interface{}) *VersionConflictError {
+	return &VersionConflictError{Entity: entity, ID: id, message: fmt.Sprintf(format, args...)}
+}
+
+// RowDeleted returns a version conflict for a row that no longer exists.
+func RowDeleted(entity string, id int64, version int64) *VersionConflictError {
+	message := fmt.Sprintf("%s has been deleted (%d)", entityName(entity), id)
+	return &VersionConflictError{Entity: entity, ID: id, Version: version, Reason: ReasonDeleted, message: message}
+}
+
+// RowChanged returns a version conflict for a row that has been changed by another request. current is the current
+// state of the row.
+func RowChanged(entity string, id int64, version int64, changeUser string, current interface{}) *VersionConflictError {
+	message := fmt.Sprintf("%s has been changed by %s (%d @ %d)", entityName(entity), changeUser, id, version)
+	return &VersionConflictError{Entity: entity, ID: id, Version: version, Reason: ReasonChanged, Current: current, message: message}
+}
+
+func (e *VersionConflictError) Error() string {
+	return e.message
+}
+
+// Extensions returns the error details for GraphQL responses.
+func (e *VersionConflictError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": CodeVersionConflict, "entity": e.Entity, "id": e.ID}
+	if e.Version != nil {
+		extensions["version"] = e.Version
+	}
+	if e.Reason != "" {
+		extensions["reason"] = e.Reason
+	}
+	if e.Current != nil {
+		extensions["current"] = e.Current
+	}
+	return extensions
+}
+
+// ValidationError is returned when the input of a request is invalid. Field is the path of the invalid input
+// (e.g. "details.amount") and Details maps transaction detail IDs to messages.
+type ValidationError struct {
+	Field   string
+	Details map[int64]string
+	message string
+}
+
+// Validation returns an error for an invalid input field.
+func Validation(field string, message string) *ValidationError {
+	return &ValidationError{Field: field, message: message}
+}
+
+// DetailValidation returns an error for invalid transaction details.
+func DetailValidation(details map[int64]string) *ValidationError {
+	return &ValidationError{Field: "details", Details: details, message: fmt.Sprintf("transaction detail errors: %v", details)}
+}
+
+func (e *ValidationError) Error() string {
+	return e.message
+}
+
+// Extensions returns the error details for GraphQL responses.
+func (e *ValidationError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": CodeValidation}
+	if e.Field != "" {
+		extensions["field"] = e.Field
+	}
+	if len(e.Details) > 0 {
+		details := make(map[string]string, len(e.Details))
+		for id, message := range e.Details {
+			details[fmt.Sprint(id)] = message
+		}
+		extensions["details"] = details
+	}
+	return extensions
+}
+
+// ConstraintError is returned when a change violates a database constraint (e.g. a unique key or a foreign key).
+type ConstraintError struct {
+	Constraint string
+	message    string
+}
+
+var constraintName = regexp.MustCompile("(?i)(?:key|constraint) [`'\"]([^`'\"]+)[`'\"]|constraint failed: ([^ ,]+)")
+
+// Constraint returns an error for a constraint violation reported by the database.
+func Constraint(err error) *ConstraintError {
+	message := err.Error()
+	name := ""
+	if match := constraintName.FindStringSubmatch(message); match != nil {
+		name = match[1] + match[2]
+	}
+	return &ConstraintError{Constraint: name, message: message}
+}
+
+func (e *ConstraintError) Error() string {
+	return e.message
+}
+
+// Extensions returns the error details for GraphQL responses.
+func (e *ConstraintError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": CodeConstraint, "constraint": e.Constraint}
+}
+
+// ForbiddenError is returned when the user doesn't have permission for a request, e.g. an account that hasn't been
+// granted to the user. AccountID is set for account permissions.
+type ForbiddenError struct {
+	AccountID *int64
+	message   string
+}
+
+// Forbidden returns an error for a request that requires the admin role or another permission of the user.
+func Forbidden(message string) *ForbiddenError {
+	return &ForbiddenError{message: message}
+}
+
+// AccountForbidden returns an error for an account that the user can't access.
+func AccountForbidden(accountID int64, access string) *ForbiddenError {
+	return &ForbiddenError{AccountID: &accountID, message: fmt.Sprintf("account not %s (%d)", access, accountID)}
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.message
+}
+
+// Extensions returns the error details for GraphQL responses.
+func (e *ForbiddenError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": CodeForbidden}
+	if e.AccountID != nil {
+		extensions["accountId"] = *e.AccountID
+	}
+	return extensions
+}
+
+// QueryLimitError is returned when a GraphQL query exceeds a limit, e.g. the maximum depth.
+type QueryLimitError struct {
+	Limit   string
+	Value   int
+	Max     int
+	message string
+}
+
+// QueryLimit returns an error for a query whose depth or cost exceeds the maximum.
+func QueryLimit(limit string, value int, max int) *QueryLimitError {
+	return &QueryLimitError{Limit: limit, Value: value, Max: max, message: fmt.Sprintf("query %s %d exceeds the limit of %d", limit, value, max)}
+}
+
+func (e *QueryLimitError) Error() string {
+	return e.message
+}
+
+// Extensions returns the error details for GraphQL responses.
+func (e *QueryLimitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": CodeQueryLimit, "limit": e.Limit, "value": e.Value, "max": e.Max}
+}
+
+// PersistedQueryError is returned when the query of a request is not a known persisted query.
+type PersistedQueryError struct {
+	Hash    string
+	code    string
+	message string
+}
+
+// PersistedQueryNotFound returns an error for a request that sends the hash of a query that hasn't been registered.
+// The message is the one used by automatic persisted query clients.
+func PersistedQueryNotFound(hash string) *PersistedQueryError {
+	return &PersistedQueryError{Hash: hash, code: CodePersistedQueryNotFound, message: "PersistedQueryNotFound"}
+}
+
+// PersistedQueryNotAllowed returns an error for a query that isn't in the persisted query manifest.
+func PersistedQueryNotAllowed(hash string) *PersistedQueryError {
+	return &PersistedQueryError{Hash: hash, code: CodePersistedQueryNotAllowed, message: fmt.Sprintf("query is not in the persisted query manifest (%s)", hash)}
+}
+
+func (e *PersistedQueryError) Error() string {
+	return e.message
+}
+
+// Extensions returns the error details for GraphQL responses.
+func (e *PersistedQueryError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code, "hash": e.Hash}
+}
+
+// Error is implemented by all of the errors in this package.
+type Error interface {
+	error
+	Extensions() map[string]interface{}
+}
@@ -0,0 +1,158 @@
+package apperror
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NotFound(t *testing.T) {
+	tests := []struct {
+		name       string
+		ids        []interface{}
+		message    string
+		extensions map[string]interface{}
+	}{
+		{"without IDs", nil, "import item(s) not found", map[string]interface{}{"code": CodeNotFound, "entity": "import_item"}},
+		{"with 1 ID", []interface{}{int64(42)}, "import item not found (42)",
+			map[string]interface{}{"code": CodeNotFound, "entity": "import_item", "ids": []interface{}{int64(42)}}},
+		{"with multiple IDs", []interface{}{int64(42), int64(96)}, "import item(s) not found [42 96]",
+			map[string]interface{}{"code": CodeNotFound, "entity": "import_item", "ids": []interface{}{int64(42), int64(96)}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := NotFound("import_item", test.ids...)
+
+			assert.EqualError(t, err, test.message)
+			assert.Equal(t, test.extensions, err.Extensions())
+		})
+	}
+}
+
+func Test_NotFoundf(t *testing.T) {
+	err := NotFoundf("transaction", "transaction not found in account (%d)", 42)
+
+	assert.EqualError(t, err, "transaction not found in account (42)")
+	assert.Equal(t, map[string]interface{}{"code": CodeNotFound, "entity": "transaction"}, err.Extensions())
+}
+
+func Test_VersionConflict(t *testing.T) {
+	err := VersionConflict("transaction_detail", 42, 3)
+
+	assert.EqualError(t, err, "transaction detail not found (42 @ 3)")
+	assert.Equal(t, map[string]interface{}{"code": CodeVersionConflict, "entity": "transaction_detail", "id": int64(42), "version": int64(3)},
+		err.Extensions())
+}
+
+func Test_VersionConflictf(t *testing.T) {
+	err := VersionConflictf("payee", "96", "payee %s has been changed", "96")
+
+	assert.EqualError(t, err, "payee 96 has been changed")
+	assert.Equal(t, map[string]interface{}{"code": CodeVersionConflict, "entity": "payee", "id": "96"}, err.Extensions())
+}
+
+func Test_RowDeleted(t *testing.T) {
+	err := RowDeleted("transaction", 42, 3)
+
+	assert.EqualError(t, err, "transaction has been deleted (42)")
+	assert.Equal(t, map[string]interface{}{"code": CodeVersionConflict, "entity": "transaction", "id": int64(42), "version": int64(3),
+		"reason": ReasonDeleted}, err.Extensions())
+}
+
+func Test_RowChanged(t *testing.T) {
+	current := map[string]interface{}{"id": 42, "version": 4}
+
+	err := RowChanged("transaction_detail", 42, 3, "somebody", current)
+
+	assert.EqualError(t, err, "transaction detail has been changed by somebody (42 @ 3)")
+	assert.Equal(t, map[string]interface{}{"code": CodeVersionConflict, "entity": "transaction_detail", "id": int64(42), "version": int64(3),
+		"reason": ReasonChanged, "current": current}, err.Extensions())
+}
+
+func Test_Validation(t *testing.T) {
+	err := Validation("add[0].details", "new transaction requires at least 1 detail")
+
+	assert.EqualError(t, err, "new transaction requires at least 1 detail")
+	assert.Equal(t, map[string]interface{}{"code": CodeValidation, "field": "add[0].details"}, err.Extensions())
+}
+
+func Test_DetailValidation(t *testing.T) {
+	err := DetailValidation(map[int64]string{42: "invalid amount"})
+
+	assert.EqualError(t, err, "transaction detail errors: map[42:invalid amount]")
+	assert.Equal(t, map[string]interface{}{
+		"code":    CodeValidation,
+		"field":   "details",
+		"details": map[string]string{"42": "invalid amount"},
+	}, err.Extensions())
+}
+
+func Test_Constraint(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		constraint string
+	}{
+		{"unique key", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'company.name'"}, "company.name"},
+		{"foreign key", &mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row: " +
+			"a foreign key constraint fails (`finances`.`account`, CONSTRAINT `account_company_fk` FOREIGN KEY (`company_id`))"},
+			"account_company_fk"},
+		{"sqlite unique", errors.New("UNIQUE constraint failed: company.name"), "company.name"},
+		{"unknown", errors.New("something failed"), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Constraint(test.err)
+
+			assert.EqualError(t, err, test.err.Error())
+			assert.Equal(t, map[string]interface{}{"code": CodeConstraint, "constraint": test.constraint}, err.Extensions())
+		})
+	}
+}
+
+func Test_Forbidden(t *testing.T) {
+	err := Forbidden("admin role required")
+
+	assert.EqualError(t, err, "admin role required")
+	assert.Equal(t, map[string]interface{}{"code": CodeForbidden}, err.Extensions())
+}
+
+func Test_AccountForbidden(t *testing.T) {
+	err := AccountForbidden(42, "writable")
+
+	assert.EqualError(t, err, "account not writable (42)")
+	assert.Equal(t, map[string]interface{}{"code": CodeForbidden, "accountId": int64(42)}, err.Extensions())
+}
+
+func Test_QueryLimit(t *testing.T) {
+	err := QueryLimit("depth", 12, 10)
+
+	assert.EqualError(t, err, "query depth 12 exceeds the limit of 10")
+	assert.Equal(t, map[string]interface{}{"code": CodeQueryLimit, "limit": "depth", "value": 12, "max": 10}, err.Extensions())
+}
+
+func Test_PersistedQueryNotFound(t *testing.T) {
+	err := PersistedQueryNotFound("abc123")
+
+	assert.EqualError(t, err, "PersistedQueryNotFound")
+	assert.Equal(t, map[string]interface{}{"code": CodePersistedQueryNotFound, "hash": "abc123"}, err.Extensions())
+}
+
+func Test_PersistedQueryNotAllowed(t *testing.T) {
+	err := PersistedQueryNotAllowed("abc123")
+
+	assert.EqualError(t, err, "query is not in the persisted query manifest (abc123)")
+	assert.Equal(t, map[string]interface{}{"code": CodePersistedQueryNotAllowed, "hash": "abc123"}, err.Extensions())
+}
+
+func Test_Error_formatsGraphQLExtensions(t *testing.T) {
+	err := gqlerrors.NewError("account not found (42)", nil, "", nil, nil, NotFound("account", int64(42)))
+
+	formatted := gqlerrors.FormatError(err)
+
+	assert.Equal(t, "account not found (42)", formatted.Message)
+	assert.Equal(t, CodeNotFound, formatted.Extensions["code"])
+}
@@ -0,0 +1,189 @@
+package attachment
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/auth"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+// maxMemory is the amount of an upload that is buffered in memory. The rest is written to temporary files.
+const maxMemory = 10 << 20
+
+var getPermissions = domain.GetPermissions
+var getAttachment = domain.GetAttachment
+var addAttachment = domain.AddAttachment
+
+// Handler uploads and downloads attachments. Uploads are POSTed to the root path as multipart form data with a
+// "file" part and either a "transactionId" or an "accountId". Downloads use the attachment ID as the path.
+type Handler struct {
+	db    *sql.DB
+	store *Store
+}
+
+// NewHandler creates an attachment handler. The handler must be mounted using http.StripPrefix.
+func NewHandler(db *sql.DB, store *Store) *Handler {
+	return &Handler{db: db, store: store}
+}
+
+type attachmentJSON struct {
+	ID            int64  `json:"id"`
+	TransactionID *int64 `json:"transactionId"`
+	AccountID     *int64 `json:"accountId"`
+	FileName      string `json:"fileName"`
+	ContentType   string `json:"contentType"`
+	Size          int64  `json:"size"`
+	Hash          string `json:"hash"`
+}
+
+// inTx runs fn in a database transaction. The transaction is committed if fn doesn't panic or return an error.
+func (h *Handler) inTx(user string, fn func(tx *database.Tx, permissions *domain.Permissions) error) (err error) {
+	sqlTx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	tx := database.NewTx(sqlTx)
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	if err := fn(tx, getPermissions(tx, user)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func parseOwnerID(r *http.Request, name string) (*int64, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return &id, nil
+}
+
+func (h *Handler) upload(w http.ResponseWriter, r *http.Request, user string) {
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	attachment := &table.Attachment{}
+	var err error
+	if attachment.TransactionID, err = parseOwnerID(r, "transactionId"); err == nil {
+		attachment.AccountID, err = parseOwnerID(r, "accountId")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	attachment.FileName = header.Filename
+	attachment.ContentType = header.Header.Get("Content-Type")
+	if attachment.ContentType == "" {
+		attachment.ContentType = "application/octet-stream"
+	}
+	if attachment.Hash, attachment.Size, err = h.store.Save(file); err != nil {
+		log.Printf("Error saving attachment: %v", err)
+		http.Error(w, "Error saving file", http.StatusInternalServerError)
+		return
+	}
+	err = h.inTx(user, func(tx *database.Tx, permissions *domain.Permissions) (err error) {
+		attachment, err = addAttachment(tx, attachment, user, permissions)
+		return err
+	})
+	switch err.(type) {
+	case nil:
+	case *apperror.NotFoundError:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case *apperror.ForbiddenError:
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(&attachmentJSON{
+		ID:            attachment.ID,
+		TransactionID: attachment.TransactionID,
+		AccountID:     attachment.AccountID,
+		FileName:      attachment.FileName,
+		ContentType:   attachment.ContentType,
+		Size:          attachment.Size,
+		Hash:          attachment.Hash,
+	})
+}
+
+func (h *Handler) download(w http.ResponseWriter, r *http.Request, user string) {
+	id, err := strconv.ParseInt(strings.Trim(r.URL.Path, "/"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var attachment *table.Attachment
+	err = h.inTx(user, func(tx *database.Tx, permissions *domain.Permissions) (err error) {
+		attachment, err = getAttachment(tx, id, permissions)
+		return err
+	})
+	if err != nil {
+		// don't reveal attachments on accounts the user can't read
+		log.Printf("Attachment %d not available to %s: %v", id, user, err)
+		http.NotFound(w, r)
+		return
+	}
+	file, err := h.store.Open(attachment.Hash)
+	if err != nil {
+		log.Printf("Error opening attachment %d: %v", id, err)
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	var modTime time.Time
+	if attachment.ChangeDate != nil {
+		modTime = *attachment.ChangeDate
+	}
+	http.ServeContent(w, r, attachment.FileName, modTime, file)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user := auth.User(r.Context())
+	if user == "" {
+		http.Error(w, "Unknown user", http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.download(w, r, user)
+	case http.MethodPost:
+		h.upload(w, r, user)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
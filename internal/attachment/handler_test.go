@@ -0,0 +1,213 @@
+package attachment
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/auth"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(t *testing.T) (*Handler, sqlmock.Sqlmock) {
+	db, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	return NewHandler(db, newTestStore(t)), mockDB
+}
+
+func newUploadRequest(fields map[string]string, content string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	if content != "" {
+		part, _ := writer.CreateFormFile("file", "receipt.txt")
+		part.Write([]byte(content))
+	}
+	writer.Close()
+	r := httptest.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r.WithContext(auth.WithUser(r.Context(), "somebody"))
+}
+
+func Test_Handler_ServeHTTP(t *testing.T) {
+	t.Run("requires user", func(t *testing.T) {
+		h, _ := newTestHandler(t)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/42", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+	t.Run("rejects other methods", func(t *testing.T) {
+		h, _ := newTestHandler(t)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("DELETE", "/42", nil)
+
+		h.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), "somebody")))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, HEAD, POST", w.Header().Get("Allow"))
+	})
+}
+
+func Test_Handler_upload(t *testing.T) {
+	permissions := domain.NewPermissions(true, nil)
+	t.Run("saves file and adds attachment", func(t *testing.T) {
+		h, mockDB := newTestHandler(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		getPermissionsStub := mocka.Function(t, &getPermissions, permissions)
+		defer getPermissionsStub.Restore()
+		txID := int64(96)
+		saved := &table.Attachment{ID: 42, TransactionID: &txID, FileName: "receipt.txt", Hash: contentHash}
+		addAttachmentStub := mocka.Function(t, &addAttachment, saved, nil)
+		defer addAttachmentStub.Restore()
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newUploadRequest(map[string]string{"transactionId": "96"}, "hello"))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `{"id":42,"transactionId":96,"accountId":null,"fileName":"receipt.txt","contentType":"","size":0,"hash":"`+
+			contentHash+`"}`, w.Body.String())
+		args := addAttachmentStub.GetCall(0).Arguments()
+		assert.Equal(t, &table.Attachment{TransactionID: &txID, FileName: "receipt.txt", ContentType: "application/octet-stream",
+			Size: 5, Hash: contentHash}, args[1])
+		assert.Equal(t, []interface{}{"somebody", permissions}, args[2:])
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+	t.Run("returns error from domain", func(t *testing.T) {
+		h, mockDB := newTestHandler(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		getPermissionsStub := mocka.Function(t, &getPermissions, permissions)
+		defer getPermissionsStub.Restore()
+		addAttachmentStub := mocka.Function(t, &addAttachment, nil,
+			apperror.Validation("transactionId", "attachment requires either a transaction or an account"))
+		defer addAttachmentStub.Restore()
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newUploadRequest(map[string]string{"accountId": "1"}, "hello"))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "attachment requires either a transaction or an account\n", w.Body.String())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+	t.Run("returns forbidden if not writable", func(t *testing.T) {
+		h, mockDB := newTestHandler(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		getPermissionsStub := mocka.Function(t, &getPermissions, permissions)
+		defer getPermissionsStub.Restore()
+		addAttachmentStub := mocka.Function(t, &addAttachment, nil, apperror.AccountForbidden(1, "writable"))
+		defer addAttachmentStub.Restore()
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newUploadRequest(map[string]string{"accountId": "1"}, "hello"))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "account not writable (1)\n", w.Body.String())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+	t.Run("returns not found for unknown transaction", func(t *testing.T) {
+		h, mockDB := newTestHandler(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		getPermissionsStub := mocka.Function(t, &getPermissions, permissions)
+		defer getPermissionsStub.Restore()
+		addAttachmentStub := mocka.Function(t, &addAttachment, nil, apperror.NotFound("transaction", int64(96)))
+		defer addAttachmentStub.Restore()
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newUploadRequest(map[string]string{"transactionId": "96"}, "hello"))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, "transaction not found (96)\n", w.Body.String())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+	t.Run("rejects invalid owner ID", func(t *testing.T) {
+		h, _ := newTestHandler(t)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newUploadRequest(map[string]string{"accountId": "x"}, "hello"))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "invalid accountId: x\n", w.Body.String())
+	})
+	t.Run("requires file", func(t *testing.T) {
+		h, _ := newTestHandler(t)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newUploadRequest(map[string]string{"accountId": "1"}, ""))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "missing file\n", w.Body.String())
+	})
+}
+
+func newDownloadRequest(path string) *http.Request {
+	r := httptest.NewRequest("GET", path, nil)
+	return r.WithContext(auth.WithUser(r.Context(), "somebody"))
+}
+
+func Test_Handler_download(t *testing.T) {
+	permissions := domain.NewPermissions(true, nil)
+	t.Run("returns file", func(t *testing.T) {
+		h, mockDB := newTestHandler(t)
+		h.store.Save(bytes.NewBufferString("hello"))
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		getPermissionsStub := mocka.Function(t, &getPermissions, permissions)
+		defer getPermissionsStub.Restore()
+		changeDate := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+		attachment := &table.Attachment{ID: 42, FileName: "receipt.txt", ContentType: "text/plain", Hash: contentHash,
+			Audited: table.Audited{ChangeDate: &changeDate}}
+		getAttachmentStub := mocka.Function(t, &getAttachment, attachment, nil)
+		defer getAttachmentStub.Restore()
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newDownloadRequest("/42"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "hello", w.Body.String())
+		assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+		assert.Equal(t, "attachment; filename=receipt.txt", w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "Sat, 02 Jan 2021 03:04:05 GMT", w.Header().Get("Last-Modified"))
+		assert.Equal(t, int64(42), getAttachmentStub.GetCall(0).Arguments()[1])
+		assert.Same(t, permissions, getAttachmentStub.GetCall(0).Arguments()[2])
+	})
+	t.Run("returns not found if not readable", func(t *testing.T) {
+		h, mockDB := newTestHandler(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		getPermissionsStub := mocka.Function(t, &getPermissions, permissions)
+		defer getPermissionsStub.Restore()
+		getAttachmentStub := mocka.Function(t, &getAttachment, nil, apperror.AccountForbidden(1, "readable"))
+		defer getAttachmentStub.Restore()
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newDownloadRequest("/42"))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+	t.Run("returns not found for invalid ID", func(t *testing.T) {
+		h, _ := newTestHandler(t)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, newDownloadRequest("/abc"))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
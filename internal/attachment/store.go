@@ -0,0 +1,81 @@
+package attachment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var validHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Store saves files in a directory using the SHA-256 hash of the content as the file name.
+type Store struct {
+	dir string
+}
+
+// NewStore creates a store for the directory, creating the directory if necessary.
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) path(hash string) (string, error) {
+	if !validHash.MatchString(hash) {
+		return "", fmt.Errorf("invalid file hash: %s", hash)
+	}
+	return filepath.Join(s.dir, hash[:2], hash), nil
+}
+
+// Save copies the content to the store. Returns the hash and size of the content.
+func (s *Store) Save(r io.Reader) (hash string, size int64, err error) {
+	file, err := ioutil.TempFile(s.dir, "upload-")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(file.Name())
+	digest := sha256.New()
+	size, err = io.Copy(io.MultiWriter(file, digest), r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	hash = hex.EncodeToString(digest.Sum(nil))
+	path, _ := s.path(hash)
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", 0, err
+	}
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		err = os.Rename(file.Name(), path)
+	}
+	return hash, size, err
+}
+
+// Open opens the file with the hash for reading.
+func (s *Store) Open(hash string) (*os.File, error) {
+	path, err := s.path(hash)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Remove deletes the file with the hash. Does nothing if the file doesn't exist.
+func (s *Store) Remove(hash string) error {
+	path, err := s.path(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
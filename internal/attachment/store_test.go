@@ -0,0 +1,87 @@
+package attachment
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const contentHash = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" // sha256 of "hello"
+
+func newTestStore(t *testing.T) *Store {
+	store, err := NewStore(filepath.Join(t.TempDir(), "attachments"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	return store
+}
+
+func Test_Store_Save(t *testing.T) {
+	t.Run("stores content by hash", func(t *testing.T) {
+		store := newTestStore(t)
+
+		hash, size, err := store.Save(bytes.NewBufferString("hello"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, contentHash, hash)
+		assert.Equal(t, int64(5), size)
+		content, _ := ioutil.ReadFile(filepath.Join(store.dir, "2c", contentHash))
+		assert.Equal(t, "hello", string(content))
+		files, _ := ioutil.ReadDir(store.dir)
+		assert.Len(t, files, 1)
+	})
+	t.Run("keeps existing file", func(t *testing.T) {
+		store := newTestStore(t)
+		store.Save(bytes.NewBufferString("hello"))
+
+		hash, _, err := store.Save(bytes.NewBufferString("hello"))
+
+		assert.Nil(t, err)
+		assert.Equal(t, contentHash, hash)
+		files, _ := ioutil.ReadDir(filepath.Join(store.dir, "2c"))
+		assert.Len(t, files, 1)
+	})
+}
+
+func Test_Store_Open(t *testing.T) {
+	t.Run("opens file", func(t *testing.T) {
+		store := newTestStore(t)
+		store.Save(bytes.NewBufferString("hello"))
+
+		file, err := store.Open(contentHash)
+
+		assert.Nil(t, err)
+		defer file.Close()
+		content, _ := ioutil.ReadAll(file)
+		assert.Equal(t, "hello", string(content))
+	})
+	t.Run("rejects invalid hash", func(t *testing.T) {
+		store := newTestStore(t)
+
+		_, err := store.Open("../connection.conf")
+
+		assert.EqualError(t, err, "invalid file hash: ../connection.conf")
+	})
+}
+
+func Test_Store_Remove(t *testing.T) {
+	t.Run("deletes file", func(t *testing.T) {
+		store := newTestStore(t)
+		store.Save(bytes.NewBufferString("hello"))
+
+		err := store.Remove(contentHash)
+
+		assert.Nil(t, err)
+		_, err = os.Stat(filepath.Join(store.dir, "2c", contentHash))
+		assert.True(t, os.IsNotExist(err))
+	})
+	t.Run("ignores missing file", func(t *testing.T) {
+		store := newTestStore(t)
+
+		assert.Nil(t, store.Remove(contentHash))
+	})
+}
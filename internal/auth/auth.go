@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+type contextKey string
+
+// userKey is the request context key for the authenticated user.
+const userKey = contextKey("user")
+
+// unixSocket is the trusted proxy value for connections over a unix domain socket.
+const unixSocket = "unix"
+
+var isAPIToken = domain.IsAPIToken
+var getAPITokenUser = domain.GetAPITokenUser
+
+// User returns the name of the authenticated user.
+func User(ctx context.Context) string {
+	user, _ := ctx.Value(userKey).(string)
+	return user
+}
+
+// WithUser returns a context containing the authenticated user.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// Handler authenticates requests before passing them to the next handler. Requests are authenticated using
+// a bearer token (personal API token or OIDC JWT) or the X-User header from a trusted proxy.
+type Handler struct {
+	next            http.Handler
+	db              *sql.DB
+	verifier        *TokenVerifier
+	trustedProxies  []*net.IPNet
+	trustUnixSocket bool
+}
+
+// NewHandler creates an authentication handler. OIDC tokens are rejected if verifier is nil. trustedProxies
+// contains IP addresses or CIDR blocks that are allowed to set X-User. Use "unix" to trust unix socket connections.
+func NewHandler(next http.Handler, db *sql.DB, verifier *TokenVerifier, trustedProxies []string) (*Handler, error) {
+	h := &Handler{next: next, db: db, verifier: verifier}
+	for _, proxy := range trustedProxies {
+		if proxy == unixSocket {
+			h.trustUnixSocket = true
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %s", proxy)
+		}
+		h.trustedProxies = append(h.trustedProxies, ipNet)
+	}
+	return h, nil
+}
+
+func (h *Handler) isTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		// unix socket connections don't have an IP address
+		return h.trustUnixSocket
+	}
+	for _, ipNet := range h.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) apiTokenUser(token string) (user string, err error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return getAPITokenUser(database.NewTx(tx), token), nil
+}
+
+func (h *Handler) authenticate(r *http.Request) (string, error) {
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		if !strings.HasPrefix(authorization, "Bearer ") {
+			return "", fmt.Errorf("unsupported authorization scheme")
+		}
+		token := strings.TrimSpace(authorization[len("Bearer "):])
+		if isAPIToken(token) {
+			user, err := h.apiTokenUser(token)
+			if err == nil && user == "" {
+				err = fmt.Errorf("invalid API token")
+			}
+			return user, err
+		}
+		if h.verifier == nil {
+			return "", fmt.Errorf("OIDC is not configured")
+		}
+		return h.verifier.Verify(token)
+	}
+	if user := r.Header.Get("X-User"); user != "" && h.isTrustedProxy(r.RemoteAddr) {
+		return user, nil
+	}
+	return "", fmt.Errorf("missing credentials")
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authenticate(r)
+	if err != nil {
+		log.Printf("Authentication failed from %s: %v", r.RemoteAddr, err)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="finances"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_loadKeySet(t *testing.T) {
+	t.Run("loads RSA and EC keys", func(t *testing.T) {
+		keys, err := loadKeySet("testdata/jwks.json")
+
+		assert.Nil(t, err)
+		assert.Len(t, keys, 2)
+		assert.IsType(t, &rsa.PublicKey{}, keys["rsa-key"])
+		assert.Equal(t, 65537, keys["rsa-key"].(*rsa.PublicKey).E)
+		assert.IsType(t, &ecdsa.PublicKey{}, keys["ec-key"])
+		assert.Equal(t, elliptic.P256(), keys["ec-key"].(*ecdsa.PublicKey).Curve)
+	})
+	t.Run("ignores encryption keys", func(t *testing.T) {
+		stub := mocka.Function(t, &readFile, []byte(`{"keys":[{"kid":"enc","use":"enc","kty":"RSA","n":"AQAB","e":"AQAB"}]}`), nil)
+		defer stub.Restore()
+
+		keys, err := loadKeySet("jwks.json")
+
+		assert.Nil(t, err)
+		assert.Empty(t, keys)
+	})
+	t.Run("returns read error", func(t *testing.T) {
+		expectedErr := errors.New("file not found")
+		stub := mocka.Function(t, &readFile, nil, expectedErr)
+		defer stub.Restore()
+
+		_, err := loadKeySet("jwks.json")
+
+		assert.Same(t, expectedErr, err)
+	})
+	tests := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"invalid JSON", `{"keys":`, "invalid JWKS file: unexpected end of JSON input"},
+		{"unsupported key type", `{"keys":[{"kid":"k1","kty":"oct"}]}`, "invalid key (k1): unsupported key type: oct"},
+		{"unsupported curve", `{"keys":[{"kid":"k1","kty":"EC","crv":"P-192"}]}`, "invalid key (k1): unsupported curve: P-192"},
+		{"invalid modulus", `{"keys":[{"kid":"k1","kty":"RSA","n":"***"}]}`, "invalid key (k1): illegal base64 data at input byte 0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := mocka.Function(t, &readFile, []byte(test.content), nil)
+			defer stub.Restore()
+
+			_, err := loadKeySet("jwks.json")
+
+			assert.EqualError(t, err, test.message)
+		})
+	}
+}
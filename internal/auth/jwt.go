@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256" // register the hash functions
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// allowed difference between the clocks of the issuer and the server
+const clockSkew = time.Minute
+
+var now = time.Now
+
+var algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// TokenVerifier validates OIDC bearer tokens (JWTs) using the keys of the issuer.
+type TokenVerifier struct {
+	issuer    string
+	audience  string
+	userClaim string
+	keys      keySet
+}
+
+// NewTokenVerifier creates a verifier for tokens from the issuer. The signing keys are loaded from the JWKS file.
+// If audience is not empty, then it must be included in the aud claim. The user name is taken from userClaim
+// (default: sub).
+func NewTokenVerifier(issuer string, jwksFile string, audience string, userClaim string) (*TokenVerifier, error) {
+	keys, err := loadKeySet(jwksFile)
+	if err != nil {
+		return nil, err
+	}
+	if userClaim == "" {
+		userClaim = "sub"
+	}
+	return &TokenVerifier{issuer: issuer, audience: audience, userClaim: userClaim, keys: keys}, nil
+}
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// Verify checks the signature and claims of the token and returns the user name.
+func (v *TokenVerifier) Verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed token")
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", errors.New("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed token signature")
+	}
+	if err := v.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return "", err
+	}
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", errors.New("malformed token claims")
+	}
+	return v.checkClaims(claims)
+}
+
+func (v *TokenVerifier) verifySignature(header tokenHeader, signed string, signature []byte) error {
+	hash, ok := algorithms[header.Alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm: %s", header.Alg)
+	}
+	key, ok := v.keys[header.Kid]
+	if !ok {
+		return fmt.Errorf("unknown key: %s", header.Kid)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg[:2] == "RS" && rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if header.Alg[:2] == "ES" && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if ecdsa.Verify(key, digest, r, s) {
+				return nil
+			}
+		}
+	}
+	return errors.New("invalid token signature")
+}
+
+func numericDate(claims map[string]interface{}, name string) (time.Time, bool) {
+	if value, ok := claims[name].(float64); ok {
+		return time.Unix(int64(value), 0), true
+	}
+	return time.Time{}, false
+}
+
+func (v *TokenVerifier) hasAudience(claims map[string]interface{}) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == v.audience
+	case []interface{}:
+		for _, value := range aud {
+			if value == v.audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (v *TokenVerifier) checkClaims(claims map[string]interface{}) (string, error) {
+	if claims["iss"] != v.issuer {
+		return "", errors.New("invalid token issuer")
+	}
+	currentTime := now()
+	if exp, ok := numericDate(claims, "exp"); !ok || !currentTime.Before(exp.Add(clockSkew)) {
+		return "", errors.New("token expired")
+	}
+	if nbf, ok := numericDate(claims, "nbf"); ok && currentTime.Add(clockSkew).Before(nbf) {
+		return "", errors.New("token not valid yet")
+	}
+	if v.audience != "" && !v.hasAudience(claims) {
+		return "", errors.New("invalid token audience")
+	}
+	if user, ok := claims[v.userClaim].(string); ok && user != "" {
+		return user, nil
+	}
+	return "", fmt.Errorf("token missing claim: %s", v.userClaim)
+}
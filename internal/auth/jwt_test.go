@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const testIssuer = "https://issuer.example.com"
+
+var testTime = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func readPrivateKey(t *testing.T, file string) crypto.Signer {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block.Type == "EC PRIVATE KEY" {
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key.(crypto.Signer)
+}
+
+func encodeSegment(t *testing.T, value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken creates a JWT signed with one of the keys in testdata.
+func signToken(t *testing.T, alg string, kid string, claims map[string]interface{}) string {
+	return signPayload(t, alg, kid, encodeSegment(t, claims))
+}
+
+func signPayload(t *testing.T, alg string, kid string, payload string) string {
+	var key crypto.Signer
+	if alg[:2] == "RS" {
+		key = readPrivateKey(t, "testdata/rsa_key.pem")
+	} else {
+		key = readPrivateKey(t, "testdata/ec_key.pem")
+	}
+	signed := encodeSegment(t, map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + payload
+	hasher := algorithms[alg].New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, key, algorithms[alg], digest)
+	case *ecdsa.PrivateKey:
+		r, s, _ := ecdsa.Sign(rand.Reader, key, digest)
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss": testIssuer,
+		"sub": "somebody",
+		"aud": "finances",
+		"exp": testTime.Add(time.Hour).Unix(),
+		"nbf": testTime.Unix(),
+	}
+}
+
+func newTestVerifier(t *testing.T, audience string) *TokenVerifier {
+	verifier, err := NewTokenVerifier(testIssuer, "testdata/jwks.json", audience, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return verifier
+}
+
+func Test_NewTokenVerifier(t *testing.T) {
+	t.Run("defaults user claim", func(t *testing.T) {
+		verifier, err := NewTokenVerifier(testIssuer, "testdata/jwks.json", "finances", "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, testIssuer, verifier.issuer)
+		assert.Equal(t, "finances", verifier.audience)
+		assert.Equal(t, "sub", verifier.userClaim)
+		assert.Len(t, verifier.keys, 2)
+	})
+	t.Run("returns key set error", func(t *testing.T) {
+		_, err := NewTokenVerifier(testIssuer, "testdata/missing.json", "", "email")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func Test_Verify(t *testing.T) {
+	stubNow := mocka.Function(t, &now, testTime)
+	defer stubNow.Restore()
+	verifier := newTestVerifier(t, "finances")
+
+	t.Run("accepts valid tokens", func(t *testing.T) {
+		for _, alg := range []string{"RS256", "RS384", "RS512"} {
+			user, err := verifier.Verify(signToken(t, alg, "rsa-key", validClaims()))
+
+			assert.Nil(t, err, alg)
+			assert.Equal(t, "somebody", user, alg)
+		}
+		for _, alg := range []string{"ES256", "ES384", "ES512"} {
+			user, err := verifier.Verify(signToken(t, alg, "ec-key", validClaims()))
+
+			assert.Nil(t, err, alg)
+			assert.Equal(t, "somebody", user, alg)
+		}
+	})
+	t.Run("accepts audience list", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = []string{"other", "finances"}
+
+		user, err := verifier.Verify(signToken(t, "RS256", "rsa-key", claims))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "somebody", user)
+	})
+	t.Run("uses configured user claim", func(t *testing.T) {
+		emailVerifier := &TokenVerifier{issuer: testIssuer, userClaim: "email", keys: verifier.keys}
+		claims := validClaims()
+		claims["email"] = "somebody@example.com"
+
+		user, err := emailVerifier.Verify(signToken(t, "ES256", "ec-key", claims))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "somebody@example.com", user)
+	})
+	t.Run("allows clock skew", func(t *testing.T) {
+		claims := validClaims()
+		claims["exp"] = testTime.Add(-clockSkew / 2).Unix()
+		claims["nbf"] = testTime.Add(clockSkew / 2).Unix()
+
+		_, err := verifier.Verify(signToken(t, "RS256", "rsa-key", claims))
+
+		assert.Nil(t, err)
+	})
+	t.Run("rejects tampered claims", func(t *testing.T) {
+		parts := strings.Split(signToken(t, "RS256", "rsa-key", validClaims()), ".")
+		claims := validClaims()
+		claims["sub"] = "admin"
+		parts[1] = encodeSegment(t, claims)
+
+		_, err := verifier.Verify(strings.Join(parts, "."))
+
+		assert.EqualError(t, err, "invalid token signature")
+	})
+	claimTests := []struct {
+		name    string
+		claim   string
+		value   interface{}
+		message string
+	}{
+		{"rejects wrong issuer", "iss", "https://other.example.com", "invalid token issuer"},
+		{"rejects expired token", "exp", testTime.Add(-2 * clockSkew).Unix(), "token expired"},
+		{"requires expiration", "exp", nil, "token expired"},
+		{"rejects token before nbf", "nbf", testTime.Add(2 * clockSkew).Unix(), "token not valid yet"},
+		{"rejects wrong audience", "aud", "other", "invalid token audience"},
+		{"rejects wrong audience list", "aud", []string{"other"}, "invalid token audience"},
+		{"requires user claim", "sub", "", "token missing claim: sub"},
+	}
+	for _, test := range claimTests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := validClaims()
+			if test.value == nil {
+				delete(claims, test.claim)
+			} else {
+				claims[test.claim] = test.value
+			}
+
+			_, err := verifier.Verify(signToken(t, "RS256", "rsa-key", claims))
+
+			assert.EqualError(t, err, test.message)
+		})
+	}
+	tokenTests := []struct {
+		name    string
+		token   string
+		message string
+	}{
+		{"rejects malformed token", "abc.def", "malformed token"},
+		{"rejects malformed header", "***.e30.c2ln", "malformed token header"},
+		{"rejects malformed signature", encodeSegment(t, map[string]string{"alg": "RS256"}) + ".e30.***", "malformed token signature"},
+		{"rejects unsupported algorithm", encodeSegment(t, map[string]string{"alg": "none"}) + ".e30.", "unsupported algorithm: none"},
+		{"rejects HMAC algorithm", encodeSegment(t, map[string]string{"alg": "HS256", "kid": "rsa-key"}) + ".e30.c2ln", "unsupported algorithm: HS256"},
+		{"rejects unknown key", encodeSegment(t, map[string]string{"alg": "RS256", "kid": "other"}) + ".e30.c2ln", "unknown key: other"},
+		{"rejects algorithm for wrong key type", signToken(t, "RS256", "ec-key", validClaims()), "invalid token signature"},
+		{"rejects malformed claims", signPayload(t, "RS256", "rsa-key", "***"), "malformed token claims"},
+	}
+	for _, test := range tokenTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := verifier.Verify(test.token)
+
+			assert.EqualError(t, err, test.message)
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package database
 
 import (
-	"database/sql"
 	"encoding/json"
 	"reflect"
 
@@ -9,38 +8,44 @@ import (
 )
 
 var accountType = reflect.TypeOf(table.Account{})
+var accountTable = mapTable("account", accountType)
 
 const accountSQL = `select a.*,
-	(select count(*) from transaction where account_id = a.id) transaction_count,
+	(select count(*) from transaction where account_id = a.id and trash_date is null) transaction_count,
 	(select sum(td.amount)
 	 from transaction tx
 	 join transaction_detail td on tx.id = td.transaction_id
 	 left join transaction_category tc on td.transaction_category_id = tc.id
-	 where tx.account_id = a.id and coalesce(tc.amount_type, '') != 'ASSET_VALUE') balance
+	 where tx.account_id = a.id and tx.trash_date is null and coalesce(tc.amount_type, '') != 'ASSET_VALUE') balance
 from account a`
 
-func runAccountQuery(tx *sql.Tx, query string, args ...interface{}) []*table.Account {
+func runAccountQuery(tx *Tx, query string, args ...interface{}) []*table.Account {
 	accounts := runQuery(tx, accountType, query, args...)
 	return accounts.([]*table.Account)
 }
 
 // GetAllAccounts loads all accounts.
-func GetAllAccounts(tx *sql.Tx) []*table.Account {
+func GetAllAccounts(tx *Tx) []*table.Account {
 	return runAccountQuery(tx, accountSQL)
 }
 
 // GetAccountByID returns the account with ID.
-func GetAccountByID(tx *sql.Tx, id int64) []*table.Account {
+func GetAccountByID(tx *Tx, id int64) []*table.Account {
 	return runAccountQuery(tx, accountSQL+" where a.id = ?", id)
 }
 
+// GetAccountsByIDs returns the accounts with the IDs.
+func GetAccountsByIDs(tx *Tx, ids []int64) []*table.Account {
+	return runAccountQuery(tx, accountSQL+" where @in(a.id)", int64sToJson(ids))
+}
+
 // GetAccountsByName returns the accounts having name.
-func GetAccountsByName(tx *sql.Tx, name string) []*table.Account {
+func GetAccountsByName(tx *Tx, name string) []*table.Account {
 	return runAccountQuery(tx, accountSQL+" where a.name = ?", name)
 }
 
 // GetAccountsByCompanyIDs returns the accounts for the sepcified companies.
-func GetAccountsByCompanyIDs(tx *sql.Tx, companyIDs []int64) []*table.Account {
+func GetAccountsByCompanyIDs(tx *Tx, companyIDs []int64) []*table.Account {
 	jsonIDs, _ := json.Marshal(companyIDs) // can't be cyclic, so ignoring error
-	return runAccountQuery(tx, accountSQL+" where json_contains(?, cast(company_id as json))", jsonIDs)
+	return runAccountQuery(tx, accountSQL+" where @in(company_id)", jsonIDs)
 }
@@ -1,14 +1,12 @@
 package database
 
 import (
-	"database/sql"
 	"encoding/json"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +15,7 @@ func Test_GetAllAccounts(t *testing.T) {
 		accounts := []*table.Account{{ID: 1}}
 		runQueryStub := mocka.Function(t, &runQuery, accounts)
 		defer runQueryStub.Restore()
-		sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 			result := GetAllAccounts(tx)
 
 			assert.Equal(t, []interface{}{tx, accountType, accountSQL, []interface{}(nil)}, runQueryStub.GetFirstCall().Arguments())
@@ -31,7 +29,7 @@ func Test_GetAccountByID(t *testing.T) {
 	accounts := []*table.Account{{ID: 1}}
 	runQueryStub := mocka.Function(t, &runQuery, accounts)
 	defer runQueryStub.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		result := GetAccountByID(tx, id)
 
 		assert.Equal(t, []interface{}{tx, accountType, accountSQL + " where a.id = ?", []interface{}{id}},
@@ -40,12 +38,26 @@ func Test_GetAccountByID(t *testing.T) {
 	})
 }
 
+func Test_GetAccountsByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		accounts := []*table.Account{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, accounts)
+		defer runQueryStub.Restore()
+
+		result := GetAccountsByIDs(tx, []int64{42, 96})
+
+		assert.Equal(t, []interface{}{tx, accountType, accountSQL + " where @in(a.id)", []interface{}{"[42,96]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, accounts, result)
+	})
+}
+
 func Test_GetAccountsByName(t *testing.T) {
 	name := "account name"
 	accounts := []*table.Account{{ID: 1}}
 	runQueryStub := mocka.Function(t, &runQuery, accounts)
 	defer runQueryStub.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		result := GetAccountsByName(tx, name)
 
 		assert.Equal(t, []interface{}{tx, accountType, accountSQL + " where a.name = ?", []interface{}{name}},
@@ -59,11 +71,11 @@ func Test_GetAccountsByCompanyID(t *testing.T) {
 	accounts := []*table.Account{{ID: 1}}
 	runQueryStub := mocka.Function(t, &runQuery, accounts)
 	defer runQueryStub.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		result := GetAccountsByCompanyIDs(tx, ids)
 
 		jsonIDs, _ := json.Marshal(ids)
-		assert.Equal(t, []interface{}{tx, accountType, accountSQL + " where json_contains(?, cast(company_id as json))", []interface{}{jsonIDs}},
+		assert.Equal(t, []interface{}{tx, accountType, accountSQL + " where @in(company_id)", []interface{}{jsonIDs}},
 			runQueryStub.GetFirstCall().Arguments())
 		assert.Equal(t, accounts, result)
 	})
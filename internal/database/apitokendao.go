@@ -0,0 +1,72 @@
+package database
+
+import (
+	"reflect"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var apiTokenType = reflect.TypeOf(table.APIToken{})
+var apiTokenTable = mapTable("api_token", apiTokenType)
+
+func runAPITokenQuery(tx *Tx, query string, args ...interface{}) []*table.APIToken {
+	tokens := runQuery(tx, apiTokenType, query, args...)
+	return tokens.([]*table.APIToken)
+}
+
+const userAPITokensSQL = `select t.*
+from api_token t
+join app_user u on t.user_id = u.id
+where u.name = ?
+order by t.name`
+
+// GetAPITokens returns the API tokens of the user.
+func GetAPITokens(tx *Tx, userName string) []*table.APIToken {
+	return runAPITokenQuery(tx, userAPITokensSQL, userName)
+}
+
+// GetAPITokensByIDs returns the API tokens with the IDs.
+func GetAPITokensByIDs(tx *Tx, ids []int64) []*table.APIToken {
+	return runAPITokenQuery(tx, "select * from api_token where @in(id)", int64sToJson(ids))
+}
+
+const apiTokenUserSQL = `select u.*
+from api_token t
+join app_user u on t.user_id = u.id
+where t.token_hash = ? and (t.expiry_date is null or t.expiry_date > current_date)`
+
+// GetAPITokenUser returns the owner of the unexpired token with the hash.
+func GetAPITokenUser(tx *Tx, tokenHash string) []*table.User {
+	users := runQuery(tx, userType, apiTokenUserSQL, tokenHash)
+	return users.([]*table.User)
+}
+
+var addAPITokenSQL = apiTokenTable.insertSQL("user_id", "name", "token_hash", "expiry_date")
+
+// AddAPIToken adds a token for the user and returns its ID. Returns a NotFoundError if the user doesn't exist.
+func AddAPIToken(tx *Tx, name string, tokenHash string, expiryDate interface{}, user string) (int64, error) {
+	users := GetUserByName(tx, user)
+	if len(users) == 0 {
+		return 0, apperror.NotFound("user", user)
+	}
+	id := runInsert(tx, addAPITokenSQL.sql, users[0].ID, name, tokenHash, expiryDate, user)
+	recordInsert(tx, "api_token", id, user)
+	return id, nil
+}
+
+const deleteAPITokensSQL = `delete from api_token
+where @in(id)
+and user_id = (select id from app_user where name = ?)`
+
+// DeleteAPITokens deletes tokens belonging to the user. Returns a NotFoundError if any of the tokens are not found.
+func DeleteAPITokens(tx *Tx, ids []int64, user string) (int64, error) {
+	var count int64
+	trackChanges(tx, "api_token", ids, user, func() {
+		count = runUpdate(tx, deleteAPITokensSQL, int64sToJson(ids), user)
+	})
+	if int(count) != len(ids) {
+		return 0, apperror.NotFoundf("api_token", "API token(s) not found")
+	}
+	return count, nil
+}
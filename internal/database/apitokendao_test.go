@@ -0,0 +1,122 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetAPITokens(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		tokens := []*table.APIToken{{ID: 42}}
+		runQueryStub := mocka.Function(t, &runQuery, tokens)
+		defer runQueryStub.Restore()
+
+		result := GetAPITokens(tx, "somebody")
+
+		assert.Equal(t, []interface{}{tx, apiTokenType, userAPITokensSQL, []interface{}{"somebody"}}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, tokens, result)
+	})
+}
+
+func Test_GetAPITokensByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		tokens := []*table.APIToken{{ID: 42}}
+		runQueryStub := mocka.Function(t, &runQuery, tokens)
+		defer runQueryStub.Restore()
+
+		result := GetAPITokensByIDs(tx, []int64{42})
+
+		assert.Equal(t, []interface{}{tx, apiTokenType, "select * from api_token where @in(id)", []interface{}{"[42]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, tokens, result)
+	})
+}
+
+func Test_GetAPITokenUser(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		users := []*table.User{{ID: 42}}
+		runQueryStub := mocka.Function(t, &runQuery, users)
+		defer runQueryStub.Restore()
+
+		result := GetAPITokenUser(tx, "hash")
+
+		assert.Equal(t, []interface{}{tx, userType, apiTokenUserSQL, []interface{}{"hash"}}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, users, result)
+	})
+}
+
+func Test_AddAPIToken(t *testing.T) {
+	t.Run("returns ID", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runQueryStub := mocka.Function(t, &runQuery, []*table.User{{ID: 7}})
+			defer runQueryStub.Restore()
+			runInsertStub := mocka.Function(t, &runInsert, int64(42))
+			defer runInsertStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			result, err := AddAPIToken(tx, "laptop", "hash", nil, "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, int64(42), result)
+			assert.Equal(t, []interface{}{"somebody"}, runQueryStub.GetFirstCall().Arguments()[3])
+			assert.Equal(t, sqltest.UpdateArgs(tx, addAPITokenSQL.sql, int64(7), "laptop", "hash", nil, "somebody"),
+				runInsertStub.GetFirstCall().Arguments())
+			assert.Equal(t, []historyCall{{"api_token", int64(42), "somebody"}}, history.inserts)
+		})
+	})
+	t.Run("returns error for unknown user", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runQueryStub := mocka.Function(t, &runQuery, []*table.User{})
+			defer runQueryStub.Restore()
+			runInsertStub := mocka.Function(t, &runInsert, int64(42))
+			defer runInsertStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			_, err := AddAPIToken(tx, "laptop", "hash", nil, "somebody")
+
+			assert.Equal(t, apperror.NotFound("user", "somebody"), err)
+			assert.Equal(t, "user not found (somebody)", err.Error())
+			assert.Equal(t, 0, runInsertStub.CallCount())
+			assert.Nil(t, history.inserts)
+		})
+	})
+}
+
+func Test_DeleteAPITokens(t *testing.T) {
+	t.Run("deletes tokens", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			result, err := DeleteAPITokens(tx, []int64{1, 2}, "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), result)
+			assert.Equal(t, sqltest.UpdateArgs(tx, deleteAPITokensSQL, "[1,2]", "somebody"), runUpdateStub.GetFirstCall().Arguments())
+			assert.Equal(t, []historyCall{{"api_token", []int64{1, 2}, "somebody"}}, history.changes)
+		})
+	})
+	t.Run("returns error if token not found", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			_, err := DeleteAPITokens(tx, []int64{1, 2}, "somebody")
+
+			assert.IsType(t, &apperror.NotFoundError{}, err)
+			assert.Equal(t, "API token(s) not found", err.Error())
+		})
+	})
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var attachmentType = reflect.TypeOf(table.Attachment{})
+var attachmentTable = mapTable("attachment", attachmentType)
+
+func runAttachmentQuery(tx *Tx, query string, args ...interface{}) []*table.Attachment {
+	attachments := runQuery(tx, attachmentType, query, args...)
+	return attachments.([]*table.Attachment)
+}
+
+// GetAttachment returns the attachment with the ID.
+func GetAttachment(tx *Tx, id int64) []*table.Attachment {
+	return runAttachmentQuery(tx, "select * from attachment where id = ?", id)
+}
+
+const txAttachmentsSQL = `select * from attachment
+where @in(transaction_id)
+order by transaction_id, id`
+
+// GetAttachmentsByTxIDs returns the attachments of the transactions.
+func GetAttachmentsByTxIDs(tx *Tx, txIDs []int64) []*table.Attachment {
+	return runAttachmentQuery(tx, txAttachmentsSQL, int64sToJson(txIDs))
+}
+
+const accountTxAttachmentsSQL = `select a.*
+from transaction t
+join attachment a on t.id = a.transaction_id
+where t.account_id = ? and t.trash_date is null
+order by a.transaction_id, a.id`
+
+// GetAttachmentsByTxAccountID returns the attachments of the transactions in the account.
+func GetAttachmentsByTxAccountID(tx *Tx, accountID int64) []*table.Attachment {
+	return runAttachmentQuery(tx, accountTxAttachmentsSQL, accountID)
+}
+
+// GetAccountAttachments returns the attachments that are linked to accounts.
+func GetAccountAttachments(tx *Tx) []*table.Attachment {
+	return runAttachmentQuery(tx, "select * from attachment where account_id is not null order by account_id, id")
+}
+
+// GetAttachmentsByHashes returns the attachments for the file hashes.
+func GetAttachmentsByHashes(tx *Tx, hashes []string) []*table.Attachment {
+	hashesJSON, _ := json.Marshal(hashes)
+	return runAttachmentQuery(tx, "select * from attachment where @in(hash)", string(hashesJSON))
+}
+
+var addAttachmentSQL = attachmentTable.insertSQL("transaction_id", "account_id", "file_name", "content_type", "size", "hash")
+
+// AddAttachment links a stored file to a transaction or an account and returns the ID of the attachment.
+func AddAttachment(tx *Tx, attachment *table.Attachment, user string) int64 {
+	id := runInsert(tx, addAttachmentSQL.sql, addAttachmentSQL.modelArgs(attachment, user)...)
+	recordInsert(tx, "attachment", id, user)
+	return id
+}
+
+const txAttachmentIDsSQL = "select id from attachment where @in(transaction_id)"
+
+// DeleteTransactionAttachments deletes the attachments of the transactions. The files are not removed.
+func DeleteTransactionAttachments(tx *Tx, txIDs []int64, user string) {
+	jsonIDs := int64sToJson(txIDs)
+	trackChanges(tx, "attachment", runIDQuery(tx, txAttachmentIDsSQL, jsonIDs), user, func() {
+		runUpdate(tx, "delete from attachment where @in(transaction_id)", jsonIDs)
+	})
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_attachmentQueries(t *testing.T) {
+	attachments := []*table.Attachment{{ID: 42}}
+	tests := []struct {
+		name  string
+		query func(tx *Tx) []*table.Attachment
+		sql   string
+		args  []interface{}
+	}{
+		{"GetAttachment", func(tx *Tx) []*table.Attachment { return GetAttachment(tx, 42) },
+			"select * from attachment where id = ?", []interface{}{int64(42)}},
+		{"GetAttachmentsByTxIDs", func(tx *Tx) []*table.Attachment { return GetAttachmentsByTxIDs(tx, []int64{1, 2}) },
+			txAttachmentsSQL, []interface{}{"[1,2]"}},
+		{"GetAttachmentsByTxAccountID", func(tx *Tx) []*table.Attachment { return GetAttachmentsByTxAccountID(tx, 96) },
+			accountTxAttachmentsSQL, []interface{}{int64(96)}},
+		{"GetAccountAttachments", GetAccountAttachments,
+			"select * from attachment where account_id is not null order by account_id, id", nil},
+		{"GetAttachmentsByHashes", func(tx *Tx) []*table.Attachment { return GetAttachmentsByHashes(tx, []string{"abc", "def"}) },
+			"select * from attachment where @in(hash)", []interface{}{`["abc","def"]`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+				runQueryStub := mocka.Function(t, &runQuery, attachments)
+				defer runQueryStub.Restore()
+
+				result := test.query(tx)
+
+				assert.Equal(t, attachments, result)
+				assert.Equal(t, []interface{}{tx, attachmentType, test.sql, test.args}, runQueryStub.GetCall(0).Arguments())
+			})
+		})
+	}
+}
+
+func Test_AddAttachment(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runInsertStub := mocka.Function(t, &runInsert, int64(42))
+		defer runInsertStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		txID := int64(96)
+		attachment := &table.Attachment{TransactionID: &txID, FileName: "receipt.pdf", ContentType: "application/pdf", Size: 1234, Hash: "abc"}
+
+		result := AddAttachment(tx, attachment, "somebody")
+
+		assert.Equal(t, int64(42), result)
+		assert.Equal(t, sqltest.UpdateArgs(tx, addAttachmentSQL.sql, &txID, (*int64)(nil), "receipt.pdf", "application/pdf", int64(1234), "abc", "somebody"),
+			runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"attachment", int64(42), "somebody"}}, history.inserts)
+	})
+}
+
+func Test_DeleteTransactionAttachments(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{1, 2})
+		defer runIDQueryStub.Restore()
+		runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+		defer runUpdateStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		DeleteTransactionAttachments(tx, []int64{42, 96}, "somebody")
+
+		assert.Equal(t, []interface{}{tx, txAttachmentIDsSQL, []interface{}{"[42,96]"}}, runIDQueryStub.GetCall(0).Arguments())
+		assert.Equal(t, sqltest.UpdateArgs(tx, "delete from attachment where @in(transaction_id)", "[42,96]"),
+			runUpdateStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"attachment", []int64{1, 2}, "somebody"}}, history.changes)
+	})
+}
@@ -1,20 +1,66 @@
 package database
 
 import (
-	"database/sql"
 	"reflect"
 
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
 var categoryType = reflect.TypeOf(table.Category{})
+var categoryTable = mapTable("transaction_category", categoryType)
 
-const categorySQL = `select c.*,
-	(select count(distinct transaction_id) from transaction_detail where transaction_category_id = c.id) transaction_count
-from transaction_category c`
+const categorySQL = `select c.* from transaction_category c`
 
 // GetAllCategories loads all transaction categories.
-func GetAllCategories(tx *sql.Tx) []*table.Category {
+func GetAllCategories(tx *Tx) []*table.Category {
 	categories := runQuery(tx, categoryType, categorySQL)
 	return categories.([]*table.Category)
 }
+
+// GetCategoriesByIDs returns the transaction categories with the IDs.
+func GetCategoriesByIDs(tx *Tx, ids []int64) []*table.Category {
+	categories := runQuery(tx, categoryType, categorySQL+" where @in(c.id)", int64sToJson(ids))
+	return categories.([]*table.Category)
+}
+
+// GetCategoriesByParentIDs returns the child categories of the parent categories.
+func GetCategoriesByParentIDs(tx *Tx, parentIDs []int64) []*table.Category {
+	categories := runQuery(tx, categoryType, categorySQL+" where @in(c.parent_id)", int64sToJson(parentIDs))
+	return categories.([]*table.Category)
+}
+
+const categoryAmountsSQL = `select td.transaction_category_id id, a.currency_id, sum(td.amount) amount
+from transaction_detail td
+join transaction t on td.transaction_id = t.id
+join account a on t.account_id = a.id
+where @in(td.transaction_category_id) and t.trash_date is null
+and (? is null or @in(t.account_id))
+group by td.transaction_category_id, a.currency_id`
+
+// GetCategoryAmounts returns the total of the details of each category for each account currency, limited to the
+// accounts if accountIDs is not nil.
+func GetCategoryAmounts(tx *Tx, categoryIDs []int64, accountIDs []int64) []*table.CurrencyAmount {
+	return runAmountQuery(tx, categoryAmountsSQL, categoryIDs, accountIDs)
+}
+
+const categoryTransactionCountsSQL = `select td.transaction_category_id id, count(distinct td.transaction_id) transaction_count
+from transaction_detail td
+join transaction t on td.transaction_id = t.id
+where @in(td.transaction_category_id) and t.trash_date is null
+and (? is null or @in(t.account_id))
+group by td.transaction_category_id`
+
+// GetCategoryTransactionCounts returns the number of transactions with details in each category, limited to the
+// accounts if accountIDs is not nil.
+func GetCategoryTransactionCounts(tx *Tx, categoryIDs []int64, accountIDs []int64) []*table.TransactionCount {
+	return runCountQuery(tx, categoryTransactionCountsSQL, categoryIDs, accountIDs)
+}
+
+var addCategorySQL = categoryTable.insertSQL("code", "description", "amount_type", "parent_id", "security", "income", "asset_exchange")
+
+// AddCategory adds a transaction category and returns its ID.
+func AddCategory(tx *Tx, category *table.Category, user string) int64 {
+	id := runInsert(tx, addCategorySQL.sql, addCategorySQL.modelArgs(category, user)...)
+	recordInsert(tx, "transaction_category", id, user)
+	return id
+}
@@ -1,7 +1,6 @@
 package database
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -15,10 +14,85 @@ func Test_GetAllCategories(t *testing.T) {
 	categories := []*table.Category{{ID: 1}}
 	runQueryStub := mocka.Function(t, &runQuery, categories)
 	defer runQueryStub.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		result := GetAllCategories(tx)
 
 		assert.Equal(t, []interface{}{tx, categoryType, categorySQL, []interface{}(nil)}, runQueryStub.GetFirstCall().Arguments())
 		assert.Equal(t, categories, result)
 	})
 }
+
+func Test_GetCategoriesByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		categories := []*table.Category{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, categories)
+		defer runQueryStub.Restore()
+
+		result := GetCategoriesByIDs(tx, []int64{42, 96})
+
+		assert.Equal(t, []interface{}{tx, categoryType, categorySQL + " where @in(c.id)", []interface{}{"[42,96]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, categories, result)
+	})
+}
+
+func Test_GetCategoriesByParentIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		categories := []*table.Category{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, categories)
+		defer runQueryStub.Restore()
+
+		result := GetCategoriesByParentIDs(tx, []int64{42, 96})
+
+		assert.Equal(t, []interface{}{tx, categoryType, categorySQL + " where @in(c.parent_id)", []interface{}{"[42,96]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, categories, result)
+	})
+}
+
+func Test_GetCategoryAmounts(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		amounts := []*table.CurrencyAmount{{ID: 42, CurrencyID: 1, Amount: 12.34}}
+		runQueryStub := mocka.Function(t, &runQuery, amounts)
+		defer runQueryStub.Restore()
+
+		result := GetCategoryAmounts(tx, []int64{42}, nil)
+
+		assert.Equal(t, []interface{}{tx, currencyAmountType, categoryAmountsSQL, []interface{}{"[42]", nil, nil}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, amounts, result)
+	})
+}
+
+func Test_AddCategory(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runInsertStub := mocka.Function(t, &runInsert, int64(42))
+		defer runInsertStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+		parentID := int64(1)
+		yes, no := table.YesNo('Y'), table.YesNo('N')
+		category := &table.Category{Code: "Buy", AmountType: "DEBIT_DEPOSIT", ParentID: &parentID, Security: &yes, Income: &no, AssetExchange: &yes}
+
+		result := AddCategory(tx, category, "somebody")
+
+		assert.Equal(t, int64(42), result)
+		assert.Equal(t, sqltest.UpdateArgs(tx, addCategorySQL.sql, "Buy", (*string)(nil), "DEBIT_DEPOSIT", &parentID, &yes, &no, &yes, "somebody"),
+			runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"transaction_category", int64(42), "somebody"}}, history.inserts)
+	})
+}
+
+func Test_GetCategoryTransactionCounts(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		counts := []*table.TransactionCount{{ID: 42, Count: 3}}
+		runQueryStub := mocka.Function(t, &runQuery, counts)
+		defer runQueryStub.Restore()
+
+		result := GetCategoryTransactionCounts(tx, []int64{42, 96}, []int64{1})
+
+		assert.Equal(t, []interface{}{tx, transactionCountType, categoryTransactionCountsSQL, []interface{}{"[42,96]", "[1]", "[1]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, counts, result)
+	})
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var changeSetType = reflect.TypeOf(table.ChangeSet{})
+var changeSetTable = mapTable("change_set", changeSetType)
+
+type changeSet struct {
+	id      string
+	user    string
+	saved   bool
+	changes []*table.ChangeHistory
+}
+
+// BeginChangeSet records the history written by the transaction as a change set. The change set is only saved if
+// the transaction changes a row.
+func BeginChangeSet(tx *Tx, id string, user string) {
+	tx.changes = &changeSet{id: id, user: user}
+}
+
+// EndChangeSet stops recording the history written by the transaction.
+func EndChangeSet(tx *Tx) {
+	tx.changes = nil
+}
+
+const insertChangeSetSQL = `insert into change_set (id, change_date, change_user) values (?, current_timestamp, ?)`
+
+// getChangeSetID returns the ID of the change set for the transaction, saving it if necessary.
+func getChangeSetID(tx *Tx) interface{} {
+	changes := tx.changes
+	if changes == nil {
+		return nil
+	}
+	if !changes.saved {
+		runUpdate(tx, insertChangeSetSQL, changes.id, changes.user)
+		changes.saved = true
+	}
+	return changes.id
+}
+
+// addChange adds the history to the change set of the transaction.
+func addChange(tx *Tx, history *table.ChangeHistory) {
+	if changes := tx.changes; changes != nil {
+		changes.changes = append(changes.changes, history)
+	}
+}
+
+// GetChanges returns the history that has been written by the transaction's change set, in order. Returns nil if the
+// transaction doesn't have a change set.
+func GetChanges(tx *Tx) []*table.ChangeHistory {
+	if changes := tx.changes; changes != nil {
+		return changes.changes
+	}
+	return nil
+}
+
+// GetChangeSet returns the change set with the ID.
+func GetChangeSet(tx *Tx, id string) []*table.ChangeSet {
+	changeSets := runQuery(tx, changeSetType, "select * from change_set where id = ?", id)
+	return changeSets.([]*table.ChangeSet)
+}
+
+// GetChangeSetHistory returns the changes in the change set, most recent first.
+func GetChangeSetHistory(tx *Tx, id string) []*table.ChangeHistory {
+	history := runQuery(tx, historyType, "select * from change_history where change_set_id = ? order by id desc", id)
+	return history.([]*table.ChangeHistory)
+}
+
+// IsCurrentVersion returns true if the row has not been changed since the history was recorded.
+func IsCurrentVersion(tx *Tx, history *table.ChangeHistory) bool {
+	current, exists := loadImages(tx, history.Entity, historyKeys(history.Entity, history.EntityKey))[history.EntityKey]
+	if history.AfterImage == nil || !exists {
+		return history.AfterImage == nil && !exists
+	}
+	if version := imageVersion(*history.AfterImage); version != nil {
+		return version == imageVersion(current)
+	}
+	return current == *history.AfterImage
+}
+
+// parseImageValues returns the column values of an image in column order.
+func parseImageValues(image string, skip ...string) (columns []string, values []interface{}) {
+	decoder := json.NewDecoder(bytes.NewBufferString(image))
+	decoder.UseNumber()
+	valueMap := make(map[string]interface{})
+	if err := decoder.Decode(&valueMap); err != nil {
+		panic(err)
+	}
+	for _, column := range skip {
+		delete(valueMap, column)
+	}
+	for column := range valueMap {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	values = make([]interface{}, len(columns))
+	for i, column := range columns {
+		if number, ok := valueMap[column].(json.Number); ok {
+			values[i] = number.String()
+		} else {
+			values[i] = valueMap[column]
+		}
+	}
+	return columns, values
+}
+
+func restoreInsert(tx *Tx, tableName string, image string, user string) {
+	columns, values := parseImageValues(image, "change_user", "change_date")
+	sql := fmt.Sprintf("insert into %s (%s, change_date, change_user) values (%scurrent_timestamp, ?)",
+		tableName, strings.Join(columns, ", "), strings.Repeat("?, ", len(columns)))
+	runUpdate(tx, sql, append(values, user)...)
+}
+
+func restoreUpdate(tx *Tx, tableName string, key string, image string, user string) {
+	keyColumn := historyKeyColumn(tableName)
+	columns, values := parseImageValues(image, keyColumn, "change_user", "change_date", "version")
+	sql := fmt.Sprintf("update %s set %s = ?, change_date = current_timestamp, change_user = ?, version = version+1 where %s = ?",
+		tableName, strings.Join(columns, " = ?, "), keyColumn)
+	runUpdate(tx, sql, append(values, user, key)...)
+}
+
+// UndoChange restores the row to its state before the change.
+func UndoChange(tx *Tx, history *table.ChangeHistory, user string) {
+	trackChanges(tx, history.Entity, historyKeys(history.Entity, history.EntityKey), user, func() {
+		switch history.Action {
+		case table.HistoryInsert:
+			runUpdate(tx, fmt.Sprintf("delete from %s where %s = ?", history.Entity, historyKeyColumn(history.Entity)), history.EntityKey)
+		case table.HistoryUpdate:
+			restoreUpdate(tx, history.Entity, history.EntityKey, *history.BeforeImage, user)
+		case table.HistoryDelete:
+			restoreInsert(tx, history.Entity, *history.BeforeImage, user)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getChangeSetID(t *testing.T) {
+	t.Run("returns nil without change set", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			assert.Nil(t, getChangeSetID(tx))
+		})
+	})
+	t.Run("saves change set once", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			BeginChangeSet(tx, "123:4", "somebody")
+			defer EndChangeSet(tx)
+
+			assert.Equal(t, "123:4", getChangeSetID(tx))
+			assert.Equal(t, "123:4", getChangeSetID(tx))
+
+			assert.Equal(t, 1, runUpdateStub.CallCount())
+			assert.Equal(t, sqltest.UpdateArgs(tx, insertChangeSetSQL, "123:4", "somebody"), runUpdateStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("returns nil after end of change set", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			BeginChangeSet(tx, "123:4", "somebody")
+			EndChangeSet(tx)
+
+			assert.Nil(t, getChangeSetID(tx))
+		})
+	})
+}
+
+func Test_GetChanges(t *testing.T) {
+	t.Run("returns nil without change set", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			addChange(tx, &table.ChangeHistory{Entity: "payee"})
+
+			assert.Nil(t, GetChanges(tx))
+		})
+	})
+	t.Run("returns changes in order", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			BeginChangeSet(tx, "123:4", "somebody")
+			defer EndChangeSet(tx)
+			changes := []*table.ChangeHistory{{Entity: "payee"}, {Entity: "transaction"}}
+
+			addChange(tx, changes[0])
+			addChange(tx, changes[1])
+
+			assert.Equal(t, changes, GetChanges(tx))
+		})
+	})
+}
+
+func Test_GetChangeSet(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		changeSets := []*table.ChangeSet{{ID: "123:4"}}
+		runQueryStub := mocka.Function(t, &runQuery, changeSets)
+		defer runQueryStub.Restore()
+
+		result := GetChangeSet(tx, "123:4")
+
+		assert.Equal(t, changeSets, result)
+		assert.Equal(t, []interface{}{tx, changeSetType, "select * from change_set where id = ?", []interface{}{"123:4"}},
+			runQueryStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_GetChangeSetHistory(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		history := []*table.ChangeHistory{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, history)
+		defer runQueryStub.Restore()
+
+		result := GetChangeSetHistory(tx, "123:4")
+
+		assert.Equal(t, history, result)
+		assert.Equal(t, []interface{}{tx, historyType, "select * from change_history where change_set_id = ? order by id desc", []interface{}{"123:4"}},
+			runQueryStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_IsCurrentVersion(t *testing.T) {
+	image := func(value string) *string { return &value }
+	tests := []struct {
+		name    string
+		after   *string
+		current map[string]string
+		result  bool
+	}{
+		{"returns true for same version", image(`{"id":42,"version":1}`), map[string]string{"42": `{"id":42,"version":1}`}, true},
+		{"returns false for new version", image(`{"id":42,"version":1}`), map[string]string{"42": `{"id":42,"version":2}`}, false},
+		{"returns false for deleted row", image(`{"id":42,"version":1}`), map[string]string{}, false},
+		{"returns true for deleted row", nil, map[string]string{}, true},
+		{"returns false for restored row", nil, map[string]string{"42": `{"id":42,"version":1}`}, false},
+		{"compares images without version", image(`{"id":42,"value":"1"}`), map[string]string{"42": `{"id":42,"value":"2"}`}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+				loadImagesStub := mocka.Function(t, &loadImages, test.current)
+				defer loadImagesStub.Restore()
+
+				result := IsCurrentVersion(tx, &table.ChangeHistory{Entity: "company", EntityKey: "42", AfterImage: test.after})
+
+				assert.Equal(t, test.result, result)
+				assert.Equal(t, []interface{}{tx, "company", []int64{42}}, loadImagesStub.GetCall(0).Arguments())
+			})
+		})
+	}
+}
+
+func Test_UndoChange(t *testing.T) {
+	before := `{"change_date":"2021-03-04 05:06:07","change_user":"other","id":42,"name":"old","amount":12.34,"version":1}`
+	tests := []struct {
+		name   string
+		action string
+		sql    string
+		args   []interface{}
+	}{
+		{"deletes inserted row", table.HistoryInsert, "delete from company where id = ?", []interface{}{"42"}},
+		{"restores updated row", table.HistoryUpdate,
+			"update company set amount = ?, name = ?, change_date = current_timestamp, change_user = ?, version = version+1 where id = ?",
+			[]interface{}{"12.34", "old", "somebody", "42"}},
+		{"inserts deleted row", table.HistoryDelete,
+			"insert into company (amount, id, name, version, change_date, change_user) values (?, ?, ?, ?, current_timestamp, ?)",
+			[]interface{}{"12.34", "42", "old", "1", "somebody"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+				history := mockHistory()
+				defer history.restore()
+				runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+				defer runUpdateStub.Restore()
+
+				UndoChange(tx, &table.ChangeHistory{Entity: "company", EntityKey: "42", Action: test.action, BeforeImage: &before}, "somebody")
+
+				assert.Equal(t, []historyCall{{"company", []int64{42}, "somebody"}}, history.changes)
+				assert.Equal(t, sqltest.UpdateArgs(tx, test.sql, test.args...), runUpdateStub.GetCall(0).Arguments())
+			})
+		})
+	}
+}
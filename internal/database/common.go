@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
 )
 
 func intsToJson(values []int) string {
@@ -15,49 +18,101 @@ func int64sToJson(values []int64) string {
 	return strings.Replace(fmt.Sprint(values), " ", ",", -1)
 }
 
-type tableModel interface {
-	PtrTo(column string) interface{}
+// accountsArg returns the query argument for a (? is null or @in(t.account_id)) condition, which doesn't limit the
+// accounts if accountIDs is nil.
+func accountsArg(accountIDs []int64) interface{} {
+	if accountIDs == nil {
+		return nil
+	}
+	return int64sToJson(accountIDs)
 }
 
 // returns a slice of model pointers
-var runQuery = func(tx *sql.Tx, modelType reflect.Type, sql string, args ...interface{}) interface{} {
-	rows, err := tx.Query(sql, args...)
+var runQuery = func(tx *Tx, modelType reflect.Type, sql string, args ...interface{}) interface{} {
+	query, args := currentDialect.bind(sql, args)
+	rows := queryRows(tx, query, args)
+	defer rows.Close()
+	columnNames, err := rows.Columns()
 	if err != nil {
 		panic(err)
 	}
-	columns, err := rows.Columns()
-	if err != nil {
-		panic(err)
+	mapping := table.MappingOf(modelType)
+	columns := make([]*table.Column, len(columnNames))
+	for i, name := range columnNames {
+		if columns[i] = mapping.Column(name); columns[i] == nil {
+			panic(fmt.Errorf("%s does not have a field for column %s", modelType.Name(), name))
+		}
 	}
 	models := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(modelType)), 0, 0)
 	for rows.Next() {
-		m := reflect.New(modelType).Interface()
+		m := reflect.New(modelType)
 		values := make([]interface{}, len(columns))
 		for i, column := range columns {
-			values[i] = m.(tableModel).PtrTo(column)
+			values[i] = currentDialect.scanTarget(column.PtrTo(m))
 		}
 		if err = rows.Scan(values...); err != nil {
 			panic(err)
 		}
-		models = reflect.Append(models, reflect.ValueOf(m))
+		models = reflect.Append(models, m)
+	}
+	if err := rows.Err(); err != nil {
+		panic(err)
 	}
 	return models.Interface()
 }
 
-func execUpdate(tx *sql.Tx, sql string, args ...interface{}) sql.Result {
-	stmt, err := tx.Prepare(sql)
-	if err != nil {
+// returns the values of the first column as int64s
+var runIDQuery = func(tx *Tx, sql string, args ...interface{}) []int64 {
+	query, args := currentDialect.bind(sql, args)
+	rows := queryRows(tx, query, args)
+	defer rows.Close()
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			panic(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
 		panic(err)
 	}
-	defer stmt.Close()
-	result, err := stmt.Exec(args...)
+	return ids
+}
+
+var transactionCountType = reflect.TypeOf(table.TransactionCount{})
+
+// runCountQuery returns the transaction counts for the parent IDs, limited to the accounts if accountIDs is not nil.
+func runCountQuery(tx *Tx, query string, parentIDs []int64, accountIDs []int64) []*table.TransactionCount {
+	accounts := accountsArg(accountIDs)
+	counts := runQuery(tx, transactionCountType, query, int64sToJson(parentIDs), accounts, accounts)
+	return counts.([]*table.TransactionCount)
+}
+
+// updateError returns a ConstraintError if err is a constraint violation. Otherwise, it returns err.
+func updateError(err error) error {
+	if currentDialect.isConstraintError(err) {
+		return apperror.Constraint(err)
+	}
+	return err
+}
+
+func execUpdate(tx *Tx, sql string, args ...interface{}) sql.Result {
+	query, args := currentDialect.bind(sql, args)
+	stmt, release := prepare(tx, query)
+	defer release()
+	timer := startStatement(tx, query, args)
+	result, err := stmt.ExecContext(tx.ctx, args...)
 	if err != nil {
-		panic(err)
+		timer.end(0)
+		panic(updateError(err))
 	}
+	count, _ := result.RowsAffected()
+	timer.end(count)
 	return result
 }
 
-var runUpdate = func(tx *sql.Tx, sql string, args ...interface{}) int64 {
+var runUpdate = func(tx *Tx, sql string, args ...interface{}) int64 {
 	count, err := execUpdate(tx, sql, args...).RowsAffected()
 	if err != nil {
 		panic(err)
@@ -65,7 +120,25 @@ var runUpdate = func(tx *sql.Tx, sql string, args ...interface{}) int64 {
 	return count
 }
 
-var runInsert = func(tx *sql.Tx, sql string, args ...interface{}) int64 {
+// insertReturningID runs an insert with a returning clause for databases that don't support LastInsertId.
+func insertReturningID(tx *Tx, sql string, args ...interface{}) int64 {
+	query, args := currentDialect.bind(sql+" returning id", args)
+	stmt, release := prepare(tx, query)
+	defer release()
+	var id int64
+	timer := startStatement(tx, query, args)
+	if err := stmt.QueryRowContext(tx.ctx, args...).Scan(&id); err != nil {
+		timer.end(0)
+		panic(updateError(err))
+	}
+	timer.end(1)
+	return id
+}
+
+var runInsert = func(tx *Tx, sql string, args ...interface{}) int64 {
+	if currentDialect.returningID {
+		return insertReturningID(tx, sql, args...)
+	}
 	id, err := execUpdate(tx, sql, args...).LastInsertId()
 	if err != nil {
 		panic(err)
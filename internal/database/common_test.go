@@ -1,14 +1,17 @@
 package database
 
 import (
-	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database/table"
 	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,9 +26,9 @@ func Test_intsToJson(t *testing.T) {
 func Test_runQuery_populatesModel(t *testing.T) {
 	name := "the company"
 	query := "select * from company where name = ?"
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		rows := sqltest.MockRows("id", "name").AddRow(42, name)
-		mock.ExpectQuery(query).WithArgs(name).WillReturnRows(rows)
+		mock.ExpectQuery(currentDialect.expand(query)).WithArgs(name).WillReturnRows(rows)
 
 		result := runQuery(tx, companyType, query, name)
 
@@ -36,9 +39,9 @@ func Test_runQuery_populatesModel(t *testing.T) {
 func Test_runQuery_panicsForQueryError(t *testing.T) {
 	name := "the company"
 	query := "select * from company where name = ?"
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		expectedErr := errors.New("query error")
-		mock.ExpectQuery(query).WithArgs(name).WillReturnError(expectedErr)
+		mock.ExpectQuery(currentDialect.expand(query)).WithArgs(name).WillReturnError(expectedErr)
 		defer func() {
 			if err := recover(); err != nil {
 				assert.Same(t, expectedErr, err)
@@ -51,12 +54,41 @@ func Test_runQuery_panicsForQueryError(t *testing.T) {
 	})
 }
 
+func Test_runIDQuery(t *testing.T) {
+	query := "select id from company where name = ?"
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		rows := sqltest.MockRows("id").AddRow(42).AddRow(96)
+		mock.ExpectQuery(currentDialect.expand(query)).WithArgs("x").WillReturnRows(rows)
+
+		result := runIDQuery(tx, query, "x")
+
+		assert.Equal(t, []int64{42, 96}, result)
+	})
+}
+
+func Test_runIDQuery_panicsForQueryError(t *testing.T) {
+	query := "select id from company where name = ?"
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		expectedErr := errors.New("query error")
+		mock.ExpectQuery(currentDialect.expand(query)).WithArgs("x").WillReturnError(expectedErr)
+		defer func() {
+			if err := recover(); err != nil {
+				assert.Same(t, expectedErr, err)
+			} else {
+				assert.Fail(t, "expected an error")
+			}
+		}()
+
+		runIDQuery(tx, query, "x")
+	})
+}
+
 func Test_runUpdate_closesStatement(t *testing.T) {
 	query := "update company set name = ? where id = ?"
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		expectedArgs := []driver.Value{"new name", 42}
 		rs := sqlmock.NewResult(-1, 1)
-		mockDB.ExpectPrepare(query).WillBeClosed().ExpectExec().WithArgs(expectedArgs...).WillReturnResult(rs)
+		mockDB.ExpectPrepare(currentDialect.expand(query)).WillBeClosed().ExpectExec().WithArgs(expectedArgs...).WillReturnResult(rs)
 
 		rowCount := runUpdate(tx, query, "new name", 42)
 
@@ -67,9 +99,9 @@ func Test_runUpdate_closesStatement(t *testing.T) {
 
 func Test_runUpdate_panicsForPrepareError(t *testing.T) {
 	query := "update company set name = ? where id = ?"
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		expectedErr := errors.New("query error")
-		mockDB.ExpectPrepare(query).WillReturnError(expectedErr)
+		mockDB.ExpectPrepare(currentDialect.expand(query)).WillReturnError(expectedErr)
 		defer func() {
 			assert.Nil(t, mockDB.ExpectationsWereMet())
 			if err := recover(); err != nil {
@@ -85,10 +117,10 @@ func Test_runUpdate_panicsForPrepareError(t *testing.T) {
 
 func Test_runUpdate_panicsForQueryError(t *testing.T) {
 	query := "update company set name = ? where id = ?"
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		expectedArgs := []driver.Value{"new name", 42}
 		expectedErr := errors.New("query error")
-		mockDB.ExpectPrepare(query).WillBeClosed().ExpectExec().WithArgs(expectedArgs...).WillReturnError(expectedErr)
+		mockDB.ExpectPrepare(currentDialect.expand(query)).WillBeClosed().ExpectExec().WithArgs(expectedArgs...).WillReturnError(expectedErr)
 		defer func() {
 			assert.Nil(t, mockDB.ExpectationsWereMet())
 			if err := recover(); err != nil {
@@ -102,16 +134,63 @@ func Test_runUpdate_panicsForQueryError(t *testing.T) {
 	})
 }
 
+// driver errors for a unique key violation and the constraint name that can be parsed from the message
+var uniqueKeyErrors = map[string]struct {
+	err        error
+	constraint string
+}{
+	"mysql":    {&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'new name' for key 'payee_ak'"}, "payee_ak"},
+	"sqlite3":  {sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, ""},
+	"postgres": {&pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "payee_ak"`}, "payee_ak"},
+}
+
+func Test_runUpdate_panicsWithConstraintError(t *testing.T) {
+	query := "update payee set name = ? where id = ?"
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		expectedArgs := []driver.Value{"new name", 42}
+		dbErr := uniqueKeyErrors[currentDialect.name].err
+		mockDB.ExpectPrepare(currentDialect.expand(query)).WillBeClosed().ExpectExec().WithArgs(expectedArgs...).WillReturnError(dbErr)
+		defer func() {
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+			if err := recover(); err != nil {
+				assert.Equal(t, apperror.Constraint(dbErr), err)
+				assert.Equal(t, uniqueKeyErrors[currentDialect.name].constraint, err.(*apperror.ConstraintError).Constraint)
+			} else {
+				assert.Fail(t, "expected an error")
+			}
+		}()
+
+		runUpdate(tx, query, "new name", 42)
+	})
+}
+
+// expectInsert adds the expectation for an insert that gets the ID from LastInsertId or a returning clause.
+func expectInsert(mockDB sqlmock.Sqlmock, query string, id int64, err error, args ...driver.Value) {
+	if currentDialect.returningID {
+		prepare := mockDB.ExpectPrepare(currentDialect.expand(query + " returning id"))
+		if err != nil {
+			prepare.ExpectQuery().WithArgs(args...).WillReturnError(err)
+		} else {
+			prepare.ExpectQuery().WithArgs(args...).WillReturnRows(sqltest.MockRows("id").AddRow(id))
+		}
+		prepare.WillBeClosed()
+	} else {
+		prepare := mockDB.ExpectPrepare(currentDialect.expand(query))
+		if err != nil {
+			prepare.ExpectExec().WithArgs(args...).WillReturnError(err)
+		} else {
+			prepare.ExpectExec().WithArgs(args...).WillReturnResult(sqlmock.NewResult(id, 1))
+		}
+		prepare.WillBeClosed()
+	}
+}
+
 func Test_runInsert_returnsID(t *testing.T) {
 	id := int64(42)
 	name := "the company"
 	query := "insert into company (name) values(?)"
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-		prepare := mockDB.ExpectPrepare(query)
-		prepare.ExpectExec().
-			WithArgs(name).
-			WillReturnResult(sqlmock.NewResult(id, 1))
-		prepare.WillBeClosed()
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		expectInsert(mockDB, query, id, nil, name)
 
 		result := runInsert(tx, query, name)
 
@@ -123,8 +202,12 @@ func Test_runInsert_returnsID(t *testing.T) {
 func Test_runInsert_panicsForPrepareError(t *testing.T) {
 	expectedErr := errors.New("prepare failed")
 	query := "insert into company (name) values(?)"
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-		mockDB.ExpectPrepare(query).WillReturnError(expectedErr)
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		if currentDialect.returningID {
+			mockDB.ExpectPrepare(currentDialect.expand(query + " returning id")).WillReturnError(expectedErr)
+		} else {
+			mockDB.ExpectPrepare(currentDialect.expand(query)).WillReturnError(expectedErr)
+		}
 		defer func() {
 			assert.Nil(t, mockDB.ExpectationsWereMet())
 			if err := recover(); err != nil {
@@ -142,10 +225,8 @@ func Test_runInsert_panicsForExecError(t *testing.T) {
 	expectedErr := errors.New("query failed")
 	name := "the company"
 	query := "insert into company (name) values(?)"
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-		prepare := mockDB.ExpectPrepare(query)
-		prepare.ExpectExec().WithArgs(name).WillReturnError(expectedErr)
-		prepare.WillBeClosed()
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		expectInsert(mockDB, query, 0, expectedErr, name)
 		defer func() {
 			assert.Nil(t, mockDB.ExpectationsWereMet())
 			if err := recover(); err != nil {
@@ -1,14 +1,13 @@
 package database
 
 import (
-	"database/sql"
 	"encoding/json"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,7 +15,7 @@ func Test_GetAllCompanies(t *testing.T) {
 	companies := []*table.Company{{ID: 1}}
 	runQueryStub := mocka.Function(t, &runQuery, companies)
 	defer runQueryStub.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		result := GetAllCompanies(tx)
 
 		assert.Equal(t, []interface{}{tx, companyType, "select * from company", []interface{}(nil)}, runQueryStub.GetFirstCall().Arguments())
@@ -29,7 +28,7 @@ func Test_GetCompanyByID(t *testing.T) {
 	companies := []*table.Company{{ID: 1}}
 	runQueryStub := mocka.Function(t, &runQuery, companies)
 	defer runQueryStub.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		result := GetCompanyByID(tx, id)
 
 		assert.Equal(t, []interface{}{tx, companyType, "select * from company where id = ?", []interface{}{id}},
@@ -43,7 +42,7 @@ func Test_GetCompanyByName(t *testing.T) {
 	companies := []*table.Company{{ID: 1}}
 	runQueryStub := mocka.Function(t, &runQuery, companies)
 	defer runQueryStub.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		result := GetCompanyByName(tx, name)
 
 		assert.Equal(t, []interface{}{tx, companyType, "select * from company where name = ?", []interface{}{name}},
@@ -57,10 +56,10 @@ func Test_GetCompaniesByIDs(t *testing.T) {
 	companies := []*table.Company{{ID: 42}}
 	runQueryStub := mocka.Function(t, &runQuery, companies)
 	defer runQueryStub.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		result := GetCompaniesByIDs(tx, ids)
 
-		assert.Equal(t, []interface{}{tx, companyType, "select * from company where json_contains(?, cast(id as json))", []interface{}{"[42,96]"}},
+		assert.Equal(t, []interface{}{tx, companyType, "select * from company where @in(id)", []interface{}{"[42,96]"}},
 			runQueryStub.GetFirstCall().Arguments())
 		assert.Equal(t, companies, result)
 	})
@@ -68,10 +67,12 @@ func Test_GetCompaniesByIDs(t *testing.T) {
 
 func Test_AddCompany(t *testing.T) {
 	id := int64(42)
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		runInsertStub := mocka.Function(t, &runInsert, id)
 		runInsertStub.OnSecondCall().Return(id + 1)
 		defer runInsertStub.Restore()
+		history := mockHistory()
+		defer history.restore()
 
 		results := AddCompany(tx, "company1", "somebody")
 
@@ -79,52 +80,61 @@ func Test_AddCompany(t *testing.T) {
 		assert.Equal(t, "company1", results.Name)
 		assert.Equal(t, "somebody", results.ChangeUser)
 		assert.Equal(t, 0, results.Version)
+		assert.Equal(t, []historyCall{{"company", id, "somebody"}}, history.inserts)
 		assert.Nil(t, mock.ExpectationsWereMet())
 	})
 }
 
 func Test_DeleteCompanies(t *testing.T) {
 	ids := []map[string]interface{}{{"id": 42, "version": 1}, {"id": 96, "version": 2}}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		count := int64(2)
 		runUpdateStub := mocka.Function(t, &runUpdate, count)
 		defer runUpdateStub.Restore()
+		history := mockHistory()
+		defer history.restore()
 
-		result := DeleteCompanies(tx, ids)
+		result := DeleteCompanies(tx, ids, "somebody")
 
 		deleteIDs, _ := json.Marshal(ids)
 		assert.Equal(t,
-			[]interface{}{tx, "delete from company where json_contains(?, json_object('id', id, 'version', version))", []interface{}{deleteIDs}},
+			[]interface{}{tx, "delete from company where @inObjects('id', id, 'version', version)", []interface{}{deleteIDs}},
 			runUpdateStub.GetCall(0).Arguments())
 		assert.Equal(t, count, result)
+		assert.Equal(t, []historyCall{{"company", []interface{}{42, 96}, "somebody"}}, history.changes)
 	})
 }
 
 func Test_UpdateCompanies(t *testing.T) {
-	t.Run("panics if not found", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-			defer func() {
-				if err := recover(); err != nil {
-					assert.Equal(t, "company not found (42 @ 1)", err.(error).Error())
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
+	t.Run("returns version conflict", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			image := `{"change_user":"other","id":42,"name":"other name","version":2}`
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
 			defer runUpdateStub.Restore()
+			loadImagesStub := mocka.Function(t, &loadImages, map[string]string{"42": image})
+			defer loadImagesStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			UpdateCompany(tx, 42, 1, "name", "somebody")
+			err := UpdateCompany(tx, 42, 1, "name", "somebody")
+
+			assert.Equal(t, apperror.RowChanged("company", 42, 1, "other", json.RawMessage(image)), err)
+			assert.Equal(t, []interface{}{tx, "company", []int64{42}}, loadImagesStub.GetCall(0).Arguments())
 		})
 	})
 	t.Run("returns companies", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			UpdateCompany(tx, 42, 1, "rename 42", "somebody")
+			err := UpdateCompany(tx, 42, 1, "rename 42", "somebody")
 
-			assert.Equal(t, []interface{}{tx, updateCompanySQL, []interface{}{"rename 42", "somebody", int64(42), int64(1)}},
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{tx, updateCompanySQL.sql, []interface{}{"rename 42", "somebody", int64(42), int64(1)}},
 				runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"company", []int64{42}, "somebody"}}, history.changes)
 		})
 	})
 }
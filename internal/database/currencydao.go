@@ -0,0 +1,117 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var currencyType = reflect.TypeOf(table.Currency{})
+var assetType = reflect.TypeOf(table.Asset{})
+var assetTable = mapTable("asset", assetType)
+var currencyAmountType = reflect.TypeOf(table.CurrencyAmount{})
+var exchangeRateType = reflect.TypeOf(table.ExchangeRate{})
+var exchangeRateTable = mapTable("exchange_rate", exchangeRateType)
+
+const currencySQL = `select c.code, a.* from currency c join asset a on c.asset_id = a.id`
+
+// GetAllCurrencies loads all currencies.
+func GetAllCurrencies(tx *Tx) []*table.Currency {
+	currencies := runQuery(tx, currencyType, currencySQL)
+	return currencies.([]*table.Currency)
+}
+
+// GetCurrencyByID returns the currency with ID.
+func GetCurrencyByID(tx *Tx, id int64) []*table.Currency {
+	currencies := runQuery(tx, currencyType, currencySQL+" where a.id = ?", id)
+	return currencies.([]*table.Currency)
+}
+
+// GetAssetsByIDs returns the assets with the IDs.
+func GetAssetsByIDs(tx *Tx, ids []int64) []*table.Asset {
+	assets := runQuery(tx, assetType, "select * from asset where @in(id)", int64sToJson(ids))
+	return assets.([]*table.Asset)
+}
+
+// runAmountQuery returns the amounts for the parent IDs, limited to the accounts if accountIDs is not nil.
+func runAmountQuery(tx *Tx, query string, parentIDs []int64, accountIDs []int64) []*table.CurrencyAmount {
+	accounts := accountsArg(accountIDs)
+	amounts := runQuery(tx, currencyAmountType, query, int64sToJson(parentIDs), accounts, accounts)
+	return amounts.([]*table.CurrencyAmount)
+}
+
+var addAssetSQL = assetTable.insertSQL("name", "type", "scale", "symbol")
+
+// AddCurrency adds a currency and returns its ID.
+func AddCurrency(tx *Tx, currency *table.Currency, user string) int64 {
+	id := runInsert(tx, addAssetSQL.sql, addAssetSQL.modelArgs(&currency.Asset, user)...)
+	runUpdate(tx, "insert into currency (asset_id, code) values (?, ?)", id, currency.Code)
+	recordInsert(tx, "asset", id, user)
+	recordInsert(tx, "currency", id, user)
+	return id
+}
+
+func runExchangeRateQuery(tx *Tx, query string, args ...interface{}) []*table.ExchangeRate {
+	rates := runQuery(tx, exchangeRateType, query, args...)
+	return rates.([]*table.ExchangeRate)
+}
+
+const exchangeRatesSQL = `select * from exchange_rate
+where (? is null or from_currency_id = ? or to_currency_id = ?)
+order by from_currency_id, to_currency_id, date`
+
+// GetExchangeRates returns the exchange rates for the currency or all exchange rates when currencyID is nil.
+func GetExchangeRates(tx *Tx, currencyID interface{}) []*table.ExchangeRate {
+	return runExchangeRateQuery(tx, exchangeRatesSQL, currencyID, currencyID, currencyID)
+}
+
+// GetExchangeRatesByIDs returns the exchange rates for the IDs.
+func GetExchangeRatesByIDs(tx *Tx, ids []int64) []*table.ExchangeRate {
+	return runExchangeRateQuery(tx, "select * from exchange_rate where @in(id)", int64sToJson(ids))
+}
+
+const latestExchangeRatesSQL = `select er.*
+from exchange_rate er
+where er.date = (
+	select max(r.date) from exchange_rate r
+	where r.from_currency_id = er.from_currency_id and r.to_currency_id = er.to_currency_id and r.date <= ?
+)`
+
+// GetLatestExchangeRates returns the most recent rate for each pair of currencies as of the date.
+func GetLatestExchangeRates(tx *Tx, date interface{}) []*table.ExchangeRate {
+	return runExchangeRateQuery(tx, latestExchangeRatesSQL, date)
+}
+
+var insertExchangeRateSQL = exchangeRateTable.insertSQL("from_currency_id", "to_currency_id", "date", "rate")
+
+// AddExchangeRate adds an exchange rate and returns its ID.
+func AddExchangeRate(tx *Tx, values InputObject, user string) int64 {
+	id := runInsert(tx, insertExchangeRateSQL.sql, insertExchangeRateSQL.inputArgs(values, nil, user)...)
+	recordInsert(tx, "exchange_rate", id, user)
+	return id
+}
+
+var updateExchangeRateSQL = exchangeRateTable.partialUpdateSQL("date", "rate")
+
+// UpdateExchangeRate updates the date and/or rate of an exchange rate.
+func UpdateExchangeRate(tx *Tx, id int64, version int64, values InputObject, user string) error {
+	var count int64
+	trackChanges(tx, "exchange_rate", []int64{id}, user, func() {
+		count = runUpdate(tx, updateExchangeRateSQL.sql, updateExchangeRateSQL.inputArgs(values, nil, user, id, version)...)
+	})
+	if count == 0 {
+		return apperror.VersionConflict("exchange_rate", id, version)
+	}
+	return nil
+}
+
+// DeleteExchangeRates deletes exchange rates and returns the number of deleted rates.
+func DeleteExchangeRates(tx *Tx, ids []map[string]interface{}, user string) (count int64) {
+	deleteIDs, _ := json.Marshal(ids)
+	trackChanges(tx, "exchange_rate", versionIDKeys(ids), user, func() {
+		count = runUpdate(tx, "delete from exchange_rate where @inObjects('id', id, 'version', version)", deleteIDs)
+	})
+	return count
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetAllCurrencies(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		currencies := []*table.Currency{{Code: "USD"}}
+		runQueryStub := mocka.Function(t, &runQuery, currencies)
+		defer runQueryStub.Restore()
+
+		result := GetAllCurrencies(tx)
+
+		assert.Equal(t, []interface{}{tx, currencyType, currencySQL, []interface{}(nil)}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, currencies, result)
+	})
+}
+
+func Test_GetCurrencyByID(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		currencies := []*table.Currency{{Code: "USD"}}
+		runQueryStub := mocka.Function(t, &runQuery, currencies)
+		defer runQueryStub.Restore()
+
+		result := GetCurrencyByID(tx, 42)
+
+		assert.Equal(t, []interface{}{tx, currencyType, currencySQL + " where a.id = ?", []interface{}{int64(42)}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, currencies, result)
+	})
+}
+
+func Test_GetAssetsByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		assets := []*table.Asset{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, assets)
+		defer runQueryStub.Restore()
+
+		result := GetAssetsByIDs(tx, []int64{42, 96})
+
+		assert.Equal(t, []interface{}{tx, assetType, "select * from asset where @in(id)", []interface{}{"[42,96]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, assets, result)
+	})
+}
+
+func Test_AddCurrency(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runInsertStub := mocka.Function(t, &runInsert, int64(42))
+		defer runInsertStub.Restore()
+		runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+		defer runUpdateStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+		symbol := "$"
+		currency := &table.Currency{Code: "USD", Asset: table.Asset{Name: "US Dollar", Type: "Currency", Scale: 2, Symbol: &symbol}}
+
+		result := AddCurrency(tx, currency, "somebody")
+
+		assert.Equal(t, int64(42), result)
+		assert.Equal(t, sqltest.UpdateArgs(tx, addAssetSQL.sql, "US Dollar", "Currency", 2, &symbol, "somebody"), runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, sqltest.UpdateArgs(tx, "insert into currency (asset_id, code) values (?, ?)", int64(42), "USD"),
+			runUpdateStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"asset", int64(42), "somebody"}, {"currency", int64(42), "somebody"}}, history.inserts)
+	})
+}
+
+func Test_GetExchangeRates(t *testing.T) {
+	tests := []struct {
+		name       string
+		currencyID interface{}
+	}{
+		{"returns all rates", nil},
+		{"returns rates for currency", int64(42)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+				rates := []*table.ExchangeRate{{ID: 1}}
+				runQueryStub := mocka.Function(t, &runQuery, rates)
+				defer runQueryStub.Restore()
+
+				result := GetExchangeRates(tx, test.currencyID)
+
+				assert.Equal(t, []interface{}{tx, exchangeRateType, exchangeRatesSQL, []interface{}{test.currencyID, test.currencyID, test.currencyID}},
+					runQueryStub.GetFirstCall().Arguments())
+				assert.Equal(t, rates, result)
+			})
+		})
+	}
+}
+
+func Test_GetExchangeRatesByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		rates := []*table.ExchangeRate{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, rates)
+		defer runQueryStub.Restore()
+
+		result := GetExchangeRatesByIDs(tx, []int64{1, 2})
+
+		assert.Equal(t, []interface{}{tx, exchangeRateType, "select * from exchange_rate where @in(id)", []interface{}{"[1,2]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, rates, result)
+	})
+}
+
+func Test_GetLatestExchangeRates(t *testing.T) {
+	date := time.Now()
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		rates := []*table.ExchangeRate{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, rates)
+		defer runQueryStub.Restore()
+
+		result := GetLatestExchangeRates(tx, date)
+
+		assert.Equal(t, []interface{}{tx, exchangeRateType, latestExchangeRatesSQL, []interface{}{date}}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, rates, result)
+	})
+}
+
+func Test_AddExchangeRate(t *testing.T) {
+	id := int64(42)
+	date := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+	values := InputObject{"fromCurrencyId": 1, "toCurrencyId": 2, "date": date, "rate": 1.23}
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runInsertStub := mocka.Function(t, &runInsert, id)
+		defer runInsertStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		result := AddExchangeRate(tx, values, "somebody")
+
+		assert.Equal(t, id, result)
+		assert.Equal(t, sqltest.UpdateArgs(tx, insertExchangeRateSQL.sql, int64(1), int64(2), date, 1.23, "somebody"), runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"exchange_rate", id, "somebody"}}, history.inserts)
+	})
+}
+
+func Test_UpdateExchangeRate(t *testing.T) {
+	values := InputObject{"rate": 1.23}
+	t.Run("updates rate", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			UpdateExchangeRate(tx, 42, 1, values, "somebody")
+
+			assert.Equal(t, sqltest.UpdateArgs(tx, updateExchangeRateSQL.sql, false, nil, true, 1.23, "somebody", int64(42), int64(1)), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"exchange_rate", []int64{42}, "somebody"}}, history.changes)
+		})
+	})
+	t.Run("returns error if not found", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := UpdateExchangeRate(tx, 42, 1, values, "somebody")
+
+			assert.EqualError(t, err, "exchange rate not found (42 @ 1)")
+		})
+	})
+}
+
+func Test_DeleteExchangeRates(t *testing.T) {
+	ids := []map[string]interface{}{{"id": 42, "version": 1}}
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+		defer runUpdateStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		result := DeleteExchangeRates(tx, ids, "somebody")
+
+		deleteIDs, _ := json.Marshal(ids)
+		assert.Equal(t, int64(1), result)
+		assert.Equal(t,
+			sqltest.UpdateArgs(tx, "delete from exchange_rate where @inObjects('id', id, 'version', version)", deleteIDs),
+			runUpdateStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"exchange_rate", []interface{}{42}, "somebody"}}, history.changes)
+	})
+}
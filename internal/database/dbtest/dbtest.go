@@ -0,0 +1,18 @@
+// Package dbtest provides helpers for testing code that runs in a database transaction.
+package dbtest
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/sqltest"
+)
+
+// TestInTx runs a test in a transaction on a sqlmock connection. The transaction is not part of a request.
+func TestInTx(t *testing.T, test func(mockDB sqlmock.Sqlmock, tx *database.Tx)) {
+	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		test(mockDB, database.NewTx(tx))
+	})
+}
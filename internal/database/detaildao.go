@@ -1,18 +1,17 @@
 package database
 
 import (
-	"database/sql"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"reflect"
 
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
 var detailType = reflect.TypeOf(table.TransactionDetail{})
+var detailTable = mapTable("transaction_detail", detailType)
 
-func runDetailQuery(tx *sql.Tx, query string, args ...interface{}) []*table.TransactionDetail {
+func runDetailQuery(tx *Tx, query string, args ...interface{}) []*table.TransactionDetail {
 	rows := runQuery(tx, detailType, query, args...)
 	return rows.([]*table.TransactionDetail)
 }
@@ -20,19 +19,19 @@ func runDetailQuery(tx *sql.Tx, query string, args ...interface{}) []*table.Tran
 const accountTxDetailsSQL = `select td.*
 from transaction t
 join transaction_detail td on t.id = td.transaction_id
-where t.account_id = ?
+where t.account_id = ? and t.trash_date is null
 order by t.id, td.id`
 
-func GetDetailsByAccountID(tx *sql.Tx, accountID int64) []*table.TransactionDetail {
+func GetDetailsByAccountID(tx *Tx, accountID int64) []*table.TransactionDetail {
 	return runDetailQuery(tx, accountTxDetailsSQL, accountID)
 }
 
 const txDetailsSQL = `select td.*
 from transaction_detail td
-where json_contains(?, cast(transaction_id as json))
+where @in(transaction_id)
 order by td.transaction_id, td.id`
 
-func GetDetailsByTxIDs(tx *sql.Tx, txIDs []int64) []*table.TransactionDetail {
+func GetDetailsByTxIDs(tx *Tx, txIDs []int64) []*table.TransactionDetail {
 	return runDetailQuery(tx, txDetailsSQL, int64sToJson(txIDs))
 }
 
@@ -40,30 +39,29 @@ const accountRelatedDetailsSQL = `select rd.*
 from transaction tx
 join transaction_detail td on tx.id = td.transaction_id
 join transaction_detail rd on td.related_detail_id = rd.id
-where tx.account_id = ?`
+where tx.account_id = ? and tx.trash_date is null`
 
-func GetRelatedDetailsByAccountID(tx *sql.Tx, accountID int64) []*table.TransactionDetail {
+func GetRelatedDetailsByAccountID(tx *Tx, accountID int64) []*table.TransactionDetail {
 	return runDetailQuery(tx, accountRelatedDetailsSQL, accountID)
 }
 
 const relatedDetailsSQL = `select rd.*
 from transaction_detail td
 join transaction_detail rd on td.related_detail_id = rd.id
-where json_contains(?, cast(td.transaction_id as json))`
+where @in(td.transaction_id)`
 
-func GetRelatedDetailsByTxIDs(tx *sql.Tx, txIDs []int64) []*table.TransactionDetail {
+func GetRelatedDetailsByTxIDs(tx *Tx, txIDs []int64) []*table.TransactionDetail {
 	return runDetailQuery(tx, relatedDetailsSQL, int64sToJson(txIDs))
 }
 
-const insertDetailSQL = `insert into transaction_detail
-(transaction_id, amount, transaction_category_id, transaction_group_id, memo, asset_quantity, exchange_asset_id, change_date, change_user, version)
-values (?, ?, ?, ?, ?, ?, ?, current_timestamp, ?, 0)`
+var insertDetailSQL = detailTable.insertSQL("transaction_id", "amount", "transaction_category_id", "transaction_group_id", "memo",
+	"asset_quantity", "exchange_asset_id")
 
-func InsertDetail(tx *sql.Tx, txID int64, amount interface{}, values InputObject, user string) {
-	id := runInsert(tx, insertDetailSQL, txID, amount, values.IntOrNull("transactionCategoryId"), values.IntOrNull("transactionGroupId"),
-		values.StringOrNull("memo"), values.FloatOrNull("assetQuantity"), values.IntOrNull("exchangeAssetId"), user)
+func InsertDetail(tx *Tx, txID int64, amount interface{}, values InputObject, user string) {
+	id := runInsert(tx, insertDetailSQL.sql, insertDetailSQL.inputArgs(values, columnValues{"transaction_id": txID, "amount": amount}, user)...)
+	recordInsert(tx, "transaction_detail", id, user)
 	if transferAccountId, setTransfer := values.GetInt("transferAccountId"); setTransfer {
-		insertTransferDetail(tx, id, transferAccountId, user)
+		insertTransferDetail(tx, id, transferAccountId, values, user)
 	}
 }
 
@@ -73,133 +71,299 @@ from transaction_detail rd
 join transaction rt on rd.transaction_id = rt.id
 where rd.id = ?`
 
+// the amount of the new detail is either the specified transfer amount or the related amount converted using the exchange rate
 const insertTransferDetailSQL = `insert into transaction_detail (transaction_id, related_detail_id, amount, change_date, change_user, version)
-select ?, ?, -amount, current_timestamp, ?, 0
+select ?, ?, coalesce(?, -amount * coalesce(?, 1)), current_timestamp, ?, 0
 from transaction_detail
 where id = ?`
 
 const setRelatedDetailSQL = `update transaction_detail set related_detail_id = ? where id = ?`
 
-var insertTransferDetail = func(tx *sql.Tx, relatedDetailId int64, accountId interface{}, user string) {
+var insertTransferDetail = func(tx *Tx, relatedDetailId int64, accountId interface{}, values InputObject, user string) {
 	txId := runInsert(tx, insertTransferTransactionSQL, accountId, user, relatedDetailId)
-	detailId := runInsert(tx, insertTransferDetailSQL, txId, relatedDetailId, user, relatedDetailId)
-	runUpdate(tx, setRelatedDetailSQL, detailId, relatedDetailId)
+	recordInsert(tx, "transaction", txId, user)
+	detailId := runInsert(tx, insertTransferDetailSQL, txId, relatedDetailId, values.FloatOrNull("transferAmount"),
+		values.FloatOrNull("exchangeRate"), user, relatedDetailId)
+	recordInsert(tx, "transaction_detail", detailId, user)
+	trackChanges(tx, "transaction_detail", []int64{relatedDetailId}, user, func() {
+		runUpdate(tx, setRelatedDetailSQL, detailId, relatedDetailId)
+	})
 }
 
+const transferTransactionIDsSQL = `select transaction_id from transaction_detail where related_detail_id = ?`
+
 const moveTransferDetailSQL = `update transaction set account_id = ?, change_date = current_timestamp, change_user = ?, version = version+1
 where id = (select d.transaction_id from transaction_detail d where d.related_detail_id = ?)`
 
-var AddOrUpdateTransfer = func(tx *sql.Tx, relatedDetailId int64, accountId interface{}, user string) {
-	count := runUpdate(tx, moveTransferDetailSQL, accountId, user, relatedDetailId)
+var AddOrUpdateTransfer = func(tx *Tx, relatedDetailId int64, accountId interface{}, values InputObject, user string) {
+	var count int64
+	trackChanges(tx, "transaction", runIDQuery(tx, transferTransactionIDsSQL, relatedDetailId), user, func() {
+		count = runUpdate(tx, moveTransferDetailSQL, accountId, user, relatedDetailId)
+	})
 	if count == 0 {
-		insertTransferDetail(tx, relatedDetailId, accountId, user)
+		insertTransferDetail(tx, relatedDetailId, accountId, values, user)
 	}
 }
 
-const setTransferAmountSQL = `update transaction_detail set amount = -?, change_date = current_timestamp, change_user = ?, version = version+1
-where related_detail_id = ?`
+const transferDetailIDsSQL = `select id from transaction_detail where related_detail_id = ?`
 
-func SetTransferAmount(tx *sql.Tx, relatedDetailId int64, amount interface{}, user string) {
-	runUpdate(tx, setTransferAmountSQL, amount, user, relatedDetailId)
+// must be executed before the related detail is updated so that the current exchange rate can be calculated
+const setTransferAmountSQL = `update transaction_detail td
+join transaction_detail rd on td.related_detail_id = rd.id
+set td.amount = coalesce(?, -coalesce(?, rd.amount) * coalesce(?, -td.amount / nullif(rd.amount, 0), 1))
+, td.change_date = current_timestamp, td.change_user = ?, td.version = td.version+1
+where td.related_detail_id = ?`
+
+// SetTransferAmount updates the other side of a transfer. If neither transferAmount nor exchangeRate is specified,
+// then the exchange rate implied by the current amounts is applied to the new amount.
+func SetTransferAmount(tx *Tx, relatedDetailId int64, amount interface{}, values InputObject, user string) {
+	trackChanges(tx, "transaction_detail", runIDQuery(tx, transferDetailIDsSQL, relatedDetailId), user, func() {
+		runUpdate(tx, setTransferAmountSQL, values.FloatOrNull("transferAmount"), amount, values.FloatOrNull("exchangeRate"), user, relatedDetailId)
+	})
 }
 
-const updateTxDetailSQL = `update transaction_detail
-set amount = case when ? then ? else amount end
-, transaction_category_id = case when ? then ? else transaction_category_id end
-, transaction_group_id = case when ? then ? else transaction_group_id end
-, memo = case when ? then ? else memo end
-, asset_quantity = case when ? then ? else asset_quantity end
-, exchange_asset_id = case when ? then ? else exchange_asset_id end
-, change_date = current_timestamp, change_user = ?, version = version+1
-where id = ? and version = ?`
+var updateTxDetailSQL = detailTable.partialUpdateSQL("amount", "transaction_category_id", "transaction_group_id", "memo",
+	"asset_quantity", "exchange_asset_id")
 
-func UpdateDetail(tx *sql.Tx, id int64, version int64, setCategory bool, categoryId interface{}, values InputObject, user string) {
-	amount, setAmount := values.GetFloat("amount")
-	groupId, setGroup := values.GetInt("transactionGroupId")
-	memo, setMemo := values.GetString("memo")
-	shares, setShares := values.GetFloat("assetQuantity")
-	securityId, setSecurity := values.GetInt("exchangeAssetId")
-	count := runUpdate(tx, updateTxDetailSQL,
-		setAmount, amount,
-		setCategory, categoryId,
-		setGroup, groupId,
-		setMemo, memo,
-		setShares, shares,
-		setSecurity, securityId,
-		user, id, version)
+// UpdateDetail updates a transaction detail. Returns a VersionConflictError if the detail has been changed or
+// deleted.
+func UpdateDetail(tx *Tx, id int64, version int64, setCategory bool, categoryId interface{}, values InputObject, user string) error {
+	fixed := columnValues{}
+	if setCategory {
+		fixed["transaction_category_id"] = categoryId
+	}
+	var count int64
+	trackChanges(tx, "transaction_detail", []int64{id}, user, func() {
+		count = runUpdate(tx, updateTxDetailSQL.sql, updateTxDetailSQL.inputArgs(values, fixed, user, id, version)...)
+	})
 	if count == 0 {
-		panic(fmt.Errorf("transaction detail not found (%d @ %d)", id, version))
+		return versionConflict(tx, "transaction_detail", id, version)
 	}
+	return nil
 }
 
-const deleteEmptyTransactionsSQL = `delete from transaction
+const emptyTransactionIDsSQL = `select id from transaction
 where not exists(select 1 from transaction_detail where transaction_id = transaction.id)`
 
+const deleteEmptyTransactionsSQL = `delete from transaction
+where @in(id)
+and not exists(select 1 from transaction_detail where transaction_id = transaction.id)`
+
+func deleteEmptyTransactions(tx *Tx, user string) {
+	if ids := runIDQuery(tx, emptyTransactionIDsSQL); len(ids) > 0 {
+		trackChanges(tx, "transaction", ids, user, func() {
+			runUpdate(tx, deleteEmptyTransactionsSQL, int64sToJson(ids))
+		})
+	}
+}
+
 const deleteDetailsSQL = `delete from transaction_detail
-where json_contains(?, json_object('ID', id, 'Version', version))`
+where @inObjects('ID', id, 'Version', version)`
 
-func DeleteDetails(tx *sql.Tx, ids []*VersionID) {
+// DeleteDetails deletes transaction details and any transactions that no longer have details. Returns a
+// NotFoundError if any of the details have been changed.
+func DeleteDetails(tx *Tx, ids []*VersionID, user string) error {
 	deleteIDs, _ := json.Marshal(ids)
-	if count := runUpdate(tx, deleteDetailsSQL, deleteIDs); int(count) != len(ids) {
-		panic(errors.New("transaction detail(s) not found"))
+	keys := make([]int64, len(ids))
+	for i, id := range ids {
+		keys[i] = id.ID
+	}
+	var count int64
+	trackChanges(tx, "transaction_detail", keys, user, func() {
+		count = runUpdate(tx, deleteDetailsSQL, deleteIDs)
+	})
+	if int(count) != len(ids) {
+		return apperror.NotFound("transaction_detail")
 	}
-	runUpdate(tx, deleteEmptyTransactionsSQL)
+	deleteEmptyTransactions(tx, user)
+	return nil
 }
 
+const relatedDetailIDsSQL = `select id from transaction_detail
+where related_detail_id in (select id from transaction_detail where @inObjects('id', transaction_id))`
+
 const deleteRelatedDetailSQL = `delete from transaction_detail
-where related_detail_id in (select id from transaction_detail where json_contains(?, json_object('id', transaction_id)))`
+where related_detail_id in (select id from transaction_detail where @inObjects('id', transaction_id))`
 
-func DeleteRelatedDetails(tx *sql.Tx, txIDs []map[string]interface{}) {
+func DeleteRelatedDetails(tx *Tx, txIDs []map[string]interface{}, user string) {
 	deleteIDs, _ := json.Marshal(txIDs)
-	if count := runUpdate(tx, deleteRelatedDetailSQL, deleteIDs); count > 0 {
-		runUpdate(tx, deleteEmptyTransactionsSQL)
+	var count int64
+	trackChanges(tx, "transaction_detail", runIDQuery(tx, relatedDetailIDsSQL, deleteIDs), user, func() {
+		count = runUpdate(tx, deleteRelatedDetailSQL, deleteIDs)
+	})
+	if count > 0 {
+		deleteEmptyTransactions(tx, user)
 	}
 }
 
-func DeleteTransactionDetails(tx *sql.Tx, txIDs []map[string]interface{}) {
+const transactionDetailIDsSQL = `select id from transaction_detail where @inObjects('id', transaction_id)`
+
+func DeleteTransactionDetails(tx *Tx, txIDs []map[string]interface{}, user string) {
 	deleteIDs, _ := json.Marshal(txIDs)
-	runUpdate(tx, "delete from transaction_detail where json_contains(?, json_object('id', transaction_id))", deleteIDs)
+	trackChanges(tx, "transaction_detail", runIDQuery(tx, transactionDetailIDsSQL, deleteIDs), user, func() {
+		runUpdate(tx, "delete from transaction_detail where @inObjects('id', transaction_id)", deleteIDs)
+	})
 }
 
+const relatedDetailIDSQL = `select related_detail_id from transaction_detail where id = ? and related_detail_id is not null`
+
 const deleteTransferDetailSQL = `delete from transaction_detail
 where id = (select related_detail_id from transaction_detail where id = ?)`
 
-func DeleteTransfer(tx *sql.Tx, relatedDetailId int64) {
-	if count := runUpdate(tx, deleteTransferDetailSQL, relatedDetailId); count != 0 {
-		runUpdate(tx, deleteEmptyTransactionsSQL)
+func DeleteTransfer(tx *Tx, relatedDetailId int64, user string) {
+	var count int64
+	trackChanges(tx, "transaction_detail", runIDQuery(tx, relatedDetailIDSQL, relatedDetailId), user, func() {
+		count = runUpdate(tx, deleteTransferDetailSQL, relatedDetailId)
+	})
+	if count != 0 {
+		deleteEmptyTransactions(tx, user)
 	}
 }
 
 const validateDetailsSQL = `select id, error
 from (
     select td.id
-    , case when t.security_id is null and tc.security = 'Y' is null then concat('security required for category: ', tc.id)
-        when tc.asset_exchange = 'Y' and td.asset_quantity is null then concat('shares required for category: ', tc.id)
-        when tc.asset_exchange = 'N' and td.asset_quantity is not null then concat('shares not allowed for category: ', tc.id)
+    , case when t.security_id is null and tc.security = 'Y' is null then @concat('security required for category: ', tc.id)
+        when tc.asset_exchange = 'Y' and td.asset_quantity is null then @concat('shares required for category: ', tc.id)
+        when tc.asset_exchange = 'N' and td.asset_quantity is not null then @concat('shares not allowed for category: ', tc.id)
         when tc.income = 'N' and td.asset_quantity < 0 then 'shares must be positive for expense category'
         when tc.income = 'Y' and td.asset_quantity > 0 then 'shares must be negative for income category'
+        when ra.currency_id = a.currency_id and td.amount <> -rd.amount then 'transfer amounts must be equal for the same currency'
+        when ra.currency_id <> a.currency_id and sign(td.amount) = sign(rd.amount) and td.amount <> 0 then 'transfer amounts must have opposite signs'
         else null
 	  end error
     from transaction_detail td
     join transaction t on td.transaction_id = t.id
+    join account a on t.account_id = a.id
     left join transaction_category tc on td.transaction_category_id = tc.id
-	where json_contains(?, td.transaction_id)) errors
+    left join transaction_detail rd on td.related_detail_id = rd.id
+    left join transaction rt on rd.transaction_id = rt.id
+    left join account ra on rt.account_id = ra.id
+	where @in(td.transaction_id)) errors
 where errors.error is not null`
 
-// ValidateDetails checks for invalid security fields and returns a map of detail ID to validation error.
-func ValidateDetails(tx *sql.Tx, transactionIDs []int64) {
-	rows, err := tx.Query(validateDetailsSQL, int64sToJson(transactionIDs))
-	if err != nil {
-		panic(err)
-	}
+// ValidateDetails checks for invalid security fields and transfer amounts. Returns a ValidationError containing a
+// map of detail ID to message.
+func ValidateDetails(tx *Tx, transactionIDs []int64) error {
+	query, args := currentDialect.bind(validateDetailsSQL, []interface{}{int64sToJson(transactionIDs)})
+	rows := queryRows(tx, query, args)
+	defer rows.Close()
 	result := make(map[int64]string)
 	for rows.Next() {
 		var id int64
 		var text string
-		rows.Scan(&id, &text)
+		if err := rows.Scan(&id, &text); err != nil {
+			panic(err)
+		}
 		result[id] = text
 	}
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
 	if len(result) > 0 {
-		panic(fmt.Errorf("transaction detail errors: %v", result))
+		return apperror.DetailValidation(result)
 	}
+	return nil
+}
+
+// transfer details are excluded because their category is determined by the related detail
+const detailsByFilterSQL = `select td.*
+from transaction t
+join transaction_detail td on t.id = td.transaction_id
+where td.related_detail_id is null and t.trash_date is null
+and (? is null or t.account_id = ?)
+and (? is null or @in(t.account_id))
+and (? is null or t.date >= ?)
+and (? is null or t.date <= ?)
+and (? is null or t.payee_id = ?)
+and (? is null or td.transaction_category_id = ?)
+and (? is null or td.memo like @concat('%', ?, '%') or t.memo like @concat('%', ?, '%'))
+order by td.transaction_id, td.id`
+
+// GetDetailsByFilter returns the non-transfer details matching the filter. The accountIds filter value
+// ([]int64) limits the details to the specified accounts.
+func GetDetailsByFilter(tx *Tx, filter InputObject) []*table.TransactionDetail {
+	accountID := filter.IntOrNull("accountId")
+	var accountIDs interface{}
+	if ids, ok := filter["accountIds"].([]int64); ok {
+		accountIDs = int64sToJson(ids)
+	}
+	startDate := filter["startDate"]
+	endDate := filter["endDate"]
+	payeeID := filter.IntOrNull("payeeId")
+	categoryID := filter.IntOrNull("transactionCategoryId")
+	memo := filter.StringOrNull("memo")
+	return runDetailQuery(tx, detailsByFilterSQL,
+		accountID, accountID,
+		accountIDs, accountIDs,
+		startDate, startDate,
+		endDate, endDate,
+		payeeID, payeeID,
+		categoryID, categoryID,
+		memo, memo, memo)
+}
+
+// the details are numbered for each category so that the page can be selected for each category
+const categoryDetailsSQL = `select td.*
+from transaction_detail td
+join (
+	select td.id, row_number() over (partition by td.transaction_category_id order by t.date, t.id, td.id) row_num
+	from transaction t
+	join transaction_detail td on t.id = td.transaction_id
+	where @in(td.transaction_category_id) and t.trash_date is null
+	and (? is null or t.date >= ?)
+	and (? is null or t.date <= ?)
+	and (? is null or @in(t.account_id))
+) page on td.id = page.id
+where page.row_num > ? and (? = 0 or page.row_num <= ?)
+order by td.transaction_category_id, page.row_num`
+
+// GetDetailsByCategoryIDs returns a page of the details of each category, limited to the accounts if accountIDs is
+// not nil.
+func GetDetailsByCategoryIDs(tx *Tx, categoryIDs []int64, filter PageFilter, accountIDs []int64) []*table.TransactionDetail {
+	return runDetailQuery(tx, categoryDetailsSQL, filter.pageArgs(categoryIDs, accountIDs)...)
+}
+
+// the details are numbered for each group so that the page can be selected for each group
+const groupDetailsSQL = `select td.*
+from transaction_detail td
+join (
+	select td.id, row_number() over (partition by td.transaction_group_id order by t.date, t.id, td.id) row_num
+	from transaction t
+	join transaction_detail td on t.id = td.transaction_id
+	where @in(td.transaction_group_id) and t.trash_date is null
+	and (? is null or t.date >= ?)
+	and (? is null or t.date <= ?)
+	and (? is null or @in(t.account_id))
+) page on td.id = page.id
+where page.row_num > ? and (? = 0 or page.row_num <= ?)
+order by td.transaction_group_id, page.row_num`
+
+// GetDetailsByGroupIDs returns a page of the details of each group, limited to the accounts if accountIDs is not
+// nil.
+func GetDetailsByGroupIDs(tx *Tx, groupIDs []int64, filter PageFilter, accountIDs []int64) []*table.TransactionDetail {
+	return runDetailQuery(tx, groupDetailsSQL, filter.pageArgs(groupIDs, accountIDs)...)
+}
+
+const updateDetailsByIDsSQL = `update transaction_detail
+set transaction_category_id = case when ? then ? else transaction_category_id end
+, transaction_group_id = case when ? then ? else transaction_group_id end
+, memo = case when ? then ? else memo end
+, change_date = current_timestamp, change_user = ?, version = version+1
+where @in(id)`
+
+// UpdateDetailsByIDs applies the same change to multiple details and returns the number of updated rows.
+func UpdateDetailsByIDs(tx *Tx, ids []int64, values InputObject, user string) int64 {
+	categoryID, setCategory := values.GetInt("transactionCategoryId")
+	groupID, setGroup := values.GetInt("transactionGroupId")
+	memo, setMemo := values.GetString("memo")
+	var count int64
+	trackChanges(tx, "transaction_detail", ids, user, func() {
+		count = runUpdate(tx, updateDetailsByIDsSQL,
+			setCategory, categoryID,
+			setGroup, groupID,
+			setMemo, memo,
+			user, int64sToJson(ids))
+	})
+	return count
 }
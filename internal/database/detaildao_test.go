@@ -1,22 +1,21 @@
 package database
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database/table"
 	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
-func testDetailsQuery(t *testing.T, doQuery func(tx *sql.Tx) ([]*table.TransactionDetail, string, []interface{})) {
+func testDetailsQuery(t *testing.T, doQuery func(tx *Tx) ([]*table.TransactionDetail, string, []interface{})) {
 	details := []*table.TransactionDetail{{ID: 96}}
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		runQueryStub := mocka.Function(t, &runQuery, details)
 		defer runQueryStub.Restore()
 
@@ -28,7 +27,7 @@ func testDetailsQuery(t *testing.T, doQuery func(tx *sql.Tx) ([]*table.Transacti
 }
 
 func Test_GetDetailsByAccountID(t *testing.T) {
-	testDetailsQuery(t, func(tx *sql.Tx) ([]*table.TransactionDetail, string, []interface{}) {
+	testDetailsQuery(t, func(tx *Tx) ([]*table.TransactionDetail, string, []interface{}) {
 		accountID := int64(42)
 
 		result := GetDetailsByAccountID(tx, accountID)
@@ -38,7 +37,7 @@ func Test_GetDetailsByAccountID(t *testing.T) {
 }
 
 func Test_GetDetailsByTxIDs(t *testing.T) {
-	testDetailsQuery(t, func(tx *sql.Tx) ([]*table.TransactionDetail, string, []interface{}) {
+	testDetailsQuery(t, func(tx *Tx) ([]*table.TransactionDetail, string, []interface{}) {
 		txIDs := []int64{42}
 
 		result := GetDetailsByTxIDs(tx, txIDs)
@@ -48,7 +47,7 @@ func Test_GetDetailsByTxIDs(t *testing.T) {
 }
 
 func Test_GetRelatedDetailsByAccountID(t *testing.T) {
-	testDetailsQuery(t, func(tx *sql.Tx) ([]*table.TransactionDetail, string, []interface{}) {
+	testDetailsQuery(t, func(tx *Tx) ([]*table.TransactionDetail, string, []interface{}) {
 		accountID := int64(42)
 
 		result := GetRelatedDetailsByAccountID(tx, accountID)
@@ -58,7 +57,7 @@ func Test_GetRelatedDetailsByAccountID(t *testing.T) {
 }
 
 func Test_GetRelatedDetailsByTxIDs(t *testing.T) {
-	testDetailsQuery(t, func(tx *sql.Tx) ([]*table.TransactionDetail, string, []interface{}) {
+	testDetailsQuery(t, func(tx *Tx) ([]*table.TransactionDetail, string, []interface{}) {
 		txIDs := []int64{42}
 
 		result := GetRelatedDetailsByTxIDs(tx, txIDs)
@@ -73,7 +72,7 @@ func Test_InsertDetail(t *testing.T) {
 	user := "user id"
 	amount := 420.0
 	t.Run("inserts detail with category", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			values := InputObject{
 				"transactionCategoryId": 96,
 				"transactionGroupId":    69,
@@ -83,25 +82,30 @@ func Test_InsertDetail(t *testing.T) {
 			}
 			runInsertStub := mocka.Function(t, &runInsert, id)
 			defer runInsertStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
 			InsertDetail(tx, txID, amount, values, user)
 
-			assert.Equal(t, sqltest.UpdateArgs(tx, insertDetailSQL, txID, amount, int64(96), int64(69), "notes", 4.2, int64(24), user),
+			assert.Equal(t, sqltest.UpdateArgs(tx, insertDetailSQL.sql, txID, amount, int64(96), int64(69), "notes", 4.2, int64(24), user),
 				runInsertStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"transaction_detail", id, user}}, history.inserts)
 		})
 	})
 	t.Run("inserts transfer", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			values := InputObject{"transferAccountId": 96}
 			runInsertStub := mocka.Function(t, &runInsert, id)
 			defer runInsertStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 			insertTransferStub := mocka.Function(t, &insertTransferDetail)
 			defer insertTransferStub.Restore()
 
 			InsertDetail(tx, txID, amount, values, user)
 
-			assert.Equal(t, sqltest.UpdateArgs(tx, insertDetailSQL, txID, amount, nil, nil, nil, nil, nil, user), runInsertStub.GetCall(0).Arguments())
-			assert.Equal(t, []interface{}{tx, id, int64(96), user}, insertTransferStub.GetCall(0).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, insertDetailSQL.sql, txID, amount, nil, nil, nil, nil, nil, user), runInsertStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, id, int64(96), values, user}, insertTransferStub.GetCall(0).Arguments())
 		})
 	})
 }
@@ -112,62 +116,113 @@ func Test_insertTransferDetail(t *testing.T) {
 	relatedDetailID := int64(69)
 	accountID := int64(123)
 	user := "user id"
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-		runInsertStub := mocka.Function(t, &runInsert, txID)
-		runInsertStub.OnCall(1).Return(detailID)
-		defer runInsertStub.Restore()
-		runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
-		defer runUpdateStub.Restore()
-
-		insertTransferDetail(tx, relatedDetailID, accountID, user)
-
-		assert.Equal(t, sqltest.UpdateArgs(tx, insertTransferTransactionSQL, accountID, user, relatedDetailID), runInsertStub.GetCall(0).Arguments())
-		assert.Equal(t, sqltest.UpdateArgs(tx, insertTransferDetailSQL, txID, relatedDetailID, user, relatedDetailID), runInsertStub.GetCall(1).Arguments())
-		assert.Equal(t, sqltest.UpdateArgs(tx, setRelatedDetailSQL, detailID, relatedDetailID), runUpdateStub.GetCall(0).Arguments())
-	})
+	tests := []struct {
+		name           string
+		values         InputObject
+		transferAmount interface{}
+		exchangeRate   interface{}
+	}{
+		{"defaults to negated amount", InputObject{}, nil, nil},
+		{"uses transfer amount", InputObject{"transferAmount": 12.34}, 12.34, nil},
+		{"uses exchange rate", InputObject{"exchangeRate": 1.5}, nil, 1.5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+				runInsertStub := mocka.Function(t, &runInsert, txID)
+				runInsertStub.OnCall(1).Return(detailID)
+				defer runInsertStub.Restore()
+				history := mockHistory()
+				defer history.restore()
+				runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+				defer runUpdateStub.Restore()
+
+				insertTransferDetail(tx, relatedDetailID, accountID, test.values, user)
+
+				assert.Equal(t, sqltest.UpdateArgs(tx, insertTransferTransactionSQL, accountID, user, relatedDetailID), runInsertStub.GetCall(0).Arguments())
+				assert.Equal(t, sqltest.UpdateArgs(tx, insertTransferDetailSQL, txID, relatedDetailID, test.transferAmount, test.exchangeRate, user, relatedDetailID),
+					runInsertStub.GetCall(1).Arguments())
+				assert.Equal(t, sqltest.UpdateArgs(tx, setRelatedDetailSQL, detailID, relatedDetailID), runUpdateStub.GetCall(0).Arguments())
+				assert.Equal(t, []historyCall{{"transaction", txID, user}, {"transaction_detail", detailID, user}}, history.inserts)
+				assert.Equal(t, []historyCall{{"transaction_detail", []int64{relatedDetailID}, user}}, history.changes)
+			})
+		})
+	}
 }
 
 func Test_AddOrUpdateTransfer(t *testing.T) {
 	relatedDetailID := int64(69)
 	accountID := int64(123)
 	user := "user id"
+	values := InputObject{"exchangeRate": 1.5}
 	t.Run("updates existing transfer", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{96})
+			defer runIDQueryStub.Restore()
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			AddOrUpdateTransfer(tx, relatedDetailID, accountID, user)
+			AddOrUpdateTransfer(tx, relatedDetailID, accountID, values, user)
 
+			assert.Equal(t, []interface{}{tx, transferTransactionIDsSQL, []interface{}{relatedDetailID}}, runIDQueryStub.GetCall(0).Arguments())
 			assert.Equal(t, sqltest.UpdateArgs(tx, moveTransferDetailSQL, accountID, user, relatedDetailID), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"transaction", []int64{96}, user}}, history.changes)
 		})
 	})
 	t.Run("inserts transfer", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{})
+			defer runIDQueryStub.Restore()
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 			insertTransferDetailStub := mocka.Function(t, &insertTransferDetail)
 			defer insertTransferDetailStub.Restore()
 
-			AddOrUpdateTransfer(tx, relatedDetailID, accountID, user)
+			AddOrUpdateTransfer(tx, relatedDetailID, accountID, values, user)
 
 			assert.Equal(t, sqltest.UpdateArgs(tx, moveTransferDetailSQL, accountID, user, relatedDetailID), runUpdateStub.GetCall(0).Arguments())
-			assert.Equal(t, []interface{}{tx, relatedDetailID, accountID, user}, insertTransferDetailStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, relatedDetailID, accountID, values, user}, insertTransferDetailStub.GetCall(0).Arguments())
 		})
 	})
 }
 
-func Test_SetTransferAmount_returnsError(t *testing.T) {
+func Test_SetTransferAmount(t *testing.T) {
 	relatedDetailID := int64(69)
 	amount := 420.0
 	user := "user id"
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-		runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
-		defer runUpdateStub.Restore()
-
-		SetTransferAmount(tx, relatedDetailID, amount, user)
-
-		assert.Equal(t, sqltest.UpdateArgs(tx, setTransferAmountSQL, amount, user, relatedDetailID), runUpdateStub.GetCall(0).Arguments())
-	})
+	tests := []struct {
+		name           string
+		values         InputObject
+		transferAmount interface{}
+		exchangeRate   interface{}
+	}{
+		{"keeps current rate", InputObject{}, nil, nil},
+		{"uses transfer amount", InputObject{"transferAmount": 12.34}, 12.34, nil},
+		{"uses exchange rate", InputObject{"exchangeRate": 1.5}, nil, 1.5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+				runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{96})
+				defer runIDQueryStub.Restore()
+				runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
+				defer runUpdateStub.Restore()
+				history := mockHistory()
+				defer history.restore()
+
+				SetTransferAmount(tx, relatedDetailID, amount, test.values, user)
+
+				assert.Equal(t, sqltest.UpdateArgs(tx, setTransferAmountSQL, test.transferAmount, amount, test.exchangeRate, user, relatedDetailID),
+					runUpdateStub.GetCall(0).Arguments())
+				assert.Equal(t, []interface{}{tx, transferDetailIDsSQL, []interface{}{relatedDetailID}}, runIDQueryStub.GetCall(0).Arguments())
+				assert.Equal(t, []historyCall{{"transaction_detail", []int64{96}, user}}, history.changes)
+			})
+		})
+	}
 }
 
 func Test_UpdateDetail(t *testing.T) {
@@ -176,7 +231,7 @@ func Test_UpdateDetail(t *testing.T) {
 	categoryID := int64(96)
 	user := "user id"
 	t.Run("updates the detail", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			values := InputObject{
 				"amount":             42.0,
 				"transactionGroupId": 69,
@@ -186,65 +241,75 @@ func Test_UpdateDetail(t *testing.T) {
 			}
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			UpdateDetail(tx, id, version, true, categoryID, values, user)
+			err := UpdateDetail(tx, id, version, true, categoryID, values, user)
 
+			assert.Nil(t, err)
 			assert.Equal(t, sqltest.UpdateArgs(
-				tx, updateTxDetailSQL, true, 42.0, true, categoryID, true, int64(69), true, "notes", true, 4.2, true, int64(24), user, id, version),
+				tx, updateTxDetailSQL.sql, true, 42.0, true, categoryID, true, int64(69), true, "notes", true, 4.2, true, int64(24), user, id, version),
 				runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"transaction_detail", []int64{id}, user}}, history.changes)
 		})
 	})
-	t.Run("panics for detail not found", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	t.Run("returns error for detail not found", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			values := InputObject{"memo": "notes"}
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
 			defer runUpdateStub.Restore()
-			defer func() {
-				assert.Equal(t, sqltest.UpdateArgs(
-					tx, updateTxDetailSQL, false, nil, false, nil, false, nil, true, "notes", false, nil, false, nil, user, id, version),
-					runUpdateStub.GetCall(0).Arguments())
-				if err := recover(); err != nil {
-					assert.Equal(t, "transaction detail not found (42 @ 1)", err.(error).Error())
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
+			loadImagesStub := mocka.Function(t, &loadImages, map[string]string{})
+			defer loadImagesStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			UpdateDetail(tx, id, version, false, nil, values, user)
+			err := UpdateDetail(tx, id, version, false, nil, values, user)
+
+			assert.Equal(t, apperror.RowDeleted("transaction_detail", id, version), err)
+			assert.Equal(t, "transaction detail has been deleted (42)", err.Error())
+			assert.Equal(t, sqltest.UpdateArgs(
+				tx, updateTxDetailSQL.sql, false, nil, false, nil, false, nil, true, "notes", false, nil, false, nil, user, id, version),
+				runUpdateStub.GetCall(0).Arguments())
 		})
 	})
 }
 
 func Test_DeleteDetails(t *testing.T) {
 	ids := []*VersionID{{42, 1}, {24, 0}}
+	user := "user id"
 	t.Run("delete details and empty transactions", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{96})
+			defer runIDQueryStub.Restore()
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
-			runUpdateStub.OnCall(1).Return(int64(0))
+			runUpdateStub.OnCall(1).Return(int64(1))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			DeleteDetails(tx, ids)
+			err := DeleteDetails(tx, ids, user)
 
+			assert.Nil(t, err)
 			assert.Equal(t, 2, runUpdateStub.CallCount())
 			idArg, _ := json.Marshal(ids)
 			assert.Equal(t, sqltest.UpdateArgs(tx, deleteDetailsSQL, idArg), runUpdateStub.GetCall(0).Arguments())
-			assert.Equal(t, sqltest.UpdateArgs(tx, deleteEmptyTransactionsSQL), runUpdateStub.GetCall(1).Arguments())
+			assert.Equal(t, []interface{}{tx, emptyTransactionIDsSQL, []interface{}(nil)}, runIDQueryStub.GetCall(0).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, deleteEmptyTransactionsSQL, "[96]"), runUpdateStub.GetCall(1).Arguments())
+			assert.Equal(t, []historyCall{{"transaction_detail", []int64{42, 24}, user}, {"transaction", []int64{96}, user}}, history.changes)
 		})
 	})
-	t.Run("panics for detail not found", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	t.Run("returns error for detail not found", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
 			defer runUpdateStub.Restore()
-			defer func() {
-				assert.Equal(t, 1, runUpdateStub.CallCount())
-				if err := recover(); err != nil {
-					assert.Equal(t, "transaction detail(s) not found", err.(error).Error())
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
+			history := mockHistory()
+			defer history.restore()
 
-			DeleteDetails(tx, ids)
+			err := DeleteDetails(tx, ids, user)
+
+			assert.Equal(t, apperror.NotFound("transaction_detail"), err)
+			assert.Equal(t, "transaction detail(s) not found", err.Error())
+			assert.Equal(t, 1, runUpdateStub.CallCount())
 		})
 	})
 }
@@ -252,27 +317,40 @@ func Test_DeleteDetails(t *testing.T) {
 func Test_DeleteRelatedDetails(t *testing.T) {
 	txIDs := []map[string]interface{}{{"id": 42, "version": 1}, {"id": 24, "version": 0}}
 	idArg, _ := json.Marshal(txIDs)
+	user := "user id"
 	t.Run("delete details and empty transactions", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{69, 70})
+			runIDQueryStub.OnSecondCall().Return([]int64{96})
+			defer runIDQueryStub.Restore()
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
-			runUpdateStub.OnCall(1).Return(int64(0))
+			runUpdateStub.OnCall(1).Return(int64(1))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			DeleteRelatedDetails(tx, txIDs)
+			DeleteRelatedDetails(tx, txIDs, user)
 
 			assert.Equal(t, 2, runUpdateStub.CallCount())
+			assert.Equal(t, []interface{}{tx, relatedDetailIDsSQL, []interface{}{idArg}}, runIDQueryStub.GetCall(0).Arguments())
 			assert.Equal(t, sqltest.UpdateArgs(tx, deleteRelatedDetailSQL, idArg), runUpdateStub.GetCall(0).Arguments())
-			assert.Equal(t, sqltest.UpdateArgs(tx, deleteEmptyTransactionsSQL), runUpdateStub.GetCall(1).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, deleteEmptyTransactionsSQL, "[96]"), runUpdateStub.GetCall(1).Arguments())
+			assert.Equal(t, []historyCall{{"transaction_detail", []int64{69, 70}, user}, {"transaction", []int64{96}, user}}, history.changes)
 		})
 	})
 	t.Run("does not delete transactions if no details", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{})
+			defer runIDQueryStub.Restore()
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			DeleteRelatedDetails(tx, txIDs)
+			DeleteRelatedDetails(tx, txIDs, user)
 
 			assert.Equal(t, 1, runUpdateStub.CallCount())
+			assert.Equal(t, 1, runIDQueryStub.CallCount())
 		})
 	})
 }
@@ -280,40 +358,57 @@ func Test_DeleteRelatedDetails(t *testing.T) {
 func Test_DeleteTransactionDetails(t *testing.T) {
 	txIDs := []map[string]interface{}{{"id": 42, "version": 1}, {"id": 24, "version": 0}}
 	idArg, _ := json.Marshal(txIDs)
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{69, 70})
+		defer runIDQueryStub.Restore()
 		runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
 		defer runUpdateStub.Restore()
+		history := mockHistory()
+		defer history.restore()
 
-		DeleteTransactionDetails(tx, txIDs)
+		DeleteTransactionDetails(tx, txIDs, "user id")
 
 		assert.Equal(t, 1, runUpdateStub.CallCount())
 		assert.Equal(t,
-			sqltest.UpdateArgs(tx, "delete from transaction_detail where json_contains(?, json_object('id', transaction_id))", idArg),
+			sqltest.UpdateArgs(tx, "delete from transaction_detail where @inObjects('id', transaction_id)", idArg),
 			runUpdateStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, transactionDetailIDsSQL, []interface{}{idArg}}, runIDQueryStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"transaction_detail", []int64{69, 70}, "user id"}}, history.changes)
 	})
 }
 
 func Test_DeleteTransfer(t *testing.T) {
 	relatedDetailID := int64(42)
+	user := "user id"
 	t.Run("delete transfer detail and empty transactions", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
-			runUpdateStub.OnCall(1).Return(int64(0))
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{69})
+			runIDQueryStub.OnSecondCall().Return([]int64{96})
+			defer runIDQueryStub.Restore()
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			DeleteTransfer(tx, relatedDetailID)
+			DeleteTransfer(tx, relatedDetailID, user)
 
 			assert.Equal(t, 2, runUpdateStub.CallCount())
+			assert.Equal(t, []interface{}{tx, relatedDetailIDSQL, []interface{}{relatedDetailID}}, runIDQueryStub.GetCall(0).Arguments())
 			assert.Equal(t, sqltest.UpdateArgs(tx, deleteTransferDetailSQL, relatedDetailID), runUpdateStub.GetCall(0).Arguments())
-			assert.Equal(t, sqltest.UpdateArgs(tx, deleteEmptyTransactionsSQL), runUpdateStub.GetCall(1).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, deleteEmptyTransactionsSQL, "[96]"), runUpdateStub.GetCall(1).Arguments())
+			assert.Equal(t, []historyCall{{"transaction_detail", []int64{69}, user}, {"transaction", []int64{96}, user}}, history.changes)
 		})
 	})
 	t.Run("ignores transfer detail not found", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{})
+			defer runIDQueryStub.Restore()
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			DeleteTransfer(tx, relatedDetailID)
+			DeleteTransfer(tx, relatedDetailID, user)
 
 			assert.Equal(t, 1, runUpdateStub.CallCount())
 		})
@@ -323,36 +418,31 @@ func Test_DeleteTransfer(t *testing.T) {
 func Test_ValidateDetails(t *testing.T) {
 	transactionIDs := []int64{42, 96, 69}
 	t.Run("runs validation query", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			rows := sqltest.MockRows("id", "error")
-			mockDB.ExpectQuery(validateDetailsSQL).WithArgs(int64sToJson(transactionIDs)).WillReturnRows(rows)
+			mockDB.ExpectQuery(currentDialect.expand(validateDetailsSQL)).WithArgs(boundArgs(validateDetailsSQL, int64sToJson(transactionIDs))...).WillReturnRows(rows)
 
-			ValidateDetails(tx, transactionIDs)
+			err := ValidateDetails(tx, transactionIDs)
 
+			assert.Nil(t, err)
 			assert.Nil(t, mockDB.ExpectationsWereMet())
 		})
 	})
-	t.Run("panics for validation errors", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	t.Run("returns error for validation errors", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			rows := sqltest.MockRows("id", "error").AddRow(int64(42), "invalid shares").AddRow(int64(96), "shares required")
-			mockDB.ExpectQuery(validateDetailsSQL).WithArgs(int64sToJson(transactionIDs)).WillReturnRows(rows)
-			defer func() {
-				assert.Nil(t, mockDB.ExpectationsWereMet())
-				if err := recover(); err != nil {
-					result := map[int64]string{42: "invalid shares", 96: "shares required"}
-					assert.Equal(t, fmt.Errorf("transaction detail errors: %v", result), err)
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
+			mockDB.ExpectQuery(currentDialect.expand(validateDetailsSQL)).WithArgs(boundArgs(validateDetailsSQL, int64sToJson(transactionIDs))...).WillReturnRows(rows)
 
-			ValidateDetails(tx, transactionIDs)
+			err := ValidateDetails(tx, transactionIDs)
+
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+			assert.Equal(t, apperror.DetailValidation(map[int64]string{42: "invalid shares", 96: "shares required"}), err)
 		})
 	})
 	t.Run("panics for query error", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			expectedErr := errors.New("query error")
-			mockDB.ExpectQuery(validateDetailsSQL).WithArgs(int64sToJson(transactionIDs)).WillReturnError(expectedErr)
+			mockDB.ExpectQuery(currentDialect.expand(validateDetailsSQL)).WithArgs(boundArgs(validateDetailsSQL, int64sToJson(transactionIDs))...).WillReturnError(expectedErr)
 			defer func() {
 				if err := recover(); err != nil {
 					assert.Same(t, expectedErr, err)
@@ -365,3 +455,78 @@ func Test_ValidateDetails(t *testing.T) {
 		})
 	})
 }
+
+func Test_GetDetailsByFilter(t *testing.T) {
+	startDate := "2020-01-01"
+	endDate := "2020-12-31"
+	tests := []struct {
+		name   string
+		filter InputObject
+		params []interface{}
+	}{
+		{"all fields", InputObject{"accountId": 1, "startDate": startDate, "endDate": endDate, "payeeId": 2, "transactionCategoryId": 3, "memo": "x"},
+			[]interface{}{int64(1), int64(1), nil, nil, startDate, startDate, endDate, endDate, int64(2), int64(2), int64(3), int64(3), "x", "x", "x"}},
+		{"account IDs", InputObject{"accountIds": []int64{1, 2}},
+			[]interface{}{nil, nil, "[1,2]", "[1,2]", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}},
+		{"no fields", InputObject{}, []interface{}{nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testDetailsQuery(t, func(tx *Tx) ([]*table.TransactionDetail, string, []interface{}) {
+				result := GetDetailsByFilter(tx, test.filter)
+
+				return result, detailsByFilterSQL, test.params
+			})
+		})
+	}
+}
+
+func Test_GetDetailsByCategoryIDs(t *testing.T) {
+	testDetailsQuery(t, func(tx *Tx) ([]*table.TransactionDetail, string, []interface{}) {
+		filter := PageFilter{StartDate: "2020-01-01", Limit: 10, Offset: 20}
+
+		result := GetDetailsByCategoryIDs(tx, []int64{42, 96}, filter, nil)
+
+		return result, categoryDetailsSQL, []interface{}{"[42,96]", "2020-01-01", "2020-01-01", nil, nil, nil, nil, 20, 10, 30}
+	})
+}
+
+func Test_GetDetailsByGroupIDs(t *testing.T) {
+	testDetailsQuery(t, func(tx *Tx) ([]*table.TransactionDetail, string, []interface{}) {
+		filter := PageFilter{EndDate: "2020-12-31"}
+
+		result := GetDetailsByGroupIDs(tx, []int64{42}, filter, []int64{1, 2})
+
+		return result, groupDetailsSQL, []interface{}{"[42]", nil, nil, "2020-12-31", "2020-12-31", "[1,2]", "[1,2]", 0, 0, 0}
+	})
+}
+
+func Test_UpdateDetailsByIDs(t *testing.T) {
+	ids := []int64{42, 96}
+	user := "user id"
+	tests := []struct {
+		name   string
+		values InputObject
+		params []interface{}
+	}{
+		{"sets category", InputObject{"transactionCategoryId": 1}, []interface{}{true, int64(1), false, nil, false, nil, user, "[42,96]"}},
+		{"sets group", InputObject{"transactionGroupId": 2}, []interface{}{false, nil, true, int64(2), false, nil, user, "[42,96]"}},
+		{"sets memo", InputObject{"memo": "notes"}, []interface{}{false, nil, false, nil, true, "notes", user, "[42,96]"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+				runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+				defer runUpdateStub.Restore()
+				history := mockHistory()
+				defer history.restore()
+
+				count := UpdateDetailsByIDs(tx, ids, test.values, user)
+
+				assert.Equal(t, int64(2), count)
+				assert.Equal(t, sqltest.UpdateArgs(tx, updateDetailsByIDsSQL, test.params...), runUpdateStub.GetCall(0).Arguments())
+				assert.Equal(t, []historyCall{{"transaction_detail", ids, user}}, history.changes)
+			})
+		})
+	}
+}
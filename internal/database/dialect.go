@@ -0,0 +1,139 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// dialect adapts the queries in this package to a database. Queries are written in MySQL syntax with macros for
+// the expressions that aren't portable, e.g. "@in(id)" to test if id is in a JSON array parameter. The macros are
+// expanded when the query is run.
+type dialect struct {
+	name string
+	// macros map the macro name to a function that renders the arguments as SQL
+	macros map[string]func(args []string) string
+	// statements replaces queries that can't be written using macros
+	statements map[string]string
+	// rewrites are applied to the query before the macros are expanded
+	rewrites []rewrite
+	// placeholder returns the parameter marker for the nth argument (starting at 1). Nil if the dialect uses "?".
+	placeholder func(n int) string
+	// convertArg converts a query argument. isArray is true if the argument is the JSON array for an @in macro.
+	convertArg func(arg interface{}, isArray bool) interface{}
+	// returningID is true if inserts must use a returning clause to get the generated ID
+	returningID bool
+	// isConstraintError returns true if the error is a not null, unique key, foreign key or check constraint violation
+	isConstraintError func(err error) bool
+	// scanTarget wraps the model field pointer when the driver returns values that can't be scanned directly
+	scanTarget     func(ptr interface{}) interface{}
+	statementCache sync.Map
+}
+
+type rewrite struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// statement is a query that has been expanded for a dialect.
+type statement struct {
+	sql string
+	// arrayArgs contains the indexes of the arguments used by @in macros
+	arrayArgs map[int]bool
+}
+
+var macroPattern = regexp.MustCompile(`@(\w+)\(([^()]*)\)`)
+
+// matches the placeholders in a query, including those added by the @in and @inObjects macros
+var argPattern = regexp.MustCompile(`@in(?:Objects)?\(|\?`)
+var placeholderPattern = regexp.MustCompile(`\?`)
+
+// statement returns the expanded query. The result is cached.
+func (d *dialect) statement(query string) *statement {
+	if stmt, ok := d.statementCache.Load(query); ok {
+		return stmt.(*statement)
+	}
+	sql := query
+	if replacement, ok := d.statements[query]; ok {
+		sql = replacement
+	}
+	stmt := &statement{arrayArgs: make(map[int]bool)}
+	for i, match := range argPattern.FindAllString(sql, -1) {
+		if match == "@in(" {
+			stmt.arrayArgs[i] = true
+		}
+	}
+	for _, rewrite := range d.rewrites {
+		sql = rewrite.pattern.ReplaceAllString(sql, rewrite.replacement)
+	}
+	sql = macroPattern.ReplaceAllStringFunc(sql, func(macro string) string {
+		match := macroPattern.FindStringSubmatch(macro)
+		render, ok := d.macros[match[1]]
+		if !ok {
+			panic(fmt.Errorf("unknown SQL macro for %s: %s", d.name, match[1]))
+		}
+		return render(splitArgs(match[2]))
+	})
+	if d.placeholder != nil {
+		n := 0
+		sql = placeholderPattern.ReplaceAllStringFunc(sql, func(string) string {
+			n++
+			return d.placeholder(n)
+		})
+	}
+	stmt.sql = sql
+	d.statementCache.Store(query, stmt)
+	return stmt
+}
+
+// expand returns the query with the macros replaced by SQL for the dialect.
+func (d *dialect) expand(query string) string {
+	return d.statement(query).sql
+}
+
+// bind returns the expanded query and the arguments converted for the dialect.
+func (d *dialect) bind(query string, args []interface{}) (string, []interface{}) {
+	stmt := d.statement(query)
+	if d.convertArg == nil {
+		return stmt.sql, args
+	}
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		converted[i] = d.convertArg(arg, stmt.arrayArgs[i])
+	}
+	return stmt.sql, converted
+}
+
+// splitArgs splits the macro arguments on commas that aren't in a quoted string.
+func splitArgs(args string) []string {
+	var result []string
+	start, quoted := 0, false
+	for i, c := range args {
+		if c == '\'' {
+			quoted = !quoted
+		} else if c == ',' && !quoted {
+			result = append(result, strings.TrimSpace(args[start:i]))
+			start = i + 1
+		}
+	}
+	return append(result, strings.TrimSpace(args[start:]))
+}
+
+var dialects = map[string]*dialect{
+	"mysql":    mysqlDialect,
+	"sqlite3":  sqliteDialect,
+	"postgres": postgresDialect,
+}
+
+var currentDialect = mysqlDialect
+
+// SetDialect selects the SQL dialect for the database driver.
+func SetDialect(driver string) error {
+	d, ok := dialects[driver]
+	if !ok {
+		return fmt.Errorf("unsupported database driver: %s", driver)
+	}
+	currentDialect = d
+	return nil
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMain runs the tests for each dialect.
+func TestMain(m *testing.M) {
+	for _, driver := range []string{"mysql", "sqlite3", "postgres"} {
+		SetDialect(driver)
+		if code := m.Run(); code != 0 {
+			os.Exit(code)
+		}
+	}
+	os.Exit(0)
+}
+
+// boundArgs returns the query arguments as they are passed to the driver for the current dialect.
+func boundArgs(query string, args ...interface{}) []driver.Value {
+	_, bound := currentDialect.bind(query, args)
+	values := make([]driver.Value, len(bound))
+	for i, arg := range bound {
+		if valuer, ok := arg.(driver.Valuer); ok {
+			value, err := valuer.Value()
+			if err != nil {
+				panic(err)
+			}
+			arg = value
+		}
+		values[i] = arg
+	}
+	return values
+}
+
+func Test_splitArgs(t *testing.T) {
+	assert.Equal(t, []string{"'a, b'", "c", "'d'"}, splitArgs("'a, b', c ,'d'"))
+	assert.Equal(t, []string{"id"}, splitArgs("id"))
+}
+
+func Test_dialect_expand(t *testing.T) {
+	tests := []struct {
+		query    string
+		mysql    string
+		sqlite   string
+		postgres string
+	}{
+		{"select * from company where @in(id)",
+			"select * from company where json_contains(?, json_array(id))",
+			"select * from company where id in (select value from json_each(?))",
+			"select * from company where id = any($1)"},
+		{"delete from company where @inObjects('id', id, 'version', version)",
+			"delete from company where json_contains(?, json_object('id', id, 'version', version))",
+			"delete from company where (id, version) in (select json_extract(value, '$.id'), json_extract(value, '$.version') from json_each(?))",
+			"delete from company where (id, version) in (select (value->>'id')::bigint, (value->>'version')::bigint from jsonb_array_elements($1::jsonb))"},
+		{"delete from transaction_detail where @inObjects('id', transaction_id)",
+			"delete from transaction_detail where json_contains(?, json_object('id', transaction_id))",
+			"delete from transaction_detail where transaction_id in (select json_extract(value, '$.id') from json_each(?))",
+			"delete from transaction_detail where (transaction_id) in (select (value->>'id')::bigint from jsonb_array_elements($1::jsonb))"},
+		{"select @concat('x: ', id, ?) from transaction",
+			"select concat('x: ', id, ?) from transaction",
+			`select ('x: ' || id || ?) from "transaction"`,
+			"select ('x: ' || id || $1) from transaction"},
+		{"select id from transaction where trash_date < @daysAgo(?)",
+			"select id from transaction where trash_date < current_timestamp - interval ? day",
+			`select id from "transaction" where trash_date < datetime('now', '-' || ? || ' days')`,
+			"select id from transaction where trash_date < current_timestamp - make_interval(days => $1)"},
+		{"select @daysBetween(t.date, ii.date) from import_item ii",
+			"select datediff(t.date, ii.date) from import_item ii",
+			"select cast(julianday(t.date) - julianday(ii.date) as integer) from import_item ii",
+			"select (t.date - ii.date) from import_item ii"},
+		{"insert into setting (name, value) values (?, ?) @upsert(name) value = @inserted(value)",
+			"insert into setting (name, value) values (?, ?) on duplicate key update value = values(value)",
+			"insert into setting (name, value) values (?, ?) on conflict (name) do update set value = excluded.value",
+			"insert into setting (name, value) values ($1, $2) on conflict (name) do update set value = excluded.value"},
+		{"create table payee (id @autoID(), name varchar(200))",
+			"create table payee (id bigint not null auto_increment primary key, name varchar(200))",
+			"create table payee (id integer primary key, name varchar(200))",
+			"create table payee (id bigserial primary key, name varchar(200))"},
+		{"select id from payee where ? is null or name = ?",
+			"select id from payee where ? is null or name = ?",
+			"select id from payee where ? is null or name = ?",
+			"select id from payee where cast($1 as text) is null or name = $2"},
+		{setTransferAmountSQL, setTransferAmountSQL, sqliteSetTransferAmountSQL, `update transaction_detail as td
+set amount = coalesce($1, -coalesce($2, rd.amount) * coalesce($3, -td.amount / nullif(rd.amount, 0), 1))
+, change_date = current_timestamp, change_user = $4, version = td.version+1
+from transaction_detail rd
+where td.related_detail_id = rd.id and td.related_detail_id = $5`},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			assert.Equal(t, test.mysql, mysqlDialect.expand(test.query))
+			assert.Equal(t, test.sqlite, sqliteDialect.expand(test.query))
+			assert.Equal(t, test.postgres, postgresDialect.expand(test.query))
+		})
+	}
+	t.Run("panics for unknown macro", func(t *testing.T) {
+		defer func() {
+			assert.Equal(t, "unknown SQL macro for mysql: unknown", fmt.Sprint(recover()))
+		}()
+
+		mysqlDialect.expand("select @unknown(id) from company")
+	})
+}
+
+func Test_SetDialect(t *testing.T) {
+	defer func(d *dialect) { currentDialect = d }(currentDialect)
+
+	assert.Nil(t, SetDialect("sqlite3"))
+	assert.Same(t, sqliteDialect, currentDialect)
+	assert.Nil(t, SetDialect("postgres"))
+	assert.Same(t, postgresDialect, currentDialect)
+	assert.Nil(t, SetDialect("mysql"))
+	assert.Same(t, mysqlDialect, currentDialect)
+	assert.EqualError(t, SetDialect("oracle"), "unsupported database driver: oracle")
+	assert.Same(t, mysqlDialect, currentDialect)
+}
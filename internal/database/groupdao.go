@@ -1,20 +1,37 @@
 package database
 
 import (
-	"database/sql"
 	"reflect"
 
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
 var groupType = reflect.TypeOf(table.Group{})
+var groupTable = mapTable("transaction_group", groupType)
 
-const groupSQL = `select g.*,
-	(select count(distinct transaction_id) from transaction_detail where transaction_group_id = g.id) transaction_count
-from transaction_group g`
+const groupSQL = `select g.* from transaction_group g`
 
 // GetAllGroups loads all groups.
-func GetAllGroups(tx *sql.Tx) []*table.Group {
+func GetAllGroups(tx *Tx) []*table.Group {
 	groups := runQuery(tx, groupType, groupSQL)
 	return groups.([]*table.Group)
 }
+
+// GetGroupsByIDs returns the groups with the IDs.
+func GetGroupsByIDs(tx *Tx, ids []int64) []*table.Group {
+	groups := runQuery(tx, groupType, groupSQL+" where @in(g.id)", int64sToJson(ids))
+	return groups.([]*table.Group)
+}
+
+const groupTransactionCountsSQL = `select td.transaction_group_id id, count(distinct td.transaction_id) transaction_count
+from transaction_detail td
+join transaction t on td.transaction_id = t.id
+where @in(td.transaction_group_id) and t.trash_date is null
+and (? is null or @in(t.account_id))
+group by td.transaction_group_id`
+
+// GetGroupTransactionCounts returns the number of transactions with details in each group, limited to the accounts if
+// accountIDs is not nil.
+func GetGroupTransactionCounts(tx *Tx, groupIDs []int64, accountIDs []int64) []*table.TransactionCount {
+	return runCountQuery(tx, groupTransactionCountsSQL, groupIDs, accountIDs)
+}
@@ -1,18 +1,16 @@
 package database
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_GetAllGroups(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		groups := []*table.Group{{ID: 1}}
 		runQueryStub := mocka.Function(t, &runQuery, groups)
 		defer runQueryStub.Restore()
@@ -23,3 +21,31 @@ func Test_GetAllGroups(t *testing.T) {
 		assert.Equal(t, groups, result)
 	})
 }
+
+func Test_GetGroupsByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		groups := []*table.Group{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, groups)
+		defer runQueryStub.Restore()
+
+		result := GetGroupsByIDs(tx, []int64{42, 96})
+
+		assert.Equal(t, []interface{}{tx, groupType, groupSQL + " where @in(g.id)", []interface{}{"[42,96]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, groups, result)
+	})
+}
+
+func Test_GetGroupTransactionCounts(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		counts := []*table.TransactionCount{{ID: 42, Count: 3}}
+		runQueryStub := mocka.Function(t, &runQuery, counts)
+		defer runQueryStub.Restore()
+
+		result := GetGroupTransactionCounts(tx, []int64{42, 96}, []int64{1})
+
+		assert.Equal(t, []interface{}{tx, transactionCountType, groupTransactionCountsSQL, []interface{}{"[42,96]", "[1]", "[1]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, counts, result)
+	})
+}
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var historyType = reflect.TypeOf(table.ChangeHistory{})
+var historyTable = mapTable("change_history", historyType)
+
+// tables that aren't identified by an id column
+var historyKeyColumns = map[string]string{
+	"setting":  "name",
+	"currency": "asset_id",
+}
+
+// tables with keys that aren't numbers
+var historyStringKeys = map[string]bool{
+	"setting": true,
+}
+
+// columns that are left out of the history images, e.g. secrets
+var historyExcludedColumns = map[string][]string{
+	"api_token": {"token_hash"},
+}
+
+// IsUndoable returns false if the table has columns that are left out of the history, since its rows can't be
+// restored from the history images.
+func IsUndoable(tableName string) bool {
+	return len(historyExcludedColumns[tableName]) == 0
+}
+
+func historyKeyColumn(tableName string) string {
+	if column, ok := historyKeyColumns[tableName]; ok {
+		return column
+	}
+	return "id"
+}
+
+// historyKeys returns the key of a row as a slice for loadImages.
+func historyKeys(tableName string, key string) interface{} {
+	if !historyStringKeys[tableName] {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		return []int64{id}
+	}
+	return []string{key}
+}
+
+// versionIDKeys returns the IDs from a list of ID/version maps.
+func versionIDKeys(ids []map[string]interface{}) []interface{} {
+	keys := make([]interface{}, len(ids))
+	for i, id := range ids {
+		keys[i] = id["id"]
+	}
+	return keys
+}
+
+// format of dates in the images that can be used to restore the row
+const imageTimeFormat = "2006-01-02 15:04:05.999999"
+
+// loadImages returns the rows as JSON objects mapped by the value of the key column. keys must be a slice.
+var loadImages = func(tx *Tx, tableName string, keys interface{}) map[string]string {
+	images := make(map[string]string)
+	if reflect.ValueOf(keys).Len() == 0 {
+		return images
+	}
+	keyColumn := historyKeyColumn(tableName)
+	keysJSON, _ := json.Marshal(keys)
+	query := fmt.Sprintf("select * from %s where @in(%s)", tableName, keyColumn)
+	query, args := currentDialect.bind(query, []interface{}{string(keysJSON)})
+	rows := queryRows(tx, query, args)
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		panic(err)
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err = rows.Scan(ptrs...); err != nil {
+			panic(err)
+		}
+		image := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			switch value := values[i].(type) {
+			case []byte:
+				image[column] = string(value)
+			case time.Time:
+				image[column] = value.UTC().Format(imageTimeFormat)
+			default:
+				image[column] = value
+			}
+		}
+		for _, column := range historyExcludedColumns[tableName] {
+			delete(image, column)
+		}
+		data, _ := json.Marshal(image)
+		images[fmt.Sprint(image[keyColumn])] = string(data)
+	}
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+	return images
+}
+
+// versionConflict returns the error for an update that didn't match the version of a row. The error includes the
+// current image of the row unless the row has been deleted or moved to the trash.
+func versionConflict(tx *Tx, tableName string, id int64, version int64) error {
+	image, ok := loadImages(tx, tableName, []int64{id})[fmt.Sprint(id)]
+	if !ok {
+		return apperror.RowDeleted(tableName, id, version)
+	}
+	var current struct {
+		ChangeUser string  `json:"change_user"`
+		TrashDate  *string `json:"trash_date"`
+	}
+	json.Unmarshal([]byte(image), &current)
+	if current.TrashDate != nil {
+		return apperror.RowDeleted(tableName, id, version)
+	}
+	return apperror.RowChanged(tableName, id, version, current.ChangeUser, json.RawMessage(image))
+}
+
+func imageVersion(image string) interface{} {
+	var values struct{ Version *int }
+	json.Unmarshal([]byte(image), &values)
+	if values.Version == nil {
+		return nil
+	}
+	return *values.Version
+}
+
+func nullableImage(image string, ok bool) interface{} {
+	if ok {
+		return image
+	}
+	return nil
+}
+
+const insertHistorySQL = `insert into change_history (entity, entity_key, action, version, before_image, after_image, change_set_id, change_date, change_user)
+values (?, ?, ?, ?, ?, ?, ?, current_timestamp, ?)`
+
+// saveHistory adds change history for the rows that were inserted, updated or deleted.
+func saveHistory(tx *Tx, tableName string, before map[string]string, after map[string]string, user string) {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var changeSetID interface{}
+	for _, key := range keys {
+		beforeImage, hasBefore := before[key]
+		afterImage, hasAfter := after[key]
+		var action string
+		var version interface{}
+		if hasBefore && hasAfter {
+			if beforeImage == afterImage {
+				continue
+			}
+			action, version = table.HistoryUpdate, imageVersion(afterImage)
+		} else if hasAfter {
+			action, version = table.HistoryInsert, imageVersion(afterImage)
+		} else {
+			action, version = table.HistoryDelete, imageVersion(beforeImage)
+		}
+		if changeSetID == nil {
+			changeSetID = getChangeSetID(tx)
+		}
+		runInsert(tx, insertHistorySQL, tableName, key, action, version,
+			nullableImage(beforeImage, hasBefore), nullableImage(afterImage, hasAfter), changeSetID, user)
+		history := &table.ChangeHistory{Entity: tableName, EntityKey: key, Action: action}
+		if version, ok := version.(int); ok {
+			history.Version = &version
+		}
+		if hasBefore {
+			history.BeforeImage = &beforeImage
+		}
+		if hasAfter {
+			history.AfterImage = &afterImage
+		}
+		addChange(tx, history)
+	}
+}
+
+// trackChanges runs the update and saves the before and after images of the affected rows. keys must be a slice
+// containing the IDs of the rows that may be changed by the update.
+var trackChanges = func(tx *Tx, tableName string, keys interface{}, user string, update func()) {
+	before := loadImages(tx, tableName, keys)
+	update()
+	saveHistory(tx, tableName, before, loadImages(tx, tableName, keys), user)
+}
+
+// recordInsert saves the image of a new row.
+var recordInsert = func(tx *Tx, tableName string, id int64, user string) {
+	saveHistory(tx, tableName, nil, loadImages(tx, tableName, []int64{id}), user)
+}
+
+const historySQL = `select * from change_history where entity = ? and entity_key = ? order by id`
+
+// GetHistory returns the changes to a row in order.
+func GetHistory(tx *Tx, tableName string, key string) []*table.ChangeHistory {
+	history := runQuery(tx, historyType, historySQL, tableName, key)
+	return history.([]*table.ChangeHistory)
+}
@@ -0,0 +1,262 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+type historyCall struct {
+	tableName string
+	keys      interface{}
+	user      string
+}
+
+type historyMock struct {
+	changes      []historyCall
+	inserts      []historyCall
+	trackChanges func(tx *Tx, tableName string, keys interface{}, user string, update func())
+	recordInsert func(tx *Tx, tableName string, id int64, user string)
+}
+
+// mockHistory replaces trackChanges and recordInsert with stubs that record the arguments.
+func mockHistory() *historyMock {
+	m := &historyMock{trackChanges: trackChanges, recordInsert: recordInsert}
+	trackChanges = func(tx *Tx, tableName string, keys interface{}, user string, update func()) {
+		m.changes = append(m.changes, historyCall{tableName, keys, user})
+		update()
+	}
+	recordInsert = func(tx *Tx, tableName string, id int64, user string) {
+		m.inserts = append(m.inserts, historyCall{tableName, id, user})
+	}
+	return m
+}
+
+func (m *historyMock) restore() {
+	trackChanges = m.trackChanges
+	recordInsert = m.recordInsert
+}
+
+func Test_historyKeys(t *testing.T) {
+	assert.Equal(t, []int64{42}, historyKeys("company", "42"))
+	assert.Equal(t, []string{"base_currency_id"}, historyKeys("setting", "base_currency_id"))
+	assert.Equal(t, []int64{42}, historyKeys("currency", "42"))
+}
+
+func Test_IsUndoable(t *testing.T) {
+	assert.True(t, IsUndoable("transaction"))
+	assert.False(t, IsUndoable("api_token"))
+}
+
+func Test_versionIDKeys(t *testing.T) {
+	ids := []map[string]interface{}{{"id": 42, "version": 1}, {"id": 96, "version": 0}}
+
+	assert.Equal(t, []interface{}{42, 96}, versionIDKeys(ids))
+}
+
+func Test_loadImages(t *testing.T) {
+	t.Run("returns JSON by id", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			changeDate := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+			query := "select * from company where @in(id)"
+			mockDB.ExpectQuery(currentDialect.expand(query)).WithArgs(boundArgs(query, "[42,96]")...).
+				WillReturnRows(sqltest.MockRows("id", "name", "change_date", "version").
+					AddRow(42, []byte("company 1"), changeDate, 1).
+					AddRow(96, nil, changeDate, 0))
+
+			result := loadImages(tx, "company", []int64{42, 96})
+
+			assert.Equal(t, map[string]string{
+				"42": `{"change_date":"2021-03-04 05:06:07","id":42,"name":"company 1","version":1}`,
+				"96": `{"change_date":"2021-03-04 05:06:07","id":96,"name":null,"version":0}`,
+			}, result)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+	})
+	t.Run("uses key column", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			query := "select * from setting where @in(name)"
+			mockDB.ExpectQuery(currentDialect.expand(query)).WithArgs(boundArgs(query, `["base_currency_id"]`)...).
+				WillReturnRows(sqltest.MockRows("name", "value").AddRow("base_currency_id", "42"))
+
+			result := loadImages(tx, "setting", []string{"base_currency_id"})
+
+			assert.Equal(t, map[string]string{"base_currency_id": `{"name":"base_currency_id","value":"42"}`}, result)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+	})
+	t.Run("uses numeric key column", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			query := "select * from currency where @in(asset_id)"
+			mockDB.ExpectQuery(currentDialect.expand(query)).WithArgs(boundArgs(query, "[42]")...).
+				WillReturnRows(sqltest.MockRows("asset_id", "code").AddRow(42, "USD"))
+
+			result := loadImages(tx, "currency", []int64{42})
+
+			assert.Equal(t, map[string]string{"42": `{"asset_id":42,"code":"USD"}`}, result)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+	})
+	t.Run("excludes secret columns", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			query := "select * from api_token where @in(id)"
+			mockDB.ExpectQuery(currentDialect.expand(query)).WithArgs(boundArgs(query, "[42]")...).
+				WillReturnRows(sqltest.MockRows("id", "name", "token_hash").AddRow(42, "laptop", "hash"))
+
+			result := loadImages(tx, "api_token", []int64{42})
+
+			assert.Equal(t, map[string]string{"42": `{"id":42,"name":"laptop"}`}, result)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+	})
+	t.Run("skips query for empty keys", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			result := loadImages(tx, "company", []int64{})
+
+			assert.Empty(t, result)
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+	})
+	t.Run("panics for query error", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			expectedErr := errors.New("query error")
+			mockDB.ExpectQuery(currentDialect.expand("select * from company where @in(id)")).WillReturnError(expectedErr)
+			defer func() {
+				assert.Same(t, expectedErr, recover())
+			}()
+
+			loadImages(tx, "company", []int64{42})
+		})
+	})
+	t.Run("panics for row error", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			query := "select * from company where @in(id)"
+			mockDB.ExpectQuery(currentDialect.expand(query)).
+				WillReturnRows(sqltest.MockRows("id").AddRow(42).AddRow(96).RowError(1, context.Canceled)).
+				RowsWillBeClosed()
+			defer func() {
+				assert.Equal(t, context.Canceled, recover())
+				assert.Nil(t, mockDB.ExpectationsWereMet())
+			}()
+
+			loadImages(tx, "company", []int64{42, 96})
+		})
+	})
+}
+
+func Test_versionConflict(t *testing.T) {
+	changed := `{"change_user":"other","id":42,"trash_date":null,"version":2}`
+	tests := []struct {
+		name   string
+		images map[string]string
+		err    error
+	}{
+		{"returns changed row", map[string]string{"42": changed}, apperror.RowChanged("transaction", 42, 1, "other", json.RawMessage(changed))},
+		{"returns deleted for trashed row", map[string]string{"42": `{"id":42,"trash_date":"2021-03-04 05:06:07"}`},
+			apperror.RowDeleted("transaction", 42, 1)},
+		{"returns deleted for missing row", map[string]string{}, apperror.RowDeleted("transaction", 42, 1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+				loadImagesStub := mocka.Function(t, &loadImages, test.images)
+				defer loadImagesStub.Restore()
+
+				err := versionConflict(tx, "transaction", 42, 1)
+
+				assert.Equal(t, test.err, err)
+				assert.Equal(t, []interface{}{tx, "transaction", []int64{42}}, loadImagesStub.GetCall(0).Arguments())
+			})
+		})
+	}
+}
+
+func Test_trackChanges(t *testing.T) {
+	user := "somebody"
+	before := map[string]string{
+		"1": `{"id":1,"name":"unchanged","version":0}`,
+		"2": `{"id":2,"name":"before","version":0}`,
+		"3": `{"id":3,"name":"deleted","version":2}`,
+	}
+	after := map[string]string{
+		"1": `{"id":1,"name":"unchanged","version":0}`,
+		"2": `{"id":2,"name":"after","version":1}`,
+		"4": `{"id":4,"name":"inserted","version":0}`,
+	}
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		keys := []int64{1, 2, 3, 4}
+		loadImagesStub := mocka.Function(t, &loadImages, before)
+		loadImagesStub.OnSecondCall().Return(after)
+		defer loadImagesStub.Restore()
+		runInsertStub := mocka.Function(t, &runInsert, int64(1))
+		defer runInsertStub.Restore()
+		runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+		defer runUpdateStub.Restore()
+		BeginChangeSet(tx, "123:4", user)
+		defer EndChangeSet(tx)
+		updated := false
+
+		trackChanges(tx, "company", keys, user, func() {
+			assert.Equal(t, 1, loadImagesStub.CallCount())
+			updated = true
+		})
+
+		assert.True(t, updated)
+		assert.Equal(t, []interface{}{tx, "company", keys}, loadImagesStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, "company", keys}, loadImagesStub.GetCall(1).Arguments())
+		assert.Equal(t, sqltest.UpdateArgs(tx, insertChangeSetSQL, "123:4", user), runUpdateStub.GetCall(0).Arguments())
+		assert.Equal(t, 3, runInsertStub.CallCount())
+		assert.Equal(t, sqltest.UpdateArgs(tx, insertHistorySQL, "company", "2", table.HistoryUpdate, 1, before["2"], after["2"], "123:4", user),
+			runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, sqltest.UpdateArgs(tx, insertHistorySQL, "company", "3", table.HistoryDelete, 2, before["3"], nil, "123:4", user),
+			runInsertStub.GetCall(1).Arguments())
+		assert.Equal(t, sqltest.UpdateArgs(tx, insertHistorySQL, "company", "4", table.HistoryInsert, 0, nil, after["4"], "123:4", user),
+			runInsertStub.GetCall(2).Arguments())
+		before2, after2, before3, after4 := before["2"], after["2"], before["3"], after["4"]
+		version0, version1, version2 := 0, 1, 2
+		assert.Equal(t, []*table.ChangeHistory{
+			{Entity: "company", EntityKey: "2", Action: table.HistoryUpdate, Version: &version1, BeforeImage: &before2, AfterImage: &after2},
+			{Entity: "company", EntityKey: "3", Action: table.HistoryDelete, Version: &version2, BeforeImage: &before3},
+			{Entity: "company", EntityKey: "4", Action: table.HistoryInsert, Version: &version0, AfterImage: &after4},
+		}, GetChanges(tx))
+	})
+}
+
+func Test_recordInsert(t *testing.T) {
+	user := "somebody"
+	image := `{"id":42,"name":"new payee","version":0}`
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		loadImagesStub := mocka.Function(t, &loadImages, map[string]string{"42": image})
+		defer loadImagesStub.Restore()
+		runInsertStub := mocka.Function(t, &runInsert, int64(1))
+		defer runInsertStub.Restore()
+
+		recordInsert(tx, "payee", 42, user)
+
+		assert.Equal(t, []interface{}{tx, "payee", []int64{42}}, loadImagesStub.GetCall(0).Arguments())
+		assert.Equal(t, sqltest.UpdateArgs(tx, insertHistorySQL, "payee", "42", table.HistoryInsert, 0, nil, image, nil, user),
+			runInsertStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_GetHistory(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		history := []*table.ChangeHistory{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, history)
+		defer runQueryStub.Restore()
+
+		result := GetHistory(tx, "transaction", "42")
+
+		assert.Equal(t, history, result)
+		assert.Equal(t, []interface{}{tx, historyType, historySQL, []interface{}{"transaction", "42"}}, runQueryStub.GetCall(0).Arguments())
+	})
+}
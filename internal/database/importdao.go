@@ -0,0 +1,110 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var importItemType = reflect.TypeOf(table.ImportItem{})
+var importItemTable = mapTable("import_item", importItemType)
+
+// suggests the payee by name, the category last used with that payee in the account and
+// an uncleared transaction in the account with the same amount within 3 days of the imported date.
+// The candidate transactions are in a derived table because SQLite can't sort a subquery on an outer column.
+const importItemSQL = `select ii.*,
+	(select p.id from payee p where p.name = ii.payee_name) payee_id,
+	(select td.transaction_category_id
+	 from transaction t
+	 join transaction_detail td on t.id = td.transaction_id
+	 join payee p on t.payee_id = p.id
+	 where t.account_id = ii.account_id and p.name = ii.payee_name and td.transaction_category_id is not null
+	 and t.trash_date is null
+	 order by t.date desc, t.id desc limit 1) transaction_category_id,
+	(select m.id from (
+	 select t.id, abs(@daysBetween(t.date, ii.date)) days
+	 from transaction t
+	 where t.account_id = ii.account_id and coalesce(t.cleared, 'N') = 'N' and t.trash_date is null
+	 and abs(@daysBetween(t.date, ii.date)) <= 3
+	 and (select sum(td.amount) from transaction_detail td where td.transaction_id = t.id) = ii.amount
+	 and not exists (select 1 from import_item mi where mi.transaction_id = t.id)
+	) m order by m.days, m.id limit 1) matched_transaction_id
+from import_item ii`
+
+// importItemQuery adds the version of the suggested transaction to the import items that match the condition.
+func importItemQuery(condition string) string {
+	return `select s.*, mt.version matched_transaction_version
+from (` + importItemSQL + " where " + condition + `) s
+left join transaction mt on mt.id = s.matched_transaction_id
+order by s.date, s.id`
+}
+
+// runImportItemQuery loads import items. A transaction is only suggested for the first of the items that match it.
+func runImportItemQuery(tx *Tx, query string, args ...interface{}) []*table.ImportItem {
+	items := runQuery(tx, importItemType, query, args...).([]*table.ImportItem)
+	matched := make(map[int64]bool)
+	for _, item := range items {
+		if item.MatchedTransactionID != nil {
+			if matched[*item.MatchedTransactionID] {
+				item.MatchedTransactionID, item.MatchedTransactionVersion = nil, nil
+			} else {
+				matched[*item.MatchedTransactionID] = true
+			}
+		}
+	}
+	return items
+}
+
+// GetImportItems returns the import items for the account that are waiting for review.
+func GetImportItems(tx *Tx, accountID int64) []*table.ImportItem {
+	return runImportItemQuery(tx, importItemQuery("ii.account_id = ? and ii.transaction_id is null"), accountID)
+}
+
+// GetImportItemsByIDs returns the import items for the specified IDs.
+func GetImportItemsByIDs(tx *Tx, ids []int64) []*table.ImportItem {
+	return runImportItemQuery(tx, importItemQuery("@in(ii.id)"), int64sToJson(ids))
+}
+
+var insertImportItemSQL = importItemTable.insertSQL("account_id", "date", "reference_number", "payee_name", "memo", "amount")
+
+// InsertImportItem adds an imported transaction to the staging area.
+func InsertImportItem(tx *Tx, accountID int64, values InputObject, user string) int64 {
+	fixed := columnValues{"account_id": accountID, "payee_name": values.StringOrNull("payee")}
+	id := runInsert(tx, insertImportItemSQL.sql, insertImportItemSQL.inputArgs(values, fixed, user)...)
+	recordInsert(tx, "import_item", id, user)
+	return id
+}
+
+const setImportTransactionSQL = `update import_item
+set transaction_id = ?, change_date = current_timestamp, change_user = ?, version = version+1
+where id = ? and version = ? and transaction_id is null`
+
+// SetImportTransaction links a pending import item to a transaction.
+func SetImportTransaction(tx *Tx, id int64, version int64, transactionID int64, user string) error {
+	var count int64
+	trackChanges(tx, "import_item", []int64{id}, user, func() {
+		count = runUpdate(tx, setImportTransactionSQL, transactionID, user, id, version)
+	})
+	if count == 0 {
+		return apperror.VersionConflict("import_item", id, version)
+	}
+	return nil
+}
+
+const deleteImportItemsSQL = `delete from import_item
+where transaction_id is null and @inObjects('id', id, 'version', version)`
+
+// DeleteImportItems discards pending import items. Returns a NotFoundError if any of them are not found.
+func DeleteImportItems(tx *Tx, ids []map[string]interface{}, user string) error {
+	deleteIDs, _ := json.Marshal(ids)
+	var count int64
+	trackChanges(tx, "import_item", versionIDKeys(ids), user, func() {
+		count = runUpdate(tx, deleteImportItemsSQL, deleteIDs)
+	})
+	if int(count) < len(ids) {
+		return apperror.NotFound("import_item")
+	}
+	return nil
+}
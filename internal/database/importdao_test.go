@@ -0,0 +1,155 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetImportItems(t *testing.T) {
+	accountID := int64(42)
+	items := []*table.ImportItem{{ID: 1}}
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runQueryStub := mocka.Function(t, &runQuery, items)
+		defer runQueryStub.Restore()
+
+		result := GetImportItems(tx, accountID)
+
+		assert.Equal(t, items, result)
+		assert.Equal(t, []interface{}{tx, importItemType,
+			importItemQuery("ii.account_id = ? and ii.transaction_id is null"), []interface{}{accountID}},
+			runQueryStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_GetImportItems_suggestsTransactionOnce(t *testing.T) {
+	txID, otherID, version := int64(96), int64(97), int64(2)
+	items := []*table.ImportItem{
+		{ID: 1, MatchedTransactionID: &txID, MatchedTransactionVersion: &version},
+		{ID: 2, MatchedTransactionID: &txID, MatchedTransactionVersion: &version},
+		{ID: 3, MatchedTransactionID: &otherID, MatchedTransactionVersion: &version},
+		{ID: 4},
+	}
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runQueryStub := mocka.Function(t, &runQuery, items)
+		defer runQueryStub.Restore()
+
+		result := GetImportItems(tx, 42)
+
+		assert.Equal(t, &txID, result[0].MatchedTransactionID)
+		assert.Equal(t, &version, result[0].MatchedTransactionVersion)
+		assert.Nil(t, result[1].MatchedTransactionID)
+		assert.Nil(t, result[1].MatchedTransactionVersion)
+		assert.Equal(t, &otherID, result[2].MatchedTransactionID)
+		assert.Nil(t, result[3].MatchedTransactionID)
+	})
+}
+
+func Test_GetImportItemsByIDs(t *testing.T) {
+	items := []*table.ImportItem{{ID: 1}}
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runQueryStub := mocka.Function(t, &runQuery, items)
+		defer runQueryStub.Restore()
+
+		result := GetImportItemsByIDs(tx, []int64{1, 2})
+
+		assert.Equal(t, items, result)
+		assert.Equal(t, []interface{}{tx, importItemType, importItemQuery("@in(ii.id)"), []interface{}{"[1,2]"}},
+			runQueryStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_InsertImportItem(t *testing.T) {
+	id := int64(42)
+	accountID := int64(96)
+	user := "user id"
+	date := time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC)
+	values := InputObject{
+		"date":            date,
+		"referenceNumber": "123",
+		"payee":           "the payee",
+		"memo":            "notes",
+		"amount":          -12.34,
+	}
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runInsertStub := mocka.Function(t, &runInsert, id)
+		defer runInsertStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		result := InsertImportItem(tx, accountID, values, user)
+
+		assert.Equal(t, id, result)
+		assert.Equal(t, sqltest.UpdateArgs(tx, insertImportItemSQL.sql, accountID, date, "123", "the payee", "notes", -12.34, user),
+			runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"import_item", id, user}}, history.inserts)
+	})
+}
+
+func Test_SetImportTransaction(t *testing.T) {
+	id := int64(42)
+	version := int64(1)
+	txID := int64(96)
+	user := "user id"
+	t.Run("links transaction", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			SetImportTransaction(tx, id, version, txID, user)
+
+			assert.Equal(t, sqltest.UpdateArgs(tx, setImportTransactionSQL, txID, user, id, version), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"import_item", []int64{id}, user}}, history.changes)
+		})
+	})
+	t.Run("returns error for import item not found", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := SetImportTransaction(tx, id, version, txID, user)
+
+			assert.EqualError(t, err, "import item not found (42 @ 1)")
+		})
+	})
+}
+
+func Test_DeleteImportItems(t *testing.T) {
+	ids := []map[string]interface{}{{"id": 42, "version": 1}, {"id": 24, "version": 0}}
+	idArg, _ := json.Marshal(ids)
+	t.Run("deletes import items", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			DeleteImportItems(tx, ids, "somebody")
+
+			assert.Equal(t, sqltest.UpdateArgs(tx, deleteImportItemsSQL, idArg), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"import_item", []interface{}{42, 24}, "somebody"}}, history.changes)
+		})
+	})
+	t.Run("returns error for import items not found", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := DeleteImportItems(tx, ids, "somebody")
+
+			assert.EqualError(t, err, "import item(s) not found")
+		})
+	})
+}
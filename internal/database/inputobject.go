@@ -1,6 +1,6 @@
 package database
 
-import "errors"
+import "github.com/jonestimd/financesd/internal/apperror"
 
 type InputObject map[string]interface{}
 
@@ -75,12 +75,14 @@ type VersionID struct {
 	Version int64
 }
 
-func (io InputObject) GetVersionID() *VersionID {
+// GetVersionID returns the ID and version of the input or nil if it doesn't have an ID. Returns a ValidationError
+// if the input has an ID without a version.
+func (io InputObject) GetVersionID() (*VersionID, error) {
 	if id, ok := io.GetInt("id"); ok {
 		if version, ok := io.GetInt("version"); ok {
-			return &VersionID{ID: id.(int64), Version: version.(int64)}
+			return &VersionID{ID: id.(int64), Version: version.(int64)}, nil
 		}
-		panic(errors.New("version is required for update/delete"))
+		return nil, apperror.Validation("version", "version is required for update/delete")
 	}
-	return nil
+	return nil, nil
 }
@@ -1,9 +1,9 @@
 package database
 
 import (
-	"errors"
 	"testing"
 
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -154,22 +154,15 @@ func Test_InputObject_GetVersionID(t *testing.T) {
 		expectedErr   error
 	}{
 		{"returns nil for no values", map[string]interface{}{}, nil, nil},
-		{"panics for id without version", map[string]interface{}{"id": 42}, nil, errors.New("version is required for update/delete")},
+		{"returns error for id without version", map[string]interface{}{"id": 42}, nil, apperror.Validation("version", "version is required for update/delete")},
 		{"returns id and version", map[string]interface{}{"id": 42, "version": 96}, &VersionID{42, 96}, nil},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			defer func() {
-				if err := recover(); err != nil {
-					assert.Equal(t, test.expectedErr, err)
-				} else if test.expectedErr != nil {
-					assert.Fail(t, "expected an error")
-				}
-			}()
-
-			value := test.values.GetVersionID()
+			value, err := test.values.GetVersionID()
 
 			assert.Equal(t, test.expectedValue, value)
+			assert.Equal(t, test.expectedErr, err)
 		})
 	}
 }
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// tableMapping generates the insert and update statements for a table from the `db` tags of its model.
+type tableMapping struct {
+	name    string
+	mapping *table.Mapping
+}
+
+// tableMappings contains the mapped tables, which are checked against the database by CheckSchema.
+var tableMappings []*tableMapping
+
+// mapTable maps a table to a model. All of the columns of the model that aren't derived must be in the table.
+func mapTable(name string, modelType reflect.Type) *tableMapping {
+	t := &tableMapping{name: name, mapping: table.MappingOf(modelType)}
+	tableMappings = append(tableMappings, t)
+	return t
+}
+
+// columns returns the model columns with the names. Panics if a column isn't a stored column of the model.
+func (t *tableMapping) columns(names []string) []*table.Column {
+	columns := make([]*table.Column, len(names))
+	for i, name := range names {
+		if columns[i] = t.mapping.Column(name); columns[i] == nil || columns[i].Derived {
+			panic(fmt.Errorf("%s is not a column of %s", name, t.name))
+		}
+	}
+	return columns
+}
+
+// modelStatement is an insert or update that was generated from a table mapping.
+type modelStatement struct {
+	sql     string
+	columns []*table.Column
+	// partial is true if each column has a flag argument that is true if the column should be updated
+	partial bool
+}
+
+const auditUpdate = "change_date = current_timestamp, change_user = ?, version = version+1"
+
+// insertSQL returns an insert of the columns. The statement sets the audit columns and the version, so the
+// arguments are the column values followed by the change user.
+func (t *tableMapping) insertSQL(names ...string) *modelStatement {
+	sql := fmt.Sprintf("insert into %s\n(%s, change_date, change_user, version)\nvalues (%scurrent_timestamp, ?, 0)",
+		t.name, strings.Join(names, ", "), strings.Repeat("?, ", len(names)))
+	return &modelStatement{sql: sql, columns: t.columns(names)}
+}
+
+// updateSQL returns an update of the columns by ID and version. The arguments are the column values followed by
+// the change user, ID and version.
+func (t *tableMapping) updateSQL(names ...string) *modelStatement {
+	sql := fmt.Sprintf("update %s set %s = ?, %s\nwhere id = ? and version = ?", t.name, strings.Join(names, " = ?, "), auditUpdate)
+	return &modelStatement{sql: sql, columns: t.columns(names)}
+}
+
+// partialUpdateSQL returns an update by ID and version that only changes some of the columns. Each column has 2
+// arguments: a flag that is true if the column should be updated and the value. The column arguments are followed
+// by the change user, ID and version.
+func (t *tableMapping) partialUpdateSQL(names ...string) *modelStatement {
+	sets := make([]string, len(names))
+	for i, name := range names {
+		sets[i] = fmt.Sprintf("%s = case when ? then ? else %s end", name, name)
+	}
+	sql := fmt.Sprintf("update %s\nset %s\n, %s\nwhere id = ? and version = ?", t.name, strings.Join(sets, "\n, "), auditUpdate)
+	return &modelStatement{sql: sql, columns: t.columns(names), partial: true}
+}
+
+// and returns a copy of the update with an additional condition.
+func (s *modelStatement) and(condition string) *modelStatement {
+	return &modelStatement{sql: s.sql + " and " + condition, columns: s.columns, partial: s.partial}
+}
+
+// modelArgs returns the values of the model's fields for the columns followed by the extra arguments. model must
+// be a pointer to the model struct.
+func (s *modelStatement) modelArgs(model interface{}, extra ...interface{}) []interface{} {
+	value := reflect.ValueOf(model)
+	args := make([]interface{}, 0, len(s.columns)+len(extra))
+	for _, column := range s.columns {
+		args = append(args, column.Value(value))
+	}
+	return append(args, extra...)
+}
+
+// columnValues contains argument values by column name.
+type columnValues map[string]interface{}
+
+// inputArgs returns the input values for the columns followed by the extra arguments. The input field of a column
+// is the column name in camel case, e.g. referenceNumber for reference_number. The value is converted to the type
+// of the model field. fixed contains column values that are used instead of the input. A partial update only
+// changes a column that isn't nullable when the input value is not null.
+func (s *modelStatement) inputArgs(input InputObject, fixed columnValues, extra ...interface{}) []interface{} {
+	args := make([]interface{}, 0, 2*len(s.columns)+len(extra))
+	for _, column := range s.columns {
+		value, set := fixed[column.Name]
+		if !set {
+			value, set = inputValue(input, column)
+			set = set && (value != nil || column.Nullable)
+		}
+		if s.partial {
+			args = append(args, set)
+		}
+		args = append(args, value)
+	}
+	return append(args, extra...)
+}
+
+var yesNoType = reflect.TypeOf(table.YesNo(0))
+
+// inputValue returns the input value for the column and true if the input contains the column's field.
+func inputValue(input InputObject, column *table.Column) (interface{}, bool) {
+	key := camelCase(column.Name)
+	if _, exists := input[key]; !exists {
+		return nil, false
+	}
+	if column.Type == yesNoType {
+		return input.YesNoOrNull(key), true
+	}
+	switch column.Type.Kind() {
+	case reflect.String:
+		return input.StringOrNull(key), true
+	case reflect.Int, reflect.Int64:
+		return input.IntOrNull(key), true
+	case reflect.Float64:
+		return input.FloatOrNull(key), true
+	}
+	return input[key], true
+}
+
+// camelCase converts a column name to the name of an input field, e.g. transaction_category_id to
+// transactionCategoryId.
+func camelCase(column string) string {
+	words := strings.Split(column, "_")
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// CheckSchema returns an error listing the mapped columns that are missing from the database.
+func CheckSchema(tx *Tx) error {
+	var missing []string
+	for _, t := range tableMappings {
+		columns := tableColumns(tx, t.name)
+		for _, column := range t.mapping.Columns {
+			if !column.Derived && !columns[column.Name] {
+				missing = append(missing, t.name+"."+column.Name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("columns missing from the database: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// tableColumns returns the names of the table's columns.
+var tableColumns = func(tx *Tx, tableName string) map[string]bool {
+	rows := queryRows(tx, currentDialect.expand(fmt.Sprintf("select * from %s where 1 = 0", tableName)), nil)
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		panic(err)
+	}
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[strings.ToLower(name)] = true
+	}
+	return columns
+}
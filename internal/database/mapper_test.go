@@ -0,0 +1,136 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTable = &tableMapping{name: "transaction", mapping: table.MappingOf(transactionType)}
+
+func Test_tableMapping_insertSQL(t *testing.T) {
+	stmt := testTable.insertSQL("account_id", "memo")
+
+	assert.Equal(t, "insert into transaction\n(account_id, memo, change_date, change_user, version)\nvalues (?, ?, current_timestamp, ?, 0)", stmt.sql)
+	assert.False(t, stmt.partial)
+}
+
+func Test_tableMapping_insertSQL_panicsForInvalidColumn(t *testing.T) {
+	tests := []struct {
+		name   string
+		column string
+	}{
+		{"unknown column", "unknown"},
+		{"derived column", "transaction_count"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payeeTable := &tableMapping{name: "payee", mapping: table.MappingOf(payeeType)}
+			defer func() {
+				assert.EqualError(t, recover().(error), test.column+" is not a column of payee")
+			}()
+
+			payeeTable.insertSQL("name", test.column)
+		})
+	}
+}
+
+func Test_tableMapping_updateSQL(t *testing.T) {
+	stmt := testTable.updateSQL("account_id", "memo")
+
+	assert.Equal(t, "update transaction set account_id = ?, memo = ?, change_date = current_timestamp, change_user = ?, version = version+1\n"+
+		"where id = ? and version = ?", stmt.sql)
+}
+
+func Test_tableMapping_partialUpdateSQL(t *testing.T) {
+	stmt := testTable.partialUpdateSQL("account_id", "memo").and("trash_date is null")
+
+	assert.Equal(t, "update transaction\nset account_id = case when ? then ? else account_id end\n, memo = case when ? then ? else memo end\n"+
+		", change_date = current_timestamp, change_user = ?, version = version+1\nwhere id = ? and version = ? and trash_date is null", stmt.sql)
+	assert.True(t, stmt.partial)
+}
+
+func Test_modelStatement_modelArgs(t *testing.T) {
+	memo := "notes"
+	stmt := testTable.insertSQL("account_id", "memo", "payee_id")
+
+	args := stmt.modelArgs(&table.Transaction{AccountID: 42, Memo: &memo}, "somebody")
+
+	assert.Equal(t, []interface{}{int64(42), &memo, (*int64)(nil), "somebody"}, args)
+}
+
+func Test_modelStatement_inputArgs(t *testing.T) {
+	input := InputObject{"date": "2020-12-25", "referenceNumber": "ref", "payeeId": 96, "cleared": true, "memo": nil}
+	t.Run("returns input values", func(t *testing.T) {
+		stmt := testTable.insertSQL("account_id", "date", "reference_number", "payee_id", "security_id", "memo", "cleared")
+
+		args := stmt.inputArgs(input, columnValues{"account_id": int64(42)}, "somebody")
+
+		assert.Equal(t, []interface{}{int64(42), "2020-12-25", "ref", int64(96), nil, nil, "Y", "somebody"}, args)
+	})
+	t.Run("returns flags for partial update", func(t *testing.T) {
+		stmt := testTable.partialUpdateSQL("account_id", "date", "payee_id", "security_id", "memo")
+
+		args := stmt.inputArgs(InputObject{"accountId": nil, "payeeId": 96, "memo": nil}, nil, "somebody", int64(1), int64(2))
+
+		assert.Equal(t, []interface{}{false, nil, false, nil, true, int64(96), false, nil, true, nil, "somebody", int64(1), int64(2)}, args)
+	})
+	t.Run("fixed value overrides input", func(t *testing.T) {
+		stmt := testTable.partialUpdateSQL("payee_id")
+
+		args := stmt.inputArgs(input, columnValues{"payee_id": nil})
+
+		assert.Equal(t, []interface{}{true, nil}, args)
+	})
+}
+
+func Test_camelCase(t *testing.T) {
+	assert.Equal(t, "memo", camelCase("memo"))
+	assert.Equal(t, "transactionCategoryId", camelCase("transaction_category_id"))
+}
+
+func Test_CheckSchema(t *testing.T) {
+	stubColumns := func(missing string) func() {
+		saved := tableColumns
+		tableColumns = func(tx *Tx, tableName string) map[string]bool {
+			columns := make(map[string]bool)
+			for _, t := range tableMappings {
+				if t.name == tableName {
+					for _, column := range t.mapping.Columns {
+						columns[column.Name] = tableName+"."+column.Name != missing
+					}
+				}
+			}
+			return columns
+		}
+		return func() { tableColumns = saved }
+	}
+	t.Run("returns nil if all columns exist", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			defer stubColumns("")()
+
+			assert.Nil(t, CheckSchema(tx))
+		})
+	})
+	t.Run("returns missing columns", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			defer stubColumns("payee.name")()
+
+			assert.EqualError(t, CheckSchema(tx), "columns missing from the database: payee.name")
+		})
+	})
+}
+
+func Test_tableColumns(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		mockDB.ExpectQuery(currentDialect.expand("select * from payee where 1 = 0")).WillReturnRows(sqltest.MockRows("ID", "name"))
+
+		columns := tableColumns(tx, "payee")
+
+		assert.Equal(t, map[string]bool{"id": true, "name": true}, columns)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
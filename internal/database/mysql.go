@@ -0,0 +1,55 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers for not null, unique key, foreign key and check constraint violations
+var constraintErrors = map[uint16]bool{1048: true, 1062: true, 1451: true, 1452: true, 3819: true}
+
+var mysqlDialect = &dialect{
+	name: "mysql",
+	macros: map[string]func(args []string) string{
+		// @in(expr): expr is in the JSON array parameter
+		"in": func(args []string) string {
+			return fmt.Sprintf("json_contains(?, json_array(%s))", args[0])
+		},
+		// @inObjects('key1', expr1, ...): the expressions match the keys of an object in the JSON array parameter
+		"inObjects": func(args []string) string {
+			return fmt.Sprintf("json_contains(?, json_object(%s))", strings.Join(args, ", "))
+		},
+		"concat": func(args []string) string {
+			return fmt.Sprintf("concat(%s)", strings.Join(args, ", "))
+		},
+		// @daysAgo(days): the timestamp of the number of days before the current time
+		"daysAgo": func(args []string) string {
+			return fmt.Sprintf("current_timestamp - interval %s day", args[0])
+		},
+		// @daysBetween(date1, date2): the number of days from date2 to date1
+		"daysBetween": func(args []string) string {
+			return fmt.Sprintf("datediff(%s, %s)", args[0], args[1])
+		},
+		// @upsert(keyColumn): starts the update clause of an insert that replaces an existing row
+		"upsert": func(args []string) string {
+			return "on duplicate key update"
+		},
+		// @inserted(column): the value of the column from the insert clause of an upsert
+		"inserted": func(args []string) string {
+			return fmt.Sprintf("values(%s)", args[0])
+		},
+		// @autoID(): the type of a generated primary key column in DDL
+		"autoID": func(args []string) string {
+			return "bigint not null auto_increment primary key"
+		},
+	},
+	isConstraintError: func(err error) bool {
+		mysqlErr, ok := err.(*mysql.MySQLError)
+		return ok && constraintErrors[mysqlErr.Number]
+	},
+	scanTarget: func(ptr interface{}) interface{} {
+		return ptr
+	},
+}
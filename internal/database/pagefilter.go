@@ -0,0 +1,26 @@
+package database
+
+// PageFilter selects a date range and a page of the transactions or details of each parent row, e.g. each payee.
+type PageFilter struct {
+	// StartDate is the earliest transaction date (inclusive) or nil
+	StartDate interface{}
+	// EndDate is the latest transaction date (inclusive) or nil
+	EndDate interface{}
+	// Limit is the maximum number of rows for each parent or 0 for no limit
+	Limit  int
+	Offset int
+}
+
+// pageArgs returns the arguments of a page query: the parent IDs, the date range, the account IDs and the page.
+// The rows are not limited by account if accountIDs is nil.
+func (f PageFilter) pageArgs(parentIDs []int64, accountIDs []int64) []interface{} {
+	var accounts interface{}
+	if accountIDs != nil {
+		accounts = int64sToJson(accountIDs)
+	}
+	return []interface{}{int64sToJson(parentIDs),
+		f.StartDate, f.StartDate,
+		f.EndDate, f.EndDate,
+		accounts, accounts,
+		f.Offset, f.Limit, f.Offset + f.Limit}
+}
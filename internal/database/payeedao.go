@@ -1,20 +1,59 @@
 package database
 
 import (
-	"database/sql"
 	"reflect"
 
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
 var payeeType = reflect.TypeOf(table.Payee{})
+var payeeTable = mapTable("payee", payeeType)
 
-const payeeSQL = `select p.*,
-	(select count(t.id) from transaction t where t.payee_id = p.id) transaction_count
-from payee p`
+const payeeSQL = `select p.* from payee p`
 
 // GetAllPayees loads all payees.
-func GetAllPayees(tx *sql.Tx) []*table.Payee {
+func GetAllPayees(tx *Tx) []*table.Payee {
 	payees := runQuery(tx, payeeType, payeeSQL)
 	return payees.([]*table.Payee)
 }
+
+// GetPayeesByIDs returns the payees with the IDs.
+func GetPayeesByIDs(tx *Tx, ids []int64) []*table.Payee {
+	payees := runQuery(tx, payeeType, payeeSQL+" where @in(p.id)", int64sToJson(ids))
+	return payees.([]*table.Payee)
+}
+
+const payeeAmountsSQL = `select t.payee_id id, a.currency_id, sum(td.amount) amount
+from transaction t
+join transaction_detail td on t.id = td.transaction_id
+join account a on t.account_id = a.id
+where @in(t.payee_id) and t.trash_date is null
+and (? is null or @in(t.account_id))
+group by t.payee_id, a.currency_id`
+
+// GetPayeeAmounts returns the total of the transactions of each payee for each account currency, limited to the
+// accounts if accountIDs is not nil.
+func GetPayeeAmounts(tx *Tx, payeeIDs []int64, accountIDs []int64) []*table.CurrencyAmount {
+	return runAmountQuery(tx, payeeAmountsSQL, payeeIDs, accountIDs)
+}
+
+const payeeTransactionCountsSQL = `select t.payee_id id, count(t.id) transaction_count
+from transaction t
+where @in(t.payee_id) and t.trash_date is null
+and (? is null or @in(t.account_id))
+group by t.payee_id`
+
+// GetPayeeTransactionCounts returns the number of transactions of each payee, limited to the accounts if accountIDs is
+// not nil.
+func GetPayeeTransactionCounts(tx *Tx, payeeIDs []int64, accountIDs []int64) []*table.TransactionCount {
+	return runCountQuery(tx, payeeTransactionCountsSQL, payeeIDs, accountIDs)
+}
+
+var addPayeeSQL = payeeTable.insertSQL("name")
+
+// AddPayee adds a new payee and returns its ID.
+func AddPayee(tx *Tx, name string, user string) int64 {
+	id := runInsert(tx, addPayeeSQL.sql, name, user)
+	recordInsert(tx, "payee", id, user)
+	return id
+}
@@ -1,7 +1,6 @@
 package database
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -12,7 +11,7 @@ import (
 )
 
 func Test_GetAllPayees(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		payees := []*table.Payee{{ID: 1}}
 		runQueryStub := mocka.Function(t, &runQuery, payees)
 		defer runQueryStub.Restore()
@@ -23,3 +22,63 @@ func Test_GetAllPayees(t *testing.T) {
 		assert.Equal(t, payees, result)
 	})
 }
+
+func Test_GetPayeesByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		payees := []*table.Payee{{ID: 1}}
+		runQueryStub := mocka.Function(t, &runQuery, payees)
+		defer runQueryStub.Restore()
+
+		result := GetPayeesByIDs(tx, []int64{42, 96})
+
+		assert.Equal(t, []interface{}{tx, payeeType, payeeSQL + " where @in(p.id)", []interface{}{"[42,96]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, payees, result)
+	})
+}
+
+func Test_GetPayeeAmounts(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		amounts := []*table.CurrencyAmount{{ID: 42, CurrencyID: 1, Amount: 12.34}}
+		runQueryStub := mocka.Function(t, &runQuery, amounts)
+		defer runQueryStub.Restore()
+
+		result := GetPayeeAmounts(tx, []int64{42, 96}, []int64{1})
+
+		assert.Equal(t, []interface{}{tx, currencyAmountType, payeeAmountsSQL, []interface{}{"[42,96]", "[1]", "[1]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, amounts, result)
+	})
+}
+
+func Test_AddPayee(t *testing.T) {
+	id := int64(42)
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runInsertStub := mocka.Function(t, &runInsert, id)
+		defer runInsertStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		result := AddPayee(tx, "the payee", "somebody")
+
+		assert.Equal(t, id, result)
+		assert.Equal(t,
+			sqltest.UpdateArgs(tx, addPayeeSQL.sql, "the payee", "somebody"),
+			runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"payee", id, "somebody"}}, history.inserts)
+	})
+}
+
+func Test_GetPayeeTransactionCounts(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		counts := []*table.TransactionCount{{ID: 42, Count: 3}}
+		runQueryStub := mocka.Function(t, &runQuery, counts)
+		defer runQueryStub.Restore()
+
+		result := GetPayeeTransactionCounts(tx, []int64{42, 96}, []int64{1})
+
+		assert.Equal(t, []interface{}{tx, transactionCountType, payeeTransactionCountsSQL, []interface{}{"[42,96]", "[1]", "[1]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, counts, result)
+	})
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL supports joins in update statements using the from clause
+const postgresSetTransferAmountSQL = `update transaction_detail as td
+set amount = coalesce(?, -coalesce(?, rd.amount) * coalesce(?, -td.amount / nullif(rd.amount, 0), 1))
+, change_date = current_timestamp, change_user = ?, version = td.version+1
+from transaction_detail rd
+where td.related_detail_id = rd.id and td.related_detail_id = ?`
+
+// PostgreSQL doesn't have the database() function
+const postgresSchemaVersionTableSQL = `select count(*) from information_schema.tables
+where table_schema = current_schema() and table_name = 'schema_version'`
+
+var postgresDialect = &dialect{
+	name: "postgres",
+	macros: map[string]func(args []string) string{
+		"in": func(args []string) string {
+			return fmt.Sprintf("%s = any(?)", args[0])
+		},
+		// the object values are compared as bigints because they are IDs and versions
+		"inObjects": func(args []string) string {
+			columns := make([]string, 0, len(args)/2)
+			values := make([]string, 0, len(args)/2)
+			for i := 0; i < len(args); i += 2 {
+				columns = append(columns, args[i+1])
+				values = append(values, fmt.Sprintf("(value->>'%s')::bigint", strings.Trim(args[i], "'")))
+			}
+			return fmt.Sprintf("(%s) in (select %s from jsonb_array_elements(?::jsonb))",
+				strings.Join(columns, ", "), strings.Join(values, ", "))
+		},
+		"concat": func(args []string) string {
+			return fmt.Sprintf("(%s)", strings.Join(args, " || "))
+		},
+		"daysAgo": func(args []string) string {
+			return fmt.Sprintf("current_timestamp - make_interval(days => %s)", args[0])
+		},
+		"daysBetween": func(args []string) string {
+			return fmt.Sprintf("(%s - %s)", args[0], args[1])
+		},
+		"upsert": func(args []string) string {
+			return fmt.Sprintf("on conflict (%s) do update set", args[0])
+		},
+		"inserted": func(args []string) string {
+			return "excluded." + args[0]
+		},
+		"autoID": func(args []string) string {
+			return "bigserial primary key"
+		},
+	},
+	statements: map[string]string{
+		setTransferAmountSQL:  postgresSetTransferAmountSQL,
+		schemaVersionTableSQL: postgresSchemaVersionTableSQL,
+	},
+	rewrites: []rewrite{
+		// the type of a parameter that is only tested for null can't be inferred
+		{regexp.MustCompile(`\? is null`), "cast(? as text) is null"},
+	},
+	placeholder: func(n int) string {
+		return fmt.Sprintf("$%d", n)
+	},
+	convertArg: func(arg interface{}, isArray bool) interface{} {
+		if bytes, ok := arg.([]byte); ok {
+			arg = string(bytes)
+		}
+		if text, ok := arg.(string); ok && isArray {
+			return jsonToArray(text)
+		}
+		return arg
+	},
+	returningID: true,
+	isConstraintError: func(err error) bool {
+		pqErr, ok := err.(*pq.Error)
+		return ok && pqErr.Code.Class() == "23"
+	},
+	scanTarget: func(ptr interface{}) interface{} {
+		return ptr
+	},
+}
+
+// jsonToArray converts a JSON array of IDs or keys to a PostgreSQL array parameter.
+func jsonToArray(text string) interface{} {
+	var values []interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		panic(err)
+	}
+	ints := make([]int64, 0, len(values))
+	strs := make([]string, 0, len(values))
+	for _, value := range values {
+		switch value := value.(type) {
+		case json.Number:
+			n, err := value.Int64()
+			if err != nil {
+				panic(err)
+			}
+			ints = append(ints, n)
+		case string:
+			strs = append(strs, value)
+		default:
+			panic(fmt.Errorf("unsupported array value: %v", value))
+		}
+	}
+	if len(strs) > 0 {
+		return pq.Array(strs)
+	}
+	return pq.Array(ints)
+}
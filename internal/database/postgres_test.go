@@ -0,0 +1,47 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_postgresDialect_isConstraintError(t *testing.T) {
+	assert.True(t, postgresDialect.isConstraintError(&pq.Error{Code: "23505"}))
+	assert.True(t, postgresDialect.isConstraintError(&pq.Error{Code: "23503"}))
+	assert.False(t, postgresDialect.isConstraintError(&pq.Error{Code: "40001"}))
+	assert.False(t, postgresDialect.isConstraintError(errors.New("constraint failed")))
+}
+
+func Test_postgresDialect_bind(t *testing.T) {
+	query := "select * from company where @in(id) and name = ?"
+
+	sql, args := postgresDialect.bind(query, []interface{}{"[42,96]", []byte("the company")})
+
+	assert.Equal(t, "select * from company where id = any($1) and name = $2", sql)
+	assert.Equal(t, []interface{}{pq.Array([]int64{42, 96}), "the company"}, args)
+}
+
+func Test_postgresDialect_bind_inObjects(t *testing.T) {
+	query := "delete from company where @inObjects('id', id, 'version', version)"
+	ids := `[{"id":42,"version":1}]`
+
+	_, args := postgresDialect.bind(query, []interface{}{[]byte(ids)})
+
+	assert.Equal(t, []interface{}{ids}, args)
+}
+
+func Test_jsonToArray(t *testing.T) {
+	assert.Equal(t, pq.Array([]int64{42, 96}), jsonToArray("[42,96]"))
+	assert.Equal(t, pq.Array([]int64{}), jsonToArray("[]"))
+	assert.Equal(t, pq.Array([]string{"base_currency_id"}), jsonToArray(`["base_currency_id"]`))
+	t.Run("panics for invalid value", func(t *testing.T) {
+		defer func() {
+			assert.EqualError(t, recover().(error), "unsupported array value: true")
+		}()
+
+		jsonToArray("[true]")
+	})
+}
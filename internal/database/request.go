@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Tx is a database transaction and the state that is shared by the statements run in it. The statements are run
+// using the context of the transaction, so that they are cancelled when the API request is cancelled or times out.
+type Tx struct {
+	*sql.Tx
+	ctx   context.Context
+	mutex sync.Mutex
+	// stats is nil if the transaction is not part of a request
+	stats *RequestStats
+	// statements contains the prepared statements of the request, keyed by the expanded query. Nil if the
+	// transaction is not part of a request.
+	statements map[string]*sql.Stmt
+	// changes is nil if the transaction doesn't record a change set
+	changes *changeSet
+}
+
+// NewTx returns a transaction that is not part of an API request. Its statements use the background context and are
+// not reused.
+func NewTx(tx *sql.Tx) *Tx {
+	return &Tx{Tx: tx, ctx: context.Background()}
+}
+
+// BeginRequest returns a transaction that runs its statements using the context of the API request. The prepared
+// statements are reused until the end of the request. The timing of the statements is added to stats, which may be nil.
+func BeginRequest(tx *sql.Tx, ctx context.Context, stats *RequestStats) *Tx {
+	if stats == nil {
+		stats = &RequestStats{}
+	}
+	return &Tx{Tx: tx, ctx: ctx, stats: stats, statements: make(map[string]*sql.Stmt)}
+}
+
+// EndRequest closes the prepared statements of the request.
+func EndRequest(tx *Tx) {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	for _, stmt := range tx.statements {
+		stmt.Close()
+	}
+	tx.statements = nil
+}
+
+// Context returns the context used to run the statements of the transaction.
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
+// requestStatement returns the prepared statement for the query from the request's cache, preparing it if necessary.
+// Returns nil if the transaction is not part of a request.
+func requestStatement(tx *Tx, query string) *sql.Stmt {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	if tx.statements == nil {
+		return nil
+	}
+	stmt, ok := tx.statements[query]
+	if !ok {
+		var err error
+		if stmt, err = tx.PrepareContext(tx.ctx, query); err != nil {
+			panic(err)
+		}
+		tx.statements[query] = stmt
+	}
+	return stmt
+}
+
+// queryRows runs a query using the request's prepared statement if the transaction is part of a request. The
+// statement is recorded when the rows are closed.
+func queryRows(tx *Tx, query string, args []interface{}) *statementRows {
+	var rows *sql.Rows
+	var err error
+	stmt := requestStatement(tx, query)
+	timer := startStatement(tx, query, args)
+	if stmt != nil {
+		rows, err = stmt.QueryContext(tx.ctx, args...)
+	} else {
+		rows, err = tx.QueryContext(tx.ctx, query, args...)
+	}
+	if err != nil {
+		timer.end(0)
+		panic(err)
+	}
+	return &statementRows{Rows: rows, timer: timer}
+}
+
+// prepare returns a prepared statement for the query. The statement is owned by the request if the transaction is
+// part of a request. Otherwise, the caller must call release when it is done with the statement.
+func prepare(tx *Tx, query string) (stmt *sql.Stmt, release func()) {
+	if stmt := requestStatement(tx, query); stmt != nil {
+		return stmt, func() {}
+	}
+	stmt, err := tx.PrepareContext(tx.ctx, query)
+	if err != nil {
+		panic(err)
+	}
+	return stmt, func() { stmt.Close() }
+}
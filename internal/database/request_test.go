@@ -0,0 +1,243 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/jonestimd/financesd/migrations"
+	"github.com/stretchr/testify/assert"
+)
+
+// testInTx runs a database test in a transaction that is not part of a request.
+func testInTx(t *testing.T, test func(mockDB sqlmock.Sqlmock, tx *Tx)) {
+	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		test(mockDB, NewTx(tx))
+	})
+}
+
+// testInRequest runs a database test in the transaction of a request that uses the context.
+func testInRequest(t *testing.T, ctx context.Context, test func(mockDB sqlmock.Sqlmock, tx *Tx)) {
+	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		test(mockDB, BeginRequest(tx, ctx, nil))
+	})
+}
+
+func Test_Tx_Context(t *testing.T) {
+	t.Run("returns background context without request", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			assert.Equal(t, context.Background(), tx.Context())
+		})
+	})
+	t.Run("returns request context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		testInRequest(t, ctx, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			defer EndRequest(tx)
+
+			assert.Same(t, ctx, tx.Context())
+		})
+	})
+}
+
+func Test_EndRequest(t *testing.T) {
+	query := "delete from company where id = ?"
+	testInRequest(t, context.Background(), func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		prepare := mockDB.ExpectPrepare(currentDialect.expand(query)).WillBeClosed()
+		prepare.ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mockDB.ExpectPrepare(currentDialect.expand(query)).WillBeClosed().
+			ExpectExec().WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		runUpdate(tx, query, 1)
+
+		EndRequest(tx)
+		runUpdate(tx, query, 2)
+
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func Test_runQuery_panicsForCancelledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	testInRequest(t, ctx, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		defer EndRequest(tx)
+		cancel()
+		defer func() {
+			assert.Equal(t, context.Canceled, recover())
+		}()
+
+		runQuery(tx, companyType, "select * from company")
+	})
+}
+
+func Test_runQuery_panicsForRequestCancelledDuringIteration(t *testing.T) {
+	query := "select * from company"
+	testInRequest(t, context.Background(), func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		defer EndRequest(tx)
+		// the driver reports the cancellation while reading the second row
+		rows := sqltest.MockRows("id").AddRow(1).AddRow(2).RowError(1, context.Canceled)
+		mockDB.ExpectPrepare(currentDialect.expand(query)).ExpectQuery().WillReturnRows(rows).RowsWillBeClosed()
+		defer func() {
+			assert.Equal(t, context.Canceled, recover())
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}()
+
+		runQuery(tx, companyType, query)
+	})
+}
+
+func Test_runIDQuery_panicsForRequestCancelledDuringIteration(t *testing.T) {
+	query := "select id from company"
+	testInRequest(t, context.Background(), func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		defer EndRequest(tx)
+		rows := sqltest.MockRows("id").AddRow(1).AddRow(2).RowError(1, context.Canceled)
+		mockDB.ExpectPrepare(currentDialect.expand(query)).ExpectQuery().WillReturnRows(rows).RowsWillBeClosed()
+		defer func() {
+			assert.Equal(t, context.Canceled, recover())
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		}()
+
+		runIDQuery(tx, query)
+	})
+}
+
+func Test_runUpdate_panicsForCancelledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	testInRequest(t, ctx, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		defer EndRequest(tx)
+		cancel()
+		defer func() {
+			assert.Equal(t, context.Canceled, recover())
+		}()
+
+		runUpdate(tx, "delete from company where id = ?", 1)
+	})
+}
+
+func Test_runUpdate_reusesRequestStatement(t *testing.T) {
+	query := "delete from company where id = ?"
+	testInRequest(t, context.Background(), func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		prepare := mockDB.ExpectPrepare(currentDialect.expand(query)).WillBeClosed()
+		prepare.ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		prepare.ExpectExec().WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.Equal(t, int64(1), runUpdate(tx, query, 1))
+		assert.Equal(t, int64(0), runUpdate(tx, query, 2))
+		EndRequest(tx)
+
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func Test_runQuery_reusesRequestStatement(t *testing.T) {
+	query := "select * from company where name = ?"
+	testInRequest(t, context.Background(), func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		prepare := mockDB.ExpectPrepare(currentDialect.expand(query)).WillBeClosed()
+		prepare.ExpectQuery().WithArgs("x").WillReturnRows(sqltest.MockRows("id").AddRow(1))
+		prepare.ExpectQuery().WithArgs("y").WillReturnRows(sqltest.MockRows("id").AddRow(2))
+
+		assert.Equal(t, []*table.Company{{ID: 1}}, runQuery(tx, companyType, query, "x"))
+		assert.Equal(t, []*table.Company{{ID: 2}}, runQuery(tx, companyType, query, "y"))
+		EndRequest(tx)
+
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func Test_runInsert_reusesRequestStatement(t *testing.T) {
+	query := "insert into company (name) values (?)"
+	testInRequest(t, context.Background(), func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		var prepare *sqlmock.ExpectedPrepare
+		if currentDialect.returningID {
+			prepare = mockDB.ExpectPrepare(currentDialect.expand(query + " returning id")).WillBeClosed()
+			prepare.ExpectQuery().WithArgs("x").WillReturnRows(sqltest.MockRows("id").AddRow(1))
+			prepare.ExpectQuery().WithArgs("y").WillReturnRows(sqltest.MockRows("id").AddRow(2))
+		} else {
+			prepare = mockDB.ExpectPrepare(currentDialect.expand(query)).WillBeClosed()
+			prepare.ExpectExec().WithArgs("x").WillReturnResult(sqlmock.NewResult(1, 1))
+			prepare.ExpectExec().WithArgs("y").WillReturnResult(sqlmock.NewResult(2, 1))
+		}
+
+		assert.Equal(t, int64(1), runInsert(tx, query, "x"))
+		assert.Equal(t, int64(2), runInsert(tx, query, "y"))
+		EndRequest(tx)
+
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+var scriptSeparator = regexp.MustCompile(`;\s*\n`)
+
+// newBenchmarkDB returns an in-memory SQLite database containing the schema and an account.
+func newBenchmarkDB(b *testing.B) (*sql.DB, int64) {
+	script, err := fs.ReadFile(migrations.Files, "schema.sql")
+	if err != nil {
+		b.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	sqlTx, err := db.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	tx := NewTx(sqlTx)
+	var statements []string
+	for _, statement := range scriptSeparator.Split(string(script), -1) {
+		if strings.TrimSpace(statement) != "" {
+			statements = append(statements, statement)
+		}
+	}
+	ExecScript(tx, statements)
+	currencyID := AddCurrency(tx, &table.Currency{Code: "USD", Asset: table.Asset{Name: "US Dollar", Type: "Currency", Scale: 2}}, "bench")
+	accountID := runInsert(tx, `insert into account (name, type, currency_id, change_date, change_user)
+		values ('Checking', 'BANK', ?, current_timestamp, 'bench')`, currencyID)
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+	return db, accountID
+}
+
+// Benchmark_insertBatch inserts a batch of 200 transactions with 2 details each, which is the size of a large import.
+func Benchmark_insertBatch(b *testing.B) {
+	if currentDialect != sqliteDialect {
+		b.Skip("uses an in-memory SQLite database")
+	}
+	db, accountID := newBenchmarkDB(b)
+	defer db.Close()
+	details := []InputObject{{"memo": "detail 1"}, {"memo": "detail 2"}}
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%v", cached), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sqlTx, err := db.Begin()
+				if err != nil {
+					b.Fatal(err)
+				}
+				tx := NewTx(sqlTx)
+				if cached {
+					tx = BeginRequest(sqlTx, context.Background(), nil)
+				}
+				ids := make([]int64, 200)
+				for j := range ids {
+					ids[j] = InsertTransaction(tx, accountID, InputObject{"date": "2021-01-01", "memo": "imported"}, "bench")
+					for _, detail := range details {
+						InsertDetail(tx, ids[j], 10.5, detail, "bench")
+					}
+				}
+				if err := ValidateDetails(tx, ids); err != nil {
+					b.Fatal(err)
+				}
+				EndRequest(tx)
+				tx.Rollback()
+			}
+		})
+	}
+}
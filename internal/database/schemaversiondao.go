@@ -0,0 +1,45 @@
+package database
+
+import (
+	"reflect"
+
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var schemaVersionType = reflect.TypeOf(table.SchemaVersion{})
+
+const createSchemaVersionSQL = `create table if not exists schema_version (
+    version int not null primary key,
+    name varchar(100) not null,
+    apply_date timestamp not null
+)`
+
+const schemaVersionTableSQL = `select count(*) from information_schema.tables
+where table_schema = database() and table_name = 'schema_version'`
+
+// GetSchemaVersions returns the migrations that have been applied to the database ordered by version. Returns an empty
+// slice without changing the database if the schema_version table doesn't exist.
+func GetSchemaVersions(tx *Tx) []*table.SchemaVersion {
+	if runIDQuery(tx, schemaVersionTableSQL)[0] == 0 {
+		return []*table.SchemaVersion{}
+	}
+	versions := runQuery(tx, schemaVersionType, "select * from schema_version order by version")
+	return versions.([]*table.SchemaVersion)
+}
+
+const insertSchemaVersionSQL = `insert into schema_version (version, name, apply_date) values (?, ?, current_timestamp)`
+
+// AddSchemaVersion records that a migration has been applied. The schema_version table is created if it doesn't exist.
+func AddSchemaVersion(tx *Tx, version int, name string) {
+	runUpdate(tx, createSchemaVersionSQL)
+	runUpdate(tx, insertSchemaVersionSQL, version, name)
+}
+
+// ExecScript executes statements that don't have parameters, e.g. DDL. The statements can use the query macros.
+func ExecScript(tx *Tx, statements []string) {
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(tx.ctx, currentDialect.expand(statement)); err != nil {
+			panic(err)
+		}
+	}
+}
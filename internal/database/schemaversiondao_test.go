@@ -0,0 +1,81 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetSchemaVersions(t *testing.T) {
+	t.Run("returns versions", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			versions := []*table.SchemaVersion{{Version: 1, Name: "asset_exchange"}}
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{1})
+			defer runIDQueryStub.Restore()
+			runQueryStub := mocka.Function(t, &runQuery, versions)
+			defer runQueryStub.Restore()
+
+			result := GetSchemaVersions(tx)
+
+			assert.Equal(t, versions, result)
+			assert.Equal(t, []interface{}{tx, schemaVersionTableSQL, []interface{}(nil)}, runIDQueryStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, schemaVersionType, "select * from schema_version order by version", []interface{}(nil)},
+				runQueryStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("returns empty slice if table doesn't exist", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{0})
+			defer runIDQueryStub.Restore()
+			runQueryStub := mocka.Function(t, &runQuery, nil)
+			defer runQueryStub.Restore()
+
+			result := GetSchemaVersions(tx)
+
+			assert.Equal(t, []*table.SchemaVersion{}, result)
+			assert.Equal(t, 0, runQueryStub.CallCount())
+		})
+	})
+}
+
+func Test_AddSchemaVersion(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+		defer runUpdateStub.Restore()
+
+		AddSchemaVersion(tx, 2, "payee")
+
+		assert.Equal(t, sqltest.UpdateArgs(tx, createSchemaVersionSQL), runUpdateStub.GetCall(0).Arguments())
+		assert.Equal(t, sqltest.UpdateArgs(tx, insertSchemaVersionSQL, 2, "payee"), runUpdateStub.GetCall(1).Arguments())
+	})
+}
+
+func Test_ExecScript(t *testing.T) {
+	statements := []string{"create table payee (id @autoID())", "create index payee_ix on payee (id)"}
+	t.Run("executes statements", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			mockDB.ExpectExec(currentDialect.expand(statements[0])).WillReturnResult(sqlmock.NewResult(0, 0))
+			mockDB.ExpectExec(statements[1]).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			ExecScript(tx, statements)
+
+			assert.Nil(t, mockDB.ExpectationsWereMet())
+		})
+	})
+	t.Run("panics for statement error", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			expectedErr := errors.New("syntax error")
+			mockDB.ExpectExec(currentDialect.expand(statements[0])).WillReturnError(expectedErr)
+			defer func() {
+				assert.Same(t, expectedErr, recover())
+			}()
+
+			ExecScript(tx, statements)
+		})
+	})
+}
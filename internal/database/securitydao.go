@@ -1,54 +1,105 @@
 package database
 
 import (
-	"database/sql"
 	"reflect"
 
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
 var securityType = reflect.TypeOf(table.Security{})
+var stockSplitType = reflect.TypeOf(table.StockSplit{})
+var stockSplitTable = mapTable("stock_split", stockSplitType)
 
-const securitySummarySQL = `select t.security_id, count(distinct t.id) transaction_count
-	, sum(coalesce(adjust_shares(t.security_id, t.date, td.asset_quantity), 0)) shares
+const securitySQL = `select s.type security_type, a.*
+from security s
+join asset a on s.asset_id = a.id`
+
+const shareChangesSQL = `select t.security_id, t.date, sum(td.asset_quantity) shares
+from tx t
+join tx_detail td on t.id = td.tx_id
+where @in(t.security_id) and td.asset_quantity is not null
+and (? is null or @in(t.account_id))
+group by t.security_id, t.date`
+
+// sums the cost basis and dividends of the security transactions
+const securityAmountsSQL = `select t.security_id, a.currency_id
+	, count(distinct t.id) transaction_count
 	, min(t.date) first_acquired
 	, sum(case when td.asset_quantity > 0
 		then abs(td.amount)*(td.asset_quantity-coalesce(sl.purchase_shares,0))/td.asset_quantity
 		else 0 end) cost_basis
 	, sum(case when td.asset_quantity is null and td.amount > 0 then td.amount else 0 end) dividends
 from tx t
+join account a on t.account_id = a.id
 join tx_detail td on t.id = td.tx_id
 left join (
 	select purchase_tx_detail_id, sum(purchase_shares) purchase_shares from security_lot
 	group by purchase_tx_detail_id
 ) sl on td.id = sl.purchase_tx_detail_id
-where t.security_id is not null`
+where @in(t.security_id)
+and (? is null or @in(t.account_id))
+group by t.security_id, a.currency_id`
 
-const securitySQL = `select s.type security_type, a.*,
-	coalesce(summary.transaction_count, 0) transaction_count,
-	coalesce(summary.shares, 0) shares,
-	summary.first_acquired, summary.cost_basis, summary.dividends
-from security s
-join asset a on s.asset_id = a.id
-left join (
-	` + securitySummarySQL + `
-	group by t.security_id
-) summary on summary.security_id = a.id`
+// GetSecurityAmounts returns the transaction count, first acquired date, cost basis and dividends of the securities
+// for each account currency, limited to the accounts if accountIDs is not nil.
+func GetSecurityAmounts(tx *Tx, securityIDs []int64, accountIDs []int64) []*table.SecurityAmount {
+	accounts := accountsArg(accountIDs)
+	amounts := runQuery(tx, reflect.TypeOf(table.SecurityAmount{}), securityAmountsSQL, int64sToJson(securityIDs), accounts, accounts)
+	return amounts.([]*table.SecurityAmount)
+}
+
+var getShareChanges = func(tx *Tx, securityIDs []int64, accountIDs []int64) []*table.ShareChange {
+	accounts := accountsArg(accountIDs)
+	changes := runQuery(tx, reflect.TypeOf(table.ShareChange{}), shareChangesSQL, int64sToJson(securityIDs), accounts, accounts)
+	return changes.([]*table.ShareChange)
+}
+
+var getStockSplits = func(tx *Tx) []*table.StockSplit {
+	splits := runQuery(tx, stockSplitType, "select * from stock_split")
+	return splits.([]*table.StockSplit)
+}
+
+// GetSecurityShares returns the number of shares of each security held in the accounts, keyed by security ID. Shares
+// in all accounts are included if accountIDs is nil. Shares acquired before a stock split are adjusted by the split
+// ratio.
+func GetSecurityShares(tx *Tx, securityIDs []int64, accountIDs []int64) map[int64]float64 {
+	splits := make(map[int64][]*table.StockSplit)
+	for _, split := range getStockSplits(tx) {
+		splits[split.SecurityID] = append(splits[split.SecurityID], split)
+	}
+	shares := make(map[int64]float64)
+	for _, change := range getShareChanges(tx, securityIDs, accountIDs) {
+		quantity := change.Shares
+		for _, split := range splits[change.SecurityID] {
+			if split.Date.After(change.Date) {
+				quantity *= split.SharesOut / split.SharesIn
+			}
+		}
+		shares[change.SecurityID] += quantity
+	}
+	return shares
+}
 
 // GetAllSecurities loads all securities.
-func GetAllSecurities(tx *sql.Tx) []*table.Security {
+func GetAllSecurities(tx *Tx) []*table.Security {
 	securities := runQuery(tx, securityType, securitySQL)
 	return securities.([]*table.Security)
 }
 
 // GetSecurityByID returns the security with ID.
-func GetSecurityByID(tx *sql.Tx, id int64) []*table.Security {
+func GetSecurityByID(tx *Tx, id int64) []*table.Security {
 	securities := runQuery(tx, securityType, securitySQL+" where a.id = ?", id)
 	return securities.([]*table.Security)
 }
 
+// GetSecuritiesByIDs returns the securities with the IDs.
+func GetSecuritiesByIDs(tx *Tx, ids []int64) []*table.Security {
+	securities := runQuery(tx, securityType, securitySQL+" where @in(a.id)", int64sToJson(ids))
+	return securities.([]*table.Security)
+}
+
 // GetSecurityBySymbol returns the security for the symbol.
-func GetSecurityBySymbol(tx *sql.Tx, symbol string) []*table.Security {
-	securities := runQuery(tx, securityType, securitySQL+" where s.symbol = ?", symbol)
+func GetSecurityBySymbol(tx *Tx, symbol string) []*table.Security {
+	securities := runQuery(tx, securityType, securitySQL+" where a.symbol = ?", symbol)
 	return securities.([]*table.Security)
 }
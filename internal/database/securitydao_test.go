@@ -1,18 +1,18 @@
 package database
 
 import (
-	"database/sql"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_GetAllSecurities(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		securities := []*table.Security{{AssetID: 1}}
 		runQueryStub := mocka.Function(t, &runQuery, securities)
 		defer runQueryStub.Restore()
@@ -25,7 +25,7 @@ func Test_GetAllSecurities(t *testing.T) {
 }
 
 func Test_GetSecurityByID(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		id := int64(42)
 		securities := []*table.Security{{AssetID: id}}
 		runQueryStub := mocka.Function(t, &runQuery, securities)
@@ -39,8 +39,22 @@ func Test_GetSecurityByID(t *testing.T) {
 	})
 }
 
+func Test_GetSecuritiesByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		securities := []*table.Security{{Asset: table.Asset{ID: 1}}}
+		runQueryStub := mocka.Function(t, &runQuery, securities)
+		defer runQueryStub.Restore()
+
+		result := GetSecuritiesByIDs(tx, []int64{42, 96})
+
+		assert.Equal(t, []interface{}{tx, securityType, securitySQL + " where @in(a.id)", []interface{}{"[42,96]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, securities, result)
+	})
+}
+
 func Test_GetSecurityBySymbol(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
 		symbol := "S1"
 		securities := []*table.Security{{AssetID: 42}}
 		runQueryStub := mocka.Function(t, &runQuery, securities)
@@ -48,8 +62,62 @@ func Test_GetSecurityBySymbol(t *testing.T) {
 
 		result := GetSecurityBySymbol(tx, symbol)
 
-		assert.Equal(t, []interface{}{tx, securityType, securitySQL + " where s.symbol = ?",
+		assert.Equal(t, []interface{}{tx, securityType, securitySQL + " where a.symbol = ?",
 			[]interface{}{symbol}}, runQueryStub.GetFirstCall().Arguments())
 		assert.Equal(t, securities, result)
 	})
 }
+
+func Test_GetSecurityShares(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		date1 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		date2 := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+		date3 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		getStockSplitsStub := mocka.Function(t, &getStockSplits, []*table.StockSplit{
+			{SecurityID: 1, Date: date2, SharesIn: 1, SharesOut: 2},
+			{SecurityID: 2, Date: date3, SharesIn: 3, SharesOut: 1},
+		})
+		defer getStockSplitsStub.Restore()
+		getShareChangesStub := mocka.Function(t, &getShareChanges, []*table.ShareChange{
+			{SecurityID: 1, Date: date1, Shares: 10},
+			{SecurityID: 1, Date: date2, Shares: 5},
+			{SecurityID: 1, Date: date3, Shares: -5},
+			{SecurityID: 2, Date: date1, Shares: 30},
+		})
+		defer getShareChangesStub.Restore()
+
+		result := GetSecurityShares(tx, []int64{1, 2, 3}, []int64{7})
+
+		assert.Equal(t, map[int64]float64{1: 20, 2: 10}, result)
+		assert.Equal(t, []interface{}{tx}, getStockSplitsStub.GetFirstCall().Arguments())
+		assert.Equal(t, []interface{}{tx, []int64{1, 2, 3}, []int64{7}}, getShareChangesStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_getShareChanges(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		changes := []*table.ShareChange{{SecurityID: 42, Shares: 10}}
+		runQueryStub := mocka.Function(t, &runQuery, changes)
+		defer runQueryStub.Restore()
+
+		result := getShareChanges(tx, []int64{42, 96}, nil)
+
+		assert.Equal(t, []interface{}{tx, reflect.TypeOf(table.ShareChange{}), shareChangesSQL, []interface{}{"[42,96]", nil, nil}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, changes, result)
+	})
+}
+
+func Test_GetSecurityAmounts(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		amounts := []*table.SecurityAmount{{SecurityID: 42, CurrencyID: 1, CostBasis: 12.34}}
+		runQueryStub := mocka.Function(t, &runQuery, amounts)
+		defer runQueryStub.Restore()
+
+		result := GetSecurityAmounts(tx, []int64{42, 96}, []int64{1})
+
+		assert.Equal(t, []interface{}{tx, reflect.TypeOf(table.SecurityAmount{}), securityAmountsSQL, []interface{}{"[42,96]", "[1]", "[1]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, amounts, result)
+	})
+}
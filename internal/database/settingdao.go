@@ -0,0 +1,47 @@
+package database
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var settingType = reflect.TypeOf(table.Setting{})
+var settingTable = mapTable("setting", settingType)
+
+const baseCurrencySetting = "base_currency_id"
+
+// GetSetting returns the setting with the name.
+func GetSetting(tx *Tx, name string) []*table.Setting {
+	settings := runQuery(tx, settingType, "select * from setting where name = ?", name)
+	return settings.([]*table.Setting)
+}
+
+const saveSettingSQL = `insert into setting (name, value, change_date, change_user, version)
+values (?, ?, current_timestamp, ?, 0)
+@upsert(name) value = @inserted(value), change_date = @inserted(change_date), change_user = @inserted(change_user), version = setting.version+1`
+
+// SaveSetting adds or updates a setting.
+func SaveSetting(tx *Tx, name string, value interface{}, user string) {
+	trackChanges(tx, "setting", []string{name}, user, func() {
+		runUpdate(tx, saveSettingSQL, name, value, user)
+	})
+}
+
+// GetBaseCurrencyID returns the ID of the base currency or nil if it has not been set.
+func GetBaseCurrencyID(tx *Tx) *int64 {
+	for _, setting := range GetSetting(tx, baseCurrencySetting) {
+		if setting.Value != nil {
+			if id, err := strconv.ParseInt(*setting.Value, 10, 64); err == nil {
+				return &id
+			}
+		}
+	}
+	return nil
+}
+
+// SetBaseCurrencyID sets the base currency.
+func SetBaseCurrencyID(tx *Tx, currencyID int64, user string) {
+	SaveSetting(tx, baseCurrencySetting, strconv.FormatInt(currencyID, 10), user)
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetSetting(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		settings := []*table.Setting{{Name: "x"}}
+		runQueryStub := mocka.Function(t, &runQuery, settings)
+		defer runQueryStub.Restore()
+
+		result := GetSetting(tx, "x")
+
+		assert.Equal(t, []interface{}{tx, settingType, "select * from setting where name = ?", []interface{}{"x"}}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, settings, result)
+	})
+}
+
+func Test_SaveSetting(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+		defer runUpdateStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		SaveSetting(tx, "x", "value", "somebody")
+
+		assert.Equal(t, sqltest.UpdateArgs(tx, saveSettingSQL, "x", "value", "somebody"), runUpdateStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"setting", []string{"x"}, "somebody"}}, history.changes)
+	})
+}
+
+func Test_GetBaseCurrencyID(t *testing.T) {
+	value := "42"
+	invalid := "x"
+	id := int64(42)
+	tests := []struct {
+		name     string
+		settings []*table.Setting
+		result   *int64
+	}{
+		{"returns nil for no setting", []*table.Setting{}, nil},
+		{"returns nil for null value", []*table.Setting{{Name: baseCurrencySetting}}, nil},
+		{"returns nil for invalid value", []*table.Setting{{Name: baseCurrencySetting, Value: &invalid}}, nil},
+		{"returns currency ID", []*table.Setting{{Name: baseCurrencySetting, Value: &value}}, &id},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+				runQueryStub := mocka.Function(t, &runQuery, test.settings)
+				defer runQueryStub.Restore()
+
+				result := GetBaseCurrencyID(tx)
+
+				assert.Equal(t, test.result, result)
+				assert.Equal(t, []interface{}{baseCurrencySetting}, runQueryStub.GetFirstCall().Arguments()[3])
+			})
+		})
+	}
+}
+
+func Test_SetBaseCurrencyID(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+		defer runUpdateStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		SetBaseCurrencyID(tx, 42, "somebody")
+
+		assert.Equal(t, sqltest.UpdateArgs(tx, saveSettingSQL, baseCurrencySetting, "42", "somebody"), runUpdateStub.GetCall(0).Arguments())
+	})
+}
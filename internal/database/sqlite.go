@@ -0,0 +1,126 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLite doesn't support joins in update statements, so the related detail is in the from clause
+const sqliteSetTransferAmountSQL = `update transaction_detail as td
+set amount = coalesce(?, -coalesce(?, rd.amount) * coalesce(?, -cast(td.amount as real) / nullif(rd.amount, 0), 1))
+, change_date = current_timestamp, change_user = ?, version = td.version+1
+from transaction_detail rd
+where td.related_detail_id = rd.id and td.related_detail_id = ?`
+
+// SQLite doesn't have information_schema
+const sqliteSchemaVersionTableSQL = `select count(*) from sqlite_master where type = 'table' and name = 'schema_version'`
+
+var sqliteDialect = &dialect{
+	name: "sqlite3",
+	macros: map[string]func(args []string) string{
+		"in": func(args []string) string {
+			return fmt.Sprintf("%s in (select value from json_each(?))", args[0])
+		},
+		"inObjects": func(args []string) string {
+			columns := make([]string, 0, len(args)/2)
+			values := make([]string, 0, len(args)/2)
+			for i := 0; i < len(args); i += 2 {
+				columns = append(columns, args[i+1])
+				values = append(values, fmt.Sprintf("json_extract(value, '$.%s')", strings.Trim(args[i], "'")))
+			}
+			if len(columns) == 1 {
+				return fmt.Sprintf("%s in (select %s from json_each(?))", columns[0], values[0])
+			}
+			return fmt.Sprintf("(%s) in (select %s from json_each(?))", strings.Join(columns, ", "), strings.Join(values, ", "))
+		},
+		"concat": func(args []string) string {
+			return fmt.Sprintf("(%s)", strings.Join(args, " || "))
+		},
+		"daysAgo": func(args []string) string {
+			return fmt.Sprintf("datetime('now', '-' || %s || ' days')", args[0])
+		},
+		"daysBetween": func(args []string) string {
+			return fmt.Sprintf("cast(julianday(%s) - julianday(%s) as integer)", args[0], args[1])
+		},
+		"upsert": func(args []string) string {
+			return fmt.Sprintf("on conflict (%s) do update set", args[0])
+		},
+		"inserted": func(args []string) string {
+			return "excluded." + args[0]
+		},
+		"autoID": func(args []string) string {
+			return "integer primary key"
+		},
+	},
+	statements: map[string]string{
+		setTransferAmountSQL:  sqliteSetTransferAmountSQL,
+		schemaVersionTableSQL: sqliteSchemaVersionTableSQL,
+	},
+	rewrites: []rewrite{
+		// transaction is a keyword
+		{regexp.MustCompile(`\btransaction\b`), `"$0"`},
+	},
+	isConstraintError: func(err error) bool {
+		sqliteErr, ok := err.(sqlite3.Error)
+		return ok && sqliteErr.Code == sqlite3.ErrConstraint
+	},
+	scanTarget: func(ptr interface{}) interface{} {
+		switch ptr.(type) {
+		case *time.Time, **time.Time:
+			return &sqliteTime{ptr: ptr}
+		}
+		return ptr
+	},
+}
+
+// sqliteTime scans dates that SQLite returns as text because the type of the column is unknown, e.g. min(date).
+type sqliteTime struct {
+	ptr interface{}
+}
+
+func parseSQLiteTime(text string) (time.Time, error) {
+	text = strings.TrimSuffix(text, "Z")
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if value, err := time.ParseInLocation(format, text, time.UTC); err == nil {
+			return value, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s", text)
+}
+
+func (t *sqliteTime) Scan(value interface{}) error {
+	var result *time.Time
+	switch value := value.(type) {
+	case time.Time:
+		result = &value
+	case string:
+		parsed, err := parseSQLiteTime(value)
+		if err != nil {
+			return err
+		}
+		result = &parsed
+	case []byte:
+		parsed, err := parseSQLiteTime(string(value))
+		if err != nil {
+			return err
+		}
+		result = &parsed
+	case nil:
+	default:
+		return fmt.Errorf("unsupported date value: %v", value)
+	}
+	switch ptr := t.ptr.(type) {
+	case **time.Time:
+		*ptr = result
+	case *time.Time:
+		if result == nil {
+			return fmt.Errorf("unexpected null date")
+		}
+		*ptr = *result
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sqliteDialect_isConstraintError(t *testing.T) {
+	assert.True(t, sqliteDialect.isConstraintError(sqlite3.Error{Code: sqlite3.ErrConstraint}))
+	assert.False(t, sqliteDialect.isConstraintError(sqlite3.Error{Code: sqlite3.ErrBusy}))
+	assert.False(t, sqliteDialect.isConstraintError(errors.New("constraint failed")))
+}
+
+func Test_sqliteDialect_scanTarget(t *testing.T) {
+	var date time.Time
+	var optionalDate *time.Time
+	var name string
+
+	assert.Equal(t, &sqliteTime{ptr: &date}, sqliteDialect.scanTarget(&date))
+	assert.Equal(t, &sqliteTime{ptr: &optionalDate}, sqliteDialect.scanTarget(&optionalDate))
+	assert.Same(t, &name, sqliteDialect.scanTarget(&name))
+}
+
+func Test_sqliteTime_Scan(t *testing.T) {
+	expected := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"time", expected},
+		{"date string", "2020-01-02"},
+		{"timestamp string", "2020-01-02 00:00:00"},
+		{"bytes", []byte("2020-01-02T00:00:00Z")},
+	}
+	for _, test := range tests {
+		t.Run("scans "+test.name, func(t *testing.T) {
+			var date time.Time
+			var optionalDate *time.Time
+
+			assert.Nil(t, (&sqliteTime{ptr: &date}).Scan(test.value))
+			assert.Nil(t, (&sqliteTime{ptr: &optionalDate}).Scan(test.value))
+
+			assert.True(t, expected.Equal(date))
+			assert.True(t, expected.Equal(*optionalDate))
+		})
+	}
+	t.Run("scans null", func(t *testing.T) {
+		date := &expected
+
+		assert.Nil(t, (&sqliteTime{ptr: &date}).Scan(nil))
+		assert.Nil(t, date)
+		assert.EqualError(t, (&sqliteTime{ptr: &expected}).Scan(nil), "unexpected null date")
+	})
+	t.Run("returns error for invalid value", func(t *testing.T) {
+		var date time.Time
+
+		assert.EqualError(t, (&sqliteTime{ptr: &date}).Scan("xyz"), "invalid date: xyz")
+		assert.EqualError(t, (&sqliteTime{ptr: &date}).Scan(42), "unsupported date value: 42")
+	})
+}
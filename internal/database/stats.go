@@ -0,0 +1,116 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// RequestStats contains the totals of the SQL statements run for an API request.
+type RequestStats struct {
+	// ID is the request ID that is used to tag the slow statement log
+	ID         string
+	Statements int
+	Rows       int64
+	Duration   time.Duration
+}
+
+func (s *RequestStats) String() string {
+	return fmt.Sprintf("sql: %d statements %d rows %v", s.Statements, s.Rows, s.Duration.Truncate(time.Microsecond))
+}
+
+// Statement contains the timing of a SQL statement.
+type Statement struct {
+	RequestID string
+	// SQL is the statement with consecutive whitespace replaced by a single space
+	SQL  string
+	Args int
+	// Duration of a query includes the time to read the rows
+	Duration time.Duration
+	// Rows is the number of rows read by a query or the number of rows affected by an update
+	Rows int64
+}
+
+func (s *Statement) String() string {
+	return fmt.Sprintf("%v, %d rows, %d args: %s", s.Duration.Truncate(time.Microsecond), s.Rows, s.Args, s.SQL)
+}
+
+var slowStatementThreshold time.Duration
+
+// SetSlowStatementThreshold sets the duration above which statements are logged. Statements are not logged if the
+// threshold is 0.
+func SetSlowStatementThreshold(threshold time.Duration) {
+	slowStatementThreshold = threshold
+}
+
+var since = time.Since
+
+// statementTimer measures the duration of a statement.
+type statementTimer struct {
+	tx    *Tx
+	query string
+	args  int
+	start time.Time
+}
+
+func startStatement(tx *Tx, query string, args []interface{}) *statementTimer {
+	return &statementTimer{tx: tx, query: query, args: len(args), start: time.Now()}
+}
+
+// end records the statement in the totals of the transaction's request.
+func (t *statementTimer) end(rows int64) {
+	statement := &Statement{SQL: normalizeSQL(t.query), Args: t.args, Duration: since(t.start), Rows: rows}
+	if stats := t.tx.stats; stats != nil {
+		t.tx.mutex.Lock()
+		statement.RequestID = stats.ID
+		stats.Statements++
+		stats.Rows += rows
+		stats.Duration += statement.Duration
+		t.tx.mutex.Unlock()
+	}
+	recordStatement(statement)
+}
+
+// recordStatement logs the statement if it is slow.
+var recordStatement = func(statement *Statement) {
+	if slowStatementThreshold > 0 && statement.Duration > slowStatementThreshold {
+		if statement.RequestID != "" {
+			log.Printf("[%s] slow statement: %v", statement.RequestID, statement)
+		} else {
+			log.Printf("slow statement: %v", statement)
+		}
+	}
+}
+
+// normalizeSQL replaces consecutive whitespace with a single space. The result isn't cached because the number of
+// distinct statements depends on the input, e.g. updates only set the columns that are in the input.
+func normalizeSQL(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// statementRows counts the rows read by a query and records the statement when the rows are closed.
+type statementRows struct {
+	*sql.Rows
+	timer *statementTimer
+	count int64
+}
+
+func (r *statementRows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	r.Close()
+	return false
+}
+
+func (r *statementRows) Close() error {
+	err := r.Rows.Close()
+	if r.timer != nil {
+		r.timer.end(r.count)
+		r.timer = nil
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RequestStats_String(t *testing.T) {
+	stats := &RequestStats{Statements: 3, Rows: 42, Duration: 1500 * time.Microsecond}
+
+	assert.Equal(t, "sql: 3 statements 42 rows 1.5ms", stats.String())
+}
+
+func captureStatements(t *testing.T) (*[]*Statement, func()) {
+	saved := recordStatement
+	statements := make([]*Statement, 0)
+	recordStatement = func(statement *Statement) {
+		statements = append(statements, statement)
+	}
+	sinceStub := mocka.Function(t, &since, 2*time.Millisecond)
+	return &statements, func() {
+		recordStatement = saved
+		sinceStub.Restore()
+	}
+}
+
+func Test_statementTimer_addsToRequestStats(t *testing.T) {
+	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, sqlTx *sql.Tx) {
+		statements, restore := captureStatements(t)
+		defer restore()
+		query := "select *\n\tfrom company where name = ?"
+		mockDB.ExpectPrepare(currentDialect.expand(query)).
+			ExpectQuery().WithArgs("x").WillReturnRows(sqltest.MockRows("id").AddRow(1).AddRow(2))
+		mockDB.ExpectPrepare(currentDialect.expand("delete from company")).
+			ExpectExec().WillReturnResult(sqlmock.NewResult(0, 5))
+		stats := &RequestStats{ID: "123:4"}
+		tx := BeginRequest(sqlTx, context.Background(), stats)
+		defer EndRequest(tx)
+
+		runQuery(tx, companyType, query, "x")
+		runUpdate(tx, "delete from company")
+
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Equal(t, &RequestStats{ID: "123:4", Statements: 2, Rows: 7, Duration: 4 * time.Millisecond}, stats)
+		assert.Equal(t, []*Statement{
+			{RequestID: "123:4", SQL: currentDialect.expand("select * from company where name = ?"), Args: 1, Duration: 2 * time.Millisecond, Rows: 2},
+			{RequestID: "123:4", SQL: "delete from company", Args: 0, Duration: 2 * time.Millisecond, Rows: 5},
+		}, *statements)
+	})
+}
+
+func Test_statementTimer_recordsStatementWithoutRequest(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		statements, restore := captureStatements(t)
+		defer restore()
+		mockDB.ExpectPrepare(currentDialect.expand("delete from company where id = ?")).
+			ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		runUpdate(tx, "delete from company where id = ?", 1)
+
+		assert.Equal(t, []*Statement{
+			{SQL: currentDialect.expand("delete from company where id = ?"), Args: 1, Duration: 2 * time.Millisecond, Rows: 1},
+		}, *statements)
+	})
+}
+
+func Test_statementRows_recordsStatementOnce(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		statements, restore := captureStatements(t)
+		defer restore()
+		mockDB.ExpectQuery(currentDialect.expand("select id from company")).WillReturnRows(sqltest.MockRows("id").AddRow(1).AddRow(2))
+
+		rows := queryRows(tx, currentDialect.expand("select id from company"), nil)
+		rows.Next()
+		rows.Close()
+		rows.Close()
+
+		assert.Len(t, *statements, 1)
+		assert.Equal(t, int64(1), (*statements)[0].Rows)
+	})
+}
+
+func Test_normalizeSQL(t *testing.T) {
+	assert.Equal(t, "select * from payee where id = ?", normalizeSQL("\n  select *\n\tfrom payee\r\n where id = ?  "))
+}
+
+func Test_recordStatement(t *testing.T) {
+	statement := &Statement{RequestID: "123:4", SQL: "select * from account", Duration: 2 * time.Second, Rows: 5}
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		requestID string
+		expected  string
+	}{
+		{"not logged without threshold", 0, "123:4", ""},
+		{"not logged below threshold", 3 * time.Second, "123:4", ""},
+		{"logs slow statement", time.Second, "123:4", "[123:4] slow statement: 2s, 5 rows, 0 args: select * from account\n"},
+		{"logs slow statement without request", time.Second, "", "slow statement: 2s, 5 rows, 0 args: select * from account\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			output, flags := log.Writer(), log.Flags()
+			log.SetOutput(&buffer)
+			log.SetFlags(0)
+			defer func() {
+				log.SetOutput(output)
+				log.SetFlags(flags)
+			}()
+			SetSlowStatementThreshold(test.threshold)
+			defer SetSlowStatementThreshold(0)
+			statement.RequestID = test.requestID
+
+			recordStatement(statement)
+
+			assert.Equal(t, test.expected, buffer.String())
+		})
+	}
+}
@@ -29,7 +29,7 @@ func Test_Account_PtrTo(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
-			field := account.PtrTo(test.column)
+			field := PtrTo(account, test.column)
 
 			assert.Same(t, test.ptr, field)
 		})
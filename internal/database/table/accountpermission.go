@@ -0,0 +1,18 @@
+package table
+
+// Values of AccountPermission.Permission.
+const (
+	PermissionNone  = "none"
+	PermissionRead  = "read"
+	PermissionWrite = "write"
+)
+
+// AccountPermission grants a user access to an account.
+type AccountPermission struct {
+	ID         int64  `db:"id"`
+	UserID     int64  `db:"user_id"`
+	AccountID  int64  `db:"account_id"`
+	Permission string `db:"permission"`
+	Version    int    `db:"version"`
+	Audited
+}
@@ -0,0 +1,30 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AccountPermission_PtrTo(t *testing.T) {
+	permission := &AccountPermission{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &permission.ID},
+		{column: "user_id", ptr: &permission.UserID},
+		{column: "account_id", ptr: &permission.AccountID},
+		{column: "permission", ptr: &permission.Permission},
+		{column: "version", ptr: &permission.Version},
+		{column: "change_user", ptr: &permission.ChangeUser},
+		{column: "change_date", ptr: &permission.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(permission, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
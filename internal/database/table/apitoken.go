@@ -0,0 +1,14 @@
+package table
+
+import "time"
+
+// APIToken is a long-lived personal token for authenticating API requests. Only the hash of the token is stored.
+type APIToken struct {
+	ID         int64      `db:"id"`
+	UserID     int64      `db:"user_id"`
+	Name       string     `db:"name"`
+	TokenHash  string     `db:"token_hash"`
+	ExpiryDate *time.Time `db:"expiry_date"`
+	Version    int        `db:"version"`
+	Audited
+}
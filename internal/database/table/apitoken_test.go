@@ -0,0 +1,31 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_APIToken_PtrTo(t *testing.T) {
+	token := &APIToken{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &token.ID},
+		{column: "user_id", ptr: &token.UserID},
+		{column: "name", ptr: &token.Name},
+		{column: "token_hash", ptr: &token.TokenHash},
+		{column: "expiry_date", ptr: &token.ExpiryDate},
+		{column: "version", ptr: &token.Version},
+		{column: "change_user", ptr: &token.ChangeUser},
+		{column: "change_date", ptr: &token.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(token, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package table
+
+// Attachment is a file linked to a transaction or an account. The file is stored by the hash of its content.
+type Attachment struct {
+	ID            int64  `db:"id"`
+	TransactionID *int64 `db:"transaction_id"`
+	AccountID     *int64 `db:"account_id"`
+	FileName      string `db:"file_name"`
+	ContentType   string `db:"content_type"`
+	Size          int64  `db:"size"`
+	Hash          string `db:"hash"`
+	Version       int    `db:"version"`
+	Audited
+}
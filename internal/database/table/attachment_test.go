@@ -0,0 +1,33 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Attachment_PtrTo(t *testing.T) {
+	attachment := &Attachment{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &attachment.ID},
+		{column: "transaction_id", ptr: &attachment.TransactionID},
+		{column: "account_id", ptr: &attachment.AccountID},
+		{column: "file_name", ptr: &attachment.FileName},
+		{column: "content_type", ptr: &attachment.ContentType},
+		{column: "size", ptr: &attachment.Size},
+		{column: "hash", ptr: &attachment.Hash},
+		{column: "version", ptr: &attachment.Version},
+		{column: "change_user", ptr: &attachment.ChangeUser},
+		{column: "change_date", ptr: &attachment.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(attachment, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
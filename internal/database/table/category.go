@@ -2,41 +2,14 @@ package table
 
 // Category categorizes a transaction detail.
 type Category struct {
-	ID               int64
-	Code             string
-	Description      *string
-	AmountType       string
-	ParentID         *int64
-	Security         *YesNo
-	Income           *YesNo
-	AssetExchange    *YesNo
-	Version          int64
-	TransactionCount int64
+	ID            int64   `db:"id"`
+	Code          string  `db:"code"`
+	Description   *string `db:"description"`
+	AmountType    string  `db:"amount_type"`
+	ParentID      *int64  `db:"parent_id"`
+	Security      *YesNo  `db:"security"`
+	Income        *YesNo  `db:"income"`
+	AssetExchange *YesNo  `db:"asset_exchange"`
+	Version       int64   `db:"version"`
 	Audited
 }
-
-func (tc *Category) PtrTo(column string) interface{} {
-	switch column {
-	case "id":
-		return &tc.ID
-	case "code":
-		return &tc.Code
-	case "description":
-		return &tc.Description
-	case "amount_type":
-		return &tc.AmountType
-	case "parent_id":
-		return &tc.ParentID
-	case "security":
-		return &tc.Security
-	case "income":
-		return &tc.Income
-	case "asset_exchange":
-		return &tc.AssetExchange
-	case "version":
-		return &tc.Version
-	case "transaction_count":
-		return &tc.TransactionCount
-	}
-	return tc.Audited.ptrToAudit(column)
-}
@@ -21,13 +21,12 @@ func Test_category_PtrTo(t *testing.T) {
 		{column: "security", ptr: &category.Security},
 		{column: "income", ptr: &category.Income},
 		{column: "version", ptr: &category.Version},
-		{column: "transaction_count", ptr: &category.TransactionCount},
 		{column: "change_user", ptr: &category.ChangeUser},
 		{column: "change_date", ptr: &category.ChangeDate},
 	}
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
-			field := category.PtrTo(test.column)
+			field := PtrTo(category, test.column)
 
 			assert.Same(t, test.ptr, field)
 		})
@@ -0,0 +1,22 @@
+package table
+
+// actions recorded in change history
+const (
+	HistoryInsert = "insert"
+	HistoryUpdate = "update"
+	HistoryDelete = "delete"
+)
+
+// ChangeHistory contains the before and after images of a row for an insert, update or delete.
+// The images are JSON objects mapping column names to values.
+type ChangeHistory struct {
+	ID          int64   `db:"id"`
+	Entity      string  `db:"entity"`
+	EntityKey   string  `db:"entity_key"`
+	Action      string  `db:"action"`
+	Version     *int    `db:"version"`
+	BeforeImage *string `db:"before_image"`
+	AfterImage  *string `db:"after_image"`
+	ChangeSetID *string `db:"change_set_id"`
+	Audited
+}
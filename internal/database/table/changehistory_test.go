@@ -0,0 +1,33 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ChangeHistory_PtrTo(t *testing.T) {
+	history := &ChangeHistory{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &history.ID},
+		{column: "entity", ptr: &history.Entity},
+		{column: "entity_key", ptr: &history.EntityKey},
+		{column: "action", ptr: &history.Action},
+		{column: "version", ptr: &history.Version},
+		{column: "before_image", ptr: &history.BeforeImage},
+		{column: "after_image", ptr: &history.AfterImage},
+		{column: "change_set_id", ptr: &history.ChangeSetID},
+		{column: "change_user", ptr: &history.ChangeUser},
+		{column: "change_date", ptr: &history.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(history, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
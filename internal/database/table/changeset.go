@@ -0,0 +1,7 @@
+package table
+
+// ChangeSet identifies the changes made by a mutation request.
+type ChangeSet struct {
+	ID string `db:"id"`
+	Audited
+}
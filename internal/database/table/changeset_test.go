@@ -0,0 +1,26 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ChangeSet_PtrTo(t *testing.T) {
+	changeSet := &ChangeSet{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &changeSet.ID},
+		{column: "change_user", ptr: &changeSet.ChangeUser},
+		{column: "change_date", ptr: &changeSet.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(changeSet, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
@@ -21,7 +21,7 @@ func Test_company_PtrTo(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
-			field := company.PtrTo(test.column)
+			field := PtrTo(company, test.column)
 
 			assert.Same(t, test.ptr, field)
 		})
@@ -0,0 +1,14 @@
+package table
+
+// Currency represents a monetary asset.
+type Currency struct {
+	Code string `db:"code"`
+	Asset
+}
+
+// CurrencyAmount is the total of the amounts in a currency for a category or payee.
+type CurrencyAmount struct {
+	ID         int64   `db:"id"`
+	CurrencyID int64   `db:"currency_id"`
+	Amount     float64 `db:"amount"`
+}
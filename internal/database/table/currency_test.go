@@ -0,0 +1,50 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Currency_PtrTo(t *testing.T) {
+	currency := &Currency{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "code", ptr: &currency.Code},
+		{column: "id", ptr: &currency.ID},
+		{column: "name", ptr: &currency.Name},
+		{column: "type", ptr: &currency.Type},
+		{column: "scale", ptr: &currency.Scale},
+		{column: "symbol", ptr: &currency.Symbol},
+		{column: "version", ptr: &currency.Version},
+		{column: "change_user", ptr: &currency.ChangeUser},
+		{column: "change_date", ptr: &currency.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(currency, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
+
+func Test_CurrencyAmount_PtrTo(t *testing.T) {
+	amount := &CurrencyAmount{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &amount.ID},
+		{column: "currency_id", ptr: &amount.CurrencyID},
+		{column: "amount", ptr: &amount.Amount},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(amount, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
@@ -28,7 +28,7 @@ func Test_TransactionDetail_PtrTo(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
-			field := detail.PtrTo(test.column)
+			field := PtrTo(detail, test.column)
 
 			assert.Same(t, test.ptr, field)
 		})
@@ -0,0 +1,16 @@
+package table
+
+import (
+	"time"
+)
+
+// ExchangeRate converts an amount of one currency to another currency as of a date.
+type ExchangeRate struct {
+	ID             int64     `db:"id"`
+	FromCurrencyID int64     `db:"from_currency_id"`
+	ToCurrencyID   int64     `db:"to_currency_id"`
+	Date           time.Time `db:"date"`
+	Rate           float64   `db:"rate"`
+	Version        int       `db:"version"`
+	Audited
+}
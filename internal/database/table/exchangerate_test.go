@@ -0,0 +1,31 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExchangeRate_PtrTo(t *testing.T) {
+	rate := &ExchangeRate{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &rate.ID},
+		{column: "from_currency_id", ptr: &rate.FromCurrencyID},
+		{column: "to_currency_id", ptr: &rate.ToCurrencyID},
+		{column: "date", ptr: &rate.Date},
+		{column: "rate", ptr: &rate.Rate},
+		{column: "version", ptr: &rate.Version},
+		{column: "change_user", ptr: &rate.ChangeUser},
+		{column: "change_date", ptr: &rate.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(rate, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
@@ -17,13 +17,12 @@ func Test_Group_PtrTo(t *testing.T) {
 		{column: "name", ptr: &group.Name},
 		{column: "description", ptr: &group.Description},
 		{column: "version", ptr: &group.Version},
-		{column: "transaction_count", ptr: &group.TransactionCount},
 		{column: "change_user", ptr: &group.ChangeUser},
 		{column: "change_date", ptr: &group.ChangeDate},
 	}
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
-			field := group.PtrTo(test.column)
+			field := PtrTo(group, test.column)
 
 			assert.Same(t, test.ptr, field)
 		})
@@ -0,0 +1,24 @@
+package table
+
+import (
+	"time"
+)
+
+// ImportItem is an imported transaction that is waiting for review.
+type ImportItem struct {
+	ID                    int64     `db:"id"`
+	AccountID             int64     `db:"account_id"`
+	Date                  time.Time `db:"date"`
+	ReferenceNumber       *string   `db:"reference_number"`
+	PayeeName             *string   `db:"payee_name"`
+	Memo                  *string   `db:"memo"`
+	Amount                float64   `db:"amount"`
+	TransactionID         *int64    `db:"transaction_id"`
+	PayeeID               *int64    `db:"payee_id,derived"`
+	TransactionCategoryID *int64    `db:"transaction_category_id,derived"`
+	MatchedTransactionID  *int64    `db:"matched_transaction_id,derived"`
+	// version of the suggested transaction
+	MatchedTransactionVersion *int64 `db:"matched_transaction_version,derived"`
+	Version                   int    `db:"version"`
+	Audited
+}
@@ -0,0 +1,37 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ImportItem_PtrTo(t *testing.T) {
+	item := &ImportItem{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &item.ID},
+		{column: "account_id", ptr: &item.AccountID},
+		{column: "date", ptr: &item.Date},
+		{column: "reference_number", ptr: &item.ReferenceNumber},
+		{column: "payee_name", ptr: &item.PayeeName},
+		{column: "memo", ptr: &item.Memo},
+		{column: "amount", ptr: &item.Amount},
+		{column: "transaction_id", ptr: &item.TransactionID},
+		{column: "payee_id", ptr: &item.PayeeID},
+		{column: "transaction_category_id", ptr: &item.TransactionCategoryID},
+		{column: "matched_transaction_id", ptr: &item.MatchedTransactionID},
+		{column: "version", ptr: &item.Version},
+		{column: "change_user", ptr: &item.ChangeUser},
+		{column: "change_date", ptr: &item.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(item, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package table
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Column is a model field that is mapped to a database column by a `db` tag, e.g. `db:"account_id"`. The derived
+// option marks a column that is calculated or renamed by a query instead of being stored in the model's table,
+// e.g. `db:"transaction_count,derived"`.
+type Column struct {
+	Name    string
+	Derived bool
+	// Nullable is true if the field is a pointer
+	Nullable bool
+	// Type is the field type without the pointer
+	Type  reflect.Type
+	index []int
+}
+
+// PtrTo returns a pointer to the column's field. model must be a pointer to a model struct.
+func (c *Column) PtrTo(model reflect.Value) interface{} {
+	return model.Elem().FieldByIndex(c.index).Addr().Interface()
+}
+
+// Value returns the value of the column's field. model must be a pointer to a model struct.
+func (c *Column) Value(model reflect.Value) interface{} {
+	return model.Elem().FieldByIndex(c.index).Interface()
+}
+
+// Mapping contains the columns of a model, including the columns of embedded structs.
+type Mapping struct {
+	Columns []*Column
+	byName  map[string]*Column
+}
+
+// Column returns the column with the name or nil if the model doesn't have the column.
+func (m *Mapping) Column(name string) *Column {
+	return m.byName[name]
+}
+
+var mappings sync.Map
+
+// MappingOf returns the column mapping of the model type. Panics if the model has a duplicate column name.
+func MappingOf(modelType reflect.Type) *Mapping {
+	if mapping, ok := mappings.Load(modelType); ok {
+		return mapping.(*Mapping)
+	}
+	mapping := &Mapping{byName: make(map[string]*Column)}
+	mapping.addColumns(modelType, nil)
+	mappings.Store(modelType, mapping)
+	return mapping
+}
+
+func (m *Mapping) addColumns(structType reflect.Type, index []int) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		tag, ok := field.Tag.Lookup("db")
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				m.addColumns(field.Type, fieldIndex)
+			}
+			continue
+		}
+		options := strings.Split(tag, ",")
+		if options[0] == "-" {
+			continue
+		}
+		if _, exists := m.byName[options[0]]; exists {
+			panic(fmt.Errorf("duplicate column %s in %s", options[0], structType.Name()))
+		}
+		column := &Column{Name: options[0], Type: field.Type, index: fieldIndex}
+		if field.Type.Kind() == reflect.Ptr {
+			column.Nullable = true
+			column.Type = field.Type.Elem()
+		}
+		for _, option := range options[1:] {
+			column.Derived = column.Derived || option == "derived"
+		}
+		m.Columns = append(m.Columns, column)
+		m.byName[column.Name] = column
+	}
+}
+
+// PtrTo returns a pointer to the field for the database column or nil if the model doesn't have the column.
+// model must be a pointer to a model struct.
+func PtrTo(model interface{}, column string) interface{} {
+	value := reflect.ValueOf(model)
+	if c := MappingOf(value.Type().Elem()).Column(column); c != nil {
+		return c.PtrTo(value)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package table
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MappingOf(t *testing.T) {
+	mapping := MappingOf(reflect.TypeOf(Security{}))
+
+	names := make([]string, len(mapping.Columns))
+	for i, column := range mapping.Columns {
+		names[i] = column.Name
+	}
+	assert.Equal(t, []string{"asset_id", "security_type", "id", "name", "type", "scale", "symbol", "version", "change_user",
+		"change_date"}, names)
+	assert.True(t, mapping.Column("security_type").Derived)
+	assert.False(t, mapping.Column("asset_id").Derived)
+	assert.True(t, mapping.Column("symbol").Nullable)
+	assert.Equal(t, reflect.TypeOf(""), mapping.Column("symbol").Type)
+	assert.Nil(t, mapping.Column("unknown"))
+	assert.Same(t, mapping, MappingOf(reflect.TypeOf(Security{})))
+}
+
+func Test_MappingOf_skipsUnmappedFields(t *testing.T) {
+	mapping := MappingOf(reflect.TypeOf(Transaction{}))
+
+	for _, column := range mapping.Columns {
+		assert.NotEqual(t, "-", column.Name)
+	}
+	assert.Nil(t, mapping.Column("details"))
+}
+
+func Test_MappingOf_panicsForDuplicateColumn(t *testing.T) {
+	type duplicate struct {
+		Name  string `db:"name"`
+		Other string `db:"name"`
+	}
+	defer func() {
+		assert.EqualError(t, recover().(error), "duplicate column name in duplicate")
+	}()
+
+	MappingOf(reflect.TypeOf(duplicate{}))
+}
+
+func Test_Column_Value(t *testing.T) {
+	memo := "notes"
+	transaction := &Transaction{ID: 42, Memo: &memo}
+	mapping := MappingOf(reflect.TypeOf(Transaction{}))
+
+	assert.Equal(t, int64(42), mapping.Column("id").Value(reflect.ValueOf(transaction)))
+	assert.Equal(t, &memo, mapping.Column("memo").Value(reflect.ValueOf(transaction)))
+}
@@ -2,23 +2,8 @@ package table
 
 // Payee represents the other party party in a financial transaction.
 type Payee struct {
-	ID               int64
-	Name             string
-	Version          int
-	TransactionCount int64
+	ID      int64  `db:"id"`
+	Name    string `db:"name"`
+	Version int    `db:"version"`
 	Audited
 }
-
-func (p *Payee) PtrTo(column string) interface{} {
-	switch column {
-	case "id":
-		return &p.ID
-	case "name":
-		return &p.Name
-	case "version":
-		return &p.Version
-	case "transaction_count":
-		return &p.TransactionCount
-	}
-	return p.Audited.ptrToAudit(column)
-}
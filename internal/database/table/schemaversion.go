@@ -0,0 +1,10 @@
+package table
+
+import "time"
+
+// SchemaVersion records a migration that has been applied to the database.
+type SchemaVersion struct {
+	Version   int       `db:"version"`
+	Name      string    `db:"name"`
+	ApplyDate time.Time `db:"apply_date"`
+}
@@ -0,0 +1,27 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SchemaVersion_PtrTo(t *testing.T) {
+	version := &SchemaVersion{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "version", ptr: &version.Version},
+		{column: "name", ptr: &version.Name},
+		{column: "apply_date", ptr: &version.ApplyDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(version, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+	assert.Nil(t, PtrTo(version, "unknown"))
+}
@@ -6,32 +6,17 @@ import (
 
 // Security represents an investment security.
 type Security struct {
-	AssetID          int64
-	Type             string
-	Shares           float64
-	FirstAcquired    *time.Time
-	CostBasis        *float64
-	Dividends        *float64
-	TransactionCount int64
+	AssetID int64  `db:"asset_id"`
+	Type    string `db:"security_type,derived"`
 	Asset
 }
 
-func (s *Security) PtrTo(column string) interface{} {
-	switch column {
-	case "asset_id":
-		return &s.AssetID
-	case "security_type":
-		return &s.Type
-	case "shares":
-		return &s.Shares
-	case "first_acquired":
-		return &s.FirstAcquired
-	case "cost_basis":
-		return &s.CostBasis
-	case "dividends":
-		return &s.Dividends
-	case "transaction_count":
-		return &s.TransactionCount
-	}
-	return s.Asset.PtrTo(column)
+// SecurityAmount is the transaction count, first acquired date, cost basis and dividends of a security in a currency.
+type SecurityAmount struct {
+	SecurityID       int64      `db:"security_id"`
+	CurrencyID       int64      `db:"currency_id"`
+	TransactionCount int64      `db:"transaction_count"`
+	FirstAcquired    *time.Time `db:"first_acquired"`
+	CostBasis        float64    `db:"cost_basis"`
+	Dividends        float64    `db:"dividends"`
 }
@@ -18,11 +18,6 @@ func Test_security_PtrTo(t *testing.T) {
 		{column: "id", ptr: &security.ID},
 		{column: "name", ptr: &security.Name},
 		{column: "type", ptr: &security.Asset.Type},
-		{column: "shares", ptr: &security.Shares},
-		{column: "first_acquired", ptr: &security.FirstAcquired},
-		{column: "cost_basis", ptr: &security.CostBasis},
-		{column: "dividends", ptr: &security.Dividends},
-		{column: "transaction_count", ptr: &security.TransactionCount},
 		{column: "scale", ptr: &security.Scale},
 		{column: "symbol", ptr: &security.Symbol},
 		{column: "version", ptr: &security.Version},
@@ -31,9 +26,30 @@ func Test_security_PtrTo(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
-			field := security.PtrTo(test.column)
+			field := PtrTo(security, test.column)
 
 			assert.Same(t, test.ptr, field)
 		})
 	}
 }
+
+func Test_SecurityAmount_PtrTo(t *testing.T) {
+	amount := &SecurityAmount{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "security_id", ptr: &amount.SecurityID},
+		{column: "currency_id", ptr: &amount.CurrencyID},
+		{column: "transaction_count", ptr: &amount.TransactionCount},
+		{column: "first_acquired", ptr: &amount.FirstAcquired},
+		{column: "cost_basis", ptr: &amount.CostBasis},
+		{column: "dividends", ptr: &amount.Dividends},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(amount, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
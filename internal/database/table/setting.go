@@ -0,0 +1,9 @@
+package table
+
+// Setting is an application configuration value.
+type Setting struct {
+	Name    string  `db:"name"`
+	Value   *string `db:"value"`
+	Version int     `db:"version"`
+	Audited
+}
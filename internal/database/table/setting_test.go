@@ -0,0 +1,28 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Setting_PtrTo(t *testing.T) {
+	setting := &Setting{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "name", ptr: &setting.Name},
+		{column: "value", ptr: &setting.Value},
+		{column: "version", ptr: &setting.Version},
+		{column: "change_user", ptr: &setting.ChangeUser},
+		{column: "change_date", ptr: &setting.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(setting, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package table
+
+import (
+	"time"
+)
+
+// StockSplit records a change in the number of shares of a security. Shares held before the split are multiplied
+// by SharesOut / SharesIn.
+type StockSplit struct {
+	ID         int64     `db:"id"`
+	SecurityID int64     `db:"security_id"`
+	Date       time.Time `db:"date"`
+	SharesIn   float64   `db:"shares_in"`
+	SharesOut  float64   `db:"shares_out"`
+	Version    int       `db:"version"`
+	Audited
+}
+
+// ShareChange is the net number of shares of a security acquired on a date.
+type ShareChange struct {
+	SecurityID int64     `db:"security_id"`
+	Date       time.Time `db:"date"`
+	Shares     float64   `db:"shares"`
+}
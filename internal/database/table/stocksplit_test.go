@@ -0,0 +1,49 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StockSplit_PtrTo(t *testing.T) {
+	split := &StockSplit{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &split.ID},
+		{column: "security_id", ptr: &split.SecurityID},
+		{column: "date", ptr: &split.Date},
+		{column: "shares_in", ptr: &split.SharesIn},
+		{column: "shares_out", ptr: &split.SharesOut},
+		{column: "version", ptr: &split.Version},
+		{column: "change_user", ptr: &split.ChangeUser},
+		{column: "change_date", ptr: &split.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(split, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
+
+func Test_ShareChange_PtrTo(t *testing.T) {
+	change := &ShareChange{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "security_id", ptr: &change.SecurityID},
+		{column: "date", ptr: &change.Date},
+		{column: "shares", ptr: &change.Shares},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(change, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
@@ -6,39 +6,22 @@ import (
 
 // Transaction represents a financial transaction.
 type Transaction struct {
-	ID              int64
-	Date            time.Time
-	Memo            *string
-	ReferenceNumber *string
-	Cleared         *YesNo
-	AccountID       int64
-	PayeeID         *int64
-	SecurityID      *int64
-	Details         []TransactionDetail
-	Version         int
+	ID              int64               `db:"id"`
+	Date            time.Time           `db:"date"`
+	Memo            *string             `db:"memo"`
+	ReferenceNumber *string             `db:"reference_number"`
+	Cleared         *YesNo              `db:"cleared"`
+	AccountID       int64               `db:"account_id"`
+	PayeeID         *int64              `db:"payee_id"`
+	SecurityID      *int64              `db:"security_id"`
+	TrashDate       *time.Time          `db:"trash_date"`
+	Details         []TransactionDetail `db:"-"`
+	Version         int                 `db:"version"`
 	Audited
 }
 
-func (t *Transaction) PtrTo(column string) interface{} {
-	switch column {
-	case "id":
-		return &t.ID
-	case "date":
-		return &t.Date
-	case "memo":
-		return &t.Memo
-	case "reference_number":
-		return &t.ReferenceNumber
-	case "cleared":
-		return &t.Cleared
-	case "account_id":
-		return &t.AccountID
-	case "payee_id":
-		return &t.PayeeID
-	case "security_id":
-		return &t.SecurityID
-	case "version":
-		return &t.Version
-	}
-	return t.Audited.ptrToAudit(column)
+// TransactionCount is the number of transactions of a payee, category or group.
+type TransactionCount struct {
+	ID    int64 `db:"id"`
+	Count int64 `db:"transaction_count"`
 }
@@ -21,13 +21,32 @@ func Test_Transaction_PtrTo(t *testing.T) {
 		{column: "account_id", ptr: &transaction.AccountID},
 		{column: "payee_id", ptr: &transaction.PayeeID},
 		{column: "security_id", ptr: &transaction.SecurityID},
+		{column: "trash_date", ptr: &transaction.TrashDate},
 		{column: "version", ptr: &transaction.Version},
 		{column: "change_user", ptr: &transaction.ChangeUser},
 		{column: "change_date", ptr: &transaction.ChangeDate},
 	}
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
-			field := transaction.PtrTo(test.column)
+			field := PtrTo(transaction, test.column)
+
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
+
+func Test_TransactionCount_PtrTo(t *testing.T) {
+	count := &TransactionCount{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &count.ID},
+		{column: "transaction_count", ptr: &count.Count},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(count, test.column)
 
 			assert.Same(t, test.ptr, field)
 		})
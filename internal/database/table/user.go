@@ -0,0 +1,13 @@
+package table
+
+// RoleAdmin is the role of users that have access to all accounts.
+const RoleAdmin = "admin"
+
+// User is a person allowed to access the application.
+type User struct {
+	ID      int64  `db:"id"`
+	Name    string `db:"name"`
+	Role    string `db:"role"`
+	Version int    `db:"version"`
+	Audited
+}
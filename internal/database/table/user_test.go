@@ -0,0 +1,29 @@
+package table
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_User_PtrTo(t *testing.T) {
+	user := &User{}
+	tests := []struct {
+		column string
+		ptr    interface{}
+	}{
+		{column: "id", ptr: &user.ID},
+		{column: "name", ptr: &user.Name},
+		{column: "role", ptr: &user.Role},
+		{column: "version", ptr: &user.Version},
+		{column: "change_user", ptr: &user.ChangeUser},
+		{column: "change_date", ptr: &user.ChangeDate},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s returns pointer to field", test.column), func(t *testing.T) {
+			field := PtrTo(user, test.column)
+			assert.Same(t, test.ptr, field)
+		})
+	}
+}
@@ -8,9 +8,9 @@ import (
 // YesNo maps a boolean value to a char column containing Y or N.
 type YesNo byte
 
-// Value implements the sql.Valuer interface.
+// Value implements the sql.Valuer interface. The value is a string because the PostgreSQL driver sends []byte as bytea.
 func (yn *YesNo) Value() (driver.Value, error) {
-	return []byte{byte(*yn)}, nil
+	return string(*yn), nil
 }
 
 // Scan implements the sql.Scanner interface.
@@ -19,6 +19,10 @@ func (yn *YesNo) Scan(value interface{}) error {
 		*yn = YesNo(b[0])
 		return nil
 	}
+	if s, ok := value.(string); ok && len(s) > 0 {
+		*yn = YesNo(s[0])
+		return nil
+	}
 	return fmt.Errorf("invalid value for YesNo: %v", value)
 }
 
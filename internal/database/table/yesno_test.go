@@ -10,7 +10,7 @@ func Test_YesNo_Value(t *testing.T) {
 
 	value, _ := yn.Value()
 
-	if !reflect.DeepEqual(value, []byte{'Y'}) {
+	if !reflect.DeepEqual(value, "Y") {
 		t.Errorf("Expected: %v, got: %v", yn, value)
 	}
 }
@@ -25,6 +25,16 @@ func Test_YesNo_Scan(t *testing.T) {
 	}
 }
 
+func Test_YesNo_ScanString(t *testing.T) {
+	yn := YesNo(0)
+
+	yn.Scan("N")
+
+	if yn != 'N' {
+		t.Errorf("Expected: N, got: %v", yn)
+	}
+}
+
 func Test_YesNo_Get(t *testing.T) {
 	y := YesNo('Y')
 	if !y.Get() {
@@ -1,33 +1,32 @@
 package database
 
 import (
-	"database/sql"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"reflect"
 
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
 var transactionType = reflect.TypeOf(table.Transaction{})
+var transactionTable = mapTable("transaction", transactionType)
 
-const accountTransactionsSQL = "select * from transaction where account_id = ? order by date, id"
+const accountTransactionsSQL = "select * from transaction where account_id = ? and trash_date is null order by date, id"
 
-func runTransactionQuery(tx *sql.Tx, query string, args ...interface{}) []*table.Transaction {
+func runTransactionQuery(tx *Tx, query string, args ...interface{}) []*table.Transaction {
 	rows := runQuery(tx, transactionType, query, args...)
 	return rows.([]*table.Transaction)
 }
 
 // GetTransactions returns all transactions for the account.
-func GetTransactions(tx *sql.Tx, accountID int64) []*table.Transaction {
+func GetTransactions(tx *Tx, accountID int64) []*table.Transaction {
 	return runTransactionQuery(tx, accountTransactionsSQL, accountID)
 }
 
-const transactionsByIDSQL = "select * from transaction where json_contains(?, cast(id as json))"
+const transactionsByIDSQL = "select * from transaction where @in(id)"
 
 // GetTransactionsByIDs returns transactions for the specified IDs.
-func GetTransactionsByIDs(tx *sql.Tx, ids []int64) []*table.Transaction {
+func GetTransactionsByIDs(tx *Tx, ids []int64) []*table.Transaction {
 	return runTransactionQuery(tx, transactionsByIDSQL, int64sToJson(ids))
 }
 
@@ -36,10 +35,10 @@ const accountRelatedTxSQL = `select rt.*
 	join transaction_detail td on tx.id = td.transaction_id
 	join transaction_detail rd on td.related_detail_id = rd.id
 	join transaction rt on rd.transaction_id = rt.id
-	where tx.account_id = ?`
+	where tx.account_id = ? and tx.trash_date is null`
 
 // GetRelatedTransactionsByAccountID returns all related transactions for the account.
-func GetRelatedTransactionsByAccountID(tx *sql.Tx, accountID int64) []*table.Transaction {
+func GetRelatedTransactionsByAccountID(tx *Tx, accountID int64) []*table.Transaction {
 	return runTransactionQuery(tx, accountRelatedTxSQL, accountID)
 }
 
@@ -47,60 +46,180 @@ const relatedTxSQL = `select rt.*
 	from transaction_detail td
 	join transaction_detail rd on td.related_detail_id = rd.id
 	join transaction rt on rd.transaction_id = rt.id
-	where json_contains(?, cast(td.transaction_id as json))`
+	where @in(td.transaction_id)`
 
 // GetRelatedTransactions returns all related transactions for the transaction IDs.
-func GetRelatedTransactions(tx *sql.Tx, relatedTxIDs []int64) []*table.Transaction {
+func GetRelatedTransactions(tx *Tx, relatedTxIDs []int64) []*table.Transaction {
 	return runTransactionQuery(tx, relatedTxSQL, int64sToJson(relatedTxIDs))
 }
 
-const insertTransactionSQL = `insert into transaction
-(account_id, date, reference_number, payee_id, security_id, memo, cleared, change_date, change_user, version)
-values (?, ?, ?, ?, ?, ?, ?, current_timestamp, ?, 0)`
+// the transactions are numbered for each payee so that the page can be selected for each payee
+const payeeTransactionsSQL = `select t.*
+from transaction t
+join (
+	select id, row_number() over (partition by payee_id order by date, id) row_num
+	from transaction
+	where @in(payee_id) and trash_date is null
+	and (? is null or date >= ?)
+	and (? is null or date <= ?)
+	and (? is null or @in(account_id))
+) page on t.id = page.id
+where page.row_num > ? and (? = 0 or page.row_num <= ?)
+order by t.payee_id, page.row_num`
+
+// GetTransactionsByPayeeIDs returns a page of the transactions of each payee, limited to the accounts if accountIDs
+// is not nil.
+func GetTransactionsByPayeeIDs(tx *Tx, payeeIDs []int64, filter PageFilter, accountIDs []int64) []*table.Transaction {
+	return runTransactionQuery(tx, payeeTransactionsSQL, filter.pageArgs(payeeIDs, accountIDs)...)
+}
+
+var insertTransactionSQL = transactionTable.insertSQL("account_id", "date", "reference_number", "payee_id", "security_id", "memo", "cleared")
 
 // InsertTransaction inserts a transaction.
-func InsertTransaction(tx *sql.Tx, accountID int64, values InputObject, user string) int64 {
-	return runInsert(tx, insertTransactionSQL, accountID, values.StringOrNull("date"), values.StringOrNull("referenceNumber"),
-		values.IntOrNull("payeeId"), values.IntOrNull("securityId"), values.StringOrNull("memo"), values.YesNoOrNull("cleared"), user)
+func InsertTransaction(tx *Tx, accountID int64, values InputObject, user string) int64 {
+	id := runInsert(tx, insertTransactionSQL.sql, insertTransactionSQL.inputArgs(values, columnValues{"account_id": accountID}, user)...)
+	recordInsert(tx, "transaction", id, user)
+	return id
 }
 
-const updateTxSQL = `update transaction
-set date = coalesce(?, date)
-, reference_number = case when ? then ? else reference_number end
-, payee_id = case when ? then ? else payee_id end
-, security_id = case when ? then ? else security_id end
-, memo = case when ? then ? else memo end
-, cleared = coalesce(?, cleared)
-, account_id = case when ? then ? else account_id end
-, change_date = current_timestamp, change_user = ?, version = version+1
-where id = ? and version = ?`
-
-// UpdateTransactions updates transactions.
-func UpdateTransaction(tx *sql.Tx, id int64, version int64, values InputObject, user string) {
-	ref, setRef := values.GetString("referenceNumber")
-	payeeId, setPayee := values.GetInt("payeeId")
-	securityId, setSecurity := values.GetInt("securityId")
-	memo, setMemo := values.GetString("memo")
-	accountId, setAccount := values.GetInt("accountId")
-	count := runUpdate(tx, updateTxSQL,
-		values.StringOrNull("date"),
-		setRef, ref,
-		setPayee, payeeId,
-		setSecurity, securityId,
-		setMemo, memo,
-		values.YesNoOrNull("cleared"),
-		setAccount, accountId,
-		user, id, version)
+var updateTxSQL = transactionTable.partialUpdateSQL("date", "reference_number", "payee_id", "security_id", "memo", "cleared",
+	"account_id").and("trash_date is null")
+
+// UpdateTransaction updates a transaction. Returns a VersionConflictError if the transaction has been changed
+// or deleted.
+func UpdateTransaction(tx *Tx, id int64, version int64, values InputObject, user string) error {
+	var count int64
+	trackChanges(tx, "transaction", []int64{id}, user, func() {
+		count = runUpdate(tx, updateTxSQL.sql, updateTxSQL.inputArgs(values, nil, user, id, version)...)
+	})
 	if count == 0 {
-		panic(fmt.Errorf("transaction not found (%d @ %d)", id, version))
+		return versionConflict(tx, "transaction", id, version)
+	}
+	return nil
+}
+
+const transferTxIDsSQL = `select distinct rd.transaction_id
+from transaction_detail td
+join transaction_detail rd on td.related_detail_id = rd.id
+where @in(td.transaction_id)`
+
+// transferGraphIDs returns the IDs of the transactions and all transactions that are connected to them by transfers.
+func transferGraphIDs(tx *Tx, ids []int64) []int64 {
+	graphIDs := make([]int64, 0, len(ids))
+	found := make(map[int64]bool)
+	for len(ids) > 0 {
+		newIDs := make([]int64, 0, len(ids))
+		for _, id := range ids {
+			if !found[id] {
+				found[id] = true
+				newIDs = append(newIDs, id)
+			}
+		}
+		if len(newIDs) == 0 {
+			break
+		}
+		graphIDs = append(graphIDs, newIDs...)
+		ids = runIDQuery(tx, transferTxIDsSQL, int64sToJson(newIDs))
+	}
+	return graphIDs
+}
+
+const trashTransactionsSQL = `update transaction
+set trash_date = current_timestamp, change_date = current_timestamp, change_user = ?, version = version+1
+where @inObjects('id', id, 'version', version) and trash_date is null`
+
+const trashTransfersSQL = `update transaction
+set trash_date = current_timestamp, change_date = current_timestamp, change_user = ?, version = version+1
+where @in(id) and trash_date is null`
+
+// TrashTransactions moves transactions and their transfer transactions to the trash. Returns a NotFoundError if the
+// number of trashed transactions is less than the number of IDs.
+func TrashTransactions(tx *Tx, ids []map[string]interface{}, user string) error {
+	// ignore duplicate IDs so that the requested transactions are the first IDs of the transfer graph
+	found := make(map[int64]bool)
+	txIDs := make([]int64, 0, len(ids))
+	trashObjects := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if txID := InputObject(id).RequireInt("id"); !found[txID] {
+			found[txID] = true
+			txIDs = append(txIDs, txID)
+			trashObjects = append(trashObjects, id)
+		}
+	}
+	trashIDs, _ := json.Marshal(trashObjects)
+	graphIDs := transferGraphIDs(tx, txIDs)
+	var count int64
+	trackChanges(tx, "transaction", graphIDs, user, func() {
+		count = runUpdate(tx, trashTransactionsSQL, user, trashIDs)
+		if len(graphIDs) > len(txIDs) {
+			runUpdate(tx, trashTransfersSQL, user, int64sToJson(graphIDs[len(txIDs):]))
+		}
+	})
+	if int(count) < len(txIDs) {
+		return apperror.NotFound("transaction")
+	}
+	return nil
+}
+
+const trashSQL = `select * from transaction
+where trash_date is not null and (? is null or account_id = ?)
+order by trash_date desc, id`
+
+// GetTrash returns the transactions in the trash, optionally limited to an account.
+func GetTrash(tx *Tx, accountID interface{}) []*table.Transaction {
+	return runTransactionQuery(tx, trashSQL, accountID, accountID)
+}
+
+const trashedIDsSQL = "select id from transaction where @in(id) and trash_date is not null"
+
+const restoreTransactionsSQL = `update transaction
+set trash_date = null, change_date = current_timestamp, change_user = ?, version = version+1
+where @in(id) and trash_date is not null`
+
+// RestoreTransactions moves transactions and their transfer transactions out of the trash. Returns the IDs of the
+// restored transactions.
+func RestoreTransactions(tx *Tx, ids []int64, user string) ([]int64, error) {
+	if trashedIDs := runIDQuery(tx, trashedIDsSQL, int64sToJson(ids)); len(trashedIDs) < len(ids) {
+		return nil, apperror.NotFoundf("transaction", "transaction(s) not found in trash")
 	}
+	graphIDs := transferGraphIDs(tx, ids)
+	trackChanges(tx, "transaction", graphIDs, user, func() {
+		runUpdate(tx, restoreTransactionsSQL, user, int64sToJson(graphIDs))
+	})
+	return graphIDs, nil
 }
 
-// DeleteTransactions deletes transactions and panics the number of deleted transactions is less than the number of IDs.
-func DeleteTransactions(tx *sql.Tx, ids []map[string]interface{}) {
-	deleteIDs, _ := json.Marshal(ids)
-	count := runUpdate(tx, "delete from transaction where json_contains(?, json_object('id', id, 'version', version))", deleteIDs)
-	if int(count) < len(ids) {
-		panic(errors.New("transaction(s) not found"))
+const expiredTrashSQL = "select id from transaction where trash_date < @daysAgo(?)"
+
+// GetExpiredTrashIDs returns the IDs of the transactions that have been in the trash for more than retentionDays.
+func GetExpiredTrashIDs(tx *Tx, retentionDays int) []int64 {
+	return runIDQuery(tx, expiredTrashSQL, retentionDays)
+}
+
+// PurgeTransactions permanently deletes transactions. The details must be deleted first.
+func PurgeTransactions(tx *Tx, ids []int64, user string) {
+	trackChanges(tx, "transaction", ids, user, func() {
+		runUpdate(tx, "delete from transaction where @in(id)", int64sToJson(ids))
+	})
+}
+
+const clearTransactionSQL = `update transaction set cleared = 'Y', change_date = current_timestamp, change_user = ?, version = version+1
+where id = ? and version = ? and account_id = ? and trash_date is null`
+
+const accountTransactionSQL = "select id from transaction where id = ? and account_id = ?"
+
+// ClearTransaction marks a transaction in the account as cleared. Returns a VersionConflictError if the transaction
+// has been changed or deleted.
+func ClearTransaction(tx *Tx, id int64, version int64, accountID int64, user string) error {
+	var count int64
+	trackChanges(tx, "transaction", []int64{id}, user, func() {
+		count = runUpdate(tx, clearTransactionSQL, user, id, version, accountID)
+	})
+	if count == 0 {
+		if len(runIDQuery(tx, accountTransactionSQL, id, accountID)) == 0 {
+			return apperror.NotFoundf("transaction", "transaction not found in account (%d)", id)
+		}
+		return versionConflict(tx, "transaction", id, version)
 	}
+	return nil
 }
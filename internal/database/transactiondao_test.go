@@ -1,23 +1,23 @@
 package database
 
 import (
-	"database/sql"
 	"encoding/json"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database/table"
 	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_GetTransactions(t *testing.T) {
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		accountID := int64(42)
 		txID := int64(69)
 		expectedTx := &table.Transaction{ID: txID}
-		mockDB.ExpectQuery(accountTransactionsSQL).WithArgs(accountID).WillReturnRows(sqltest.MockRows("id").AddRow(txID))
+		mockDB.ExpectQuery(currentDialect.expand(accountTransactionsSQL)).WithArgs(accountID).WillReturnRows(sqltest.MockRows("id").AddRow(txID))
 
 		result := GetTransactions(tx, accountID)
 
@@ -27,11 +27,11 @@ func Test_GetTransactions(t *testing.T) {
 }
 
 func Test_GetRelatedTransactionsByAccountID(t *testing.T) {
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		accountID := int64(42)
 		txID := int64(69)
 		expectedTx := &table.Transaction{ID: txID}
-		mockDB.ExpectQuery(accountRelatedTxSQL).WithArgs(accountID).WillReturnRows(sqltest.MockRows("id").AddRow(txID))
+		mockDB.ExpectQuery(currentDialect.expand(accountRelatedTxSQL)).WithArgs(accountID).WillReturnRows(sqltest.MockRows("id").AddRow(txID))
 
 		result := GetRelatedTransactionsByAccountID(tx, accountID)
 
@@ -41,11 +41,11 @@ func Test_GetRelatedTransactionsByAccountID(t *testing.T) {
 }
 
 func Test_GetRelatedTransactions(t *testing.T) {
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		relatedIDs := []int64{42}
 		txID := int64(69)
 		expectedTx := &table.Transaction{ID: txID}
-		mockDB.ExpectQuery(relatedTxSQL).WithArgs(int64sToJson(relatedIDs)).WillReturnRows(sqltest.MockRows("id").AddRow(txID))
+		mockDB.ExpectQuery(currentDialect.expand(relatedTxSQL)).WithArgs(boundArgs(relatedTxSQL, int64sToJson(relatedIDs))...).WillReturnRows(sqltest.MockRows("id").AddRow(txID))
 
 		result := GetRelatedTransactions(tx, relatedIDs)
 
@@ -54,6 +54,33 @@ func Test_GetRelatedTransactions(t *testing.T) {
 	})
 }
 
+func Test_GetTransactionsByPayeeIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		accountIDs []int64
+		accounts   interface{}
+	}{
+		{"all accounts", nil, nil},
+		{"readable accounts", []int64{}, "[]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+				filter := PageFilter{StartDate: "2020-01-01", EndDate: "2020-12-31", Limit: 10}
+				mockDB.ExpectQuery(currentDialect.expand(payeeTransactionsSQL)).
+					WithArgs(boundArgs(payeeTransactionsSQL, "[42]", "2020-01-01", "2020-01-01", "2020-12-31", "2020-12-31",
+						test.accounts, test.accounts, 0, 10, 10)...).
+					WillReturnRows(sqltest.MockRows("id").AddRow(69))
+
+				result := GetTransactionsByPayeeIDs(tx, []int64{42}, filter, test.accountIDs)
+
+				assert.Equal(t, []*table.Transaction{{ID: 69}}, result)
+				assert.Nil(t, mockDB.ExpectationsWereMet())
+			})
+		})
+	}
+}
+
 func Test_InsertTransaction(t *testing.T) {
 	accountID := int64(96)
 	user := "user id"
@@ -66,16 +93,19 @@ func Test_InsertTransaction(t *testing.T) {
 		"memo":            "notes",
 		"cleared":         true,
 	}
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		runInsertStub := mocka.Function(t, &runInsert, id)
 		defer runInsertStub.Restore()
+		history := mockHistory()
+		defer history.restore()
 
 		result := InsertTransaction(tx, int64(accountID), values, user)
 
 		assert.Equal(t, id, result)
 		assert.Equal(t,
-			sqltest.UpdateArgs(tx, insertTransactionSQL, accountID, "2020-12-25", "abc", int64(123), int64(456), "notes", "Y", user),
+			sqltest.UpdateArgs(tx, insertTransactionSQL.sql, accountID, "2020-12-25", "abc", int64(123), int64(456), "notes", "Y", user),
 			runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"transaction", id, user}}, history.inserts)
 	})
 }
 
@@ -100,48 +130,51 @@ func Test_UpdateTransaction(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			update := InputObject{}
 			update[test.field] = test.value
-			sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+			testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 				expectedArgs := []interface{}{
-					tx, updateTxSQL,
-					[]interface{}{update["date"],
+					tx, updateTxSQL.sql,
+					[]interface{}{test.field == "date", update["date"],
 						test.field == "referenceNumber", update["referenceNumber"],
 						test.field == "payeeId", update.IntOrNull("payeeId"),
 						test.field == "securityId", update.IntOrNull("securityId"),
 						test.field == "memo", update["memo"],
-						update.YesNoOrNull("cleared"),
+						test.field == "cleared", update.YesNoOrNull("cleared"),
 						test.field == "accountId", update.IntOrNull("accountId"),
 						user, id, version}}
 				runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
 				defer runUpdateStub.Restore()
+				history := mockHistory()
+				defer history.restore()
 
-				UpdateTransaction(tx, id, version, update, user)
+				err := UpdateTransaction(tx, id, version, update, user)
 
+				assert.Nil(t, err)
 				assert.Equal(t, expectedArgs, runUpdateStub.GetCall(0).Arguments())
+				assert.Equal(t, []historyCall{{"transaction", []int64{id}, user}}, history.changes)
 				assert.Nil(t, mockDB.ExpectationsWereMet())
 			})
 		})
 	}
-	t.Run("panics for not found", func(t *testing.T) {
+	t.Run("returns error for trashed transaction", func(t *testing.T) {
 		update := InputObject{}
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
 			defer runUpdateStub.Restore()
-			defer func() {
-				assert.Nil(t, mockDB.ExpectationsWereMet())
-				if err := recover(); err != nil {
-					assert.Equal(t, "transaction not found (42 @ 1)", err.(error).Error())
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
-
-			UpdateTransaction(tx, id, version, update, user)
+			loadImagesStub := mocka.Function(t, &loadImages, map[string]string{"42": `{"id":42,"trash_date":"2021-03-04 05:06:07"}`})
+			defer loadImagesStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := UpdateTransaction(tx, id, version, update, user)
+
+			assert.Equal(t, apperror.RowDeleted("transaction", id, version), err)
+			assert.Equal(t, "transaction has been deleted (42)", err.Error())
 		})
 	})
 }
 
 func Test_GetTransactionsByIDs(t *testing.T) {
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
 		transactions := []*table.Transaction{{ID: 42}}
 		runQueryStub := mocka.Function(t, &runQuery, transactions)
 		defer runQueryStub.Restore()
@@ -152,35 +185,219 @@ func Test_GetTransactionsByIDs(t *testing.T) {
 	})
 }
 
-func Test_DeleteTransactions(t *testing.T) {
+func Test_transferGraphIDs(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{42, 96})
+		runIDQueryStub.OnSecondCall().Return([]int64{42, 24})
+		runIDQueryStub.OnThirdCall().Return([]int64{96})
+		defer runIDQueryStub.Restore()
+
+		result := transferGraphIDs(tx, []int64{42})
+
+		assert.Equal(t, []int64{42, 96, 24}, result)
+		assert.Equal(t, 3, runIDQueryStub.CallCount())
+		assert.Equal(t, []interface{}{tx, transferTxIDsSQL, []interface{}{"[42]"}}, runIDQueryStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, transferTxIDsSQL, []interface{}{"[96]"}}, runIDQueryStub.GetCall(1).Arguments())
+		assert.Equal(t, []interface{}{tx, transferTxIDsSQL, []interface{}{"[24]"}}, runIDQueryStub.GetCall(2).Arguments())
+	})
+}
+
+func Test_TrashTransactions(t *testing.T) {
 	ids := []map[string]interface{}{{"id": 42, "version": 1}, {"id": 24, "version": 0}}
 	idArg, _ := json.Marshal(ids)
-	t.Run("deletes transactions", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	t.Run("trashes transactions and transfers", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{96})
+			runIDQueryStub.OnSecondCall().Return([]int64{42})
+			defer runIDQueryStub.Restore()
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := TrashTransactions(tx, ids, "user id")
+
+			assert.Nil(t, err)
+			assert.Equal(t, sqltest.UpdateArgs(tx, trashTransactionsSQL, "user id", idArg), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, trashTransfersSQL, "user id", "[96]"), runUpdateStub.GetCall(1).Arguments())
+			assert.Equal(t, []historyCall{{"transaction", []int64{42, 24, 96}, "user id"}}, history.changes)
+		})
+	})
+	t.Run("ignores duplicate IDs", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{96})
+			runIDQueryStub.OnSecondCall().Return([]int64{42})
+			defer runIDQueryStub.Restore()
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := TrashTransactions(tx, append(ids, map[string]interface{}{"id": 42, "version": 1}), "user id")
+
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{tx, transferTxIDsSQL, []interface{}{"[42,24]"}}, runIDQueryStub.GetCall(0).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, trashTransactionsSQL, "user id", idArg), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, trashTransfersSQL, "user id", "[96]"), runUpdateStub.GetCall(1).Arguments())
+			assert.Equal(t, []historyCall{{"transaction", []int64{42, 24, 96}, "user id"}}, history.changes)
+		})
+	})
+	t.Run("skips transfer update if no transfers", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{})
+			defer runIDQueryStub.Restore()
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
 			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
 
-			DeleteTransactions(tx, ids)
+			TrashTransactions(tx, ids, "user id")
 
-			assert.Equal(t,
-				sqltest.UpdateArgs(tx, "delete from transaction where json_contains(?, json_object('id', id, 'version', version))", idArg),
-				runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, 1, runUpdateStub.CallCount())
 		})
 	})
-	t.Run("panics for transactions not found", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	t.Run("returns error for transactions not found", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{})
+			defer runIDQueryStub.Restore()
 			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
 			defer runUpdateStub.Restore()
-			defer func() {
-				assert.Nil(t, mockDB.ExpectationsWereMet())
-				if err := recover(); err != nil {
-					assert.Equal(t, "transaction(s) not found", err.(error).Error())
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
-
-			DeleteTransactions(tx, ids)
+			history := mockHistory()
+			defer history.restore()
+
+			err := TrashTransactions(tx, ids, "user id")
+
+			assert.Equal(t, apperror.NotFound("transaction"), err)
+			assert.Equal(t, "transaction(s) not found", err.Error())
+		})
+	})
+}
+
+func Test_GetTrash(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		transactions := []*table.Transaction{{ID: 42}}
+		runQueryStub := mocka.Function(t, &runQuery, transactions)
+		defer runQueryStub.Restore()
+
+		result := GetTrash(tx, int64(96))
+
+		assert.Equal(t, transactions, result)
+		assert.Equal(t, []interface{}{tx, transactionType, trashSQL, []interface{}{int64(96), int64(96)}}, runQueryStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_RestoreTransactions(t *testing.T) {
+	t.Run("restores transfer graph", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{42})
+			runIDQueryStub.OnSecondCall().Return([]int64{96})
+			runIDQueryStub.OnThirdCall().Return([]int64{42})
+			defer runIDQueryStub.Restore()
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			result, err := RestoreTransactions(tx, []int64{42}, "user id")
+
+			assert.Nil(t, err)
+			assert.Equal(t, []int64{42, 96}, result)
+			assert.Equal(t, []interface{}{tx, trashedIDsSQL, []interface{}{"[42]"}}, runIDQueryStub.GetCall(0).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, restoreTransactionsSQL, "user id", "[42,96]"), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"transaction", []int64{42, 96}, "user id"}}, history.changes)
+		})
+	})
+	t.Run("returns error for transaction not in trash", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{42})
+			defer runIDQueryStub.Restore()
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
+			defer runUpdateStub.Restore()
+
+			_, err := RestoreTransactions(tx, []int64{42, 96}, "user id")
+
+			assert.IsType(t, &apperror.NotFoundError{}, err)
+			assert.Equal(t, "transaction(s) not found in trash", err.Error())
+			assert.Equal(t, 0, runUpdateStub.CallCount())
+		})
+	})
+}
+
+func Test_GetExpiredTrashIDs(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{42})
+		defer runIDQueryStub.Restore()
+
+		result := GetExpiredTrashIDs(tx, 30)
+
+		assert.Equal(t, []int64{42}, result)
+		assert.Equal(t, []interface{}{tx, expiredTrashSQL, []interface{}{30}}, runIDQueryStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_PurgeTransactions(t *testing.T) {
+	testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+		runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+		defer runUpdateStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		PurgeTransactions(tx, []int64{42, 96}, "user id")
+
+		assert.Equal(t, sqltest.UpdateArgs(tx, "delete from transaction where @in(id)", "[42,96]"),
+			runUpdateStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"transaction", []int64{42, 96}, "user id"}}, history.changes)
+	})
+}
+
+func Test_ClearTransaction(t *testing.T) {
+	id := int64(42)
+	version := int64(3)
+	accountID := int64(96)
+	user := "user id"
+	t.Run("sets cleared", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := ClearTransaction(tx, id, version, accountID, user)
+
+			assert.Nil(t, err)
+			assert.Equal(t, sqltest.UpdateArgs(tx, clearTransactionSQL, user, id, version, accountID), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"transaction", []int64{id}, user}}, history.changes)
+		})
+	})
+	t.Run("returns error for transaction not found", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
+			defer runUpdateStub.Restore()
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{})
+			defer runIDQueryStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := ClearTransaction(tx, id, version, accountID, user)
+
+			assert.EqualError(t, err, "transaction not found in account (42)")
+			assert.Equal(t, []interface{}{tx, accountTransactionSQL, []interface{}{id, accountID}}, runIDQueryStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("returns version conflict", func(t *testing.T) {
+		testInTx(t, func(mockDB sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
+			defer runUpdateStub.Restore()
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{id})
+			defer runIDQueryStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+			loadImagesStub := mocka.Function(t, &loadImages, map[string]string{})
+			defer loadImagesStub.Restore()
+
+			err := ClearTransaction(tx, id, version, accountID, user)
+
+			assert.Equal(t, apperror.RowDeleted("transaction", id, version), err)
 		})
 	})
 }
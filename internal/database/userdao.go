@@ -0,0 +1,130 @@
+package database
+
+import (
+	"reflect"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var userType = reflect.TypeOf(table.User{})
+var accountPermissionType = reflect.TypeOf(table.AccountPermission{})
+var userTable = mapTable("app_user", userType)
+var accountPermissionTable = mapTable("account_permission", accountPermissionType)
+
+// GetUserByName returns the user with the name.
+func GetUserByName(tx *Tx, name string) []*table.User {
+	users := runQuery(tx, userType, "select * from app_user where name = ?", name)
+	return users.([]*table.User)
+}
+
+// GetAllUsers loads all users ordered by name.
+func GetAllUsers(tx *Tx) []*table.User {
+	users := runQuery(tx, userType, "select * from app_user order by name")
+	return users.([]*table.User)
+}
+
+// GetUsersByIDs returns the users with the IDs.
+func GetUsersByIDs(tx *Tx, ids []int64) []*table.User {
+	users := runQuery(tx, userType, "select * from app_user where @in(id) order by name", int64sToJson(ids))
+	return users.([]*table.User)
+}
+
+var addUserSQL = userTable.insertSQL("name", "role")
+
+// AddUser adds a user and returns its ID.
+func AddUser(tx *Tx, name string, role string, user string) int64 {
+	id := runInsert(tx, addUserSQL.sql, addUserSQL.modelArgs(&table.User{Name: name, Role: role}, user)...)
+	recordInsert(tx, "app_user", id, user)
+	return id
+}
+
+var updateUserSQL = userTable.partialUpdateSQL("role")
+
+// UpdateUser updates the role of a user.
+func UpdateUser(tx *Tx, id int64, version int64, values InputObject, user string) error {
+	var count int64
+	trackChanges(tx, "app_user", []int64{id}, user, func() {
+		count = runUpdate(tx, updateUserSQL.sql, updateUserSQL.inputArgs(values, nil, user, id, version)...)
+	})
+	if count == 0 {
+		return versionConflict(tx, "app_user", id, version)
+	}
+	return nil
+}
+
+func runAccountPermissionQuery(tx *Tx, query string, args ...interface{}) []*table.AccountPermission {
+	permissions := runQuery(tx, accountPermissionType, query, args...)
+	return permissions.([]*table.AccountPermission)
+}
+
+// GetAccountPermissions returns the account permissions granted to the user.
+func GetAccountPermissions(tx *Tx, userID int64) []*table.AccountPermission {
+	return runAccountPermissionQuery(tx, "select * from account_permission where user_id = ?", userID)
+}
+
+// GetAccountPermissionsByUserIDs returns the account permissions granted to the users.
+func GetAccountPermissionsByUserIDs(tx *Tx, userIDs []int64) []*table.AccountPermission {
+	return runAccountPermissionQuery(tx, "select * from account_permission where @in(user_id) order by user_id, account_id",
+		int64sToJson(userIDs))
+}
+
+// GetAccountPermissionsByIDs returns the account permissions with the IDs.
+func GetAccountPermissionsByIDs(tx *Tx, ids []int64) []*table.AccountPermission {
+	return runAccountPermissionQuery(tx, "select * from account_permission where @in(id) order by user_id, account_id",
+		int64sToJson(ids))
+}
+
+var addAccountPermissionSQL = accountPermissionTable.insertSQL("user_id", "account_id", "permission")
+
+const setAccountPermissionSQL = "update account_permission set permission = ?, " + auditUpdate + " where id = ?"
+
+// GrantAccountPermission sets the user's permission for the account and returns the ID of the account permission.
+func GrantAccountPermission(tx *Tx, userID int64, accountID int64, permission string, user string) int64 {
+	ids := runIDQuery(tx, "select id from account_permission where user_id = ? and account_id = ?", userID, accountID)
+	if len(ids) > 0 {
+		trackChanges(tx, "account_permission", ids, user, func() {
+			runUpdate(tx, setAccountPermissionSQL, permission, user, ids[0])
+		})
+		return ids[0]
+	}
+	grant := &table.AccountPermission{UserID: userID, AccountID: accountID, Permission: permission}
+	id := runInsert(tx, addAccountPermissionSQL.sql, addAccountPermissionSQL.modelArgs(grant, user)...)
+	recordInsert(tx, "account_permission", id, user)
+	return id
+}
+
+// RevokeAccountPermissions deletes account permissions and returns the number of deleted permissions. Returns a
+// NotFoundError if any of the permissions are not found.
+func RevokeAccountPermissions(tx *Tx, ids []int64, user string) (int64, error) {
+	var count int64
+	trackChanges(tx, "account_permission", ids, user, func() {
+		count = runUpdate(tx, "delete from account_permission where @in(id)", int64sToJson(ids))
+	})
+	if int(count) != len(ids) {
+		return 0, apperror.NotFoundf("account_permission", "account permission(s) not found")
+	}
+	return count, nil
+}
+
+// includes the accounts of related transfer details
+const transactionAccountIDsSQL = `select t.account_id
+from transaction t
+where @in(t.id)
+union
+select rt.account_id
+from transaction_detail td
+join transaction_detail rd on td.related_detail_id = rd.id
+join transaction rt on rd.transaction_id = rt.id
+where @in(td.transaction_id)`
+
+// GetTransactionAccountIDs returns the IDs of the accounts affected by changes to the transactions.
+func GetTransactionAccountIDs(tx *Tx, txIDs []int64) []int64 {
+	jsonIDs := int64sToJson(txIDs)
+	return runIDQuery(tx, transactionAccountIDsSQL, jsonIDs, jsonIDs)
+}
+
+// GetImportItemAccountIDs returns the IDs of the accounts of the import items.
+func GetImportItemAccountIDs(tx *Tx, ids []int64) []int64 {
+	return runIDQuery(tx, "select distinct account_id from import_item where @in(id)", int64sToJson(ids))
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetUserByName(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		users := []*table.User{{ID: 42}}
+		runQueryStub := mocka.Function(t, &runQuery, users)
+		defer runQueryStub.Restore()
+
+		result := GetUserByName(tx, "somebody")
+
+		assert.Equal(t, []interface{}{tx, userType, "select * from app_user where name = ?", []interface{}{"somebody"}}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, users, result)
+	})
+}
+
+func Test_GetAllUsers(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		users := []*table.User{{ID: 42}}
+		runQueryStub := mocka.Function(t, &runQuery, users)
+		defer runQueryStub.Restore()
+
+		result := GetAllUsers(tx)
+
+		assert.Equal(t, []interface{}{tx, userType, "select * from app_user order by name", []interface{}(nil)}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, users, result)
+	})
+}
+
+func Test_GetUsersByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		users := []*table.User{{ID: 42}}
+		runQueryStub := mocka.Function(t, &runQuery, users)
+		defer runQueryStub.Restore()
+
+		result := GetUsersByIDs(tx, []int64{42, 96})
+
+		assert.Equal(t, []interface{}{tx, userType, "select * from app_user where @in(id) order by name", []interface{}{"[42,96]"}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, users, result)
+	})
+}
+
+func Test_AddUser(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runInsertStub := mocka.Function(t, &runInsert, int64(42))
+		defer runInsertStub.Restore()
+		history := mockHistory()
+		defer history.restore()
+
+		result := AddUser(tx, "alice", table.RoleAdmin, "somebody")
+
+		assert.Equal(t, int64(42), result)
+		assert.Equal(t, sqltest.UpdateArgs(tx, addUserSQL.sql, "alice", table.RoleAdmin, "somebody"), runInsertStub.GetCall(0).Arguments())
+		assert.Equal(t, []historyCall{{"app_user", int64(42), "somebody"}}, history.inserts)
+	})
+}
+
+func Test_UpdateUser(t *testing.T) {
+	values := InputObject{"role": table.RoleAdmin}
+	t.Run("updates role", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := UpdateUser(tx, 42, 1, values, "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, sqltest.UpdateArgs(tx, updateUserSQL.sql, true, table.RoleAdmin, "somebody", int64(42), int64(1)),
+				runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"app_user", []int64{42}, "somebody"}}, history.changes)
+		})
+	})
+	t.Run("returns error for version conflict", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(0))
+			defer runUpdateStub.Restore()
+			loadImagesStub := mocka.Function(t, &loadImages, map[string]string{})
+			defer loadImagesStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			err := UpdateUser(tx, 42, 1, values, "somebody")
+
+			assert.Equal(t, apperror.RowDeleted("app_user", 42, 1), err)
+		})
+	})
+}
+
+func Test_GetAccountPermissions(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		permissions := []*table.AccountPermission{{UserID: 42, AccountID: 96}}
+		runQueryStub := mocka.Function(t, &runQuery, permissions)
+		defer runQueryStub.Restore()
+
+		result := GetAccountPermissions(tx, 42)
+
+		assert.Equal(t, []interface{}{tx, accountPermissionType, "select * from account_permission where user_id = ?", []interface{}{int64(42)}},
+			runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, permissions, result)
+	})
+}
+
+func Test_GetAccountPermissionsByUserIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		permissions := []*table.AccountPermission{{UserID: 42, AccountID: 96}}
+		runQueryStub := mocka.Function(t, &runQuery, permissions)
+		defer runQueryStub.Restore()
+
+		result := GetAccountPermissionsByUserIDs(tx, []int64{42})
+
+		assert.Equal(t, []interface{}{tx, accountPermissionType, "select * from account_permission where @in(user_id) order by user_id, account_id",
+			[]interface{}{"[42]"}}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, permissions, result)
+	})
+}
+
+func Test_GetAccountPermissionsByIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		permissions := []*table.AccountPermission{{ID: 1, UserID: 42, AccountID: 96}}
+		runQueryStub := mocka.Function(t, &runQuery, permissions)
+		defer runQueryStub.Restore()
+
+		result := GetAccountPermissionsByIDs(tx, []int64{1})
+
+		assert.Equal(t, []interface{}{tx, accountPermissionType, "select * from account_permission where @in(id) order by user_id, account_id",
+			[]interface{}{"[1]"}}, runQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, permissions, result)
+	})
+}
+
+func Test_GrantAccountPermission(t *testing.T) {
+	const idQuery = "select id from account_permission where user_id = ? and account_id = ?"
+	t.Run("updates existing permission", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{7})
+			defer runIDQueryStub.Restore()
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			runInsertStub := mocka.Function(t, &runInsert, int64(0))
+			defer runInsertStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			result := GrantAccountPermission(tx, 42, 96, table.PermissionWrite, "somebody")
+
+			assert.Equal(t, int64(7), result)
+			assert.Equal(t, []interface{}{tx, idQuery, []interface{}{int64(42), int64(96)}}, runIDQueryStub.GetCall(0).Arguments())
+			assert.Equal(t, sqltest.UpdateArgs(tx, setAccountPermissionSQL, table.PermissionWrite, "somebody", int64(7)),
+				runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"account_permission", []int64{7}, "somebody"}}, history.changes)
+			assert.Equal(t, 0, runInsertStub.CallCount())
+		})
+	})
+	t.Run("adds new permission", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{})
+			defer runIDQueryStub.Restore()
+			runInsertStub := mocka.Function(t, &runInsert, int64(7))
+			defer runInsertStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			result := GrantAccountPermission(tx, 42, 96, table.PermissionRead, "somebody")
+
+			assert.Equal(t, int64(7), result)
+			assert.Equal(t, sqltest.UpdateArgs(tx, addAccountPermissionSQL.sql, int64(42), int64(96), table.PermissionRead, "somebody"),
+				runInsertStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"account_permission", int64(7), "somebody"}}, history.inserts)
+		})
+	})
+}
+
+func Test_RevokeAccountPermissions(t *testing.T) {
+	t.Run("deletes permissions", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(2))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			count, err := RevokeAccountPermissions(tx, []int64{1, 2}, "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), count)
+			assert.Equal(t, sqltest.UpdateArgs(tx, "delete from account_permission where @in(id)", "[1,2]"), runUpdateStub.GetCall(0).Arguments())
+			assert.Equal(t, []historyCall{{"account_permission", []int64{1, 2}, "somebody"}}, history.changes)
+		})
+	})
+	t.Run("returns error for missing permission", func(t *testing.T) {
+		testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+			runUpdateStub := mocka.Function(t, &runUpdate, int64(1))
+			defer runUpdateStub.Restore()
+			history := mockHistory()
+			defer history.restore()
+
+			_, err := RevokeAccountPermissions(tx, []int64{1, 2}, "somebody")
+
+			assert.EqualError(t, err, "account permission(s) not found")
+		})
+	})
+}
+
+func Test_GetTransactionAccountIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{96})
+		defer runIDQueryStub.Restore()
+
+		result := GetTransactionAccountIDs(tx, []int64{1, 2})
+
+		assert.Equal(t, []interface{}{tx, transactionAccountIDsSQL, []interface{}{"[1,2]", "[1,2]"}}, runIDQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, []int64{96}, result)
+	})
+}
+
+func Test_GetImportItemAccountIDs(t *testing.T) {
+	testInTx(t, func(mock sqlmock.Sqlmock, tx *Tx) {
+		runIDQueryStub := mocka.Function(t, &runIDQuery, []int64{96})
+		defer runIDQueryStub.Restore()
+
+		result := GetImportItemAccountIDs(tx, []int64{1, 2})
+
+		assert.Equal(t, []interface{}{tx, "select distinct account_id from import_item where @in(id)", []interface{}{"[1,2]"}},
+			runIDQueryStub.GetFirstCall().Arguments())
+		assert.Equal(t, []int64{96}, result)
+	})
+}
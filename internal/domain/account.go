@@ -1,9 +1,9 @@
 package domain
 
 import (
-	"database/sql"
-
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
@@ -22,7 +22,7 @@ func (a *Account) Resolve(p graphql.ResolveParams) (interface{}, error) {
 }
 
 // GetCompany returns the company for the account.
-func (a *Account) GetCompany(tx *sql.Tx) *Company {
+func (a *Account) GetCompany(tx *database.Tx) *Company {
 	if a.CompanyID == nil {
 		return nil
 	}
@@ -30,26 +30,53 @@ func (a *Account) GetCompany(tx *sql.Tx) *Company {
 	return a.source.companiesByID[*a.CompanyID]
 }
 
+// GetCurrency returns the currency of the account.
+func (a *Account) GetCurrency(tx *database.Tx) *table.Currency {
+	a.source.loadCurrencies(tx)
+	return a.source.currencyByID[a.CurrencyID]
+}
+
+// GetAttachments returns the files attached to the account.
+func (a *Account) GetAttachments(tx *database.Tx) []*table.Attachment {
+	a.source.loadAttachments(tx)
+	return a.source.attachments[a.ID]
+}
+
+// GetBalance returns the account balance converted to the currency. Returns a NotFoundError if the currency or the
+// exchange rate doesn't exist.
+func (a *Account) GetBalance(tx *database.Tx, currencyID int64) (string, error) {
+	if currencyID == a.CurrencyID {
+		return a.Balance, nil
+	}
+	a.source.loadCurrencies(tx)
+	currency, ok := a.source.currencyByID[currencyID]
+	if !ok {
+		return "", apperror.NotFound("currency", currencyID)
+	}
+	a.source.loadExchangeRates(tx)
+	return a.source.rates.Convert(a.Balance, a.CurrencyID, currency)
+}
+
 // GetAllAccounts loads all accounts.
-func GetAllAccounts(tx *sql.Tx) []*Account {
+func GetAllAccounts(tx *database.Tx) []*Account {
 	accounts := getAllAccounts(tx)
 	return newCompanySource().setAccounts(accounts, true)
 }
 
 // GetAccountByID returns the account with ID.
-func GetAccountByID(tx *sql.Tx, id int64) []*Account {
+func GetAccountByID(tx *database.Tx, id int64) []*Account {
 	accounts := getAccountByID(tx, id)
 	return newCompanySource().setAccounts(accounts, false)
 }
 
 // GetAccountsByName returns the accounts having name.
-func GetAccountsByName(tx *sql.Tx, name string) []*Account {
+func GetAccountsByName(tx *database.Tx, name string) []*Account {
 	accounts := getAccountsByName(tx, name)
 	return newCompanySource().setAccounts(accounts, false)
 }
 
 // GetAccountsByCompanyIDs returns the accounts for the sepcified companies.
-func GetAccountsByCompanyIDs(tx *sql.Tx, companyIDs []int64) []*Account {
+func GetAccountsByCompanyIDs(tx *database.Tx, companyIDs []int64) []*Account {
 	accounts := getAccountsByCompanyIDs(tx, companyIDs)
 	return newCompanySource().setAccounts(accounts, true)
 }
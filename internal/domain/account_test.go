@@ -1,13 +1,14 @@
 package domain
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,7 +36,7 @@ func Test_Account_GetCompany(t *testing.T) {
 }
 
 func Test_GetAllAccounts(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		dbAccounts := []*table.Account{{ID: 1}}
 		getAllAccountsStub := mocka.Function(t, &getAllAccounts, dbAccounts)
 		defer getAllAccountsStub.Restore()
@@ -50,7 +51,7 @@ func Test_GetAllAccounts(t *testing.T) {
 
 func Test_GetAccountByID(t *testing.T) {
 	id := int64(42)
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		dbAccounts := []*table.Account{{ID: 1}}
 		getAccountByIDStub := mocka.Function(t, &getAccountByID, dbAccounts)
 		defer getAccountByIDStub.Restore()
@@ -65,7 +66,7 @@ func Test_GetAccountByID(t *testing.T) {
 
 func Test_GetAccountsByName(t *testing.T) {
 	name := "account name"
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		dbAccounts := []*table.Account{{ID: 1}}
 		getAccountsByNameStub := mocka.Function(t, &getAccountsByName, dbAccounts)
 		defer getAccountsByNameStub.Restore()
@@ -80,7 +81,7 @@ func Test_GetAccountsByName(t *testing.T) {
 
 func Test_GetAccountsByCompanyID(t *testing.T) {
 	ids := []int64{42, 69}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		dbAccounts := []*table.Account{{ID: 1}}
 		getAccountsByCompanyIDsStub := mocka.Function(t, &getAccountsByCompanyIDs, dbAccounts)
 		defer getAccountsByCompanyIDsStub.Restore()
@@ -92,3 +93,56 @@ func Test_GetAccountsByCompanyID(t *testing.T) {
 		assert.NotNil(t, result[0].source)
 	})
 }
+
+func Test_Account_GetCurrency(t *testing.T) {
+	currency := &table.Currency{Code: "USD", Asset: table.Asset{ID: 1}}
+	source := &companySource{currencyConverter: currencyConverter{currencyByID: map[int64]*table.Currency{1: currency}}}
+	account := &Account{Account: &table.Account{CurrencyID: 1}, source: source}
+
+	result := account.GetCurrency(nil)
+
+	assert.Same(t, currency, result)
+}
+
+func Test_Account_GetAttachments(t *testing.T) {
+	attachments := []*table.Attachment{{ID: 42}}
+	source := &companySource{attachments: map[int64][]*table.Attachment{1: attachments}}
+	account := &Account{Account: &table.Account{ID: 1}, source: source}
+
+	result := account.GetAttachments(nil)
+
+	assert.Equal(t, attachments, result)
+}
+
+func Test_Account_GetBalance(t *testing.T) {
+	usd := &table.Currency{Code: "USD", Asset: table.Asset{ID: 1, Scale: 2}}
+	eur := &table.Currency{Code: "EUR", Asset: table.Asset{ID: 2, Scale: 2}}
+	rates := newExchangeRates(nil, []*table.ExchangeRate{{FromCurrencyID: 2, ToCurrencyID: 1, Rate: 1.25}})
+	source := &companySource{currencyConverter: currencyConverter{currencyByID: map[int64]*table.Currency{1: usd, 2: eur}, rates: rates}}
+	tests := []struct {
+		name       string
+		currencyID int64
+		balance    string
+	}{
+		{"returns balance for account currency", 2, "100.00"},
+		{"returns converted balance", 1, "125.00"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			account := &Account{Account: &table.Account{CurrencyID: 2, Balance: "100.00"}, source: source}
+
+			result, err := account.GetBalance(nil, test.currencyID)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.balance, result)
+		})
+	}
+	t.Run("returns error for unknown currency", func(t *testing.T) {
+		account := &Account{Account: &table.Account{CurrencyID: 2, Balance: "100.00"}, source: source}
+
+		result, err := account.GetBalance(nil, 3)
+
+		assert.Equal(t, "", result)
+		assert.Equal(t, apperror.NotFound("currency", int64(3)), err)
+	})
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// APITokenPrefix identifies personal API tokens in bearer credentials.
+const APITokenPrefix = "fin_"
+
+// NewAPIToken contains a newly created token. The token value is only available when it is created.
+type NewAPIToken struct {
+	Token    string
+	APIToken *table.APIToken
+}
+
+var randomRead = rand.Read
+
+func hashAPIToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// IsAPIToken returns true if the bearer token is a personal API token.
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// GetAPITokenUser returns the name of the user that owns the token or an empty string if the token is unknown or expired.
+func GetAPITokenUser(tx *database.Tx, token string) string {
+	if users := getAPITokenUser(tx, hashAPIToken(token)); len(users) > 0 {
+		return users[0].Name
+	}
+	return ""
+}
+
+// CreateAPIToken generates a new API token for the user.
+func CreateAPIToken(tx *database.Tx, name string, expiryDate interface{}, user string) (*NewAPIToken, error) {
+	secret := make([]byte, 32)
+	if _, err := randomRead(secret); err != nil {
+		panic(err)
+	}
+	token := APITokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	id, err := addAPIToken(tx, name, hashAPIToken(token), expiryDate, user)
+	if err != nil {
+		return nil, err
+	}
+	return &NewAPIToken{Token: token, APIToken: getAPITokensByIDs(tx, []int64{id})[0]}, nil
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_hashAPIToken(t *testing.T) {
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", hashAPIToken("hello"))
+}
+
+func Test_IsAPIToken(t *testing.T) {
+	assert.True(t, IsAPIToken("fin_abc"))
+	assert.False(t, IsAPIToken("eyJhbGciOi.x.y"))
+}
+
+func Test_GetAPITokenUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		users  []*table.User
+		result string
+	}{
+		{"returns user name", []*table.User{{Name: "somebody"}}, "somebody"},
+		{"returns empty string for unknown token", []*table.User{}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+				getUserStub := mocka.Function(t, &getAPITokenUser, test.users)
+				defer getUserStub.Restore()
+
+				result := GetAPITokenUser(tx, "hello")
+
+				assert.Equal(t, test.result, result)
+				assert.Equal(t, []interface{}{tx, hashAPIToken("hello")}, getUserStub.GetFirstCall().Arguments())
+			})
+		})
+	}
+}
+
+func Test_CreateAPIToken(t *testing.T) {
+	t.Run("adds token", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			tokens := []*table.APIToken{{ID: 42}}
+			addStub := mocka.Function(t, &addAPIToken, int64(42), nil)
+			defer addStub.Restore()
+			getStub := mocka.Function(t, &getAPITokensByIDs, tokens)
+			defer getStub.Restore()
+
+			result, err := CreateAPIToken(tx, "laptop", nil, "somebody")
+
+			assert.Nil(t, err)
+			assert.True(t, IsAPIToken(result.Token))
+			assert.Equal(t, 47, len(result.Token))
+			assert.Same(t, tokens[0], result.APIToken)
+			assert.Equal(t, []interface{}{tx, "laptop", hashAPIToken(result.Token), nil, "somebody"}, addStub.GetFirstCall().Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{42}}, getStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("returns error for unknown user", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			addStub := mocka.Function(t, &addAPIToken, int64(0), apperror.NotFound("user", "somebody"))
+			defer addStub.Restore()
+
+			result, err := CreateAPIToken(tx, "laptop", nil, "somebody")
+
+			assert.Nil(t, result)
+			assert.EqualError(t, err, "user not found (somebody)")
+		})
+	})
+	t.Run("panics for random error", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			randomStub := mocka.Function(t, &randomRead, 0, errors.New("no entropy"))
+			defer randomStub.Restore()
+			defer expectPanic(t, "no entropy")
+
+			CreateAPIToken(tx, "laptop", nil, "somebody")
+		})
+	})
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// attachmentAccountID returns the account that owns an attachment.
+func attachmentAccountID(tx *database.Tx, attachment *table.Attachment) (int64, error) {
+	if attachment.AccountID != nil {
+		return *attachment.AccountID, nil
+	}
+	transactions := getTransactionsByIDs(tx, []int64{*attachment.TransactionID})
+	if len(transactions) == 0 {
+		return 0, apperror.NotFound("transaction", *attachment.TransactionID)
+	}
+	return transactions[0].AccountID, nil
+}
+
+// GetAttachment returns the attachment with the ID. Returns a ForbiddenError if the user can't view the account that
+// owns the attachment.
+func GetAttachment(tx *database.Tx, id int64, permissions *Permissions) (*table.Attachment, error) {
+	attachments := getAttachment(tx, id)
+	if len(attachments) == 0 {
+		return nil, apperror.NotFound("attachment", id)
+	}
+	accountID, err := attachmentAccountID(tx, attachments[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := permissions.RequireRead(accountID); err != nil {
+		return nil, err
+	}
+	return attachments[0], nil
+}
+
+// AddAttachment links a stored file to a transaction or an account. Returns a ForbiddenError if the user can't change
+// the account.
+func AddAttachment(tx *database.Tx, attachment *table.Attachment, user string, permissions *Permissions) (*table.Attachment, error) {
+	if (attachment.TransactionID == nil) == (attachment.AccountID == nil) {
+		return nil, apperror.Validation("transactionId", "attachment requires either a transaction or an account")
+	}
+	accountID, err := attachmentAccountID(tx, attachment)
+	if err != nil {
+		return nil, err
+	}
+	if err := permissions.RequireWrite(accountID); err != nil {
+		return nil, err
+	}
+	return getAttachment(tx, addAttachment(tx, attachment, user))[0], nil
+}
+
+// unreferencedHashes returns the file hashes that are no longer used by any attachment.
+func unreferencedHashes(tx *database.Tx, hashes []string) []string {
+	if len(hashes) == 0 {
+		return nil
+	}
+	referenced := make(map[string]bool)
+	for _, attachment := range getAttachmentsByHashes(tx, hashes) {
+		referenced[attachment.Hash] = true
+	}
+	orphans := make([]string, 0)
+	for _, hash := range hashes {
+		if !referenced[hash] {
+			referenced[hash] = true
+			orphans = append(orphans, hash)
+		}
+	}
+	return orphans
+}
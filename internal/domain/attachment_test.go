@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetAttachment(t *testing.T) {
+	accountID := int64(1)
+	txID := int64(96)
+	readable := NewPermissions(false, map[int64]string{accountID: table.PermissionRead})
+	t.Run("returns account attachment", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			attachment := &table.Attachment{ID: 42, AccountID: &accountID}
+			getAttachmentStub := mocka.Function(t, &getAttachment, []*table.Attachment{attachment})
+			defer getAttachmentStub.Restore()
+
+			result, err := GetAttachment(tx, 42, readable)
+
+			assert.Nil(t, err)
+			assert.Same(t, attachment, result)
+			assert.Equal(t, []interface{}{tx, int64(42)}, getAttachmentStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("checks transaction account", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			attachment := &table.Attachment{ID: 42, TransactionID: &txID}
+			getAttachmentStub := mocka.Function(t, &getAttachment, []*table.Attachment{attachment})
+			defer getAttachmentStub.Restore()
+			getTransactionsStub := mocka.Function(t, &getTransactionsByIDs, []*table.Transaction{{ID: txID, AccountID: 2}})
+			defer getTransactionsStub.Restore()
+
+			result, err := GetAttachment(tx, 42, readable)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.AccountForbidden(2, "readable"), err)
+		})
+	})
+	t.Run("returns error if not found", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			getAttachmentStub := mocka.Function(t, &getAttachment, []*table.Attachment{})
+			defer getAttachmentStub.Restore()
+
+			_, err := GetAttachment(tx, 42, readable)
+
+			assert.EqualError(t, err, "attachment not found (42)")
+		})
+	})
+}
+
+func Test_AddAttachment(t *testing.T) {
+	accountID := int64(1)
+	txID := int64(96)
+	writable := NewPermissions(false, map[int64]string{accountID: table.PermissionWrite})
+	t.Run("adds transaction attachment", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			attachment := &table.Attachment{TransactionID: &txID, FileName: "receipt.pdf"}
+			saved := &table.Attachment{ID: 42, TransactionID: &txID, FileName: "receipt.pdf"}
+			getTransactionsStub := mocka.Function(t, &getTransactionsByIDs, []*table.Transaction{{ID: txID, AccountID: accountID}})
+			defer getTransactionsStub.Restore()
+			addAttachmentStub := mocka.Function(t, &addAttachment, int64(42))
+			defer addAttachmentStub.Restore()
+			getAttachmentStub := mocka.Function(t, &getAttachment, []*table.Attachment{saved})
+			defer getAttachmentStub.Restore()
+
+			result, err := AddAttachment(tx, attachment, "somebody", writable)
+
+			assert.Nil(t, err)
+			assert.Same(t, saved, result)
+			assert.Equal(t, []interface{}{tx, []int64{txID}}, getTransactionsStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, attachment, "somebody"}, addAttachmentStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, int64(42)}, getAttachmentStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("requires writable account", func(t *testing.T) {
+		readable := NewPermissions(false, map[int64]string{accountID: table.PermissionRead})
+
+		result, err := AddAttachment(nil, &table.Attachment{AccountID: &accountID}, "somebody", readable)
+
+		assert.Nil(t, result)
+		assert.Equal(t, apperror.AccountForbidden(1, "writable"), err)
+	})
+	t.Run("requires one owner", func(t *testing.T) {
+		_, err := AddAttachment(nil, &table.Attachment{AccountID: &accountID, TransactionID: &txID}, "somebody", writable)
+
+		assert.EqualError(t, err, "attachment requires either a transaction or an account")
+	})
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// Category is the type of a transaction detail.
+type Category struct {
+	source *categorySource
+	*table.Category
+}
+
+func (c *Category) Resolve(p graphql.ResolveParams) (interface{}, error) {
+	return defaultResolveFn(replaceSource(p, c.Category))
+}
+
+// GetParent returns the parent of the category or nil if it is a top level category.
+func (c *Category) GetParent(tx *database.Tx) *Category {
+	return c.source.get(tx, c.ParentID)
+}
+
+// GetChildren returns the categories that have this category as their parent.
+func (c *Category) GetChildren(tx *database.Tx) []*Category {
+	children, _ := c.source.children.get(tx, &c.ID, c.source.loadChildren).([]*Category)
+	return children
+}
+
+// GetDetails returns a page of the category's transaction details in the accounts. Details in all accounts are
+// returned if accountIDs is nil.
+func (c *Category) GetDetails(tx *database.Tx, filter database.PageFilter, accountIDs []int64) []*TransactionDetail {
+	details, _ := c.source.details.get(tx, c.ID, filter, func(tx *database.Tx, categoryIDs []int64) map[int64]interface{} {
+		byCategoryID := make(map[int64]interface{})
+		for _, detail := range newDetailSource(getDetailsByCategoryIDs(tx, categoryIDs, filter, accountIDs)) {
+			categoryDetails, _ := byCategoryID[*detail.TransactionCategoryID].([]*TransactionDetail)
+			byCategoryID[*detail.TransactionCategoryID] = append(categoryDetails, detail)
+		}
+		return byCategoryID
+	}).([]*TransactionDetail)
+	return details
+}
+
+// GetAmount returns the total of the category's transaction details in the accounts, converted to the currency.
+// Details in all accounts are included if accountIDs is nil. The load uses the same account IDs for every category in
+// the request. Returns a NotFoundError if the currency or an exchange rate doesn't exist.
+func (c *Category) GetAmount(tx *database.Tx, currencyID *int64, accountIDs []int64) (string, error) {
+	amounts, _ := c.source.amounts.get(tx, &c.ID, func(tx *database.Tx, categoryIDs []int64) map[int64]interface{} {
+		return groupAmounts(getCategoryAmounts(tx, categoryIDs, accountIDs))
+	}).(map[int64]float64)
+	return c.source.total(tx, amounts, currencyID)
+}
+
+// GetTransactionCount returns the number of transactions with details in the category in the accounts. Transactions
+// in all accounts are counted if accountIDs is nil. The load uses the same account IDs for every category in the
+// request.
+func (c *Category) GetTransactionCount(tx *database.Tx, accountIDs []int64) int64 {
+	count, _ := c.source.counts.get(tx, &c.ID, func(tx *database.Tx, categoryIDs []int64) map[int64]interface{} {
+		return groupCounts(getCategoryTransactionCounts(tx, categoryIDs, accountIDs))
+	}).(int64)
+	return count
+}
+
+// categorySource provides categories, their children, their details, their transaction counts and their amounts for
+// a GraphQL request.
+type categorySource struct {
+	byID     *batchLoader
+	children *batchLoader
+	details  *pagedLoader
+	counts   *batchLoader
+	amounts  *batchLoader
+	currencyConverter
+}
+
+func newCategorySource() *categorySource {
+	return &categorySource{byID: newBatchLoader(), children: newBatchLoader(), details: newPagedLoader(),
+		counts: newBatchLoader(), amounts: newBatchLoader()}
+}
+
+// setCategories wraps the categories and queues their parents and children to be loaded. The children don't need to
+// be loaded if dbCategories contains all of the categories.
+func (cs *categorySource) setCategories(dbCategories []*table.Category, all bool) []*Category {
+	categories := make([]*Category, len(dbCategories))
+	for i, category := range dbCategories {
+		categories[i] = &Category{source: cs, Category: category}
+		cs.byID.set(category.ID, categories[i])
+		cs.details.add(category.ID)
+		cs.counts.add(&category.ID)
+		cs.amounts.add(&category.ID)
+		if !all {
+			cs.children.add(&category.ID)
+		}
+	}
+	if all {
+		byParentID := make(map[int64][]*Category)
+		for _, category := range categories {
+			if category.ParentID != nil {
+				byParentID[*category.ParentID] = append(byParentID[*category.ParentID], category)
+			}
+		}
+		for _, category := range categories {
+			cs.children.set(category.ID, byParentID[category.ID])
+		}
+	}
+	for _, category := range categories {
+		cs.byID.add(category.ParentID)
+	}
+	return categories
+}
+
+// get returns the category with the ID, loading all pending categories if necessary.
+func (cs *categorySource) get(tx *database.Tx, id *int64) *Category {
+	category, _ := cs.byID.get(tx, id, func(tx *database.Tx, ids []int64) map[int64]interface{} {
+		byID := make(map[int64]interface{}, len(ids))
+		for _, category := range cs.setCategories(getCategoriesByIDs(tx, ids), false) {
+			byID[category.ID] = category
+		}
+		return byID
+	}).(*Category)
+	return category
+}
+
+func (cs *categorySource) loadChildren(tx *database.Tx, parentIDs []int64) map[int64]interface{} {
+	byParentID := make(map[int64]interface{}, len(parentIDs))
+	for _, category := range cs.setCategories(getCategoriesByParentIDs(tx, parentIDs), false) {
+		children, _ := byParentID[*category.ParentID].([]*Category)
+		byParentID[*category.ParentID] = append(children, category)
+	}
+	return byParentID
+}
+
+// GetAllCategories loads all transaction categories.
+func GetAllCategories(tx *database.Tx) []*Category {
+	return newCategorySource().setCategories(getAllCategories(tx), true)
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Category_Resolve(t *testing.T) {
+	defaultResolveStub := mocka.Function(t, &defaultResolveFn, "result", nil)
+	defer defaultResolveStub.Restore()
+	category := &Category{Category: &table.Category{ID: 42}}
+
+	result, err := category.Resolve(graphql.ResolveParams{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "result", result)
+	assert.Same(t, category.Category, defaultResolveStub.GetCall(0).Arguments()[0].(graphql.ResolveParams).Source)
+}
+
+func Test_GetAllCategories(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		parentID := int64(1)
+		getAllStub := mocka.Function(t, &getAllCategories, []*table.Category{
+			{ID: 1}, {ID: 2, ParentID: &parentID}, {ID: 3, ParentID: &parentID},
+		})
+		defer getAllStub.Restore()
+		getByIDsStub := mocka.Function(t, &getCategoriesByIDs, nil)
+		defer getByIDsStub.Restore()
+		getByParentIDsStub := mocka.Function(t, &getCategoriesByParentIDs, nil)
+		defer getByParentIDsStub.Restore()
+
+		categories := GetAllCategories(tx)
+
+		assert.Nil(t, categories[0].GetParent(tx))
+		assert.Same(t, categories[0], categories[1].GetParent(tx))
+		assert.Equal(t, []*Category{categories[1], categories[2]}, categories[0].GetChildren(tx))
+		assert.Nil(t, categories[1].GetChildren(tx))
+		assert.Equal(t, 0, getByIDsStub.CallCount())
+		assert.Equal(t, 0, getByParentIDsStub.CallCount())
+	})
+}
+
+func Test_Category_GetParent(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		parentIDs := []int64{1, 2}
+		getByIDsStub := mocka.Function(t, &getCategoriesByIDs, []*table.Category{{ID: 1}, {ID: 2}})
+		defer getByIDsStub.Restore()
+		source := newCategorySource()
+		categories := source.setCategories([]*table.Category{
+			{ID: 3, ParentID: &parentIDs[0]}, {ID: 4, ParentID: &parentIDs[1]},
+		}, false)
+
+		parent := categories[0].GetParent(tx)
+
+		assert.Equal(t, int64(1), parent.ID)
+		assert.Same(t, source, parent.source)
+		assert.Equal(t, int64(2), categories[1].GetParent(tx).ID)
+		assert.Equal(t, 1, getByIDsStub.CallCount())
+		assert.ElementsMatch(t, parentIDs, getByIDsStub.GetCall(0).Arguments()[1])
+	})
+}
+
+func Test_Category_GetChildren(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		parentIDs := []int64{1, 2}
+		getByParentIDsStub := mocka.Function(t, &getCategoriesByParentIDs, []*table.Category{
+			{ID: 3, ParentID: &parentIDs[0]}, {ID: 4, ParentID: &parentIDs[0]},
+		})
+		defer getByParentIDsStub.Restore()
+		source := newCategorySource()
+		categories := source.setCategories([]*table.Category{{ID: 1}, {ID: 2}}, false)
+
+		children := categories[0].GetChildren(tx)
+
+		assert.Len(t, children, 2)
+		assert.Equal(t, int64(3), children[0].ID)
+		assert.Equal(t, int64(4), children[1].ID)
+		assert.Same(t, categories[0], children[0].GetParent(tx))
+		assert.Nil(t, categories[1].GetChildren(tx))
+		assert.Equal(t, 1, getByParentIDsStub.CallCount())
+		assert.ElementsMatch(t, parentIDs, getByParentIDsStub.GetCall(0).Arguments()[1])
+	})
+}
+
+func Test_Category_GetDetails(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		categoryIDs := []int64{1, 2}
+		getAllStub := mocka.Function(t, &getAllCategories, []*table.Category{{ID: 1}, {ID: 2}})
+		defer getAllStub.Restore()
+		getDetailsStub := mocka.Function(t, &getDetailsByCategoryIDs, []*table.TransactionDetail{
+			{ID: 20, TransactionID: 10, TransactionCategoryID: &categoryIDs[0]},
+			{ID: 21, TransactionID: 10, TransactionCategoryID: &categoryIDs[1]},
+		})
+		defer getDetailsStub.Restore()
+		filter := database.PageFilter{Offset: 10}
+		accountIDs := []int64{42}
+		categories := GetAllCategories(tx)
+
+		details := categories[0].GetDetails(tx, filter, accountIDs)
+
+		assert.Len(t, details, 1)
+		assert.Equal(t, int64(20), details[0].ID)
+		assert.Equal(t, []int64{10}, details[0].txSource.txIDs)
+		assert.Equal(t, int64(21), categories[1].GetDetails(tx, filter, accountIDs)[0].ID)
+		assert.Equal(t, 1, getDetailsStub.CallCount())
+		args := getDetailsStub.GetCall(0).Arguments()
+		assert.ElementsMatch(t, categoryIDs, args[1])
+		assert.Equal(t, filter, args[2])
+		assert.Equal(t, accountIDs, args[3])
+	})
+}
+
+func Test_Category_GetAmount(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		getAllStub := mocka.Function(t, &getAllCategories, []*table.Category{{ID: 1}, {ID: 2}})
+		defer getAllStub.Restore()
+		getAmountsStub := mocka.Function(t, &getCategoryAmounts, []*table.CurrencyAmount{
+			{ID: 1, CurrencyID: 1, Amount: 12.34},
+			{ID: 2, CurrencyID: 2, Amount: 56.78},
+		})
+		defer getAmountsStub.Restore()
+		getBaseCurrencyStub := mocka.Function(t, &getBaseCurrencyID, nil)
+		defer getBaseCurrencyStub.Restore()
+		getRatesStub := mocka.Function(t, &getLatestExchangeRates, []*table.ExchangeRate{})
+		defer getRatesStub.Restore()
+		categories := GetAllCategories(tx)
+
+		amount1, err1 := categories[0].GetAmount(tx, nil, nil)
+		amount2, err2 := categories[1].GetAmount(tx, nil, nil)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, "12.34", amount1)
+		assert.Equal(t, "56.78", amount2)
+		assert.ElementsMatch(t, []int64{1, 2}, getAmountsStub.GetCall(0).Arguments()[1])
+		assert.Nil(t, getAmountsStub.GetCall(0).Arguments()[2])
+		assert.Equal(t, 1, getAmountsStub.CallCount())
+	})
+}
+
+func Test_Category_GetTransactionCount(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		getAllStub := mocka.Function(t, &getAllCategories, []*table.Category{{ID: 1}, {ID: 2}})
+		defer getAllStub.Restore()
+		getCountsStub := mocka.Function(t, &getCategoryTransactionCounts, []*table.TransactionCount{{ID: 1, Count: 3}})
+		defer getCountsStub.Restore()
+		accountIDs := []int64{42}
+		categories := GetAllCategories(tx)
+
+		count1 := categories[0].GetTransactionCount(tx, accountIDs)
+		count2 := categories[1].GetTransactionCount(tx, accountIDs)
+
+		assert.Equal(t, int64(3), count1)
+		assert.Equal(t, int64(0), count2)
+		assert.ElementsMatch(t, []int64{1, 2}, getCountsStub.GetCall(0).Arguments()[1])
+		assert.Equal(t, accountIDs, getCountsStub.GetCall(0).Arguments()[2])
+		assert.Equal(t, 1, getCountsStub.CallCount())
+	})
+}
@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"strconv"
+
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// entities that belong to the transactions of an account
+var transactionEntities = map[string]bool{"transaction": true, "transaction_detail": true, "attachment": true}
+
+// entities that are shared by all accounts
+var referenceEntities = map[string]bool{
+	"payee": true, "transaction_category": true, "transaction_group": true, "asset": true, "security": true,
+}
+
+// RowChange identifies a row that was inserted, updated or deleted by a request.
+type RowChange struct {
+	Entity  string
+	ID      int64
+	Version *int
+	Action  string
+	// accounts of a transaction, transaction detail or attachment, before and after the change
+	accountIDs *idSet
+	// transactions of a transaction detail or attachment
+	transactionIDs *idSet
+}
+
+// accounts returns the IDs of the accounts of the change. Returns nil if the change doesn't belong to an account.
+func (c *RowChange) accounts() map[int64]struct{} {
+	if c.accountIDs == nil {
+		return nil
+	}
+	return c.accountIDs.ids
+}
+
+func (c *RowChange) hasAccount(accountID int64) bool {
+	_, ok := c.accounts()[accountID]
+	return ok
+}
+
+// ChangeEvent contains the rows that were changed by a committed request.
+type ChangeEvent struct {
+	ChangeSetID string
+	User        string
+	Changes     []*RowChange
+}
+
+// NewChangeEvent returns the rows that have been changed by the transaction's change set. Multiple changes to a row are
+// combined into one with the last version. Returns nil if no rows have been changed.
+func NewChangeEvent(tx *database.Tx, changeSetID string, user string) *ChangeEvent {
+	changes := make([]*RowChange, 0)
+	byKey := make(map[string]*RowChange)
+	txAccountIDs := make(map[int64]*idSet)
+	for _, row := range getChanges(tx) {
+		id, err := strconv.ParseInt(row.EntityKey, 10, 64)
+		if err != nil {
+			continue
+		}
+		history := NewChangeHistory(row)
+		key := row.Entity + ":" + row.EntityKey
+		change, ok := byKey[key]
+		if !ok {
+			change = &RowChange{Entity: row.Entity, ID: id, Action: row.Action, accountIDs: newIDSet(), transactionIDs: newIDSet()}
+			byKey[key] = change
+			changes = append(changes, change)
+		} else if change.Action != table.HistoryInsert || row.Action == table.HistoryDelete {
+			change.Action = row.Action
+		}
+		change.Version = row.Version
+		switch row.Entity {
+		case "transaction":
+			history.addImageIDs(change.accountIDs, "account_id")
+			txAccountIDs[id] = change.accountIDs
+		case "attachment":
+			history.addImageIDs(change.accountIDs, "account_id")
+			history.addImageIDs(change.transactionIDs, "transaction_id")
+		case "transaction_detail":
+			history.addImageIDs(change.transactionIDs, "transaction_id")
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	addTransactionAccountIDs(tx, changes, txAccountIDs)
+	return &ChangeEvent{ChangeSetID: changeSetID, User: user, Changes: changes}
+}
+
+// addTransactionAccountIDs adds the accounts of the transactions of the details and attachments. txAccountIDs contains
+// the accounts of the transactions that were changed by the request. The other transactions are loaded.
+func addTransactionAccountIDs(tx *database.Tx, changes []*RowChange, txAccountIDs map[int64]*idSet) {
+	missingIDs := newIDSet()
+	for _, change := range changes {
+		for txID := range change.transactionIDs.ids {
+			if _, ok := txAccountIDs[txID]; !ok {
+				missingIDs.Add(txID)
+			}
+		}
+	}
+	if len(missingIDs.ids) > 0 {
+		for _, transaction := range getTransactionsByIDs(tx, missingIDs.Values()) {
+			accountIDs := newIDSet()
+			accountIDs.Add(transaction.AccountID)
+			txAccountIDs[transaction.ID] = accountIDs
+		}
+	}
+	for _, change := range changes {
+		for txID := range change.transactionIDs.ids {
+			if accountIDs, ok := txAccountIDs[txID]; ok {
+				for accountID := range accountIDs.ids {
+					change.accountIDs.Add(accountID)
+				}
+			}
+		}
+	}
+}
+
+// filter returns an event containing the changes that match the predicate. Returns nil if none of the changes match.
+func (e *ChangeEvent) filter(predicate func(*RowChange) bool) *ChangeEvent {
+	changes := make([]*RowChange, 0)
+	for _, change := range e.Changes {
+		if predicate(change) {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return &ChangeEvent{ChangeSetID: e.ChangeSetID, User: e.User, Changes: changes}
+}
+
+// ForAccount returns the changes to the transactions, transaction details and attachments of the account.
+func (e *ChangeEvent) ForAccount(accountID int64) *ChangeEvent {
+	return e.filter(func(change *RowChange) bool {
+		return transactionEntities[change.Entity] && change.hasAccount(accountID)
+	})
+}
+
+// ForAccounts returns the changes to companies and to the accounts that the user can view. Also includes the changes
+// to the transactions of the accounts, which change the account balances and transaction counts.
+func (e *ChangeEvent) ForAccounts(permissions *Permissions) *ChangeEvent {
+	return e.filter(func(change *RowChange) bool {
+		switch change.Entity {
+		case "company":
+			return true
+		case "account":
+			return permissions.CanRead(change.ID)
+		case "transaction":
+			for accountID := range change.accounts() {
+				if permissions.CanRead(accountID) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// ForReferenceData returns the changes to payees, categories, groups, assets and securities.
+func (e *ChangeEvent) ForReferenceData() *ChangeEvent {
+	return e.filter(func(change *RowChange) bool {
+		return referenceEntities[change.Entity]
+	})
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRowChange(entity string, id int64, accountIDs ...int64) *RowChange {
+	change := &RowChange{Entity: entity, ID: id, accountIDs: newIDSet(), transactionIDs: newIDSet()}
+	for _, accountID := range accountIDs {
+		change.accountIDs.Add(accountID)
+	}
+	return change
+}
+
+func Test_NewChangeEvent(t *testing.T) {
+	version1, version2 := 1, 2
+	txBefore, txAfter := `{"id":42,"account_id":1,"version":1}`, `{"id":42,"account_id":2,"version":2}`
+	detail1, detail2 := `{"id":11,"transaction_id":42}`, `{"id":12,"transaction_id":96}`
+	attachment := `{"id":5,"transaction_id":96,"account_id":3}`
+	t.Run("returns nil for no changes", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getChangesStub := mocka.Function(t, &getChanges, nil)
+			defer getChangesStub.Restore()
+
+			assert.Nil(t, NewChangeEvent(tx, "123:4", "somebody"))
+		})
+	})
+	t.Run("returns changed rows", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getChangesStub := mocka.Function(t, &getChanges, []*table.ChangeHistory{
+				{Entity: "payee", EntityKey: "7", Action: table.HistoryInsert, Version: &version1},
+				{Entity: "payee", EntityKey: "7", Action: table.HistoryUpdate, Version: &version2},
+				{Entity: "transaction", EntityKey: "42", Action: table.HistoryUpdate, Version: &version2, BeforeImage: &txBefore, AfterImage: &txAfter},
+				{Entity: "transaction_detail", EntityKey: "11", Action: table.HistoryDelete, BeforeImage: &detail1},
+				{Entity: "transaction_detail", EntityKey: "12", Action: table.HistoryUpdate, AfterImage: &detail2},
+				{Entity: "attachment", EntityKey: "5", Action: table.HistoryInsert, AfterImage: &attachment},
+				{Entity: "setting", EntityKey: "base_currency", Action: table.HistoryUpdate},
+			})
+			defer getChangesStub.Restore()
+			getTransactionsStub := mocka.Function(t, &getTransactionsByIDs, []*table.Transaction{{ID: 96, AccountID: 3}})
+			defer getTransactionsStub.Restore()
+
+			event := NewChangeEvent(tx, "123:4", "somebody")
+
+			assert.Equal(t, "123:4", event.ChangeSetID)
+			assert.Equal(t, "somebody", event.User)
+			assert.Len(t, event.Changes, 5)
+			assert.Equal(t, &RowChange{Entity: "payee", ID: 7, Version: &version2, Action: table.HistoryInsert,
+				accountIDs: newIDSet(), transactionIDs: newIDSet()}, event.Changes[0])
+			assert.ElementsMatch(t, []int64{1, 2}, event.Changes[1].accountIDs.Values())
+			assert.Equal(t, table.HistoryDelete, event.Changes[2].Action)
+			assert.ElementsMatch(t, []int64{1, 2}, event.Changes[2].accountIDs.Values())
+			assert.Equal(t, []int64{3}, event.Changes[3].accountIDs.Values())
+			assert.Equal(t, []int64{3}, event.Changes[4].accountIDs.Values())
+			assert.Equal(t, []interface{}{tx, []int64{96}}, getTransactionsStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("keeps delete after insert", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getChangesStub := mocka.Function(t, &getChanges, []*table.ChangeHistory{
+				{Entity: "payee", EntityKey: "7", Action: table.HistoryInsert, Version: &version1},
+				{Entity: "payee", EntityKey: "7", Action: table.HistoryDelete, Version: &version1},
+			})
+			defer getChangesStub.Restore()
+
+			event := NewChangeEvent(tx, "123:4", "somebody")
+
+			assert.Len(t, event.Changes, 1)
+			assert.Equal(t, table.HistoryDelete, event.Changes[0].Action)
+		})
+	})
+}
+
+func Test_ChangeEvent_ForAccount(t *testing.T) {
+	event := &ChangeEvent{ChangeSetID: "123:4", User: "somebody", Changes: []*RowChange{
+		newRowChange("transaction", 42, 1, 2),
+		newRowChange("transaction_detail", 11, 1),
+		newRowChange("attachment", 5, 3),
+		newRowChange("payee", 7),
+	}}
+
+	assert.Equal(t, &ChangeEvent{ChangeSetID: "123:4", User: "somebody", Changes: event.Changes[:2]}, event.ForAccount(1))
+	assert.Equal(t, &ChangeEvent{ChangeSetID: "123:4", User: "somebody", Changes: event.Changes[2:3]}, event.ForAccount(3))
+	assert.Nil(t, event.ForAccount(4))
+}
+
+func Test_ChangeEvent_ForAccounts(t *testing.T) {
+	event := &ChangeEvent{ChangeSetID: "123:4", User: "somebody", Changes: []*RowChange{
+		newRowChange("company", 3),
+		newRowChange("account", 1),
+		newRowChange("account", 2),
+		newRowChange("transaction", 42, 2),
+		newRowChange("transaction", 43, 1),
+		newRowChange("transaction_detail", 11, 1),
+	}}
+	permissions := NewPermissions(false, map[int64]string{1: table.PermissionRead})
+
+	assert.Equal(t, []*RowChange{event.Changes[0], event.Changes[1], event.Changes[4]}, event.ForAccounts(permissions).Changes)
+	assert.Equal(t, event.Changes[:5], event.ForAccounts(NewPermissions(true, nil)).Changes)
+	assert.Nil(t, (&ChangeEvent{Changes: event.Changes[2:4]}).ForAccounts(permissions))
+}
+
+func Test_ChangeEvent_ForReferenceData(t *testing.T) {
+	event := &ChangeEvent{ChangeSetID: "123:4", User: "somebody", Changes: []*RowChange{
+		newRowChange("payee", 7),
+		newRowChange("transaction", 42, 1),
+		newRowChange("transaction_category", 3),
+		newRowChange("transaction_group", 4),
+		newRowChange("security", 5),
+		newRowChange("asset", 6),
+	}}
+
+	result := event.ForReferenceData()
+
+	assert.Equal(t, []*RowChange{event.Changes[0], event.Changes[2], event.Changes[3], event.Changes[4], event.Changes[5]}, result.Changes)
+	assert.Nil(t, (&ChangeEvent{Changes: event.Changes[1:2]}).ForReferenceData())
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+)
+
+// UndoChangeSet restores the rows changed by a mutation request to their previous state. Returns a VersionConflictError if any of the rows have
+// been changed since, a ForbiddenError if the user can't change the rows or a ValidationError if the rows of a table
+// can't be restored from the history, e.g. API tokens.
+func UndoChangeSet(tx *database.Tx, id string, user string, permissions *Permissions) ([]*ChangeHistory, error) {
+	changeSets := getChangeSet(tx, id)
+	if len(changeSets) == 0 {
+		return nil, apperror.NotFound("change_set", id)
+	}
+	if !permissions.IsAdmin() && changeSets[0].ChangeUser != user {
+		return nil, apperror.Forbidden("change set belongs to another user")
+	}
+	rows := getChangeSetHistory(tx, id)
+	history := make([]*ChangeHistory, len(rows))
+	byEntity := make(map[string][]*ChangeHistory)
+	for i, row := range rows {
+		if !database.IsUndoable(row.Entity) {
+			return nil, apperror.Validation("changeSetId", fmt.Sprintf("%s changes can't be undone", row.Entity))
+		}
+		history[i] = NewChangeHistory(row)
+		byEntity[row.Entity] = append(byEntity[row.Entity], history[i])
+	}
+	for tableName, tableHistory := range byEntity {
+		if err := permissions.RequireHistoryWrite(tx, tableName, tableHistory); err != nil {
+			return nil, err
+		}
+	}
+	// rows are most recent first, so only the first change to each row needs to be current
+	checked := make(map[string]bool)
+	for _, row := range rows {
+		if key := row.Entity + ":" + row.EntityKey; !checked[key] {
+			if !isCurrentVersion(tx, row) {
+				return nil, apperror.VersionConflictf(row.Entity, row.EntityKey,
+					"%s %s has been changed since change set %s", row.Entity, row.EntityKey, id)
+			}
+			checked[key] = true
+		}
+	}
+	for _, row := range rows {
+		undoChange(tx, row, user)
+	}
+	return history, nil
+}
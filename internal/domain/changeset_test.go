@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+type undoStubs struct {
+	getChangeSet        *mocka.Stub
+	getChangeSetHistory *mocka.Stub
+	isCurrentVersion    *mocka.Stub
+	undoChange          *mocka.Stub
+}
+
+func mockUndo(t *testing.T, changeSets []*table.ChangeSet, history []*table.ChangeHistory, current bool) *undoStubs {
+	return &undoStubs{
+		getChangeSet:        mocka.Function(t, &getChangeSet, changeSets),
+		getChangeSetHistory: mocka.Function(t, &getChangeSetHistory, history),
+		isCurrentVersion:    mocka.Function(t, &isCurrentVersion, current),
+		undoChange:          mocka.Function(t, &undoChange),
+	}
+}
+
+func (s *undoStubs) restore() {
+	s.getChangeSet.Restore()
+	s.getChangeSetHistory.Restore()
+	s.isCurrentVersion.Restore()
+	s.undoChange.Restore()
+}
+
+func Test_UndoChangeSet(t *testing.T) {
+	image := `{"id":42,"account_id":1,"version":1}`
+	changeSets := []*table.ChangeSet{{ID: "123:4", Audited: table.Audited{ChangeUser: "somebody"}}}
+	history := []*table.ChangeHistory{
+		{ID: 3, Entity: "transaction", EntityKey: "42", Action: table.HistoryUpdate, AfterImage: &image},
+		{ID: 2, Entity: "payee", EntityKey: "96", Action: table.HistoryInsert, AfterImage: &image},
+		{ID: 1, Entity: "transaction", EntityKey: "42", Action: table.HistoryInsert, AfterImage: &image},
+	}
+	writable := NewPermissions(false, map[int64]string{1: table.PermissionWrite})
+	t.Run("undoes changes", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			stubs := mockUndo(t, changeSets, history, true)
+			defer stubs.restore()
+
+			result, err := UndoChangeSet(tx, "123:4", "somebody", writable)
+
+			assert.Nil(t, err)
+			assert.Len(t, result, 3)
+			assert.Same(t, history[0], result[0].ChangeHistory)
+			assert.Equal(t, []interface{}{tx, "123:4"}, stubs.getChangeSet.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, "123:4"}, stubs.getChangeSetHistory.GetCall(0).Arguments())
+			assert.Equal(t, 2, stubs.isCurrentVersion.CallCount())
+			assert.Equal(t, []interface{}{tx, history[0]}, stubs.isCurrentVersion.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, history[1]}, stubs.isCurrentVersion.GetCall(1).Arguments())
+			assert.Equal(t, 3, stubs.undoChange.CallCount())
+			for i, row := range history {
+				assert.Equal(t, []interface{}{tx, row, "somebody"}, stubs.undoChange.GetCall(i).Arguments())
+			}
+		})
+	})
+	t.Run("allows admin to undo changes of other users", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			stubs := mockUndo(t, changeSets, history, true)
+			defer stubs.restore()
+
+			UndoChangeSet(tx, "123:4", "admin", NewPermissions(true, nil))
+
+			assert.Equal(t, 3, stubs.undoChange.CallCount())
+		})
+	})
+	errorTests := []struct {
+		name       string
+		changeSets []*table.ChangeSet
+		current    bool
+		message    string
+	}{
+		{"returns error for unknown change set", []*table.ChangeSet{}, true, "change set not found (123:4)"},
+		{"returns error for changed row", changeSets, false, "transaction 42 has been changed since change set 123:4"},
+	}
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+				stubs := mockUndo(t, test.changeSets, history, test.current)
+				defer stubs.restore()
+
+				result, err := UndoChangeSet(tx, "123:4", "somebody", writable)
+
+				assert.Nil(t, result)
+				assert.EqualError(t, err, test.message)
+				assert.Equal(t, 0, stubs.undoChange.CallCount())
+			})
+		})
+	}
+	forbiddenTests := []struct {
+		name        string
+		user        string
+		permissions *Permissions
+		err         error
+	}{
+		{"returns error for change set of another user", "other", writable, apperror.Forbidden("change set belongs to another user")},
+		{"returns error if account not writable", "somebody", NewPermissions(false, map[int64]string{1: table.PermissionRead}),
+			apperror.AccountForbidden(1, "writable")},
+	}
+	for _, test := range forbiddenTests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+				stubs := mockUndo(t, changeSets, history, true)
+				defer stubs.restore()
+
+				result, err := UndoChangeSet(tx, "123:4", test.user, test.permissions)
+
+				assert.Nil(t, result)
+				assert.Equal(t, test.err, err)
+				assert.Equal(t, 0, stubs.undoChange.CallCount())
+			})
+		})
+	}
+	t.Run("returns error for API token changes", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			tokenImage := `{"id":7,"user_id":42,"name":"laptop"}`
+			tokenHistory := []*table.ChangeHistory{
+				{ID: 4, Entity: "api_token", EntityKey: "7", Action: table.HistoryDelete, BeforeImage: &tokenImage},
+			}
+			stubs := mockUndo(t, changeSets, tokenHistory, true)
+			defer stubs.restore()
+
+			result, err := UndoChangeSet(tx, "123:4", "admin", NewPermissions(true, nil))
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.Validation("changeSetId", "api_token changes can't be undone"), err)
+			assert.Equal(t, 0, stubs.isCurrentVersion.CallCount())
+			assert.Equal(t, 0, stubs.undoChange.CallCount())
+		})
+	})
+}
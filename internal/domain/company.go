@@ -1,9 +1,8 @@
 package domain
 
 import (
-	"database/sql"
-
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
@@ -22,7 +21,7 @@ func (c *Company) Resolve(p graphql.ResolveParams) (interface{}, error) {
 }
 
 // GetAccounts returns the accounts for the company.
-func (c *Company) GetAccounts(tx *sql.Tx) []*Account {
+func (c *Company) GetAccounts(tx *database.Tx) []*Account {
 	if c.source.accounts == nil {
 		c.source.loadAccounts(tx)
 	}
@@ -36,46 +35,52 @@ func (c *Company) GetAccounts(tx *sql.Tx) []*Account {
 }
 
 // GetAllCompanies loads all companies.
-func GetAllCompanies(tx *sql.Tx) []*Company {
+func GetAllCompanies(tx *database.Tx) []*Company {
 	return newCompanySource().setCompanies(getAllCompanies(tx))
 }
 
 // GetCompanyByID returns the company with the ID.
-func GetCompanyByID(tx *sql.Tx, id int64) []*Company {
+func GetCompanyByID(tx *database.Tx, id int64) []*Company {
 	return newCompanySource().setCompanies(getCompanyByID(tx, id))
 }
 
 // GetCompanyByName returns the company with the name.
-func GetCompanyByName(tx *sql.Tx, name string) []*Company {
+func GetCompanyByName(tx *database.Tx, name string) []*Company {
 	return newCompanySource().setCompanies(getCompanyByName(tx, name))
 }
 
 // GetCompaniesByIDs loads specified companies.
-var GetCompaniesByIDs = func(tx *sql.Tx, ids []int64) []*Company {
+var GetCompaniesByIDs = func(tx *database.Tx, ids []int64) []*Company {
 	return newCompanySource().setCompanies(getCompaniesByIDs(tx, ids))
 }
 
 // AddCompanies adds new companies.
-func AddCompanies(tx *sql.Tx, names []string, user string) []*Company {
+func AddCompanies(tx *database.Tx, names []string, user string) ([]*Company, error) {
 	companies := make([]*table.Company, len(names))
 	for i, name := range names {
-		validateName(name)
+		if err := validateName(name); err != nil {
+			return nil, err
+		}
 		companies[i] = addCompany(tx, name, user)
 	}
-	return newCompanySource().setCompanies(companies)
+	return newCompanySource().setCompanies(companies), nil
 }
 
 // UpdateCompanies updates company names.
-func UpdateCompanies(tx *sql.Tx, args interface{}, user string) []*Company {
+func UpdateCompanies(tx *database.Tx, args interface{}, user string) ([]*Company, error) {
 	updates := args.([]interface{})
 	ids := make([]int64, len(updates))
 	for i, company := range updates {
 		values := company.(map[string]interface{})
 		ids[i] = int64(values["id"].(int))
 		name := values["name"].(string)
-		validateName(name)
+		if err := validateName(name); err != nil {
+			return nil, err
+		}
 		version := int64(values["version"].(int))
-		updateCompany(tx, ids[i], version, name, user)
+		if err := updateCompany(tx, ids[i], version, name, user); err != nil {
+			return nil, err
+		}
 	}
-	return GetCompaniesByIDs(tx, ids)
+	return GetCompaniesByIDs(tx, ids), nil
 }
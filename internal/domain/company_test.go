@@ -1,13 +1,14 @@
 package domain
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,7 +38,7 @@ func Test_Company_GetAccounts(t *testing.T) {
 }
 
 func Test_GetAllCompanies(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		companies := []*table.Company{{ID: 1}}
 		getAllCompaniesStub := mocka.Function(t, &getAllCompanies, companies)
 		defer getAllCompaniesStub.Restore()
@@ -52,7 +53,7 @@ func Test_GetAllCompanies(t *testing.T) {
 
 func Test_GetCompanyByID(t *testing.T) {
 	id := int64(42)
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		companies := []*table.Company{{ID: 1}}
 		getCompanyByIDStub := mocka.Function(t, &getCompanyByID, companies)
 		defer getCompanyByIDStub.Restore()
@@ -67,7 +68,7 @@ func Test_GetCompanyByID(t *testing.T) {
 
 func Test_GetCompanyByName(t *testing.T) {
 	name := "the company"
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		companies := []*table.Company{{ID: 1}}
 		getCompanyByNameStub := mocka.Function(t, &getCompanyByName, companies)
 		defer getCompanyByNameStub.Restore()
@@ -82,7 +83,7 @@ func Test_GetCompanyByName(t *testing.T) {
 
 func Test_GetCompaniesByIDs(t *testing.T) {
 	ids := []int64{42, 96}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		companies := []*table.Company{{ID: 42}}
 		getCompaniesByIDsStub := mocka.Function(t, &getCompaniesByIDs, companies)
 		defer getCompaniesByIDsStub.Restore()
@@ -97,17 +98,18 @@ func Test_GetCompaniesByIDs(t *testing.T) {
 
 func Test_AddCompanies(t *testing.T) {
 	id := int64(42)
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		company1 := &table.Company{ID: id}
 		company2 := &table.Company{ID: id + 1}
 		addCompanyStub := mocka.Function(t, &addCompany, company1)
 		addCompanyStub.OnCall(1).Return(company2)
 		defer addCompanyStub.Restore()
-		validateNameStub := mocka.Function(t, &validateName)
+		validateNameStub := mocka.Function(t, &validateName, nil)
 		defer validateNameStub.Restore()
 
-		results := AddCompanies(tx, []string{"company1", "company2"}, "somebody")
+		results, err := AddCompanies(tx, []string{"company1", "company2"}, "somebody")
 
+		assert.Nil(t, err)
 		assert.Equal(t, 2, validateNameStub.CallCount())
 		assert.Equal(t, []interface{}{"company1"}, validateNameStub.GetCall(0).Arguments())
 		assert.Equal(t, []interface{}{"company2"}, validateNameStub.GetCall(1).Arguments())
@@ -123,17 +125,18 @@ func Test_UpdateCompanies(t *testing.T) {
 		map[string]interface{}{"id": 42, "name": "rename 42", "version": 1},
 		map[string]interface{}{"id": 96, "name": "rename 96", "version": 1},
 	}
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 		companies := []*Company{{Company: &table.Company{ID: 42, Name: "new name"}}}
-		updateCompanyStub := mocka.Function(t, &updateCompany)
+		updateCompanyStub := mocka.Function(t, &updateCompany, nil)
 		defer updateCompanyStub.Restore()
 		getCompaniesStub := mocka.Function(t, &GetCompaniesByIDs, companies)
 		defer getCompaniesStub.Restore()
-		validateNameStub := mocka.Function(t, &validateName)
+		validateNameStub := mocka.Function(t, &validateName, nil)
 		defer validateNameStub.Restore()
 
-		result := UpdateCompanies(tx, updates, "somebody")
+		result, err := UpdateCompanies(tx, updates, "somebody")
 
+		assert.Nil(t, err)
 		assert.Equal(t, companies, result)
 		assert.Equal(t, 2, updateCompanyStub.CallCount())
 		assert.Equal(t, []interface{}{tx, int64(42), int64(1), "rename 42", "somebody"}, updateCompanyStub.GetCall(0).Arguments())
@@ -144,3 +147,29 @@ func Test_UpdateCompanies(t *testing.T) {
 		assert.Equal(t, []interface{}{"rename 96"}, validateNameStub.GetCall(1).Arguments())
 	})
 }
+
+func Test_UpdateCompanies_returnsError(t *testing.T) {
+	updates := []interface{}{map[string]interface{}{"id": 42, "name": "rename 42", "version": 1}}
+	t.Run("for invalid name", func(t *testing.T) {
+		validateNameStub := mocka.Function(t, &validateName, apperror.Validation("name", "name must not be empty"))
+		defer validateNameStub.Restore()
+
+		result, err := UpdateCompanies(nil, updates, "somebody")
+
+		assert.Nil(t, result)
+		assert.EqualError(t, err, "name must not be empty")
+	})
+	t.Run("for version conflict", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			updateCompanyStub := mocka.Function(t, &updateCompany, apperror.VersionConflict("company", 42, 1))
+			defer updateCompanyStub.Restore()
+			validateNameStub := mocka.Function(t, &validateName, nil)
+			defer validateNameStub.Restore()
+
+			result, err := UpdateCompanies(tx, updates, "somebody")
+
+			assert.Nil(t, result)
+			assert.EqualError(t, err, "company not found (42 @ 1)")
+		})
+	})
+}
@@ -1,8 +1,7 @@
 package domain
 
 import (
-	"database/sql"
-
+	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
@@ -11,6 +10,8 @@ type companySource struct {
 	accounts      []*Account
 	companyIDs    []int64
 	companiesByID map[int64]*Company
+	attachments   map[int64][]*table.Attachment
+	currencyConverter
 }
 
 func newCompanySource() *companySource {
@@ -45,7 +46,7 @@ func (cs *companySource) setCompanies(dbCompanies []*table.Company) []*Company {
 	return companies
 }
 
-func (cs *companySource) loadCompanies(tx *sql.Tx) {
+func (cs *companySource) loadCompanies(tx *database.Tx) {
 	if cs.companiesByID == nil {
 		companies := GetCompaniesByIDs(tx, cs.companyIDs)
 		cs.companiesByID = make(map[int64]*Company, len(companies))
@@ -56,7 +57,7 @@ func (cs *companySource) loadCompanies(tx *sql.Tx) {
 	}
 }
 
-func (cs *companySource) loadAccounts(tx *sql.Tx) {
+func (cs *companySource) loadAccounts(tx *database.Tx) {
 	if cs.accounts == nil {
 		accounts := GetAccountsByCompanyIDs(tx, cs.companyIDs)
 		cs.accounts = accounts
@@ -65,3 +66,12 @@ func (cs *companySource) loadAccounts(tx *sql.Tx) {
 		}
 	}
 }
+
+func (cs *companySource) loadAttachments(tx *database.Tx) {
+	if cs.attachments == nil {
+		cs.attachments = make(map[int64][]*table.Attachment)
+		for _, attachment := range getAccountAttachments(tx) {
+			cs.attachments[*attachment.AccountID] = append(cs.attachments[*attachment.AccountID], attachment)
+		}
+	}
+}
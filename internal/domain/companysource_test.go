@@ -1,11 +1,12 @@
 package domain
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
 	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
@@ -69,9 +70,9 @@ func Test_companySource_setCompanies(t *testing.T) {
 }
 
 func Test_companySource_loadCompanies(t *testing.T) {
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 		cs := &companySource{companyIDs: []int64{10, 20}}
-		mockDB.ExpectQuery("select * from company where json_contains(?, cast(id as json))").
+		mockDB.ExpectQuery("select * from company where json_contains(?, json_array(id))").
 			WithArgs("[10,20]").
 			WillReturnRows(sqltest.MockRows("id").AddRow(cs.companyIDs[0]).AddRow(cs.companyIDs[1]))
 
@@ -87,7 +88,7 @@ func Test_companySource_loadCompanies(t *testing.T) {
 }
 
 func Test_companySource_loadAccounts(t *testing.T) {
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 		accountIDs := []int64{1, 2}
 		dbAccounts := []*table.Account{{ID: 1}, {ID: 2}}
 		getAccountsStub := mocka.Function(t, &getAccountsByCompanyIDs, dbAccounts)
@@ -104,3 +105,52 @@ func Test_companySource_loadAccounts(t *testing.T) {
 		}
 	})
 }
+
+func Test_companySource_loadCurrencies(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		currencies := []*table.Currency{{Code: "USD", Asset: table.Asset{ID: 1}}, {Code: "EUR", Asset: table.Asset{ID: 2}}}
+		getCurrenciesStub := mocka.Function(t, &getAllCurrencies, currencies)
+		defer getCurrenciesStub.Restore()
+		cs := &companySource{}
+
+		cs.loadCurrencies(tx)
+		cs.loadCurrencies(tx)
+
+		assert.Equal(t, 1, getCurrenciesStub.CallCount())
+		assert.Equal(t, map[int64]*table.Currency{1: currencies[0], 2: currencies[1]}, cs.currencyByID)
+	})
+}
+
+func Test_companySource_loadExchangeRates(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		baseID := int64(1)
+		rates := []*table.ExchangeRate{{FromCurrencyID: 2, ToCurrencyID: 1, Rate: 1.5}}
+		getBaseStub := mocka.Function(t, &getBaseCurrencyID, &baseID)
+		defer getBaseStub.Restore()
+		getRatesStub := mocka.Function(t, &getLatestExchangeRates, rates)
+		defer getRatesStub.Restore()
+		cs := &companySource{}
+
+		cs.loadExchangeRates(tx)
+		cs.loadExchangeRates(tx)
+
+		assert.Equal(t, 1, getRatesStub.CallCount())
+		assert.Equal(t, newExchangeRates(&baseID, rates), cs.rates)
+	})
+}
+
+func Test_companySource_loadAttachments(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		accountID := int64(1)
+		attachments := []*table.Attachment{{ID: 1, AccountID: &accountID}, {ID: 2, AccountID: &accountID}}
+		getAttachmentsStub := mocka.Function(t, &getAccountAttachments, attachments)
+		defer getAttachmentsStub.Restore()
+		cs := &companySource{}
+
+		cs.loadAttachments(tx)
+		cs.loadAttachments(tx)
+
+		assert.Equal(t, 1, getAttachmentsStub.CallCount())
+		assert.Equal(t, map[int64][]*table.Attachment{1: attachments}, cs.attachments)
+	})
+}
@@ -0,0 +1,210 @@
+package domain
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+type currencyPair struct {
+	from int64
+	to   int64
+}
+
+// exchangeRates converts amounts between currencies using the latest rates.
+type exchangeRates struct {
+	baseCurrencyID *int64
+	rates          map[currencyPair]float64
+}
+
+func newExchangeRates(baseCurrencyID *int64, dbRates []*table.ExchangeRate) *exchangeRates {
+	rates := make(map[currencyPair]float64, len(dbRates))
+	for _, rate := range dbRates {
+		rates[currencyPair{rate.FromCurrencyID, rate.ToCurrencyID}] = rate.Rate
+	}
+	return &exchangeRates{baseCurrencyID: baseCurrencyID, rates: rates}
+}
+
+func (er *exchangeRates) directRate(from int64, to int64) (float64, bool) {
+	if from == to {
+		return 1, true
+	}
+	if rate, ok := er.rates[currencyPair{from, to}]; ok {
+		return rate, true
+	}
+	if rate, ok := er.rates[currencyPair{to, from}]; ok && rate != 0 {
+		return 1 / rate, true
+	}
+	return 0, false
+}
+
+// Rate returns the rate for converting from one currency to another. If there is no rate for
+// the pair of currencies, then the conversion is done through the base currency.
+func (er *exchangeRates) Rate(from int64, to int64) (float64, bool) {
+	if rate, ok := er.directRate(from, to); ok {
+		return rate, true
+	}
+	if er.baseCurrencyID != nil {
+		if toBase, ok := er.directRate(from, *er.baseCurrencyID); ok {
+			if fromBase, ok := er.directRate(*er.baseCurrencyID, to); ok {
+				return toBase * fromBase, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// rateNotFound returns the error for a missing exchange rate.
+func rateNotFound(from int64, to int64) error {
+	return apperror.NotFoundf("exchange_rate", "no exchange rate from %d to %d", from, to)
+}
+
+// Convert converts an amount from one currency to another and formats it using the scale of the target currency.
+// Returns a NotFoundError if there is no exchange rate for the currencies.
+func (er *exchangeRates) Convert(amount string, from int64, to *table.Currency) (string, error) {
+	rate, ok := er.Rate(from, to.ID)
+	if !ok {
+		return "", rateNotFound(from, to.ID)
+	}
+	value, _ := strconv.ParseFloat(amount, 64)
+	return formatAmount(value*rate, to), nil
+}
+
+// formatAmount rounds a converted amount to the scale of the currency and formats it with that many decimal places,
+// e.g. 198.00 for a currency with a scale of 2. The amount is formatted without trailing zeros if the currency is nil.
+func formatAmount(amount float64, currency *table.Currency) string {
+	if currency == nil {
+		return strconv.FormatFloat(amount, 'f', -1, 64)
+	}
+	scale := math.Pow10(currency.Scale)
+	return strconv.FormatFloat(math.Round(amount*scale)/scale, 'f', currency.Scale, 64)
+}
+
+// currencyConverter converts the amounts of a GraphQL request using the latest exchange rates.
+type currencyConverter struct {
+	currencyByID map[int64]*table.Currency
+	rates        *exchangeRates
+}
+
+func (cc *currencyConverter) loadCurrencies(tx *database.Tx) {
+	if cc.currencyByID == nil {
+		currencies := getAllCurrencies(tx)
+		cc.currencyByID = make(map[int64]*table.Currency, len(currencies))
+		for _, currency := range currencies {
+			cc.currencyByID[currency.ID] = currency
+		}
+	}
+}
+
+func (cc *currencyConverter) loadExchangeRates(tx *database.Tx) {
+	if cc.rates == nil {
+		cc.rates = newExchangeRates(getBaseCurrencyID(tx), getLatestExchangeRates(tx, time.Now()))
+	}
+}
+
+// total returns the sum of the amounts (keyed by currency ID) converted to the currency and formatted using its
+// scale. The currency defaults to the base currency. The amounts are added without converting them if the currency is
+// nil and the base currency is not set. Returns a NotFoundError if the currency or an exchange rate doesn't exist.
+func (cc *currencyConverter) total(tx *database.Tx, amounts map[int64]float64, currencyID *int64) (string, error) {
+	cc.loadExchangeRates(tx)
+	if currencyID == nil {
+		currencyID = cc.rates.baseCurrencyID
+	}
+	total := 0.0
+	if currencyID == nil {
+		for _, amount := range amounts {
+			total += amount
+		}
+		return formatAmount(total, nil), nil
+	}
+	cc.loadCurrencies(tx)
+	currency, ok := cc.currencyByID[*currencyID]
+	if !ok {
+		return "", apperror.NotFound("currency", *currencyID)
+	}
+	for from, amount := range amounts {
+		rate, ok := cc.rates.Rate(from, currency.ID)
+		if !ok {
+			return "", rateNotFound(from, currency.ID)
+		}
+		total += amount * rate
+	}
+	return formatAmount(total, currency), nil
+}
+
+// groupAmounts returns the amounts keyed by currency ID for each parent ID.
+func groupAmounts(rows []*table.CurrencyAmount) map[int64]interface{} {
+	byID := make(map[int64]interface{})
+	for _, row := range rows {
+		amounts, ok := byID[row.ID].(map[int64]float64)
+		if !ok {
+			amounts = make(map[int64]float64)
+			byID[row.ID] = amounts
+		}
+		amounts[row.CurrencyID] += row.Amount
+	}
+	return byID
+}
+
+// GetBaseCurrency returns the base currency or nil if it has not been set.
+func GetBaseCurrency(tx *database.Tx) *table.Currency {
+	if id := getBaseCurrencyID(tx); id != nil {
+		if currencies := getCurrencyByID(tx, *id); len(currencies) > 0 {
+			return currencies[0]
+		}
+	}
+	return nil
+}
+
+// SetBaseCurrency sets the base currency and returns it.
+func SetBaseCurrency(tx *database.Tx, currencyID int64, user string) (*table.Currency, error) {
+	currencies := getCurrencyByID(tx, currencyID)
+	if len(currencies) == 0 {
+		return nil, apperror.NotFound("currency", currencyID)
+	}
+	setBaseCurrencyID(tx, currencyID, user)
+	return currencies[0], nil
+}
+
+func validateExchangeRate(values database.InputObject) error {
+	if rate, ok := values.GetFloat("rate"); ok && (rate == nil || rate.(float64) <= 0) {
+		return apperror.Validation("rate", "exchange rate must be positive")
+	}
+	return nil
+}
+
+// AddExchangeRates adds exchange rates and returns their IDs.
+func AddExchangeRates(tx *database.Tx, adds []map[string]interface{}, user string) ([]int64, error) {
+	ids := make([]int64, len(adds))
+	for i, add := range adds {
+		values := database.InputObject(add)
+		if err := validateExchangeRate(values); err != nil {
+			return nil, err
+		}
+		if values.RequireInt("fromCurrencyId") == values.RequireInt("toCurrencyId") {
+			return nil, apperror.Validation("toCurrencyId", "exchange rate requires different currencies")
+		}
+		ids[i] = addExchangeRate(tx, values, user)
+	}
+	return ids, nil
+}
+
+// UpdateExchangeRates updates exchange rates and returns their IDs.
+func UpdateExchangeRates(tx *database.Tx, updates []map[string]interface{}, user string) ([]int64, error) {
+	ids := make([]int64, len(updates))
+	for i, update := range updates {
+		values := database.InputObject(update)
+		if err := validateExchangeRate(values); err != nil {
+			return nil, err
+		}
+		ids[i] = values.RequireInt("id")
+		if err := updateExchangeRate(tx, ids[i], values.RequireInt("version"), values, user); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
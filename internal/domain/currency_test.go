@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_exchangeRates_Rate(t *testing.T) {
+	baseID := int64(1)
+	dbRates := []*table.ExchangeRate{
+		{FromCurrencyID: 2, ToCurrencyID: 1, Rate: 1.25},
+		{FromCurrencyID: 1, ToCurrencyID: 3, Rate: 100},
+	}
+	tests := []struct {
+		name   string
+		base   *int64
+		from   int64
+		to     int64
+		rate   float64
+		exists bool
+	}{
+		{"returns 1 for same currency", nil, 4, 4, 1, true},
+		{"returns direct rate", nil, 2, 1, 1.25, true},
+		{"returns inverse rate", nil, 1, 2, 0.8, true},
+		{"returns rate through base currency", &baseID, 2, 3, 125, true},
+		{"returns false without base currency", nil, 2, 3, 0, false},
+		{"returns false for unknown currency", &baseID, 2, 4, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rates := newExchangeRates(test.base, dbRates)
+
+			rate, ok := rates.Rate(test.from, test.to)
+
+			assert.Equal(t, test.exists, ok)
+			assert.InDelta(t, test.rate, rate, 0.0000001)
+		})
+	}
+}
+
+func Test_exchangeRates_Convert(t *testing.T) {
+	rates := newExchangeRates(nil, []*table.ExchangeRate{{FromCurrencyID: 2, ToCurrencyID: 1, Rate: 110}})
+	yen := &table.Currency{Code: "JPY", Asset: table.Asset{ID: 1, Scale: 0}}
+	t.Run("uses scale of currency", func(t *testing.T) {
+		result, err := rates.Convert("12.345", 2, yen)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "1358", result)
+	})
+	t.Run("returns error for missing rate", func(t *testing.T) {
+		result, err := rates.Convert("1", 3, yen)
+
+		assert.Equal(t, "", result)
+		assert.Equal(t, apperror.NotFoundf("exchange_rate", "no exchange rate from 3 to 1"), err)
+	})
+}
+
+func Test_formatAmount(t *testing.T) {
+	dollar := &table.Currency{Code: "USD", Asset: table.Asset{ID: 1, Scale: 2}}
+	tests := []struct {
+		amount   float64
+		currency *table.Currency
+		expected string
+	}{
+		{198, dollar, "198.00"},
+		{40.125, dollar, "40.13"},
+		{-0.5, &table.Currency{Asset: table.Asset{Scale: 0}}, "-1"},
+		{40.5, nil, "40.5"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, formatAmount(test.amount, test.currency))
+	}
+}
+
+func Test_currencyConverter_total(t *testing.T) {
+	usdID, eurID := int64(1), int64(2)
+	amounts := map[int64]float64{usdID: 10, eurID: 5.555}
+	currencies := map[int64]*table.Currency{
+		usdID: {Code: "USD", Asset: table.Asset{ID: usdID, Scale: 2}},
+		eurID: {Code: "EUR", Asset: table.Asset{ID: eurID, Scale: 2}},
+	}
+	rates := []*table.ExchangeRate{{FromCurrencyID: eurID, ToCurrencyID: usdID, Rate: 2}}
+	tests := []struct {
+		name       string
+		baseID     *int64
+		currencyID *int64
+		total      string
+	}{
+		{"converts to currency", nil, &usdID, "21.11"},
+		{"converts to base currency", &eurID, nil, "10.56"},
+		{"adds amounts without base currency", nil, nil, "15.555"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			converter := &currencyConverter{currencyByID: currencies, rates: newExchangeRates(test.baseID, rates)}
+
+			total, err := converter.total(nil, amounts, test.currencyID)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.total, total)
+		})
+	}
+	t.Run("returns error for unknown currency", func(t *testing.T) {
+		otherID := int64(3)
+		converter := &currencyConverter{currencyByID: currencies, rates: newExchangeRates(nil, rates)}
+
+		_, err := converter.total(nil, amounts, &otherID)
+
+		assert.Equal(t, apperror.NotFound("currency", otherID), err)
+	})
+	t.Run("returns error for missing rate", func(t *testing.T) {
+		otherID := int64(3)
+		currencies := map[int64]*table.Currency{otherID: {Asset: table.Asset{ID: otherID}}}
+		converter := &currencyConverter{currencyByID: currencies, rates: newExchangeRates(nil, rates)}
+
+		_, err := converter.total(nil, amounts, &otherID)
+
+		assert.Regexp(t, "no exchange rate from [12] to 3", err.Error())
+		assert.IsType(t, &apperror.NotFoundError{}, err)
+	})
+}
+
+func Test_groupAmounts(t *testing.T) {
+	result := groupAmounts([]*table.CurrencyAmount{
+		{ID: 42, CurrencyID: 1, Amount: 12.34},
+		{ID: 42, CurrencyID: 2, Amount: 56.78},
+		{ID: 96, CurrencyID: 1, Amount: 1},
+	})
+
+	assert.Equal(t, map[int64]interface{}{
+		42: map[int64]float64{1: 12.34, 2: 56.78},
+		96: map[int64]float64{1: 1},
+	}, result)
+}
+
+func Test_GetBaseCurrency(t *testing.T) {
+	baseID := int64(42)
+	currency := &table.Currency{Code: "USD"}
+	tests := []struct {
+		name       string
+		baseID     *int64
+		currencies []*table.Currency
+		result     *table.Currency
+	}{
+		{"returns nil if not set", nil, nil, nil},
+		{"returns nil if not found", &baseID, []*table.Currency{}, nil},
+		{"returns base currency", &baseID, []*table.Currency{currency}, currency},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+				getBaseStub := mocka.Function(t, &getBaseCurrencyID, test.baseID)
+				defer getBaseStub.Restore()
+				getCurrencyStub := mocka.Function(t, &getCurrencyByID, test.currencies)
+				defer getCurrencyStub.Restore()
+
+				result := GetBaseCurrency(tx)
+
+				assert.Equal(t, test.result, result)
+			})
+		})
+	}
+}
+
+func Test_SetBaseCurrency(t *testing.T) {
+	currency := &table.Currency{Code: "USD"}
+	t.Run("sets base currency", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			getCurrencyStub := mocka.Function(t, &getCurrencyByID, []*table.Currency{currency})
+			defer getCurrencyStub.Restore()
+			setBaseStub := mocka.Function(t, &setBaseCurrencyID)
+			defer setBaseStub.Restore()
+
+			result, err := SetBaseCurrency(tx, 42, "somebody")
+
+			assert.Nil(t, err)
+			assert.Same(t, currency, result)
+			assert.Equal(t, []interface{}{tx, int64(42), "somebody"}, setBaseStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("returns error for unknown currency", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			getCurrencyStub := mocka.Function(t, &getCurrencyByID, []*table.Currency{})
+			defer getCurrencyStub.Restore()
+
+			_, err := SetBaseCurrency(tx, 42, "somebody")
+
+			assert.EqualError(t, err, "currency not found (42)")
+		})
+	})
+}
+
+func Test_AddExchangeRates(t *testing.T) {
+	errTests := []struct {
+		name  string
+		value map[string]interface{}
+		err   string
+	}{
+		{"returns error for zero rate", map[string]interface{}{"fromCurrencyId": 1, "toCurrencyId": 2, "rate": 0.0}, "exchange rate must be positive"},
+		{"returns error for same currency", map[string]interface{}{"fromCurrencyId": 1, "toCurrencyId": 1, "rate": 1.0}, "exchange rate requires different currencies"},
+	}
+	for _, test := range errTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := AddExchangeRates(nil, []map[string]interface{}{test.value}, "somebody")
+
+			assert.EqualError(t, err, test.err)
+		})
+	}
+	t.Run("adds rates", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			add := map[string]interface{}{"fromCurrencyId": 1, "toCurrencyId": 2, "rate": 1.5}
+			addStub := mocka.Function(t, &addExchangeRate, int64(42))
+			defer addStub.Restore()
+
+			ids, err := AddExchangeRates(tx, []map[string]interface{}{add}, "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, []int64{42}, ids)
+			assert.Equal(t, []interface{}{tx, database.InputObject(add), "somebody"}, addStub.GetCall(0).Arguments())
+		})
+	})
+}
+
+func Test_UpdateExchangeRates(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		update := map[string]interface{}{"id": 42, "version": 1, "rate": 1.5}
+		updateStub := mocka.Function(t, &updateExchangeRate, nil)
+		defer updateStub.Restore()
+
+		ids, err := UpdateExchangeRates(tx, []map[string]interface{}{update}, "somebody")
+
+		assert.Nil(t, err)
+		assert.Equal(t, []int64{42}, ids)
+		assert.Equal(t, []interface{}{tx, int64(42), int64(1), database.InputObject(update), "somebody"}, updateStub.GetCall(0).Arguments())
+	})
+}
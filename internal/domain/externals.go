@@ -16,21 +16,87 @@ var getAllAccounts = database.GetAllAccounts
 var getAccountByID = database.GetAccountByID
 var getAccountsByName = database.GetAccountsByName
 var getAccountsByCompanyIDs = database.GetAccountsByCompanyIDs
+var getAccountsByIDs = database.GetAccountsByIDs
+
+var getUserByName = database.GetUserByName
+var getAllUsers = database.GetAllUsers
+var getUsersByIDs = database.GetUsersByIDs
+var addUser = database.AddUser
+var updateUser = database.UpdateUser
+var getAccountPermissions = database.GetAccountPermissions
+var getAccountPermissionsByUserIDs = database.GetAccountPermissionsByUserIDs
+var grantAccountPermission = database.GrantAccountPermission
+var getTransactionAccountIDs = database.GetTransactionAccountIDs
+var getImportItemAccountIDs = database.GetImportItemAccountIDs
+
+var getHistory = database.GetHistory
+var getChangeSet = database.GetChangeSet
+var getChangeSetHistory = database.GetChangeSetHistory
+var getChanges = database.GetChanges
+var isCurrentVersion = database.IsCurrentVersion
+var undoChange = database.UndoChange
+
+var getAPITokenUser = database.GetAPITokenUser
+var getAPITokensByIDs = database.GetAPITokensByIDs
+var addAPIToken = database.AddAPIToken
+
+var getAllCurrencies = database.GetAllCurrencies
+var getCurrencyByID = database.GetCurrencyByID
+var getAssetsByIDs = database.GetAssetsByIDs
+var getLatestExchangeRates = database.GetLatestExchangeRates
+var addExchangeRate = database.AddExchangeRate
+var updateExchangeRate = database.UpdateExchangeRate
+var getBaseCurrencyID = database.GetBaseCurrencyID
+var setBaseCurrencyID = database.SetBaseCurrencyID
 
 var getTransactions = database.GetTransactions
 var getTransactionsByIDs = database.GetTransactionsByIDs
 var insertTransaction = database.InsertTransaction
 var updateTransaction = database.UpdateTransaction
-var deleteTransactions = database.DeleteTransactions
+var trashTransactions = database.TrashTransactions
+var getTrash = database.GetTrash
+var restoreTransactions = database.RestoreTransactions
+var getExpiredTrashIDs = database.GetExpiredTrashIDs
+var purgeTransactions = database.PurgeTransactions
+var clearTransaction = database.ClearTransaction
+
+var addPayee = database.AddPayee
+var getAllPayees = database.GetAllPayees
+var getPayeesByIDs = database.GetPayeesByIDs
+var getTransactionsByPayeeIDs = database.GetTransactionsByPayeeIDs
+var getPayeeAmounts = database.GetPayeeAmounts
+var getPayeeTransactionCounts = database.GetPayeeTransactionCounts
+var getAllCategories = database.GetAllCategories
+var getCategoriesByIDs = database.GetCategoriesByIDs
+var getCategoriesByParentIDs = database.GetCategoriesByParentIDs
+var getCategoryAmounts = database.GetCategoryAmounts
+var getCategoryTransactionCounts = database.GetCategoryTransactionCounts
+var getAllGroups = database.GetAllGroups
+var getGroupsByIDs = database.GetGroupsByIDs
+var getGroupTransactionCounts = database.GetGroupTransactionCounts
+var getAllSecurities = database.GetAllSecurities
+var getSecurityByID = database.GetSecurityByID
+var getSecuritiesByIDs = database.GetSecuritiesByIDs
+var getSecurityBySymbol = database.GetSecurityBySymbol
+var getSecurityAmounts = database.GetSecurityAmounts
+var getSecurityShares = database.GetSecurityShares
+
+var getImportItemsByIDs = database.GetImportItemsByIDs
+var insertImportItem = database.InsertImportItem
+var setImportTransaction = database.SetImportTransaction
 
 var getDetailsByTxIDs = database.GetDetailsByTxIDs
 var getDetailsByAccountID = database.GetDetailsByAccountID
+var getDetailsByCategoryIDs = database.GetDetailsByCategoryIDs
+var getDetailsByGroupIDs = database.GetDetailsByGroupIDs
 var getRelatedTransactions = database.GetRelatedTransactions
 var getRelatedTransactionsByAccountID = database.GetRelatedTransactionsByAccountID
 var getRelatedDetailsByTxIDs = database.GetRelatedDetailsByTxIDs
 var getRelatedDetailsByAccountID = database.GetRelatedDetailsByAccountID
 var insertDetail = database.InsertDetail
 var updateDetail = database.UpdateDetail
+var getDetailsByFilter = database.GetDetailsByFilter
+var updateDetailsByIDs = database.UpdateDetailsByIDs
 var validateDetails = database.ValidateDetails
 var addOrUpdateTransfer = database.AddOrUpdateTransfer
 var setTransferAmount = database.SetTransferAmount
@@ -39,4 +105,12 @@ var deleteRelatedDetails = database.DeleteRelatedDetails
 var deleteTransactionDetails = database.DeleteTransactionDetails
 var deleteTransfer = database.DeleteTransfer
 
+var getAttachment = database.GetAttachment
+var getAttachmentsByTxIDs = database.GetAttachmentsByTxIDs
+var getAttachmentsByTxAccountID = database.GetAttachmentsByTxAccountID
+var getAccountAttachments = database.GetAccountAttachments
+var getAttachmentsByHashes = database.GetAttachmentsByHashes
+var addAttachment = database.AddAttachment
+var deleteTransactionAttachments = database.DeleteTransactionAttachments
+
 var defaultResolveFn = graphql.DefaultResolveFn
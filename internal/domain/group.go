@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// Group is an alternate categorization for transaction details.
+type Group struct {
+	source *groupSource
+	*table.Group
+}
+
+func (g *Group) Resolve(p graphql.ResolveParams) (interface{}, error) {
+	return defaultResolveFn(replaceSource(p, g.Group))
+}
+
+// GetDetails returns a page of the group's transaction details in the accounts. Details in all accounts are returned
+// if accountIDs is nil.
+func (g *Group) GetDetails(tx *database.Tx, filter database.PageFilter, accountIDs []int64) []*TransactionDetail {
+	details, _ := g.source.details.get(tx, g.ID, filter, func(tx *database.Tx, groupIDs []int64) map[int64]interface{} {
+		byGroupID := make(map[int64]interface{})
+		for _, detail := range newDetailSource(getDetailsByGroupIDs(tx, groupIDs, filter, accountIDs)) {
+			groupDetails, _ := byGroupID[*detail.TransactionGroupID].([]*TransactionDetail)
+			byGroupID[*detail.TransactionGroupID] = append(groupDetails, detail)
+		}
+		return byGroupID
+	}).([]*TransactionDetail)
+	return details
+}
+
+// GetTransactionCount returns the number of transactions with details in the group in the accounts. Transactions in
+// all accounts are counted if accountIDs is nil. The load uses the same account IDs for every group in the request.
+func (g *Group) GetTransactionCount(tx *database.Tx, accountIDs []int64) int64 {
+	count, _ := g.source.counts.get(tx, &g.ID, func(tx *database.Tx, groupIDs []int64) map[int64]interface{} {
+		return groupCounts(getGroupTransactionCounts(tx, groupIDs, accountIDs))
+	}).(int64)
+	return count
+}
+
+// groupSource provides groups, their details and their transaction counts for a GraphQL request.
+type groupSource struct {
+	byID    *batchLoader
+	details *pagedLoader
+	counts  *batchLoader
+}
+
+func newGroupSource() *groupSource {
+	return &groupSource{byID: newBatchLoader(), details: newPagedLoader(), counts: newBatchLoader()}
+}
+
+func (gs *groupSource) setGroups(dbGroups []*table.Group) []*Group {
+	groups := make([]*Group, len(dbGroups))
+	for i, group := range dbGroups {
+		groups[i] = &Group{source: gs, Group: group}
+		gs.byID.set(group.ID, groups[i])
+		gs.details.add(group.ID)
+		gs.counts.add(&group.ID)
+	}
+	return groups
+}
+
+// get returns the group with the ID, loading all pending groups if necessary.
+func (gs *groupSource) get(tx *database.Tx, id *int64) *Group {
+	group, _ := gs.byID.get(tx, id, func(tx *database.Tx, ids []int64) map[int64]interface{} {
+		byID := make(map[int64]interface{}, len(ids))
+		for _, group := range gs.setGroups(getGroupsByIDs(tx, ids)) {
+			byID[group.ID] = group
+		}
+		return byID
+	}).(*Group)
+	return group
+}
+
+// GetAllGroups loads all transaction groups.
+func GetAllGroups(tx *database.Tx) []*Group {
+	return newGroupSource().setGroups(getAllGroups(tx))
+}
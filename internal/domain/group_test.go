@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Group_Resolve(t *testing.T) {
+	defaultResolveStub := mocka.Function(t, &defaultResolveFn, "result", nil)
+	defer defaultResolveStub.Restore()
+	group := &Group{Group: &table.Group{ID: 42}}
+
+	result, err := group.Resolve(graphql.ResolveParams{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "result", result)
+	assert.Same(t, group.Group, defaultResolveStub.GetCall(0).Arguments()[0].(graphql.ResolveParams).Source)
+}
+
+func Test_GetAllGroups(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		dbGroups := []*table.Group{{ID: 1}, {ID: 2}}
+		getAllStub := mocka.Function(t, &getAllGroups, dbGroups)
+		defer getAllStub.Restore()
+
+		groups := GetAllGroups(tx)
+
+		assert.Len(t, groups, 2)
+		assert.Same(t, dbGroups[0], groups[0].Group)
+		assert.Same(t, dbGroups[1], groups[1].Group)
+		assert.Same(t, groups[0].source, groups[1].source)
+	})
+}
+
+func Test_Group_GetDetails(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		groupIDs := []int64{1, 2}
+		getAllStub := mocka.Function(t, &getAllGroups, []*table.Group{{ID: 1}, {ID: 2}})
+		defer getAllStub.Restore()
+		getDetailsStub := mocka.Function(t, &getDetailsByGroupIDs, []*table.TransactionDetail{
+			{ID: 20, TransactionID: 10, TransactionGroupID: &groupIDs[1]},
+			{ID: 21, TransactionID: 11, TransactionGroupID: &groupIDs[1]},
+		})
+		defer getDetailsStub.Restore()
+		filter := database.PageFilter{StartDate: "2020-01-01"}
+		groups := GetAllGroups(tx)
+
+		details := groups[1].GetDetails(tx, filter, nil)
+
+		assert.Len(t, details, 2)
+		assert.Equal(t, int64(20), details[0].ID)
+		assert.Equal(t, int64(21), details[1].ID)
+		assert.ElementsMatch(t, []int64{10, 11}, details[0].txSource.txIDs)
+		assert.Nil(t, groups[0].GetDetails(tx, filter, nil))
+		assert.Equal(t, 1, getDetailsStub.CallCount())
+		args := getDetailsStub.GetCall(0).Arguments()
+		assert.ElementsMatch(t, groupIDs, args[1])
+		assert.Equal(t, filter, args[2])
+		assert.Nil(t, args[3])
+	})
+}
+
+func Test_Group_GetTransactionCount(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		getAllStub := mocka.Function(t, &getAllGroups, []*table.Group{{ID: 1}, {ID: 2}})
+		defer getAllStub.Restore()
+		getCountsStub := mocka.Function(t, &getGroupTransactionCounts, []*table.TransactionCount{{ID: 1, Count: 3}})
+		defer getCountsStub.Restore()
+		accountIDs := []int64{42}
+		groups := GetAllGroups(tx)
+
+		count1 := groups[0].GetTransactionCount(tx, accountIDs)
+		count2 := groups[1].GetTransactionCount(tx, accountIDs)
+
+		assert.Equal(t, int64(3), count1)
+		assert.Equal(t, int64(0), count2)
+		assert.ElementsMatch(t, []int64{1, 2}, getCountsStub.GetCall(0).Arguments()[1])
+		assert.Equal(t, accountIDs, getCountsStub.GetCall(0).Arguments()[2])
+		assert.Equal(t, 1, getCountsStub.CallCount())
+	})
+}
@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// columns that are returned as separate fields of the history instead of changes
+var historyAuditColumns = map[string]bool{"version": true, "change_user": true, "change_date": true}
+
+// ChangeHistory is a version of a row with the changes from the previous version.
+type ChangeHistory struct {
+	before map[string]interface{}
+	after  map[string]interface{}
+	*table.ChangeHistory
+}
+
+// FieldChange is the before and after value of a field.
+type FieldChange struct {
+	Field  string
+	Before *string
+	After  *string
+}
+
+func (h *ChangeHistory) Resolve(p graphql.ResolveParams) (interface{}, error) {
+	return defaultResolveFn(replaceSource(p, h.ChangeHistory))
+}
+
+func parseImage(image *string) map[string]interface{} {
+	if image == nil {
+		return nil
+	}
+	values := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(*image), &values); err != nil {
+		panic(err)
+	}
+	return values
+}
+
+// NewChangeHistory parses the images of the history.
+func NewChangeHistory(history *table.ChangeHistory) *ChangeHistory {
+	return &ChangeHistory{before: parseImage(history.BeforeImage), after: parseImage(history.AfterImage), ChangeHistory: history}
+}
+
+// fieldName converts a column name to the name of the GraphQL field.
+func fieldName(column string) string {
+	parts := strings.Split(column, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.Title(parts[i])
+	}
+	return strings.Join(parts, "")
+}
+
+func formatValue(value interface{}) *string {
+	var text string
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case string:
+		text = value
+	case float64:
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		text = fmt.Sprint(value)
+	}
+	return &text
+}
+
+// Changes returns the fields that were changed, excluding the audit fields.
+func (h *ChangeHistory) Changes() []*FieldChange {
+	columns := make([]string, 0, len(h.after))
+	for column := range h.before {
+		columns = append(columns, column)
+	}
+	for column := range h.after {
+		if _, ok := h.before[column]; !ok {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	changes := make([]*FieldChange, 0)
+	for _, column := range columns {
+		if historyAuditColumns[column] {
+			continue
+		}
+		before, after := formatValue(h.before[column]), formatValue(h.after[column])
+		if before == nil && after == nil || before != nil && after != nil && *before == *after {
+			continue
+		}
+		changes = append(changes, &FieldChange{Field: fieldName(column), Before: before, After: after})
+	}
+	return changes
+}
+
+// addImageIDs adds the values of the ID column from the before and after images.
+func (h *ChangeHistory) addImageIDs(ids *idSet, column string) {
+	for _, image := range []map[string]interface{}{h.before, h.after} {
+		if id, ok := image[column].(float64); ok {
+			ids.Add(int64(id))
+		}
+	}
+}
+
+// GetHistory returns the changes to the row of the table identified by the key.
+func GetHistory(tx *database.Tx, tableName string, key string) []*ChangeHistory {
+	rows := getHistory(tx, tableName, key)
+	history := make([]*ChangeHistory, len(rows))
+	for i, row := range rows {
+		history[i] = NewChangeHistory(row)
+	}
+	return history
+}
+
+// historyAccountIDs returns the accounts of the history. Returns adminOnly = true if the history is only visible to
+// admins.
+func historyAccountIDs(tx *database.Tx, tableName string, history []*ChangeHistory) (accountIDs []int64, adminOnly bool) {
+	ids := newIDSet()
+	switch tableName {
+	case "api_token":
+		return nil, true
+	case "transaction", "import_item":
+		for _, version := range history {
+			version.addImageIDs(ids, "account_id")
+		}
+	case "transaction_detail", "attachment":
+		txIDs := newIDSet()
+		for _, version := range history {
+			version.addImageIDs(txIDs, "transaction_id")
+			if tableName == "attachment" {
+				version.addImageIDs(ids, "account_id")
+			}
+		}
+		if len(txIDs.ids) > 0 {
+			for _, id := range getTransactionAccountIDs(tx, txIDs.Values()) {
+				ids.Add(id)
+			}
+			// the transaction has been deleted
+			if len(ids.ids) == 0 {
+				return nil, true
+			}
+		}
+	}
+	return ids.Values(), false
+}
+
+// RequireHistoryRead returns a ForbiddenError if the history belongs to an account that the user can't view.
+func (p *Permissions) RequireHistoryRead(tx *database.Tx, tableName string, history []*ChangeHistory) error {
+	if p.admin {
+		return nil
+	}
+	accountIDs, adminOnly := historyAccountIDs(tx, tableName, history)
+	if adminOnly {
+		return p.RequireAdmin()
+	}
+	return p.RequireRead(accountIDs...)
+}
+
+// RequireHistoryWrite returns a ForbiddenError if the history belongs to an account that the user can't change.
+func (p *Permissions) RequireHistoryWrite(tx *database.Tx, tableName string, history []*ChangeHistory) error {
+	if p.admin {
+		return nil
+	}
+	accountIDs, adminOnly := historyAccountIDs(tx, tableName, history)
+	if adminOnly {
+		return p.RequireAdmin()
+	}
+	return p.RequireWrite(accountIDs...)
+}
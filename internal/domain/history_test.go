@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHistory(before, after string) *ChangeHistory {
+	history := &table.ChangeHistory{ID: 1}
+	if before != "" {
+		history.BeforeImage = &before
+	}
+	if after != "" {
+		history.AfterImage = &after
+	}
+	return NewChangeHistory(history)
+}
+
+func Test_ChangeHistory_Resolve(t *testing.T) {
+	expectedResult := "result"
+	defaultResolveStub := mocka.Function(t, &defaultResolveFn, expectedResult, nil)
+	defer defaultResolveStub.Restore()
+	history := newTestHistory("", `{"id":42}`)
+
+	result, err := history.Resolve(graphql.ResolveParams{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedResult, result)
+	assert.Same(t, history.ChangeHistory, defaultResolveStub.GetCall(0).Arguments()[0].(graphql.ResolveParams).Source)
+}
+
+func Test_fieldName(t *testing.T) {
+	assert.Equal(t, "id", fieldName("id"))
+	assert.Equal(t, "accountId", fieldName("account_id"))
+	assert.Equal(t, "exchangeRateDate", fieldName("exchange_rate_date"))
+}
+
+func Test_ChangeHistory_Changes(t *testing.T) {
+	strPtr := func(value string) *string { return &value }
+	tests := []struct {
+		name    string
+		history *ChangeHistory
+		changes []*FieldChange
+	}{
+		{"returns all fields for insert", newTestHistory("", `{"id":42,"name":"new","memo":null,"version":0}`), []*FieldChange{
+			{Field: "id", After: strPtr("42")},
+			{Field: "name", After: strPtr("new")},
+		}},
+		{"returns all fields for delete", newTestHistory(`{"id":42,"amount":12.5,"version":3}`, ""), []*FieldChange{
+			{Field: "amount", Before: strPtr("12.5")},
+			{Field: "id", Before: strPtr("42")},
+		}},
+		{"returns changed fields for update", newTestHistory(
+			`{"id":42,"payee_id":1,"memo":null,"cleared":false,"change_user":"a","version":1}`,
+			`{"id":42,"payee_id":2,"memo":"memo","cleared":false,"change_user":"b","version":2}`,
+		), []*FieldChange{
+			{Field: "memo", After: strPtr("memo")},
+			{Field: "payeeId", Before: strPtr("1"), After: strPtr("2")},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.changes, test.history.Changes())
+		})
+	}
+}
+
+func Test_GetHistory(t *testing.T) {
+	dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+		after := `{"id":42}`
+		rows := []*table.ChangeHistory{{ID: 1, AfterImage: &after}}
+		getHistoryStub := mocka.Function(t, &getHistory, rows)
+		defer getHistoryStub.Restore()
+
+		result := GetHistory(tx, "payee", "42")
+
+		assert.Equal(t, []*ChangeHistory{{after: map[string]interface{}{"id": float64(42)}, ChangeHistory: rows[0]}}, result)
+		assert.Equal(t, []interface{}{tx, "payee", "42"}, getHistoryStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_RequireHistoryRead(t *testing.T) {
+	readable := NewPermissions(false, map[int64]string{1: table.PermissionRead})
+	t.Run("allows admin", func(t *testing.T) {
+		assert.Nil(t, NewPermissions(true, nil).RequireHistoryRead(nil, "api_token", nil))
+	})
+	t.Run("requires admin for API tokens", func(t *testing.T) {
+		assert.Equal(t, apperror.Forbidden("admin role required"), readable.RequireHistoryRead(nil, "api_token", nil))
+	})
+	t.Run("allows entities without account", func(t *testing.T) {
+		assert.Nil(t, readable.RequireHistoryRead(nil, "payee", []*ChangeHistory{newTestHistory("", `{"id":42}`)}))
+	})
+	t.Run("checks transaction accounts", func(t *testing.T) {
+		history := []*ChangeHistory{newTestHistory(`{"account_id":1}`, `{"account_id":2}`)}
+
+		assert.Equal(t, apperror.AccountForbidden(2, "readable"), readable.RequireHistoryRead(nil, "transaction", history))
+	})
+	t.Run("checks import item accounts", func(t *testing.T) {
+		assert.Nil(t, readable.RequireHistoryRead(nil, "import_item", []*ChangeHistory{newTestHistory("", `{"account_id":1}`)}))
+	})
+	t.Run("checks transaction detail accounts", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getAccountIDsStub := mocka.Function(t, &getTransactionAccountIDs, []int64{1})
+			defer getAccountIDsStub.Restore()
+
+			assert.Nil(t, readable.RequireHistoryRead(tx, "transaction_detail", []*ChangeHistory{newTestHistory("", `{"transaction_id":96}`)}))
+
+			assert.Equal(t, []interface{}{tx, []int64{96}}, getAccountIDsStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("checks attachment accounts", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getAccountIDsStub := mocka.Function(t, &getTransactionAccountIDs, []int64{1})
+			defer getAccountIDsStub.Restore()
+			history := []*ChangeHistory{newTestHistory("", `{"transaction_id":96}`), newTestHistory("", `{"account_id":2}`)}
+
+			assert.Equal(t, apperror.AccountForbidden(2, "readable"), readable.RequireHistoryRead(tx, "attachment", history))
+		})
+	})
+	t.Run("requires admin for details of deleted transaction", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getAccountIDsStub := mocka.Function(t, &getTransactionAccountIDs, []int64{})
+			defer getAccountIDsStub.Restore()
+
+			history := []*ChangeHistory{newTestHistory(`{"transaction_id":96}`, "")}
+
+			assert.Equal(t, apperror.Forbidden("admin role required"), readable.RequireHistoryRead(tx, "transaction_detail", history))
+		})
+	})
+}
+
+func Test_RequireHistoryWrite(t *testing.T) {
+	history := []*ChangeHistory{newTestHistory(`{"account_id":1}`, "")}
+	t.Run("allows admin", func(t *testing.T) {
+		assert.Nil(t, NewPermissions(true, nil).RequireHistoryWrite(nil, "api_token", nil))
+	})
+	t.Run("requires admin for API tokens", func(t *testing.T) {
+		assert.Equal(t, apperror.Forbidden("admin role required"), NewPermissions(false, nil).RequireHistoryWrite(nil, "api_token", nil))
+	})
+	t.Run("allows writable account", func(t *testing.T) {
+		permissions := NewPermissions(false, map[int64]string{1: table.PermissionWrite})
+
+		assert.Nil(t, permissions.RequireHistoryWrite(nil, "transaction", history))
+	})
+	t.Run("requires writable account", func(t *testing.T) {
+		permissions := NewPermissions(false, map[int64]string{1: table.PermissionRead})
+
+		assert.Equal(t, apperror.AccountForbidden(1, "writable"), permissions.RequireHistoryWrite(nil, "transaction", history))
+	})
+}
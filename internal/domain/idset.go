@@ -14,6 +14,10 @@ func (s *idSet) Add(id int64) {
 	}
 }
 
+func (s *idSet) Remove(id int64) {
+	delete(s.ids, id)
+}
+
 func (s *idSet) Values() []int64 {
 	ids := make([]int64, len(s.ids))
 	i := 0
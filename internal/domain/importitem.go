@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+const dateFormat = "2006-01-02"
+
+// AddImportItems adds imported transactions to the staging area for the account and returns the new import items.
+func AddImportItems(tx *database.Tx, accountID int64, items []map[string]interface{}, user string) []*table.ImportItem {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = insertImportItem(tx, accountID, item, user)
+	}
+	return getImportItemsByIDs(tx, ids)
+}
+
+func loadImportItems(tx *database.Tx, values []map[string]interface{}) ([]*table.ImportItem, error) {
+	ids := make([]int64, len(values))
+	for i, value := range values {
+		ids[i] = database.InputObject(value).RequireInt("id")
+	}
+	itemsByID := make(map[int64]*table.ImportItem, len(ids))
+	for _, item := range getImportItemsByIDs(tx, ids) {
+		itemsByID[item.ID] = item
+	}
+	items := make([]*table.ImportItem, len(ids))
+	for i, id := range ids {
+		if item, ok := itemsByID[id]; ok && item.TransactionID == nil {
+			items[i] = item
+		} else {
+			return nil, apperror.NotFound("import_item", id)
+		}
+	}
+	return items, nil
+}
+
+func optionalString(value *string) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+func optionalInt(value *int64) interface{} {
+	if value == nil {
+		return nil
+	}
+	return int(*value)
+}
+
+// AcceptImportItems adds new transactions for the import items and returns the transaction IDs.
+// The suggested payee and category are used unless they are provided in the input. A new payee is only added once
+// for items with the same payee name.
+func AcceptImportItems(tx *database.Tx, accepts []map[string]interface{}, user string) ([]int64, error) {
+	items, err := loadImportItems(tx, accepts)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(items))
+	newPayeeIDs := make(map[string]int)
+	for i, item := range items {
+		values := database.InputObject(accepts[i])
+		payeeID, setPayee := values["payeeId"]
+		if !setPayee {
+			payeeID = optionalInt(item.PayeeID)
+			if item.PayeeID == nil && item.PayeeName != nil {
+				id, ok := newPayeeIDs[*item.PayeeName]
+				if !ok {
+					id = int(addPayee(tx, *item.PayeeName, user))
+					newPayeeIDs[*item.PayeeName] = id
+				}
+				payeeID = id
+			}
+		}
+		categoryID, setCategory := values["transactionCategoryId"]
+		if !setCategory {
+			categoryID = optionalInt(item.TransactionCategoryID)
+		}
+		transaction := map[string]interface{}{
+			"date":            item.Date.Format(dateFormat),
+			"referenceNumber": optionalString(item.ReferenceNumber),
+			"payeeId":         payeeID,
+			"memo":            optionalString(item.Memo),
+			"details":         []map[string]interface{}{{"amount": item.Amount, "transactionCategoryId": categoryID}},
+		}
+		txIDs, err := InsertTransactions(tx, item.AccountID, []map[string]interface{}{transaction}, user)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = txIDs[0]
+		if err := setImportTransaction(tx, item.ID, values.RequireInt("version"), ids[i], user); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// MatchImportItems links the import items to existing transactions, marks the transactions as cleared and
+// returns the transaction IDs. The suggested transaction is used unless one is provided in the input. The version of
+// the transaction is required when the transaction is provided and defaults to the version of the suggested
+// transaction. A transaction can only be matched to one of the items.
+func MatchImportItems(tx *database.Tx, matches []map[string]interface{}, user string) ([]int64, error) {
+	items, err := loadImportItems(tx, matches)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(items))
+	matched := make(map[int64]bool, len(items))
+	for i, item := range items {
+		values := database.InputObject(matches[i])
+		var txVersion interface{}
+		if txID, ok := values.GetInt("transactionId"); ok && txID != nil {
+			ids[i] = txID.(int64)
+			if txVersion, ok = values.GetInt("transactionVersion"); !ok || txVersion == nil {
+				return nil, apperror.Validation(fmt.Sprintf("match[%d].transactionVersion", i),
+					"transactionVersion is required with transactionId")
+			}
+		} else if item.MatchedTransactionID != nil {
+			ids[i] = *item.MatchedTransactionID
+			if txVersion, ok = values.GetInt("transactionVersion"); !ok || txVersion == nil {
+				txVersion = *item.MatchedTransactionVersion
+			}
+		} else {
+			return nil, apperror.Validation(fmt.Sprintf("match[%d].transactionId", i),
+				fmt.Sprintf("no matching transaction for import item (%d)", item.ID))
+		}
+		if matched[ids[i]] {
+			return nil, apperror.Validation(fmt.Sprintf("match[%d].transactionId", i),
+				fmt.Sprintf("transaction is matched to more than one import item (%d)", ids[i]))
+		}
+		matched[ids[i]] = true
+		if err := clearTransaction(tx, ids[i], txVersion.(int64), item.AccountID, user); err != nil {
+			return nil, err
+		}
+		if err := setImportTransaction(tx, item.ID, values.RequireInt("version"), ids[i], user); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,248 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AddImportItems(t *testing.T) {
+	accountID := int64(42)
+	user := "user id"
+	items := []map[string]interface{}{{"amount": 12.34}, {"amount": 43.21}}
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		importItems := []*table.ImportItem{{ID: 1}, {ID: 2}}
+		insertImportItemStub := mocka.Function(t, &insertImportItem, int64(1))
+		insertImportItemStub.OnCall(1).Return(int64(2))
+		defer insertImportItemStub.Restore()
+		getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, importItems)
+		defer getImportItemsStub.Restore()
+
+		result := AddImportItems(tx, accountID, items, user)
+
+		assert.Equal(t, importItems, result)
+		assert.Equal(t, []interface{}{tx, accountID, database.InputObject(items[0]), user}, insertImportItemStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, accountID, database.InputObject(items[1]), user}, insertImportItemStub.GetCall(1).Arguments())
+		assert.Equal(t, []interface{}{tx, []int64{1, 2}}, getImportItemsStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_AcceptImportItems(t *testing.T) {
+	user := "user id"
+	accountID := int64(96)
+	txID := int64(123)
+	date := time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC)
+	payeeName := "the payee"
+	memo := "notes"
+	payeeID := int64(69)
+	categoryID := int64(24)
+	tests := []struct {
+		name     string
+		item     *table.ImportItem
+		accept   map[string]interface{}
+		addPayee bool
+		tx       database.InputObject
+		detail   database.InputObject
+	}{
+		{
+			name:   "uses suggestions",
+			item:   &table.ImportItem{ID: 42, AccountID: accountID, Date: date, PayeeName: &payeeName, Amount: 12.34, PayeeID: &payeeID, TransactionCategoryID: &categoryID},
+			accept: map[string]interface{}{"id": 42, "version": 1},
+			tx:     database.InputObject{"date": "2020-12-25", "referenceNumber": nil, "payeeId": 69, "memo": nil},
+			detail: database.InputObject{"amount": 12.34, "transactionCategoryId": 24},
+		},
+		{
+			name:   "uses input values",
+			item:   &table.ImportItem{ID: 42, AccountID: accountID, Date: date, Memo: &memo, Amount: 12.34, PayeeID: &payeeID, TransactionCategoryID: &categoryID},
+			accept: map[string]interface{}{"id": 42, "version": 1, "payeeId": 1, "transactionCategoryId": nil},
+			tx:     database.InputObject{"date": "2020-12-25", "referenceNumber": nil, "payeeId": 1, "memo": "notes"},
+			detail: database.InputObject{"amount": 12.34, "transactionCategoryId": nil},
+		},
+		{
+			name:     "adds new payee",
+			item:     &table.ImportItem{ID: 42, AccountID: accountID, Date: date, PayeeName: &payeeName, Amount: 12.34},
+			accept:   map[string]interface{}{"id": 42, "version": 1},
+			addPayee: true,
+			tx:       database.InputObject{"date": "2020-12-25", "referenceNumber": nil, "payeeId": 99, "memo": nil},
+			detail:   database.InputObject{"amount": 12.34, "transactionCategoryId": nil},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+				getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, []*table.ImportItem{test.item})
+				defer getImportItemsStub.Restore()
+				addPayeeStub := mocka.Function(t, &addPayee, int64(99))
+				defer addPayeeStub.Restore()
+				insertTransactionStub := mocka.Function(t, &insertTransaction, txID)
+				defer insertTransactionStub.Restore()
+				insertDetailStub := mocka.Function(t, &insertDetail)
+				defer insertDetailStub.Restore()
+				validateDetailsStub := mocka.Function(t, &validateDetails, nil)
+				defer validateDetailsStub.Restore()
+				setImportTransactionStub := mocka.Function(t, &setImportTransaction, nil)
+				defer setImportTransactionStub.Restore()
+
+				result, err := AcceptImportItems(tx, []map[string]interface{}{test.accept}, user)
+
+				assert.Nil(t, err)
+				assert.Equal(t, []int64{txID}, result)
+				if test.addPayee {
+					assert.Equal(t, []interface{}{tx, payeeName, user}, addPayeeStub.GetCall(0).Arguments())
+				} else {
+					assert.Equal(t, 0, addPayeeStub.CallCount())
+				}
+				txValues := insertTransactionStub.GetCall(0).Arguments()[2].(database.InputObject)
+				delete(txValues, "details")
+				assert.Equal(t, test.tx, txValues)
+				assert.Equal(t, []interface{}{tx, txID, test.item.Amount, test.detail, user}, insertDetailStub.GetCall(0).Arguments())
+				assert.Equal(t, []interface{}{tx, []int64{txID}}, validateDetailsStub.GetCall(0).Arguments())
+				assert.Equal(t, []interface{}{tx, int64(42), int64(1), txID, user}, setImportTransactionStub.GetCall(0).Arguments())
+			})
+		})
+	}
+	t.Run("adds new payee once", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			items := []*table.ImportItem{
+				{ID: 42, AccountID: accountID, Date: date, PayeeName: &payeeName, Amount: 12.34},
+				{ID: 43, AccountID: accountID, Date: date, PayeeName: &payeeName, Amount: 43.21},
+			}
+			getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, items)
+			defer getImportItemsStub.Restore()
+			addPayeeStub := mocka.Function(t, &addPayee, int64(99))
+			defer addPayeeStub.Restore()
+			insertTransactionStub := mocka.Function(t, &insertTransaction, txID)
+			defer insertTransactionStub.Restore()
+			insertDetailStub := mocka.Function(t, &insertDetail)
+			defer insertDetailStub.Restore()
+			validateDetailsStub := mocka.Function(t, &validateDetails, nil)
+			defer validateDetailsStub.Restore()
+			setImportTransactionStub := mocka.Function(t, &setImportTransaction, nil)
+			defer setImportTransactionStub.Restore()
+
+			_, err := AcceptImportItems(tx, []map[string]interface{}{{"id": 42, "version": 1}, {"id": 43, "version": 1}}, user)
+
+			assert.Nil(t, err)
+			assert.Equal(t, 1, addPayeeStub.CallCount())
+			assert.Equal(t, 99, insertTransactionStub.GetCall(0).Arguments()[2].(database.InputObject)["payeeId"])
+			assert.Equal(t, 99, insertTransactionStub.GetCall(1).Arguments()[2].(database.InputObject)["payeeId"])
+		})
+	})
+	t.Run("returns error for item already reviewed", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			item := &table.ImportItem{ID: 42, TransactionID: &txID}
+			getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, []*table.ImportItem{item})
+			defer getImportItemsStub.Restore()
+
+			_, err := AcceptImportItems(tx, []map[string]interface{}{{"id": 42, "version": 1}}, user)
+
+			assert.EqualError(t, err, "import item not found (42)")
+		})
+	})
+}
+
+func Test_MatchImportItems(t *testing.T) {
+	user := "user id"
+	accountID := int64(96)
+	matchedID := int64(123)
+	matchedVersion := int64(2)
+	tests := []struct {
+		name      string
+		match     map[string]interface{}
+		txID      int64
+		txVersion int64
+	}{
+		{"uses suggested transaction", map[string]interface{}{"id": 42, "version": 1}, matchedID, matchedVersion},
+		{"uses input version of suggested transaction", map[string]interface{}{"id": 42, "version": 1, "transactionVersion": 1}, matchedID, 1},
+		{"uses input transaction", map[string]interface{}{"id": 42, "version": 1, "transactionId": 321, "transactionVersion": 5}, 321, 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+				item := &table.ImportItem{ID: 42, AccountID: accountID, MatchedTransactionID: &matchedID, MatchedTransactionVersion: &matchedVersion}
+				getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, []*table.ImportItem{item})
+				defer getImportItemsStub.Restore()
+				clearTransactionStub := mocka.Function(t, &clearTransaction, nil)
+				defer clearTransactionStub.Restore()
+				setImportTransactionStub := mocka.Function(t, &setImportTransaction, nil)
+				defer setImportTransactionStub.Restore()
+
+				result, err := MatchImportItems(tx, []map[string]interface{}{test.match}, user)
+
+				assert.Nil(t, err)
+				assert.Equal(t, []int64{test.txID}, result)
+				assert.Equal(t, []interface{}{tx, test.txID, test.txVersion, accountID, user}, clearTransactionStub.GetCall(0).Arguments())
+				assert.Equal(t, []interface{}{tx, int64(42), int64(1), test.txID, user}, setImportTransactionStub.GetCall(0).Arguments())
+			})
+		})
+	}
+	t.Run("returns error for no matching transaction", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			item := &table.ImportItem{ID: 42, AccountID: accountID}
+			getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, []*table.ImportItem{item})
+			defer getImportItemsStub.Restore()
+
+			_, err := MatchImportItems(tx, []map[string]interface{}{{"id": 42, "version": 1}}, user)
+
+			assert.EqualError(t, err, "no matching transaction for import item (42)")
+		})
+	})
+	t.Run("requires version of input transaction", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			item := &table.ImportItem{ID: 42, AccountID: accountID}
+			getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, []*table.ImportItem{item})
+			defer getImportItemsStub.Restore()
+
+			_, err := MatchImportItems(tx, []map[string]interface{}{{"id": 42, "version": 1, "transactionId": 321}}, user)
+
+			assert.Equal(t, apperror.Validation("match[0].transactionVersion", "transactionVersion is required with transactionId"), err)
+		})
+	})
+	t.Run("returns error for transaction matched twice", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			items := []*table.ImportItem{
+				{ID: 42, AccountID: accountID, MatchedTransactionID: &matchedID, MatchedTransactionVersion: &matchedVersion},
+				{ID: 43, AccountID: accountID},
+			}
+			getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, items)
+			defer getImportItemsStub.Restore()
+			clearTransactionStub := mocka.Function(t, &clearTransaction, nil)
+			defer clearTransactionStub.Restore()
+			setImportTransactionStub := mocka.Function(t, &setImportTransaction, nil)
+			defer setImportTransactionStub.Restore()
+
+			_, err := MatchImportItems(tx, []map[string]interface{}{
+				{"id": 42, "version": 1},
+				{"id": 43, "version": 1, "transactionId": 123, "transactionVersion": 2},
+			}, user)
+
+			assert.Equal(t, apperror.Validation("match[1].transactionId", "transaction is matched to more than one import item (123)"), err)
+			assert.Equal(t, 1, clearTransactionStub.CallCount())
+		})
+	})
+}
+
+func Test_MatchImportItems_returnsVersionConflict(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		txID, txVersion := int64(123), int64(1)
+		item := &table.ImportItem{ID: 42, AccountID: 96, MatchedTransactionID: &txID, MatchedTransactionVersion: &txVersion}
+		getImportItemsStub := mocka.Function(t, &getImportItemsByIDs, []*table.ImportItem{item})
+		defer getImportItemsStub.Restore()
+		clearTransactionStub := mocka.Function(t, &clearTransaction, nil)
+		defer clearTransactionStub.Restore()
+		setImportTransactionStub := mocka.Function(t, &setImportTransaction, apperror.VersionConflict("import_item", 42, 1))
+		defer setImportTransactionStub.Restore()
+
+		result, err := MatchImportItems(tx, []map[string]interface{}{{"id": 42, "version": 1}}, "somebody")
+
+		assert.Nil(t, result)
+		assert.EqualError(t, err, "import item not found (42 @ 1)")
+	})
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// Payee is the other party in a transaction.
+type Payee struct {
+	source *payeeSource
+	*table.Payee
+}
+
+func (p *Payee) Resolve(params graphql.ResolveParams) (interface{}, error) {
+	return defaultResolveFn(replaceSource(params, p.Payee))
+}
+
+// GetTransactions returns a page of the payee's transactions in the accounts. Transactions in all accounts are
+// returned if accountIDs is nil.
+func (p *Payee) GetTransactions(tx *database.Tx, filter database.PageFilter, accountIDs []int64) []*Transaction {
+	transactions, _ := p.source.transactions.get(tx, p.ID, filter, func(tx *database.Tx, payeeIDs []int64) map[int64]interface{} {
+		rows := getTransactionsByPayeeIDs(tx, payeeIDs, filter, accountIDs)
+		ids := make([]int64, len(rows))
+		for i, row := range rows {
+			ids[i] = row.ID
+		}
+		source := &transactionSource{txIDs: ids}
+		byPayeeID := make(map[int64]interface{})
+		for _, transaction := range source.setSource(rows) {
+			payeeTransactions, _ := byPayeeID[*transaction.PayeeID].([]*Transaction)
+			byPayeeID[*transaction.PayeeID] = append(payeeTransactions, transaction)
+		}
+		return byPayeeID
+	}).([]*Transaction)
+	return transactions
+}
+
+// GetAmount returns the total of the payee's transactions in the accounts, converted to the currency. Transactions in
+// all accounts are included if accountIDs is nil. The load uses the same account IDs for every payee in the request.
+// Returns a NotFoundError if the currency or an exchange rate doesn't exist.
+func (p *Payee) GetAmount(tx *database.Tx, currencyID *int64, accountIDs []int64) (string, error) {
+	amounts, _ := p.source.amounts.get(tx, &p.ID, func(tx *database.Tx, payeeIDs []int64) map[int64]interface{} {
+		return groupAmounts(getPayeeAmounts(tx, payeeIDs, accountIDs))
+	}).(map[int64]float64)
+	return p.source.total(tx, amounts, currencyID)
+}
+
+// GetTransactionCount returns the number of the payee's transactions in the accounts. Transactions in all accounts
+// are counted if accountIDs is nil. The load uses the same account IDs for every payee in the request.
+func (p *Payee) GetTransactionCount(tx *database.Tx, accountIDs []int64) int64 {
+	count, _ := p.source.counts.get(tx, &p.ID, func(tx *database.Tx, payeeIDs []int64) map[int64]interface{} {
+		return groupCounts(getPayeeTransactionCounts(tx, payeeIDs, accountIDs))
+	}).(int64)
+	return count
+}
+
+// payeeSource provides payees, their transactions, their transaction counts and their amounts for a GraphQL request.
+type payeeSource struct {
+	byID         *batchLoader
+	transactions *pagedLoader
+	counts       *batchLoader
+	amounts      *batchLoader
+	currencyConverter
+}
+
+func newPayeeSource() *payeeSource {
+	return &payeeSource{byID: newBatchLoader(), transactions: newPagedLoader(), counts: newBatchLoader(), amounts: newBatchLoader()}
+}
+
+func (ps *payeeSource) setPayees(dbPayees []*table.Payee) []*Payee {
+	payees := make([]*Payee, len(dbPayees))
+	for i, payee := range dbPayees {
+		payees[i] = &Payee{source: ps, Payee: payee}
+		ps.byID.set(payee.ID, payees[i])
+		ps.transactions.add(payee.ID)
+		ps.counts.add(&payee.ID)
+		ps.amounts.add(&payee.ID)
+	}
+	return payees
+}
+
+// get returns the payee with the ID, loading all pending payees if necessary.
+func (ps *payeeSource) get(tx *database.Tx, id *int64) *Payee {
+	payee, _ := ps.byID.get(tx, id, func(tx *database.Tx, ids []int64) map[int64]interface{} {
+		byID := make(map[int64]interface{}, len(ids))
+		for _, payee := range ps.setPayees(getPayeesByIDs(tx, ids)) {
+			byID[payee.ID] = payee
+		}
+		return byID
+	}).(*Payee)
+	return payee
+}
+
+// GetAllPayees loads all payees.
+func GetAllPayees(tx *database.Tx) []*Payee {
+	return newPayeeSource().setPayees(getAllPayees(tx))
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Payee_Resolve(t *testing.T) {
+	defaultResolveStub := mocka.Function(t, &defaultResolveFn, "result", nil)
+	defer defaultResolveStub.Restore()
+	payee := &Payee{Payee: &table.Payee{ID: 42}}
+
+	result, err := payee.Resolve(graphql.ResolveParams{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "result", result)
+	assert.Same(t, payee.Payee, defaultResolveStub.GetCall(0).Arguments()[0].(graphql.ResolveParams).Source)
+}
+
+func Test_GetAllPayees(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		dbPayees := []*table.Payee{{ID: 1}, {ID: 2}}
+		getAllStub := mocka.Function(t, &getAllPayees, dbPayees)
+		defer getAllStub.Restore()
+
+		payees := GetAllPayees(tx)
+
+		assert.Len(t, payees, 2)
+		assert.Same(t, dbPayees[0], payees[0].Payee)
+		assert.Same(t, dbPayees[1], payees[1].Payee)
+		assert.Same(t, payees[0].source, payees[1].source)
+		assert.Equal(t, []interface{}{tx}, getAllStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_Payee_GetTransactions(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		payeeIDs := []int64{1, 2, 3}
+		getAllStub := mocka.Function(t, &getAllPayees, []*table.Payee{{ID: 1}, {ID: 2}, {ID: 3}})
+		defer getAllStub.Restore()
+		getTransactionsStub := mocka.Function(t, &getTransactionsByPayeeIDs, []*table.Transaction{
+			{ID: 10, PayeeID: &payeeIDs[0]},
+			{ID: 11, PayeeID: &payeeIDs[1]},
+			{ID: 12, PayeeID: &payeeIDs[0]},
+		})
+		defer getTransactionsStub.Restore()
+		filter := database.PageFilter{Limit: 2}
+		accountIDs := []int64{42}
+		payees := GetAllPayees(tx)
+
+		transactions := payees[0].GetTransactions(tx, filter, accountIDs)
+
+		assert.Len(t, transactions, 2)
+		assert.Equal(t, int64(10), transactions[0].ID)
+		assert.Equal(t, int64(12), transactions[1].ID)
+		assert.Equal(t, []int64{10, 11, 12}, transactions[0].source.txIDs)
+		assert.Equal(t, int64(11), payees[1].GetTransactions(tx, filter, accountIDs)[0].ID)
+		assert.Nil(t, payees[2].GetTransactions(tx, filter, accountIDs))
+		assert.Equal(t, 1, getTransactionsStub.CallCount())
+		args := getTransactionsStub.GetCall(0).Arguments()
+		assert.ElementsMatch(t, payeeIDs, args[1])
+		assert.Equal(t, filter, args[2])
+		assert.Equal(t, accountIDs, args[3])
+	})
+}
+
+func Test_Payee_GetAmount(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		getAllStub := mocka.Function(t, &getAllPayees, []*table.Payee{{ID: 1}, {ID: 2}})
+		defer getAllStub.Restore()
+		getAmountsStub := mocka.Function(t, &getPayeeAmounts, []*table.CurrencyAmount{
+			{ID: 1, CurrencyID: 1, Amount: 12.34},
+			{ID: 1, CurrencyID: 2, Amount: 10},
+		})
+		defer getAmountsStub.Restore()
+		getBaseCurrencyStub := mocka.Function(t, &getBaseCurrencyID, nil)
+		defer getBaseCurrencyStub.Restore()
+		getRatesStub := mocka.Function(t, &getLatestExchangeRates, []*table.ExchangeRate{{FromCurrencyID: 2, ToCurrencyID: 1, Rate: 1.5}})
+		defer getRatesStub.Restore()
+		getCurrenciesStub := mocka.Function(t, &getAllCurrencies, []*table.Currency{{Asset: table.Asset{ID: 1, Scale: 2}}})
+		defer getCurrenciesStub.Restore()
+		currencyID := int64(1)
+		accountIDs := []int64{42}
+		payees := GetAllPayees(tx)
+
+		amount1, err1 := payees[0].GetAmount(tx, &currencyID, accountIDs)
+		amount2, err2 := payees[1].GetAmount(tx, &currencyID, accountIDs)
+
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.Equal(t, "27.34", amount1)
+		assert.Equal(t, "0.00", amount2)
+		assert.ElementsMatch(t, []int64{1, 2}, getAmountsStub.GetCall(0).Arguments()[1])
+		assert.Equal(t, accountIDs, getAmountsStub.GetCall(0).Arguments()[2])
+		assert.Equal(t, 1, getAmountsStub.CallCount())
+	})
+}
+
+func Test_Payee_GetTransactionCount(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		getAllStub := mocka.Function(t, &getAllPayees, []*table.Payee{{ID: 1}, {ID: 2}})
+		defer getAllStub.Restore()
+		getCountsStub := mocka.Function(t, &getPayeeTransactionCounts, []*table.TransactionCount{{ID: 1, Count: 3}})
+		defer getCountsStub.Restore()
+		accountIDs := []int64{42}
+		payees := GetAllPayees(tx)
+
+		count1 := payees[0].GetTransactionCount(tx, accountIDs)
+		count2 := payees[1].GetTransactionCount(tx, accountIDs)
+
+		assert.Equal(t, int64(3), count1)
+		assert.Equal(t, int64(0), count2)
+		assert.ElementsMatch(t, []int64{1, 2}, getCountsStub.GetCall(0).Arguments()[1])
+		assert.Equal(t, accountIDs, getCountsStub.GetCall(0).Arguments()[2])
+		assert.Equal(t, 1, getCountsStub.CallCount())
+	})
+}
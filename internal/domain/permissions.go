@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"sort"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// Permissions determines which accounts a user can access. Admins can access all accounts.
+type Permissions struct {
+	admin       bool
+	byAccountID map[int64]string
+}
+
+// NewPermissions creates permissions using a map of account ID to permission.
+func NewPermissions(admin bool, byAccountID map[int64]string) *Permissions {
+	return &Permissions{admin: admin, byAccountID: byAccountID}
+}
+
+// GetPermissions loads the permissions of the user. Unknown users can't access any accounts.
+func GetPermissions(tx *database.Tx, userName string) *Permissions {
+	users := getUserByName(tx, userName)
+	if len(users) == 0 {
+		return NewPermissions(false, nil)
+	}
+	if users[0].Role == table.RoleAdmin {
+		return NewPermissions(true, nil)
+	}
+	byAccountID := make(map[int64]string)
+	for _, grant := range getAccountPermissions(tx, users[0].ID) {
+		byAccountID[grant.AccountID] = grant.Permission
+	}
+	return NewPermissions(false, byAccountID)
+}
+
+// IsAdmin returns true if the user has the admin role.
+func (p *Permissions) IsAdmin() bool {
+	return p.admin
+}
+
+// CanRead returns true if the user can view the account.
+func (p *Permissions) CanRead(accountID int64) bool {
+	permission := p.byAccountID[accountID]
+	return p.admin || permission == table.PermissionRead || permission == table.PermissionWrite
+}
+
+// CanWrite returns true if the user can change the account.
+func (p *Permissions) CanWrite(accountID int64) bool {
+	return p.admin || p.byAccountID[accountID] == table.PermissionWrite
+}
+
+// RequireAdmin returns a ForbiddenError if the user does not have the admin role.
+func (p *Permissions) RequireAdmin() error {
+	if !p.admin {
+		return apperror.Forbidden("admin role required")
+	}
+	return nil
+}
+
+// RequireRead returns a ForbiddenError if the user can't view any of the accounts.
+func (p *Permissions) RequireRead(accountIDs ...int64) error {
+	for _, id := range accountIDs {
+		if !p.CanRead(id) {
+			return apperror.AccountForbidden(id, "readable")
+		}
+	}
+	return nil
+}
+
+// RequireWrite returns a ForbiddenError if the user can't change any of the accounts.
+func (p *Permissions) RequireWrite(accountIDs ...int64) error {
+	for _, id := range accountIDs {
+		if !p.CanWrite(id) {
+			return apperror.AccountForbidden(id, "writable")
+		}
+	}
+	return nil
+}
+
+// ReadableAccountIDs returns the IDs of the accounts the user can view. Returns nil for admins, who can view all
+// accounts.
+func (p *Permissions) ReadableAccountIDs() []int64 {
+	if p.admin {
+		return nil
+	}
+	ids := make([]int64, 0, len(p.byAccountID))
+	for id := range p.byAccountID {
+		if p.CanRead(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// WritableAccountIDs returns the IDs of the accounts granted write permission. Not applicable to admins.
+func (p *Permissions) WritableAccountIDs() []int64 {
+	ids := make([]int64, 0, len(p.byAccountID))
+	for id, permission := range p.byAccountID {
+		if permission == table.PermissionWrite {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// FilterAccounts returns the accounts that the user can view.
+func (p *Permissions) FilterAccounts(accounts []*Account) []*Account {
+	if p.admin {
+		return accounts
+	}
+	readable := make([]*Account, 0, len(accounts))
+	for _, account := range accounts {
+		if p.CanRead(account.ID) {
+			readable = append(readable, account)
+		}
+	}
+	return readable
+}
+
+// RequireTransactionWrite returns a ForbiddenError if the user can't change all of the accounts affected by the
+// transaction changes.
+// The affected accounts include the accounts of the existing transactions and their transfers, the new accounts of
+// the changes and the transfer accounts of the detail changes.
+func (p *Permissions) RequireTransactionWrite(tx *database.Tx, txIDs []int64, changes []map[string]interface{}) error {
+	if p.admin {
+		return nil
+	}
+	accountIDs := newIDSet()
+	if len(txIDs) > 0 {
+		for _, id := range getTransactionAccountIDs(tx, txIDs) {
+			accountIDs.Add(id)
+		}
+	}
+	for _, change := range changes {
+		if id, ok := database.InputObject(change).IntOrNull("accountId").(int64); ok {
+			accountIDs.Add(id)
+		}
+		if details, ok := change["details"].([]map[string]interface{}); ok {
+			for _, detail := range details {
+				if id, ok := database.InputObject(detail).IntOrNull("transferAccountId").(int64); ok {
+					accountIDs.Add(id)
+				}
+			}
+		}
+	}
+	ids := accountIDs.Values()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return p.RequireWrite(ids...)
+}
+
+// RequireImportWrite returns a ForbiddenError if the user can't change the accounts of the import items.
+func (p *Permissions) RequireImportWrite(tx *database.Tx, ids []int64) error {
+	if !p.admin && len(ids) > 0 {
+		return p.RequireWrite(getImportItemAccountIDs(tx, ids)...)
+	}
+	return nil
+}
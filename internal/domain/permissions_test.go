@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func expectPanic(t *testing.T, message string) {
+	if err := recover(); err != nil {
+		assert.Equal(t, message, err.(error).Error())
+	} else {
+		assert.Fail(t, "expected an error")
+	}
+}
+
+func Test_GetPermissions(t *testing.T) {
+	t.Run("returns no access for unknown user", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getUserStub := mocka.Function(t, &getUserByName, []*table.User{})
+			defer getUserStub.Restore()
+
+			result := GetPermissions(tx, "somebody")
+
+			assert.Equal(t, NewPermissions(false, nil), result)
+			assert.Equal(t, []interface{}{tx, "somebody"}, getUserStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("returns admin", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getUserStub := mocka.Function(t, &getUserByName, []*table.User{{ID: 42, Role: table.RoleAdmin}})
+			defer getUserStub.Restore()
+			getPermissionsStub := mocka.Function(t, &getAccountPermissions, nil)
+			defer getPermissionsStub.Restore()
+
+			result := GetPermissions(tx, "somebody")
+
+			assert.True(t, result.IsAdmin())
+			assert.Equal(t, 0, getPermissionsStub.CallCount())
+		})
+	})
+	t.Run("returns account permissions", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getUserStub := mocka.Function(t, &getUserByName, []*table.User{{ID: 42, Role: "user"}})
+			defer getUserStub.Restore()
+			grants := []*table.AccountPermission{
+				{AccountID: 1, Permission: table.PermissionRead},
+				{AccountID: 2, Permission: table.PermissionWrite},
+			}
+			getPermissionsStub := mocka.Function(t, &getAccountPermissions, grants)
+			defer getPermissionsStub.Restore()
+
+			result := GetPermissions(tx, "somebody")
+
+			assert.Equal(t, NewPermissions(false, map[int64]string{1: table.PermissionRead, 2: table.PermissionWrite}), result)
+			assert.Equal(t, []interface{}{tx, int64(42)}, getPermissionsStub.GetFirstCall().Arguments())
+		})
+	})
+}
+
+func Test_Permissions_CanRead_CanWrite(t *testing.T) {
+	user := NewPermissions(false, map[int64]string{1: table.PermissionRead, 2: table.PermissionWrite, 3: table.PermissionNone})
+	admin := NewPermissions(true, nil)
+	tests := []struct {
+		name        string
+		permissions *Permissions
+		accountID   int64
+		canRead     bool
+		canWrite    bool
+	}{
+		{"read", user, 1, true, false},
+		{"write", user, 2, true, true},
+		{"none", user, 3, false, false},
+		{"not granted", user, 4, false, false},
+		{"admin", admin, 4, true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.canRead, test.permissions.CanRead(test.accountID))
+			assert.Equal(t, test.canWrite, test.permissions.CanWrite(test.accountID))
+		})
+	}
+}
+
+func Test_Permissions_RequireAdmin(t *testing.T) {
+	assert.Nil(t, NewPermissions(true, nil).RequireAdmin())
+	assert.Equal(t, apperror.Forbidden("admin role required"), NewPermissions(false, nil).RequireAdmin())
+}
+
+func Test_Permissions_RequireRead(t *testing.T) {
+	permissions := NewPermissions(false, map[int64]string{1: table.PermissionRead})
+
+	assert.Nil(t, permissions.RequireRead(1))
+	assert.Equal(t, apperror.AccountForbidden(2, "readable"), permissions.RequireRead(1, 2))
+}
+
+func Test_Permissions_RequireWrite(t *testing.T) {
+	permissions := NewPermissions(false, map[int64]string{1: table.PermissionWrite, 2: table.PermissionRead})
+
+	assert.Nil(t, permissions.RequireWrite(1))
+	assert.Equal(t, apperror.AccountForbidden(2, "writable"), permissions.RequireWrite(1, 2))
+}
+
+func Test_Permissions_ReadableAccountIDs(t *testing.T) {
+	t.Run("returns readable accounts", func(t *testing.T) {
+		permissions := NewPermissions(false, map[int64]string{3: table.PermissionWrite, 1: table.PermissionRead, 2: "none"})
+
+		assert.Equal(t, []int64{1, 3}, permissions.ReadableAccountIDs())
+	})
+	t.Run("returns nil for admin", func(t *testing.T) {
+		assert.Nil(t, NewPermissions(true, nil).ReadableAccountIDs())
+	})
+}
+
+func Test_Permissions_WritableAccountIDs(t *testing.T) {
+	permissions := NewPermissions(false, map[int64]string{3: table.PermissionWrite, 1: table.PermissionWrite, 2: table.PermissionRead})
+
+	assert.Equal(t, []int64{1, 3}, permissions.WritableAccountIDs())
+}
+
+func Test_Permissions_FilterAccounts(t *testing.T) {
+	accounts := []*Account{NewAccount(1, nil), NewAccount(2, nil), NewAccount(3, nil)}
+	t.Run("returns readable accounts", func(t *testing.T) {
+		permissions := NewPermissions(false, map[int64]string{1: table.PermissionRead, 3: table.PermissionWrite})
+
+		assert.Equal(t, []*Account{accounts[0], accounts[2]}, permissions.FilterAccounts(accounts))
+	})
+	t.Run("returns all accounts for admin", func(t *testing.T) {
+		assert.Equal(t, accounts, NewPermissions(true, nil).FilterAccounts(accounts))
+	})
+}
+
+func Test_Permissions_RequireTransactionWrite(t *testing.T) {
+	changes := []map[string]interface{}{
+		{"accountId": 3, "details": []map[string]interface{}{{"transferAccountId": 4}, {"transferAccountId": nil}}},
+	}
+	t.Run("checks affected accounts", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getAccountIDsStub := mocka.Function(t, &getTransactionAccountIDs, []int64{1, 2})
+			defer getAccountIDsStub.Restore()
+			permissions := NewPermissions(false, map[int64]string{1: table.PermissionWrite, 2: table.PermissionWrite, 3: table.PermissionWrite})
+
+			err := permissions.RequireTransactionWrite(tx, []int64{42}, changes)
+
+			assert.Equal(t, apperror.AccountForbidden(4, "writable"), err)
+		})
+	})
+	t.Run("allows writable accounts", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getAccountIDsStub := mocka.Function(t, &getTransactionAccountIDs, []int64{1})
+			defer getAccountIDsStub.Restore()
+			permissions := NewPermissions(false, map[int64]string{1: table.PermissionWrite, 3: table.PermissionWrite, 4: table.PermissionWrite})
+
+			err := permissions.RequireTransactionWrite(tx, []int64{42}, changes)
+
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{tx, []int64{42}}, getAccountIDsStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("skips query for admin", func(t *testing.T) {
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			getAccountIDsStub := mocka.Function(t, &getTransactionAccountIDs, []int64{1})
+			defer getAccountIDsStub.Restore()
+
+			err := NewPermissions(true, nil).RequireTransactionWrite(tx, []int64{42}, changes)
+
+			assert.Nil(t, err)
+			assert.Equal(t, 0, getAccountIDsStub.CallCount())
+		})
+	})
+}
+
+func Test_Permissions_RequireImportWrite(t *testing.T) {
+	dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+		getAccountIDsStub := mocka.Function(t, &getImportItemAccountIDs, []int64{1, 2})
+		defer getAccountIDsStub.Restore()
+		permissions := NewPermissions(false, map[int64]string{1: table.PermissionWrite})
+
+		err := permissions.RequireImportWrite(tx, []int64{42})
+
+		assert.Equal(t, apperror.AccountForbidden(2, "writable"), err)
+	})
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// batchLoader loads rows by ID for a GraphQL request. The IDs are collected as the transactions and details are
+// loaded, so the first lookup loads the rows for all of them with a single query. An ID that is added after a
+// lookup is loaded with the next batch.
+type batchLoader struct {
+	pending *idSet
+	byID    map[int64]interface{}
+}
+
+// loadFn returns the rows for the IDs, keyed by ID.
+type loadFn func(tx *database.Tx, ids []int64) map[int64]interface{}
+
+func newBatchLoader() *batchLoader {
+	return &batchLoader{pending: newIDSet(), byID: make(map[int64]interface{})}
+}
+
+// add queues the ID for the next batch if it hasn't been loaded.
+func (l *batchLoader) add(id *int64) {
+	if id != nil {
+		if _, ok := l.byID[*id]; !ok {
+			l.pending.Add(*id)
+		}
+	}
+}
+
+// set stores a row that has already been loaded.
+func (l *batchLoader) set(id int64, row interface{}) {
+	l.pending.Remove(id)
+	l.byID[id] = row
+}
+
+// get returns the row for the ID, loading the pending IDs if the row hasn't been loaded. Returns nil if id is nil or
+// the row doesn't exist.
+func (l *batchLoader) get(tx *database.Tx, id *int64, load loadFn) interface{} {
+	if id == nil {
+		return nil
+	}
+	if _, ok := l.byID[*id]; !ok {
+		l.pending.Add(*id)
+		ids := l.pending.Values()
+		// the load may queue IDs for the next batch
+		l.pending = newIDSet()
+		rows := load(tx, ids)
+		for _, id := range ids {
+			l.byID[id] = rows[id]
+		}
+	}
+	return l.byID[*id]
+}
+
+// pagedLoader loads the transactions or details of parent rows (e.g. payees) using a batchLoader for each page
+// filter. The first lookup for a filter loads the page for all of the parents with a single query.
+type pagedLoader struct {
+	parentIDs []int64
+	byFilter  map[database.PageFilter]*batchLoader
+}
+
+func newPagedLoader() *pagedLoader {
+	return &pagedLoader{byFilter: make(map[database.PageFilter]*batchLoader)}
+}
+
+// add includes the parent in the next batch for each filter.
+func (l *pagedLoader) add(parentID int64) {
+	l.parentIDs = append(l.parentIDs, parentID)
+	for _, loader := range l.byFilter {
+		loader.add(&parentID)
+	}
+}
+
+// get returns the page of children of the parent. The load function must use the same account IDs for every call
+// with the filter.
+func (l *pagedLoader) get(tx *database.Tx, parentID int64, filter database.PageFilter, load loadFn) interface{} {
+	loader, ok := l.byFilter[filter]
+	if !ok {
+		loader = newBatchLoader()
+		for i := range l.parentIDs {
+			loader.add(&l.parentIDs[i])
+		}
+		l.byFilter[filter] = loader
+	}
+	return loader.get(tx, &parentID, load)
+}
+
+// referenceSource provides the payees, accounts, securities, categories, groups, assets and transactions of the
+// transactions and details of a transactionSource.
+type referenceSource struct {
+	payees       *payeeSource
+	accounts     *batchLoader
+	securities   *batchLoader
+	categories   *categorySource
+	groups       *groupSource
+	assets       *batchLoader
+	transactions *batchLoader
+}
+
+func newReferenceSource() *referenceSource {
+	return &referenceSource{
+		payees:       newPayeeSource(),
+		accounts:     newBatchLoader(),
+		securities:   newBatchLoader(),
+		categories:   newCategorySource(),
+		groups:       newGroupSource(),
+		assets:       newBatchLoader(),
+		transactions: newBatchLoader(),
+	}
+}
+
+func (rs *referenceSource) addTransaction(transaction *table.Transaction) {
+	rs.payees.byID.add(transaction.PayeeID)
+	rs.accounts.add(&transaction.AccountID)
+	rs.securities.add(transaction.SecurityID)
+}
+
+func (rs *referenceSource) addDetail(detail *table.TransactionDetail) {
+	rs.categories.byID.add(detail.TransactionCategoryID)
+	rs.groups.byID.add(detail.TransactionGroupID)
+	rs.assets.add(detail.ExchangeAssetID)
+}
+
+func loadAccounts(tx *database.Tx, ids []int64) map[int64]interface{} {
+	byID := make(map[int64]interface{}, len(ids))
+	for _, account := range newCompanySource().setAccounts(getAccountsByIDs(tx, ids), false) {
+		byID[account.ID] = account
+	}
+	return byID
+}
+
+func loadSecurities(tx *database.Tx, ids []int64) map[int64]interface{} {
+	byID := make(map[int64]interface{}, len(ids))
+	for _, security := range newSecuritySource().setSecurities(getSecuritiesByIDs(tx, ids)) {
+		byID[security.ID] = security
+	}
+	return byID
+}
+
+func loadAssets(tx *database.Tx, ids []int64) map[int64]interface{} {
+	byID := make(map[int64]interface{}, len(ids))
+	for _, asset := range getAssetsByIDs(tx, ids) {
+		byID[asset.ID] = asset
+	}
+	return byID
+}
@@ -0,0 +1,173 @@
+package domain
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func Test_batchLoader_get(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		batches := make([][]int64, 0)
+		load := func(tx *database.Tx, ids []int64) map[int64]interface{} {
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			batches = append(batches, ids)
+			rows := make(map[int64]interface{})
+			for _, id := range ids {
+				if id != 3 {
+					rows[id] = id * 10
+				}
+			}
+			return rows
+		}
+		loader := newBatchLoader()
+		loader.add(int64Ptr(1))
+		loader.add(int64Ptr(2))
+		loader.add(nil)
+
+		assert.Nil(t, loader.get(tx, nil, load))
+		assert.Equal(t, int64(20), loader.get(tx, int64Ptr(2), load))
+		assert.Equal(t, int64(10), loader.get(tx, int64Ptr(1), load))
+		loader.add(int64Ptr(1))
+		assert.Nil(t, loader.get(tx, int64Ptr(3), load))
+		assert.Nil(t, loader.get(tx, int64Ptr(3), load))
+
+		assert.Equal(t, [][]int64{{1, 2}, {3}}, batches)
+	})
+}
+
+func Test_Transaction_references(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		payee := &table.Payee{ID: 1}
+		security := &table.Security{Asset: table.Asset{ID: 3}}
+		account := &table.Account{ID: 2}
+		getPayeesStub := mocka.Function(t, &getPayeesByIDs, []*table.Payee{payee})
+		defer getPayeesStub.Restore()
+		getAccountsStub := mocka.Function(t, &getAccountsByIDs, []*table.Account{account})
+		defer getAccountsStub.Restore()
+		getSecuritiesStub := mocka.Function(t, &getSecuritiesByIDs, []*table.Security{security})
+		defer getSecuritiesStub.Restore()
+		source := &transactionSource{txIDs: []int64{10, 11}}
+		transactions := source.setSource([]*table.Transaction{
+			{ID: 10, AccountID: 2, PayeeID: &payee.ID, SecurityID: &security.ID},
+			{ID: 11, AccountID: 2},
+		})
+
+		assert.Same(t, payee, transactions[0].GetPayee(tx).Payee)
+		assert.Nil(t, transactions[1].GetPayee(tx))
+		assert.Same(t, account, transactions[0].GetAccount(tx).Account)
+		assert.Same(t, transactions[0].GetAccount(tx), transactions[1].GetAccount(tx))
+		assert.Same(t, security, transactions[0].GetSecurity(tx).Security)
+		assert.Nil(t, transactions[1].GetSecurity(tx))
+
+		assert.Equal(t, []interface{}{tx, []int64{1}}, getPayeesStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, []int64{2}}, getAccountsStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, []int64{3}}, getSecuritiesStub.GetCall(0).Arguments())
+		assert.Equal(t, 1, getPayeesStub.CallCount())
+		assert.Equal(t, 1, getAccountsStub.CallCount())
+		assert.Equal(t, 1, getSecuritiesStub.CallCount())
+	})
+}
+
+func Test_TransactionDetail_references(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		category := &table.Category{ID: 1}
+		group := &table.Group{ID: 2}
+		asset := &table.Asset{ID: 3}
+		getCategoriesStub := mocka.Function(t, &getCategoriesByIDs, []*table.Category{category})
+		defer getCategoriesStub.Restore()
+		getGroupsStub := mocka.Function(t, &getGroupsByIDs, []*table.Group{group})
+		defer getGroupsStub.Restore()
+		getAssetsStub := mocka.Function(t, &getAssetsByIDs, []*table.Asset{asset})
+		defer getAssetsStub.Restore()
+		source := &transactionSource{txIDs: []int64{10}}
+		source.setDetails([]*table.TransactionDetail{
+			{ID: 20, TransactionID: 10, TransactionCategoryID: &category.ID, TransactionGroupID: &group.ID, ExchangeAssetID: &asset.ID},
+			{ID: 21, TransactionID: 10},
+		})
+		details := source.detailsByTxID[10]
+
+		assert.Same(t, category, details[0].GetCategory(tx).Category)
+		assert.Nil(t, details[1].GetCategory(tx))
+		assert.Same(t, group, details[0].GetGroup(tx).Group)
+		assert.Nil(t, details[1].GetGroup(tx))
+		assert.Same(t, asset, details[0].GetExchangeAsset(tx))
+		assert.Nil(t, details[1].GetExchangeAsset(tx))
+
+		assert.Equal(t, []interface{}{tx, []int64{1}}, getCategoriesStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, []int64{2}}, getGroupsStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, []int64{3}}, getAssetsStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_pagedLoader_get(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		batches := make([][]int64, 0)
+		load := func(tx *database.Tx, ids []int64) map[int64]interface{} {
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			batches = append(batches, ids)
+			rows := make(map[int64]interface{})
+			for _, id := range ids {
+				rows[id] = id * 10
+			}
+			return rows
+		}
+		filter1 := database.PageFilter{Limit: 10}
+		filter2 := database.PageFilter{Limit: 10, Offset: 10}
+		loader := newPagedLoader()
+		loader.add(1)
+		loader.add(2)
+
+		assert.Equal(t, int64(10), loader.get(tx, 1, filter1, load))
+		assert.Equal(t, int64(20), loader.get(tx, 2, filter1, load))
+		loader.add(3)
+		assert.Equal(t, int64(30), loader.get(tx, 3, filter1, load))
+		assert.Equal(t, int64(20), loader.get(tx, 2, filter2, load))
+
+		assert.Equal(t, [][]int64{{1, 2}, {3}, {1, 2, 3}}, batches)
+	})
+}
+
+func Test_TransactionDetail_GetTransaction(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		t.Run("loads transactions of details", func(t *testing.T) {
+			getTransactionsStub := mocka.Function(t, &getTransactionsByIDs, []*table.Transaction{{ID: 10}, {ID: 11}})
+			defer getTransactionsStub.Restore()
+			details := newDetailSource([]*table.TransactionDetail{{ID: 20, TransactionID: 10}, {ID: 21, TransactionID: 11}})
+
+			transaction := details[0].GetTransaction(tx)
+
+			assert.Equal(t, int64(10), transaction.ID)
+			assert.Same(t, details[1].txSource, transaction.source)
+			assert.Equal(t, int64(11), details[1].GetTransaction(tx).ID)
+			assert.Equal(t, 1, getTransactionsStub.CallCount())
+			ids := getTransactionsStub.GetCall(0).Arguments()[1].([]int64)
+			assert.ElementsMatch(t, []int64{10, 11}, ids)
+			assert.ElementsMatch(t, []int64{10, 11}, details[0].txSource.txIDs)
+		})
+		t.Run("returns loaded transaction", func(t *testing.T) {
+			getTransactionsStub := mocka.Function(t, &getTransactions, []*table.Transaction{{ID: 10}})
+			defer getTransactionsStub.Restore()
+			getTransactionsByIDsStub := mocka.Function(t, &getTransactionsByIDs, nil)
+			defer getTransactionsByIDsStub.Restore()
+			getDetailsStub := mocka.Function(t, &getDetailsByAccountID, []*table.TransactionDetail{{ID: 20, TransactionID: 10}})
+			defer getDetailsStub.Restore()
+			transactions := GetTransactions(tx, 42)
+
+			detail := transactions[0].GetDetails(tx)[0]
+
+			assert.Same(t, transactions[0], detail.GetTransaction(tx))
+			assert.Equal(t, 0, getTransactionsByIDsStub.CallCount())
+		})
+	})
+}
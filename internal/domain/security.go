@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// Security is an investment asset.
+type Security struct {
+	source *securitySource
+	*table.Security
+}
+
+func (s *Security) Resolve(p graphql.ResolveParams) (interface{}, error) {
+	return defaultResolveFn(replaceSource(p, s.Security))
+}
+
+// GetShares returns the number of shares held in the accounts. Shares in all accounts are included if accountIDs is
+// nil. The load uses the same account IDs for every security in the request.
+func (s *Security) GetShares(tx *database.Tx, accountIDs []int64) float64 {
+	shares, _ := s.source.shares.get(tx, &s.ID, func(tx *database.Tx, ids []int64) map[int64]interface{} {
+		byID := make(map[int64]interface{})
+		for id, shares := range getSecurityShares(tx, ids, accountIDs) {
+			byID[id] = shares
+		}
+		return byID
+	}).(float64)
+	return shares
+}
+
+// GetFirstAcquired returns the date of the first transaction in the accounts. Returns nil if the security has no
+// transactions in the accounts.
+func (s *Security) GetFirstAcquired(tx *database.Tx, accountIDs []int64) *time.Time {
+	var firstAcquired *time.Time
+	for _, amount := range s.source.getAmounts(tx, s.ID, accountIDs) {
+		if firstAcquired == nil || amount.FirstAcquired != nil && amount.FirstAcquired.Before(*firstAcquired) {
+			firstAcquired = amount.FirstAcquired
+		}
+	}
+	return firstAcquired
+}
+
+// GetTransactionCount returns the number of transactions in the accounts.
+func (s *Security) GetTransactionCount(tx *database.Tx, accountIDs []int64) int64 {
+	var count int64
+	for _, amount := range s.source.getAmounts(tx, s.ID, accountIDs) {
+		count += amount.TransactionCount
+	}
+	return count
+}
+
+// GetCostBasis returns the cost basis of the shares held in the accounts, converted to the currency. Returns nil if
+// the security has no transactions in the accounts. Returns a NotFoundError if the currency or an exchange rate
+// doesn't exist.
+func (s *Security) GetCostBasis(tx *database.Tx, currencyID *int64, accountIDs []int64) (*string, error) {
+	amounts := s.source.getAmounts(tx, s.ID, accountIDs)
+	if len(amounts) == 0 {
+		return nil, nil
+	}
+	costBasis := make(map[int64]float64)
+	for _, amount := range amounts {
+		costBasis[amount.CurrencyID] += amount.CostBasis
+	}
+	total, err := s.source.total(tx, costBasis, currencyID)
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// GetDividends returns the dividends received in the accounts, converted to the currency. Returns nil if the security
+// has no transactions in the accounts. Returns a NotFoundError if the currency or an exchange rate doesn't exist.
+func (s *Security) GetDividends(tx *database.Tx, currencyID *int64, accountIDs []int64) (*string, error) {
+	amounts := s.source.getAmounts(tx, s.ID, accountIDs)
+	if len(amounts) == 0 {
+		return nil, nil
+	}
+	dividends := make(map[int64]float64)
+	for _, amount := range amounts {
+		dividends[amount.CurrencyID] += amount.Dividends
+	}
+	total, err := s.source.total(tx, dividends, currencyID)
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// securitySource provides securities, their shares and their amounts in each currency for a GraphQL request.
+type securitySource struct {
+	shares  *batchLoader
+	amounts *batchLoader
+	currencyConverter
+}
+
+func newSecuritySource() *securitySource {
+	return &securitySource{shares: newBatchLoader(), amounts: newBatchLoader()}
+}
+
+func (ss *securitySource) setSecurities(dbSecurities []*table.Security) []*Security {
+	securities := make([]*Security, len(dbSecurities))
+	for i, security := range dbSecurities {
+		securities[i] = &Security{source: ss, Security: security}
+		ss.shares.add(&security.ID)
+		ss.amounts.add(&security.ID)
+	}
+	return securities
+}
+
+// getAmounts returns the transaction count, first acquired date, cost basis and dividends of the security in the
+// accounts for each currency, loading the amounts of all pending securities if necessary. The load uses the same
+// account IDs for every security in the request.
+func (ss *securitySource) getAmounts(tx *database.Tx, id int64, accountIDs []int64) []*table.SecurityAmount {
+	amounts, _ := ss.amounts.get(tx, &id, func(tx *database.Tx, ids []int64) map[int64]interface{} {
+		byID := make(map[int64]interface{})
+		for _, amount := range getSecurityAmounts(tx, ids, accountIDs) {
+			securityAmounts, _ := byID[amount.SecurityID].([]*table.SecurityAmount)
+			byID[amount.SecurityID] = append(securityAmounts, amount)
+		}
+		return byID
+	}).([]*table.SecurityAmount)
+	return amounts
+}
+
+// GetAllSecurities loads all securities.
+func GetAllSecurities(tx *database.Tx) []*Security {
+	return newSecuritySource().setSecurities(getAllSecurities(tx))
+}
+
+// GetSecurityByID returns the security with ID.
+func GetSecurityByID(tx *database.Tx, id int64) []*Security {
+	return newSecuritySource().setSecurities(getSecurityByID(tx, id))
+}
+
+// GetSecurityBySymbol returns the security for the symbol.
+func GetSecurityBySymbol(tx *database.Tx, symbol string) []*Security {
+	return newSecuritySource().setSecurities(getSecurityBySymbol(tx, symbol))
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Security_Resolve(t *testing.T) {
+	defaultResolveStub := mocka.Function(t, &defaultResolveFn, "result", nil)
+	defer defaultResolveStub.Restore()
+	security := &Security{Security: &table.Security{AssetID: 42}}
+
+	result, err := security.Resolve(graphql.ResolveParams{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "result", result)
+	assert.Same(t, security.Security, defaultResolveStub.GetCall(0).Arguments()[0].(graphql.ResolveParams).Source)
+}
+
+func Test_GetSecurities(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		dbSecurities := []*table.Security{{Asset: table.Asset{ID: 1}}, {Asset: table.Asset{ID: 2}}}
+		getAllStub := mocka.Function(t, &getAllSecurities, dbSecurities)
+		defer getAllStub.Restore()
+		getByIDStub := mocka.Function(t, &getSecurityByID, dbSecurities[:1])
+		defer getByIDStub.Restore()
+		getBySymbolStub := mocka.Function(t, &getSecurityBySymbol, dbSecurities[1:])
+		defer getBySymbolStub.Restore()
+
+		securities := GetAllSecurities(tx)
+		byID := GetSecurityByID(tx, 1)
+		bySymbol := GetSecurityBySymbol(tx, "S2")
+
+		assert.Len(t, securities, 2)
+		assert.Same(t, dbSecurities[0], securities[0].Security)
+		assert.Same(t, dbSecurities[1], securities[1].Security)
+		assert.Same(t, securities[0].source, securities[1].source)
+		assert.Same(t, dbSecurities[0], byID[0].Security)
+		assert.Same(t, dbSecurities[1], bySymbol[0].Security)
+		assert.Equal(t, []interface{}{tx}, getAllStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, int64(1)}, getByIDStub.GetCall(0).Arguments())
+		assert.Equal(t, []interface{}{tx, "S2"}, getBySymbolStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_Security_amounts(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		usdID := int64(1)
+		date1 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		date2 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		accountIDs := []int64{7}
+		getAllStub := mocka.Function(t, &getAllSecurities, []*table.Security{
+			{Asset: table.Asset{ID: 42}},
+			{Asset: table.Asset{ID: 96}},
+		})
+		defer getAllStub.Restore()
+		getAmountsStub := mocka.Function(t, &getSecurityAmounts, []*table.SecurityAmount{
+			{SecurityID: 42, CurrencyID: 1, TransactionCount: 2, FirstAcquired: &date2, CostBasis: 50, Dividends: 1},
+			{SecurityID: 42, CurrencyID: 2, TransactionCount: 3, FirstAcquired: &date1, CostBasis: 50, Dividends: 4},
+		})
+		defer getAmountsStub.Restore()
+		getBaseCurrencyStub := mocka.Function(t, &getBaseCurrencyID, &usdID)
+		defer getBaseCurrencyStub.Restore()
+		getRatesStub := mocka.Function(t, &getLatestExchangeRates, []*table.ExchangeRate{{FromCurrencyID: 2, ToCurrencyID: 1, Rate: 1.1}})
+		defer getRatesStub.Restore()
+		getCurrenciesStub := mocka.Function(t, &getAllCurrencies, []*table.Currency{{Asset: table.Asset{ID: 1, Scale: 2}}})
+		defer getCurrenciesStub.Restore()
+		securities := GetAllSecurities(tx)
+
+		totalCost, err := securities[0].GetCostBasis(tx, nil, accountIDs)
+		assert.Nil(t, err)
+		assert.Equal(t, "105.00", *totalCost)
+		totalDividends, err := securities[0].GetDividends(tx, &usdID, accountIDs)
+		assert.Nil(t, err)
+		assert.Equal(t, "5.40", *totalDividends)
+		assert.Equal(t, int64(5), securities[0].GetTransactionCount(tx, accountIDs))
+		assert.Equal(t, &date1, securities[0].GetFirstAcquired(tx, accountIDs))
+		totalCost, err = securities[1].GetCostBasis(tx, nil, accountIDs)
+		assert.Nil(t, err)
+		assert.Nil(t, totalCost)
+		totalDividends, err = securities[1].GetDividends(tx, nil, accountIDs)
+		assert.Nil(t, err)
+		assert.Nil(t, totalDividends)
+		assert.Equal(t, int64(0), securities[1].GetTransactionCount(tx, accountIDs))
+		assert.Nil(t, securities[1].GetFirstAcquired(tx, accountIDs))
+		assert.ElementsMatch(t, []int64{42, 96}, getAmountsStub.GetCall(0).Arguments()[1])
+		assert.Equal(t, accountIDs, getAmountsStub.GetCall(0).Arguments()[2])
+		assert.Equal(t, 1, getAmountsStub.CallCount())
+	})
+}
+
+func Test_Security_GetShares(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		accountIDs := []int64{7}
+		getAllStub := mocka.Function(t, &getAllSecurities, []*table.Security{
+			{Asset: table.Asset{ID: 42}},
+			{Asset: table.Asset{ID: 96}},
+		})
+		defer getAllStub.Restore()
+		getSharesStub := mocka.Function(t, &getSecurityShares, map[int64]float64{42: 12.5})
+		defer getSharesStub.Restore()
+		securities := GetAllSecurities(tx)
+
+		assert.Equal(t, 12.5, securities[0].GetShares(tx, accountIDs))
+		assert.Equal(t, 0.0, securities[1].GetShares(tx, accountIDs))
+		assert.ElementsMatch(t, []int64{42, 96}, getSharesStub.GetCall(0).Arguments()[1])
+		assert.Equal(t, accountIDs, getSharesStub.GetCall(0).Arguments()[2])
+		assert.Equal(t, 1, getSharesStub.CallCount())
+	})
+}
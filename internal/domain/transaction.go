@@ -1,10 +1,10 @@
 package domain
 
 import (
-	"database/sql"
-	"errors"
+	"fmt"
 
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/database/table"
 )
@@ -29,72 +29,149 @@ func (t *Transaction) Resolve(p graphql.ResolveParams) (interface{}, error) {
 }
 
 // GetDetails returns details for a transaction in the account.
-func (t *Transaction) GetDetails(tx *sql.Tx) []*TransactionDetail {
+func (t *Transaction) GetDetails(tx *database.Tx) []*TransactionDetail {
 	t.source.loadTransactionDetails(tx)
 	return t.source.detailsByTxID[t.ID]
 }
 
+// GetAttachments returns the files attached to the transaction.
+func (t *Transaction) GetAttachments(tx *database.Tx) []*table.Attachment {
+	t.source.loadAttachments(tx)
+	return t.source.attachmentsByTxID[t.ID]
+}
+
+// GetPayee returns the payee of the transaction.
+func (t *Transaction) GetPayee(tx *database.Tx) *Payee {
+	return t.source.references().payees.get(tx, t.PayeeID)
+}
+
+// GetAccount returns the account of the transaction.
+func (t *Transaction) GetAccount(tx *database.Tx) *Account {
+	account, _ := t.source.references().accounts.get(tx, &t.AccountID, loadAccounts).(*Account)
+	return account
+}
+
+// GetSecurity returns the security of the transaction.
+func (t *Transaction) GetSecurity(tx *database.Tx) *Security {
+	security, _ := t.source.references().securities.get(tx, t.SecurityID, loadSecurities).(*Security)
+	return security
+}
+
 // GetTransactions returns all transactions for the account.
-func GetTransactions(tx *sql.Tx, accountID int64) []*Transaction {
+func GetTransactions(tx *database.Tx, accountID int64) []*Transaction {
 	at := &transactionSource{accountID: accountID}
 	return at.setSource(getTransactions(tx, accountID))
 }
 
 // GetTransactionsByIDs returns transactions for the specified IDs.
-func GetTransactionsByIDs(tx *sql.Tx, ids []int64) []*Transaction {
+func GetTransactionsByIDs(tx *database.Tx, ids []int64) []*Transaction {
 	source := &transactionSource{txIDs: ids}
 	return source.setSource(getTransactionsByIDs(tx, ids))
 }
 
 // InsertTransactions inserts transactions.
-func InsertTransactions(tx *sql.Tx, accountID int64, inserts []map[string]interface{}, user string) []int64 {
+func InsertTransactions(tx *database.Tx, accountID int64, inserts []map[string]interface{}, user string) ([]int64, error) {
 	ids := make([]int64, len(inserts))
 	for i, transaction := range inserts {
-		if detailsArg, ok := transaction["details"]; !ok {
-			panic(errors.New("new transaction requires at least 1 detail"))
-		} else {
-			details := detailsArg.([]map[string]interface{})
-			if len(details) < 1 {
-				panic(errors.New("new transaction requires at least 1 detail"))
-			}
-			ids[i] = insertTransaction(tx, accountID, transaction, user)
-			for _, detail := range details {
-				if amount, ok := database.InputObject(detail).GetFloat("amount"); ok && amount != nil {
-					insertDetail(tx, ids[i], amount, detail, user)
-				} else {
-					panic(errors.New("new transaction detail requires amount"))
-				}
+		details, _ := transaction["details"].([]map[string]interface{})
+		if len(details) < 1 {
+			return nil, apperror.Validation(fmt.Sprintf("add[%d].details", i), "new transaction requires at least 1 detail")
+		}
+		ids[i] = insertTransaction(tx, accountID, transaction, user)
+		for j, detail := range details {
+			if amount, ok := database.InputObject(detail).GetFloat("amount"); ok && amount != nil {
+				insertDetail(tx, ids[i], amount, detail, user)
+			} else {
+				return nil, apperror.Validation(fmt.Sprintf("add[%d].details[%d].amount", i, j), "new transaction detail requires amount")
 			}
 		}
 	}
 	if len(ids) > 0 {
-		validateDetails(tx, ids)
+		if err := validateDetails(tx, ids); err != nil {
+			return nil, err
+		}
 	}
-	return ids
+	return ids, nil
 }
 
 // UpdateTransactions updates transactions.
-func UpdateTransactions(tx *sql.Tx, updates []map[string]interface{}, user string) []int64 {
+func UpdateTransactions(tx *database.Tx, updates []map[string]interface{}, user string) ([]int64, error) {
 	ids := make([]int64, len(updates))
 	for i, transaction := range updates {
 		values := database.InputObject(transaction)
 		ids[i] = values.RequireInt("id")
 		version := values.RequireInt("version")
-		updateTransaction(tx, ids[i], version, values, user)
+		if err := updateTransaction(tx, ids[i], version, values, user); err != nil {
+			return nil, err
+		}
 		if details, ok := values["details"]; ok {
 			detailUpdates := details.([]map[string]interface{})
-			updateTxDetails(tx, ids[i], detailUpdates, user)
+			if err := updateTxDetails(tx, ids[i], detailUpdates, fmt.Sprintf("update[%d].details", i), user); err != nil {
+				return nil, err
+			}
 		}
 	}
 	if len(ids) > 0 {
-		validateDetails(tx, ids)
+		if err := validateDetails(tx, ids); err != nil {
+			return nil, err
+		}
 	}
-	return ids
+	return ids, nil
 }
 
-// DeleteTransactions deletes transactions.
-func DeleteTransactions(tx *sql.Tx, ids []map[string]interface{}) {
-	deleteRelatedDetails(tx, ids)
-	deleteTransactionDetails(tx, ids)
-	deleteTransactions(tx, ids)
+// DeleteTransactions moves transactions and their transfer transactions to the trash.
+func DeleteTransactions(tx *database.Tx, ids []map[string]interface{}, user string) error {
+	return trashTransactions(tx, ids, user)
+}
+
+// GetTrash returns the transactions in the trash, optionally limited to an account.
+func GetTrash(tx *database.Tx, accountID interface{}) []*Transaction {
+	rows := getTrash(tx, accountID)
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	source := &transactionSource{txIDs: ids}
+	return source.setSource(rows)
+}
+
+// RestoreTransactions moves transactions and their transfer transactions out of the trash and returns the restored
+// transactions.
+func RestoreTransactions(tx *database.Tx, ids []int64, user string) ([]*Transaction, error) {
+	restoredIDs, err := restoreTransactions(tx, ids, user)
+	if err != nil {
+		return nil, err
+	}
+	return GetTransactionsByIDs(tx, restoredIDs), nil
+}
+
+// PurgeTrash permanently deletes the transactions that have been in the trash for more than retentionDays. Returns
+// the number of deleted transactions and the hashes of the attachment files that are no longer used.
+func PurgeTrash(tx *database.Tx, retentionDays int, user string) (int, []string) {
+	ids := getExpiredTrashIDs(tx, retentionDays)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	hashes := make([]string, 0)
+	for _, attachment := range getAttachmentsByTxIDs(tx, ids) {
+		hashes = append(hashes, attachment.Hash)
+	}
+	deleteTransactionAttachments(tx, ids, user)
+	txIDs := make([]map[string]interface{}, len(ids))
+	for i, id := range ids {
+		txIDs[i] = map[string]interface{}{"id": id}
+	}
+	deleteRelatedDetails(tx, txIDs, user)
+	deleteTransactionDetails(tx, txIDs, user)
+	purgeTransactions(tx, ids, user)
+	return len(ids), unreferencedHashes(tx, hashes)
+}
+
+// groupCounts returns the transaction count for each parent ID.
+func groupCounts(rows []*table.TransactionCount) map[int64]interface{} {
+	byID := make(map[int64]interface{}, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row.Count
+	}
+	return byID
 }
@@ -1,15 +1,15 @@
 package domain
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,12 +29,14 @@ func Test_Transaction_Resolve(t *testing.T) {
 
 func Test_GetTransactions(t *testing.T) {
 	accountID := int64(42)
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 		txID := int64(69)
 		expectedTx := &Transaction{
-			Transaction: &table.Transaction{ID: txID},
-			source:      &transactionSource{accountID: accountID},
+			Transaction: &table.Transaction{ID: txID, AccountID: accountID},
+			source:      &transactionSource{accountID: accountID, refs: newReferenceSource()},
 		}
+		expectedTx.source.refs.addTransaction(expectedTx.Transaction)
+		expectedTx.source.refs.transactions.set(txID, expectedTx)
 		getTransactionsStub := mocka.Function(t, &getTransactions, []*table.Transaction{expectedTx.Transaction})
 		defer getTransactionsStub.Restore()
 
@@ -56,6 +58,17 @@ func Test_GetDetails(t *testing.T) {
 	assert.Equal(t, detailsByTxID[txID], result)
 }
 
+func Test_Transaction_GetAttachments(t *testing.T) {
+	txID := int64(96)
+	attachments := []*table.Attachment{{ID: 42, TransactionID: &txID}}
+	source := &transactionSource{attachmentsByTxID: map[int64][]*table.Attachment{txID: attachments}}
+	transaction := &Transaction{Transaction: &table.Transaction{ID: txID}, source: source}
+
+	result := transaction.GetAttachments(nil)
+
+	assert.Equal(t, attachments, result)
+}
+
 func Test_InsertTransactions(t *testing.T) {
 	accountID := int64(42)
 	user := "user id"
@@ -63,31 +76,34 @@ func Test_InsertTransactions(t *testing.T) {
 	errTests := []struct {
 		name    string
 		inserts []map[string]interface{}
+		field   string
 		err     string
 	}{
-		{"panics for no details", []map[string]interface{}{{"date": "2020-12-25"}}, "new transaction requires at least 1 detail"},
-		{"panics for empty details", []map[string]interface{}{{"details": []map[string]interface{}{}}}, "new transaction requires at least 1 detail"},
-		{"panics for no amount", []map[string]interface{}{{"details": []map[string]interface{}{{"memo": "x"}}}}, "new transaction detail requires amount"},
+		{"returns error for no details", []map[string]interface{}{{"date": "2020-12-25"}},
+			"add[0].details", "new transaction requires at least 1 detail"},
+		{"returns error for empty details", []map[string]interface{}{{"details": []map[string]interface{}{}}},
+			"add[0].details", "new transaction requires at least 1 detail"},
+		{"returns error for no amount", []map[string]interface{}{{"details": []map[string]interface{}{{"amount": 1.0}, {"memo": "x"}}}},
+			"add[0].details[1].amount", "new transaction detail requires amount"},
 	}
 	for _, test := range errTests {
 		t.Run(test.name, func(t *testing.T) {
-			sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 				insertTransactionStub := mocka.Function(t, &insertTransaction, txID)
-				defer func() {
-					insertTransactionStub.Restore()
-					if err := recover(); err != nil {
-						assert.Equal(t, test.err, err.(error).Error())
-					} else {
-						assert.Fail(t, "expected an error")
-					}
-				}()
-
-				InsertTransactions(tx, accountID, test.inserts, user)
+				defer insertTransactionStub.Restore()
+				insertDetailStub := mocka.Function(t, &insertDetail)
+				defer insertDetailStub.Restore()
+
+				ids, err := InsertTransactions(tx, accountID, test.inserts, user)
+
+				assert.Nil(t, ids)
+				assert.EqualError(t, err, test.err)
+				assert.Equal(t, test.field, err.(*apperror.ValidationError).Field)
 			})
 		})
 	}
 	t.Run("returns ids", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			amount := 234.56
 			details := []map[string]interface{}{{"amount": amount}}
 			inserts := []map[string]interface{}{
@@ -97,11 +113,12 @@ func Test_InsertTransactions(t *testing.T) {
 			defer insertTransactionStub.Restore()
 			insertDetailStub := mocka.Function(t, &insertDetail)
 			defer insertDetailStub.Restore()
-			validateDetailsStub := mocka.Function(t, &validateDetails)
+			validateDetailsStub := mocka.Function(t, &validateDetails, nil)
 			defer validateDetailsStub.Restore()
 
-			result := InsertTransactions(tx, accountID, inserts, user)
+			result, err := InsertTransactions(tx, accountID, inserts, user)
 
+			assert.Nil(t, err)
 			assert.Equal(t, []int64{txID}, result)
 			assert.Equal(t, []interface{}{tx, accountID, database.InputObject(inserts[0]), user}, insertTransactionStub.GetCall(0).Arguments())
 			assert.Equal(t, []interface{}{tx, txID, amount, database.InputObject(details[0]), user}, insertDetailStub.GetCall(0).Arguments())
@@ -119,14 +136,15 @@ func Test_UpdateTransactions(t *testing.T) {
 			"id":      id,
 			"version": version,
 		}
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-			updateTransactionStub := mocka.Function(t, &updateTransaction)
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			updateTransactionStub := mocka.Function(t, &updateTransaction, nil)
 			defer updateTransactionStub.Restore()
-			validateDetailsStub := mocka.Function(t, &validateDetails)
+			validateDetailsStub := mocka.Function(t, &validateDetails, nil)
 			defer validateDetailsStub.Restore()
 
-			ids := UpdateTransactions(tx, []map[string]interface{}{update}, user)
+			ids, err := UpdateTransactions(tx, []map[string]interface{}{update}, user)
 
+			assert.Nil(t, err)
 			assert.Equal(t, []int64{42}, ids)
 			assert.Equal(t, []interface{}{tx, int64(id), int64(version), update, user}, updateTransactionStub.GetCall(0).Arguments())
 			assert.Equal(t, []interface{}{tx, []int64{int64(id)}}, validateDetailsStub.GetCall(0).Arguments())
@@ -138,24 +156,54 @@ func Test_UpdateTransactions(t *testing.T) {
 			"version": version,
 			"details": []map[string]interface{}{},
 		}
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-			updateTransactionStub := mocka.Function(t, &updateTransaction)
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			updateTransactionStub := mocka.Function(t, &updateTransaction, nil)
 			defer updateTransactionStub.Restore()
-			updateTxDetailsStub := mocka.Function(t, &updateTxDetails)
+			updateTxDetailsStub := mocka.Function(t, &updateTxDetails, nil)
 			defer updateTxDetailsStub.Restore()
-			validateDetailsStub := mocka.Function(t, &validateDetails)
+			validateDetailsStub := mocka.Function(t, &validateDetails, nil)
 			defer validateDetailsStub.Restore()
 
-			UpdateTransactions(tx, []map[string]interface{}{update}, user)
+			_, err := UpdateTransactions(tx, []map[string]interface{}{update}, user)
 
-			assert.Equal(t, []interface{}{tx, int64(id), update["details"], user}, updateTxDetailsStub.GetCall(0).Arguments())
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{tx, int64(id), update["details"], "update[0].details", user}, updateTxDetailsStub.GetCall(0).Arguments())
 			assert.Equal(t, []interface{}{tx, []int64{int64(id)}}, validateDetailsStub.GetCall(0).Arguments())
 		})
 	})
+	t.Run("returns version conflict", func(t *testing.T) {
+		update := map[string]interface{}{"id": id, "version": version}
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			updateTransactionStub := mocka.Function(t, &updateTransaction, apperror.VersionConflict("transaction", 42, 1))
+			defer updateTransactionStub.Restore()
+			validateDetailsStub := mocka.Function(t, &validateDetails, nil)
+			defer validateDetailsStub.Restore()
+
+			ids, err := UpdateTransactions(tx, []map[string]interface{}{update}, user)
+
+			assert.Nil(t, ids)
+			assert.EqualError(t, err, "transaction not found (42 @ 1)")
+			assert.Equal(t, 0, validateDetailsStub.CallCount())
+		})
+	})
+	t.Run("returns detail validation errors", func(t *testing.T) {
+		update := map[string]interface{}{"id": id, "version": version}
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			updateTransactionStub := mocka.Function(t, &updateTransaction, nil)
+			defer updateTransactionStub.Restore()
+			validateDetailsStub := mocka.Function(t, &validateDetails, apperror.DetailValidation(map[int64]string{96: "invalid amount"}))
+			defer validateDetailsStub.Restore()
+
+			ids, err := UpdateTransactions(tx, []map[string]interface{}{update}, user)
+
+			assert.Nil(t, ids)
+			assert.Equal(t, map[int64]string{96: "invalid amount"}, err.(*apperror.ValidationError).Details)
+		})
+	})
 }
 
 func Test_GetTransactionsByIDs(t *testing.T) {
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 		transactions := []*table.Transaction{{ID: 42}}
 		getTransactionsByIDsStub := mocka.Function(t, &getTransactionsByIDs, transactions)
 		defer getTransactionsByIDsStub.Restore()
@@ -170,18 +218,106 @@ func Test_GetTransactionsByIDs(t *testing.T) {
 
 func Test_DeleteTransactions(t *testing.T) {
 	txIDs := []map[string]interface{}{{"id": 1, "version": 0}, {"id": 2, "version": 9}}
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-		deleteRelatedDetailsStub := mocka.Function(t, &deleteRelatedDetails)
-		defer deleteRelatedDetailsStub.Restore()
-		deleteTransactionDetailsStub := mocka.Function(t, &deleteTransactionDetails)
-		defer deleteTransactionDetailsStub.Restore()
-		deleteTransactionsStub := mocka.Function(t, &deleteTransactions)
-		defer deleteTransactionsStub.Restore()
-
-		DeleteTransactions(tx, txIDs)
-
-		assert.Equal(t, 1, deleteRelatedDetailsStub.CallCount())
-		assert.Equal(t, 1, deleteTransactionDetailsStub.CallCount())
-		assert.Equal(t, 1, deleteTransactionsStub.CallCount())
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		trashTransactionsStub := mocka.Function(t, &trashTransactions, nil)
+		defer trashTransactionsStub.Restore()
+
+		err := DeleteTransactions(tx, txIDs, "somebody")
+
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{tx, txIDs, "somebody"}, trashTransactionsStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_GetTrash(t *testing.T) {
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		rows := []*table.Transaction{{ID: 42}, {ID: 96}}
+		getTrashStub := mocka.Function(t, &getTrash, rows)
+		defer getTrashStub.Restore()
+
+		result := GetTrash(tx, int64(1))
+
+		assert.Len(t, result, 2)
+		assert.Same(t, rows[0], result[0].Transaction)
+		assert.Equal(t, []int64{42, 96}, result[0].source.txIDs)
+		assert.Equal(t, []interface{}{tx, int64(1)}, getTrashStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_RestoreTransactions(t *testing.T) {
+	t.Run("returns restored transactions", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			rows := []*table.Transaction{{ID: 42}, {ID: 96}}
+			restoreStub := mocka.Function(t, &restoreTransactions, []int64{42, 96}, nil)
+			defer restoreStub.Restore()
+			getTransactionsStub := mocka.Function(t, &getTransactionsByIDs, rows)
+			defer getTransactionsStub.Restore()
+
+			result, err := RestoreTransactions(tx, []int64{42}, "somebody")
+
+			assert.Nil(t, err)
+			assert.Len(t, result, 2)
+			assert.Equal(t, []interface{}{tx, []int64{42}, "somebody"}, restoreStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{42, 96}}, getTransactionsStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("returns error if not in trash", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			restoreStub := mocka.Function(t, &restoreTransactions, nil, apperror.NotFoundf("transaction", "transaction(s) not found in trash"))
+			defer restoreStub.Restore()
+
+			result, err := RestoreTransactions(tx, []int64{42}, "somebody")
+
+			assert.Nil(t, result)
+			assert.EqualError(t, err, "transaction(s) not found in trash")
+		})
+	})
+}
+
+func Test_PurgeTrash(t *testing.T) {
+	t.Run("deletes expired transactions", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			getExpiredStub := mocka.Function(t, &getExpiredTrashIDs, []int64{42, 96})
+			defer getExpiredStub.Restore()
+			deleteRelatedDetailsStub := mocka.Function(t, &deleteRelatedDetails)
+			defer deleteRelatedDetailsStub.Restore()
+			deleteTransactionDetailsStub := mocka.Function(t, &deleteTransactionDetails)
+			defer deleteTransactionDetailsStub.Restore()
+			purgeTransactionsStub := mocka.Function(t, &purgeTransactions)
+			defer purgeTransactionsStub.Restore()
+			getAttachmentsStub := mocka.Function(t, &getAttachmentsByTxIDs, []*table.Attachment{{Hash: "abc"}, {Hash: "def"}})
+			defer getAttachmentsStub.Restore()
+			deleteAttachmentsStub := mocka.Function(t, &deleteTransactionAttachments)
+			defer deleteAttachmentsStub.Restore()
+			getByHashesStub := mocka.Function(t, &getAttachmentsByHashes, []*table.Attachment{{Hash: "def"}})
+			defer getByHashesStub.Restore()
+			txIDs := []map[string]interface{}{{"id": int64(42)}, {"id": int64(96)}}
+
+			count, hashes := PurgeTrash(tx, 30, "somebody")
+
+			assert.Equal(t, 2, count)
+			assert.Equal(t, []string{"abc"}, hashes)
+			assert.Equal(t, []interface{}{tx, []int64{42, 96}}, getAttachmentsStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{42, 96}, "somebody"}, deleteAttachmentsStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, []string{"abc", "def"}}, getByHashesStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, 30}, getExpiredStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, txIDs, "somebody"}, deleteRelatedDetailsStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, txIDs, "somebody"}, deleteTransactionDetailsStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{42, 96}, "somebody"}, purgeTransactionsStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("does nothing if trash is empty", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			getExpiredStub := mocka.Function(t, &getExpiredTrashIDs, []int64{})
+			defer getExpiredStub.Restore()
+			purgeTransactionsStub := mocka.Function(t, &purgeTransactions)
+			defer purgeTransactionsStub.Restore()
+
+			count, hashes := PurgeTrash(tx, 30, "somebody")
+
+			assert.Equal(t, 0, count)
+			assert.Nil(t, hashes)
+			assert.Equal(t, 0, purgeTransactionsStub.CallCount())
+		})
 	})
 }
@@ -1,10 +1,10 @@
 package domain
 
 import (
-	"database/sql"
-	"errors"
+	"fmt"
 
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/database/table"
 )
@@ -30,7 +30,7 @@ func (d *TransactionDetail) SetRelatedDetail(detail *TransactionDetail) {
 }
 
 // GetRelatedDetail returns the related detail.
-func (d *TransactionDetail) GetRelatedDetail(tx *sql.Tx) *TransactionDetail {
+func (d *TransactionDetail) GetRelatedDetail(tx *database.Tx) *TransactionDetail {
 	if d.RelatedDetailID == nil {
 		return nil
 	}
@@ -44,16 +44,46 @@ func (d *TransactionDetail) SetRelatedTransaction(transaction *Transaction) {
 }
 
 // GetRelatedTransaction returns the transaction for a related detail.
-func (d *TransactionDetail) GetRelatedTransaction(tx *sql.Tx) *Transaction {
+func (d *TransactionDetail) GetRelatedTransaction(tx *database.Tx) *Transaction {
 	d.txSource.loadRelatedTransactions(tx)
 	return d.txSource.relatedTxByID[d.TransactionID]
 }
 
-var updateTxDetails = func(tx *sql.Tx, txID int64, details []map[string]interface{}, user string) {
+// GetTransaction returns the transaction of the detail.
+func (d *TransactionDetail) GetTransaction(tx *database.Tx) *Transaction {
+	transaction, _ := d.txSource.references().transactions.get(tx, &d.TransactionID, d.txSource.loadTransactions).(*Transaction)
+	return transaction
+}
+
+// GetCategory returns the category of the detail.
+func (d *TransactionDetail) GetCategory(tx *database.Tx) *Category {
+	return d.txSource.references().categories.get(tx, d.TransactionCategoryID)
+}
+
+// GetGroup returns the group of the detail.
+func (d *TransactionDetail) GetGroup(tx *database.Tx) *Group {
+	return d.txSource.references().groups.get(tx, d.TransactionGroupID)
+}
+
+// GetExchangeAsset returns the asset that was exchanged by the detail.
+func (d *TransactionDetail) GetExchangeAsset(tx *database.Tx) *table.Asset {
+	asset, _ := d.txSource.references().assets.get(tx, d.ExchangeAssetID, loadAssets).(*table.Asset)
+	return asset
+}
+
+// fields for setting the amount of the other side of a transfer
+var transferAmountFields = []string{"transferAmount", "exchangeRate"}
+
+// updateTxDetails adds, updates and deletes the details of a transaction. The path is used to identify the details
+// in validation errors.
+var updateTxDetails = func(tx *database.Tx, txID int64, details []map[string]interface{}, path string, user string) error {
 	deleteIDs := make([]*database.VersionID, 0)
-	for _, detail := range details {
+	for i, detail := range details {
 		values := database.InputObject(detail)
-		id := values.GetVersionID()
+		id, err := values.GetVersionID()
+		if err != nil {
+			return apperror.Validation(fmt.Sprintf("%s[%d].version", path, i), err.Error())
+		}
 		if id != nil {
 			if len(detail) == 2 {
 				deleteIDs = append(deleteIDs, id)
@@ -62,31 +92,73 @@ var updateTxDetails = func(tx *sql.Tx, txID int64, details []map[string]interfac
 				transferAccountId, setTransfer := values.GetInt("transferAccountId")
 				categoryId, setCategory := values.GetInt("transactionCategoryId")
 				if setTransfer && setCategory {
-					panic(errors.New("cannot specify both transferAccountId and transactionCategoryId"))
+					return apperror.Validation(fmt.Sprintf("%s[%d].transferAccountId", path, i),
+						"cannot specify both transferAccountId and transactionCategoryId")
+				}
+				if (setAmount || hasAny(values, transferAmountFields)) && (!setCategory || setTransfer) {
+					setTransferAmount(tx, id.ID, amount, values, user)
 				}
 				if setTransfer {
 					setCategory = true
 					categoryId = nil
 				}
-				updateDetail(tx, id.ID, id.Version, setCategory, categoryId, values, user)
+				if err := updateDetail(tx, id.ID, id.Version, setCategory, categoryId, values, user); err != nil {
+					return err
+				}
 				if setCategory && transferAccountId == nil {
-					deleteTransfer(tx, id.ID)
+					deleteTransfer(tx, id.ID, user)
 				} else if setTransfer {
-					addOrUpdateTransfer(tx, id.ID, transferAccountId, user)
-				}
-				if setAmount && !setCategory {
-					setTransferAmount(tx, id.ID, amount, user)
+					addOrUpdateTransfer(tx, id.ID, transferAccountId, values, user)
 				}
 			}
 		} else {
 			if amount, ok := values.GetFloat("amount"); ok && amount != nil {
 				insertDetail(tx, txID, amount, values, user)
 			} else {
-				panic(errors.New("new transaction detail requires amount"))
+				return apperror.Validation(fmt.Sprintf("%s[%d].amount", path, i), "new transaction detail requires amount")
 			}
 		}
 	}
 	if len(deleteIDs) > 0 {
-		deleteDetails(tx, deleteIDs)
+		return deleteDetails(tx, deleteIDs, user)
+	}
+	return nil
+}
+
+var bulkFilterFields = []string{"accountId", "startDate", "endDate", "payeeId", "transactionCategoryId", "memo"}
+var bulkChangeFields = []string{"transactionCategoryId", "transactionGroupId", "memo"}
+
+func hasAny(values map[string]interface{}, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// BulkUpdateDetails applies the change to all of the details matching the filter and returns the number of
+// affected details. When dryRun is true, the details are not updated.
+func BulkUpdateDetails(tx *database.Tx, filter map[string]interface{}, change map[string]interface{}, dryRun bool, user string) (int64, error) {
+	if !hasAny(filter, bulkFilterFields) {
+		return 0, apperror.Validation("filter", "filter requires at least 1 field")
+	}
+	if !hasAny(change, bulkChangeFields) {
+		return 0, apperror.Validation("change", "change requires at least 1 field")
+	}
+	details := getDetailsByFilter(tx, filter)
+	if dryRun || len(details) == 0 {
+		return int64(len(details)), nil
+	}
+	ids := make([]int64, len(details))
+	txIDs := newIDSet()
+	for i, detail := range details {
+		ids[i] = detail.ID
+		txIDs.Add(detail.TransactionID)
+	}
+	count := updateDetailsByIDs(tx, ids, change, user)
+	if err := validateDetails(tx, txIDs.Values()); err != nil {
+		return 0, err
 	}
+	return count, nil
 }
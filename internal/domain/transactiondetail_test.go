@@ -1,15 +1,15 @@
 package domain
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -68,21 +68,17 @@ func Test_updateTxDetails_update(t *testing.T) {
 	id := 42
 	txID := int64(69)
 	version := 1
-	t.Run("panics for id without version", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	t.Run("returns error for id without version", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			update := database.InputObject{
 				"id":     id,
 				"amount": 42.0,
 			}
-			defer func() {
-				if err := recover(); err != nil {
-					assert.Equal(t, "version is required for update/delete", err.(error).Error())
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
-
-			updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+
+			err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
+
+			assert.EqualError(t, err, "version is required for update/delete")
+			assert.Equal(t, "details[0].version", err.(*apperror.ValidationError).Field)
 		})
 	})
 	t.Run("update amount", func(t *testing.T) {
@@ -93,18 +89,19 @@ func Test_updateTxDetails_update(t *testing.T) {
 				"transactionCategoryId": nil,
 				"amount":                42.0,
 			}
-			sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-				updateDetailStub := mocka.Function(t, &updateDetail)
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+				updateDetailStub := mocka.Function(t, &updateDetail, nil)
 				defer updateDetailStub.Restore()
 				deleteTransferStub := mocka.Function(t, &deleteTransfer)
 				defer deleteTransferStub.Restore()
 				setTransferAmountStub := mocka.Function(t, &setTransferAmount)
 				defer setTransferAmountStub.Restore()
 
-				updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+				err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
 
+				assert.Nil(t, err)
 				assert.Equal(t, []interface{}{tx, int64(id), int64(version), true, nil, update, user}, updateDetailStub.GetCall(0).Arguments())
-				assert.Equal(t, []interface{}{tx, int64(id)}, deleteTransferStub.GetCall(0).Arguments())
+				assert.Equal(t, []interface{}{tx, int64(id), user}, deleteTransferStub.GetCall(0).Arguments())
 				assert.Equal(t, 0, setTransferAmountStub.CallCount())
 			})
 		})
@@ -114,19 +111,20 @@ func Test_updateTxDetails_update(t *testing.T) {
 				"version": version,
 				"amount":  42.0,
 			}
-			sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-				updateDetailStub := mocka.Function(t, &updateDetail)
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+				updateDetailStub := mocka.Function(t, &updateDetail, nil)
 				defer updateDetailStub.Restore()
 				deleteTransferStub := mocka.Function(t, &deleteTransfer)
 				defer deleteTransferStub.Restore()
 				setTransferAmountStub := mocka.Function(t, &setTransferAmount)
 				defer setTransferAmountStub.Restore()
 
-				updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+				err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
 
+				assert.Nil(t, err)
 				assert.Equal(t, []interface{}{tx, int64(id), int64(version), false, nil, update, user}, updateDetailStub.GetCall(0).Arguments())
 				assert.Equal(t, 0, deleteTransferStub.CallCount())
-				assert.Equal(t, []interface{}{tx, int64(id), 42.0, user}, setTransferAmountStub.GetCall(0).Arguments())
+				assert.Equal(t, []interface{}{tx, int64(id), 42.0, update, user}, setTransferAmountStub.GetCall(0).Arguments())
 			})
 		})
 	})
@@ -136,16 +134,17 @@ func Test_updateTxDetails_update(t *testing.T) {
 			"version":               version,
 			"transactionCategoryId": 96,
 		}
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-			updateDetailStub := mocka.Function(t, &updateDetail)
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			updateDetailStub := mocka.Function(t, &updateDetail, nil)
 			defer updateDetailStub.Restore()
 			deleteTransferStub := mocka.Function(t, &deleteTransfer)
 			defer deleteTransferStub.Restore()
 
-			updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+			err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
 
+			assert.Nil(t, err)
 			assert.Equal(t, []interface{}{tx, int64(id), int64(version), true, int64(96), update, user}, updateDetailStub.GetCall(0).Arguments())
-			assert.Equal(t, []interface{}{tx, int64(id)}, deleteTransferStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, int64(id), user}, deleteTransferStub.GetCall(0).Arguments())
 		})
 	})
 	t.Run("update transfer account", func(t *testing.T) {
@@ -155,48 +154,63 @@ func Test_updateTxDetails_update(t *testing.T) {
 		}
 		t.Run("deletes transfer", func(t *testing.T) {
 			update["transferAccountId"] = nil
-			sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-				updateDetailStub := mocka.Function(t, &updateDetail)
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+				updateDetailStub := mocka.Function(t, &updateDetail, nil)
 				defer updateDetailStub.Restore()
 				deleteTransferStub := mocka.Function(t, &deleteTransfer)
 				defer deleteTransferStub.Restore()
 
-				updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+				err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
 
-				assert.Equal(t, []interface{}{tx, int64(id)}, deleteTransferStub.GetCall(0).Arguments())
+				assert.Nil(t, err)
+				assert.Equal(t, []interface{}{tx, int64(id), user}, deleteTransferStub.GetCall(0).Arguments())
 			})
 		})
 		t.Run("updates transfer", func(t *testing.T) {
 			update["transferAccountId"] = 96
-			sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-				updateDetailStub := mocka.Function(t, &updateDetail)
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+				updateDetailStub := mocka.Function(t, &updateDetail, nil)
+				defer updateDetailStub.Restore()
+				addOrUpdateTransferStub := mocka.Function(t, &addOrUpdateTransfer)
+				defer addOrUpdateTransferStub.Restore()
+
+				err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
+
+				assert.Nil(t, err)
+				assert.Equal(t, []interface{}{tx, int64(id), int64(96), update, user}, addOrUpdateTransferStub.GetCall(0).Arguments())
+			})
+		})
+		t.Run("updates transfer with exchange rate", func(t *testing.T) {
+			update := database.InputObject{"id": id, "version": version, "transferAccountId": 96, "exchangeRate": 1.5}
+			dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+				setTransferAmountStub := mocka.Function(t, &setTransferAmount)
+				defer setTransferAmountStub.Restore()
+				updateDetailStub := mocka.Function(t, &updateDetail, nil)
 				defer updateDetailStub.Restore()
 				addOrUpdateTransferStub := mocka.Function(t, &addOrUpdateTransfer)
 				defer addOrUpdateTransferStub.Restore()
 
-				updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+				err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
 
-				assert.Equal(t, []interface{}{tx, int64(id), int64(96), user}, addOrUpdateTransferStub.GetCall(0).Arguments())
+				assert.Nil(t, err)
+				assert.Equal(t, []interface{}{tx, int64(id), nil, update, user}, setTransferAmountStub.GetCall(0).Arguments())
+				assert.Equal(t, []interface{}{tx, int64(id), int64(96), update, user}, addOrUpdateTransferStub.GetCall(0).Arguments())
 			})
 		})
 	})
-	t.Run("panics for transfer with category", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	t.Run("returns error for transfer with category", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			update := database.InputObject{
 				"id":                    id,
 				"version":               version,
 				"transactionCategoryId": 96,
 				"transferAccountId":     69,
 			}
-			defer func() {
-				if err := recover(); err != nil {
-					assert.Equal(t, "cannot specify both transferAccountId and transactionCategoryId", err.(error).Error())
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
-
-			updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+
+			err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
+
+			assert.EqualError(t, err, "cannot specify both transferAccountId and transactionCategoryId")
+			assert.Equal(t, "details[0].transferAccountId", err.(*apperror.ValidationError).Field)
 		})
 	})
 }
@@ -204,28 +218,25 @@ func Test_updateTxDetails_update(t *testing.T) {
 func Test_updateTxDetails_insert(t *testing.T) {
 	user := "user id"
 	txID := int64(69)
-	t.Run("panics for no amount", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+	t.Run("returns error for no amount", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			update := database.InputObject{}
-			defer func() {
-				if err := recover(); err != nil {
-					assert.Equal(t, "new transaction detail requires amount", err.(error).Error())
-				} else {
-					assert.Fail(t, "expected an error")
-				}
-			}()
-
-			updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+
+			err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
+
+			assert.EqualError(t, err, "new transaction detail requires amount")
+			assert.Equal(t, "details[0].amount", err.(*apperror.ValidationError).Field)
 		})
 	})
 	t.Run("inserts a detail", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			update := database.InputObject{"amount": 42.0}
 			insertDetailStub := mocka.Function(t, &insertDetail)
 			defer insertDetailStub.Restore()
 
-			updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+			err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
 
+			assert.Nil(t, err)
 			assert.Equal(t, []interface{}{tx, txID, 42.0, update, user}, insertDetailStub.GetCall(0).Arguments())
 		})
 	})
@@ -240,13 +251,69 @@ func Test_updateTxDetails_delete(t *testing.T) {
 		"id":      id,
 		"version": version,
 	}
-	versionID := update.GetVersionID()
-	sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
-		deleteDetailsStub := mocka.Function(t, &deleteDetails)
+	versionID, _ := update.GetVersionID()
+	dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+		deleteDetailsStub := mocka.Function(t, &deleteDetails, nil)
 		defer deleteDetailsStub.Restore()
 
-		updateTxDetails(tx, txID, []map[string]interface{}{update}, user)
+		err := updateTxDetails(tx, txID, []map[string]interface{}{update}, "details", user)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []interface{}{tx, []*database.VersionID{versionID}, user}, deleteDetailsStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_BulkUpdateDetails(t *testing.T) {
+	user := "user id"
+	filter := map[string]interface{}{"accountId": 96}
+	change := map[string]interface{}{"transactionCategoryId": 42}
+	details := []*table.TransactionDetail{{ID: 1, TransactionID: 10}, {ID: 2, TransactionID: 10}}
+	errTests := []struct {
+		name   string
+		filter map[string]interface{}
+		change map[string]interface{}
+		err    string
+	}{
+		{"returns error for empty filter", map[string]interface{}{}, change, "filter requires at least 1 field"},
+		{"returns error for empty change", filter, map[string]interface{}{}, "change requires at least 1 field"},
+	}
+	for _, test := range errTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := BulkUpdateDetails(nil, test.filter, test.change, false, user)
+
+			assert.EqualError(t, err, test.err)
+		})
+	}
+	t.Run("returns count for dry run", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			getDetailsStub := mocka.Function(t, &getDetailsByFilter, details)
+			defer getDetailsStub.Restore()
+			updateDetailsStub := mocka.Function(t, &updateDetailsByIDs, int64(0))
+			defer updateDetailsStub.Restore()
+
+			count, err := BulkUpdateDetails(tx, filter, change, true, user)
+
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), count)
+			assert.Equal(t, []interface{}{tx, database.InputObject(filter)}, getDetailsStub.GetCall(0).Arguments())
+			assert.Equal(t, 0, updateDetailsStub.CallCount())
+		})
+	})
+	t.Run("updates and validates details", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			getDetailsStub := mocka.Function(t, &getDetailsByFilter, details)
+			defer getDetailsStub.Restore()
+			updateDetailsStub := mocka.Function(t, &updateDetailsByIDs, int64(2))
+			defer updateDetailsStub.Restore()
+			validateDetailsStub := mocka.Function(t, &validateDetails, nil)
+			defer validateDetailsStub.Restore()
 
-		assert.Equal(t, []interface{}{tx, []*database.VersionID{versionID}}, deleteDetailsStub.GetCall(0).Arguments())
+			count, err := BulkUpdateDetails(tx, filter, change, false, user)
+
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), count)
+			assert.Equal(t, []interface{}{tx, []int64{1, 2}, database.InputObject(change), user}, updateDetailsStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{10}}, validateDetailsStub.GetCall(0).Arguments())
+		})
 	})
 }
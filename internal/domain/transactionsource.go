@@ -1,8 +1,7 @@
 package domain
 
 import (
-	"database/sql"
-
+	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/database/table"
 )
 
@@ -13,18 +12,55 @@ type transactionSource struct {
 	detailsByTxID      map[int64][]*TransactionDetail
 	relatedDetailsByID map[int64]*TransactionDetail
 	relatedTxByID      map[int64]*Transaction
+	attachmentsByTxID  map[int64][]*table.Attachment
+	refs               *referenceSource
+}
+
+// references returns the source of the payees, accounts, etc. for the transactions and details.
+func (ts *transactionSource) references() *referenceSource {
+	if ts.refs == nil {
+		ts.refs = newReferenceSource()
+	}
+	return ts.refs
 }
 
 func (ts *transactionSource) setSource(dbTransactions []*table.Transaction) []*Transaction {
 	transactions := make([]*Transaction, len(dbTransactions))
 	for i, tx := range dbTransactions {
 		transactions[i] = &Transaction{source: ts, Transaction: tx}
+		ts.references().addTransaction(tx)
+		ts.references().transactions.set(tx.ID, transactions[i])
 	}
 	return transactions
 }
 
+// loadTransactions loads the transactions of details that were loaded without their transactions.
+func (ts *transactionSource) loadTransactions(tx *database.Tx, ids []int64) map[int64]interface{} {
+	byID := make(map[int64]interface{}, len(ids))
+	for _, transaction := range ts.setSource(getTransactionsByIDs(tx, ids)) {
+		byID[transaction.ID] = transaction
+	}
+	return byID
+}
+
+// newDetailSource wraps details that are loaded without their transactions, e.g. the details of a category.
+func newDetailSource(dbDetails []*table.TransactionDetail) []*TransactionDetail {
+	txIDs := newIDSet()
+	for _, detail := range dbDetails {
+		txIDs.Add(detail.TransactionID)
+	}
+	ts := &transactionSource{txIDs: txIDs.Values()}
+	details := make([]*TransactionDetail, len(dbDetails))
+	for i, detail := range dbDetails {
+		details[i] = &TransactionDetail{txSource: ts, TransactionDetail: detail}
+		ts.references().addDetail(detail)
+		ts.references().transactions.add(&detail.TransactionID)
+	}
+	return details
+}
+
 // loadTransactionDetails loads transaction details grouped by transaction ID.
-func (ts *transactionSource) loadTransactionDetails(tx *sql.Tx) {
+func (ts *transactionSource) loadTransactionDetails(tx *database.Tx) {
 	if ts.detailsByTxID == nil {
 		if ts.txIDs != nil {
 			ts.setDetails(getDetailsByTxIDs(tx, ts.txIDs))
@@ -41,12 +77,13 @@ func (ts *transactionSource) setDetails(details []*table.TransactionDetail) {
 			ts.detailsByTxID[dbDetail.TransactionID] = make([]*TransactionDetail, 0, 1)
 		}
 		detail := &TransactionDetail{txSource: ts, TransactionDetail: dbDetail}
+		ts.references().addDetail(dbDetail)
 		ts.detailsByTxID[dbDetail.TransactionID] = append(ts.detailsByTxID[dbDetail.TransactionID], detail)
 	}
 }
 
 // loadRelatedDetails loads all related details for the query.
-func (ts *transactionSource) loadRelatedDetails(tx *sql.Tx) {
+func (ts *transactionSource) loadRelatedDetails(tx *database.Tx) {
 	if ts.relatedDetailsByID == nil {
 		if ts.txIDs != nil {
 			ts.setRelatedDetails(getRelatedDetailsByTxIDs(tx, ts.txIDs))
@@ -60,11 +97,12 @@ func (ts *transactionSource) setRelatedDetails(details []*table.TransactionDetai
 	ts.relatedDetailsByID = make(map[int64]*TransactionDetail, len(details))
 	for _, detail := range details {
 		ts.relatedDetailsByID[detail.ID] = &TransactionDetail{TransactionDetail: detail, txSource: ts}
+		ts.references().addDetail(detail)
 	}
 }
 
 // loadRelatedTransactions loads all related transactions for the account.
-func (ts *transactionSource) loadRelatedTransactions(tx *sql.Tx) {
+func (ts *transactionSource) loadRelatedTransactions(tx *database.Tx) {
 	if ts.relatedTxByID == nil {
 		if ts.txIDs != nil {
 			ts.setRelatedTransactions(getRelatedTransactions(tx, ts.txIDs))
@@ -81,3 +119,19 @@ func (ts *transactionSource) setRelatedTransactions(dbTransactions []*table.Tran
 		ts.relatedTxByID[tx.ID] = tx
 	}
 }
+
+// loadAttachments loads the attachments grouped by transaction ID.
+func (ts *transactionSource) loadAttachments(tx *database.Tx) {
+	if ts.attachmentsByTxID == nil {
+		var attachments []*table.Attachment
+		if ts.txIDs != nil {
+			attachments = getAttachmentsByTxIDs(tx, ts.txIDs)
+		} else {
+			attachments = getAttachmentsByTxAccountID(tx, ts.accountID)
+		}
+		ts.attachmentsByTxID = make(map[int64][]*table.Attachment)
+		for _, attachment := range attachments {
+			ts.attachmentsByTxID[*attachment.TransactionID] = append(ts.attachmentsByTxID[*attachment.TransactionID], attachment)
+		}
+	}
+}
@@ -1,13 +1,13 @@
 package domain
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,7 +16,7 @@ func Test_transactionSource_loadTransactionDetails(t *testing.T) {
 	txID := int64(96)
 	detailID := int64(69)
 	t.Run("loads transaction details by account ID", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			txSource := &transactionSource{accountID: accountID}
 			detail := &table.TransactionDetail{
 				ID:            detailID,
@@ -32,7 +32,7 @@ func Test_transactionSource_loadTransactionDetails(t *testing.T) {
 		})
 	})
 	t.Run("loads transaction details by tx IDs", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			txSource := &transactionSource{txIDs: []int64{txID}}
 			detail := &table.TransactionDetail{
 				ID:            detailID,
@@ -53,7 +53,7 @@ func Test_transactionSource_loadRelatedDetails(t *testing.T) {
 	id := int64(42)
 	txID := int64(96)
 	t.Run("returns for existing details", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			relatedDetail := NewTransactionDetail(id, -1)
 			txSource := &transactionSource{accountID: 69, relatedDetailsByID: map[int64]*TransactionDetail{id: relatedDetail}}
 
@@ -61,7 +61,7 @@ func Test_transactionSource_loadRelatedDetails(t *testing.T) {
 		})
 	})
 	t.Run("loads related details by account ID", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			txSource := &transactionSource{accountID: 69}
 			relatedDetail := &table.TransactionDetail{ID: id}
 			getRelatedDetailsStub := mocka.Function(t, &getRelatedDetailsByAccountID, []*table.TransactionDetail{relatedDetail})
@@ -75,7 +75,7 @@ func Test_transactionSource_loadRelatedDetails(t *testing.T) {
 		})
 	})
 	t.Run("loads related details by transaction IDs", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			txSource := &transactionSource{txIDs: []int64{txID}}
 			relatedDetail := &table.TransactionDetail{ID: id}
 			getRelatedDetailsStub := mocka.Function(t, &getRelatedDetailsByTxIDs, []*table.TransactionDetail{relatedDetail})
@@ -93,7 +93,7 @@ func Test_transactionSource_loadRelatedDetails(t *testing.T) {
 func Test_transactionSource_loadRelatedTransactions(t *testing.T) {
 	id := int64(42)
 	t.Run("loads related transactions by account id", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			txSource := &transactionSource{accountID: 69}
 			transaction := &table.Transaction{ID: id}
 			getTransactionsStub := mocka.Function(t, &getRelatedTransactionsByAccountID, []*table.Transaction{transaction})
@@ -106,7 +106,7 @@ func Test_transactionSource_loadRelatedTransactions(t *testing.T) {
 		})
 	})
 	t.Run("loads related transactions by transaction ids", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			txSource := &transactionSource{txIDs: []int64{69}}
 			transaction := &table.Transaction{ID: id}
 			getTransactionsStub := mocka.Function(t, &getRelatedTransactions, []*table.Transaction{transaction})
@@ -119,7 +119,7 @@ func Test_transactionSource_loadRelatedTransactions(t *testing.T) {
 		})
 	})
 	t.Run("returns for existing transactions", func(t *testing.T) {
-		sqltest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *sql.Tx) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
 			relatedTx := &Transaction{Transaction: &table.Transaction{ID: id}}
 			txSource := &transactionSource{accountID: 69, relatedTxByID: map[int64]*Transaction{id: relatedTx}}
 
@@ -127,3 +127,34 @@ func Test_transactionSource_loadRelatedTransactions(t *testing.T) {
 		})
 	})
 }
+
+func Test_transactionSource_loadAttachments(t *testing.T) {
+	txID := int64(96)
+	attachment := &table.Attachment{ID: 1, TransactionID: &txID}
+	t.Run("loads attachments by account ID", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			txSource := &transactionSource{accountID: 42}
+			getAttachmentsStub := mocka.Function(t, &getAttachmentsByTxAccountID, []*table.Attachment{attachment})
+			defer getAttachmentsStub.Restore()
+
+			txSource.loadAttachments(tx)
+			txSource.loadAttachments(tx)
+
+			assert.Equal(t, []*table.Attachment{attachment}, txSource.attachmentsByTxID[txID])
+			assert.Equal(t, 1, getAttachmentsStub.CallCount())
+			assert.Equal(t, []interface{}{tx, int64(42)}, getAttachmentsStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("loads attachments by tx IDs", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mockDB sqlmock.Sqlmock, tx *database.Tx) {
+			txSource := &transactionSource{txIDs: []int64{txID}}
+			getAttachmentsStub := mocka.Function(t, &getAttachmentsByTxIDs, []*table.Attachment{attachment})
+			defer getAttachmentsStub.Restore()
+
+			txSource.loadAttachments(tx)
+
+			assert.Equal(t, []*table.Attachment{attachment}, txSource.attachmentsByTxID[txID])
+			assert.Equal(t, []interface{}{tx, []int64{txID}}, getAttachmentsStub.GetCall(0).Arguments())
+		})
+	})
+}
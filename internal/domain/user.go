@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+// RoleUser is the role of users that can only access the accounts they have been granted.
+const RoleUser = "user"
+
+// User is a person allowed to access the application.
+type User struct {
+	source *userSource
+	*table.User
+}
+
+func (u *User) Resolve(p graphql.ResolveParams) (interface{}, error) {
+	return defaultResolveFn(replaceSource(p, u.User))
+}
+
+// GetAccountPermissions returns the account permissions granted to the user.
+func (u *User) GetAccountPermissions(tx *database.Tx) []*table.AccountPermission {
+	permissions, _ := u.source.permissions.get(tx, &u.ID, func(tx *database.Tx, userIDs []int64) map[int64]interface{} {
+		byUserID := make(map[int64]interface{})
+		for _, permission := range getAccountPermissionsByUserIDs(tx, userIDs) {
+			userPermissions, _ := byUserID[permission.UserID].([]*table.AccountPermission)
+			byUserID[permission.UserID] = append(userPermissions, permission)
+		}
+		return byUserID
+	}).([]*table.AccountPermission)
+	return permissions
+}
+
+// userSource provides users and their account permissions for a GraphQL request.
+type userSource struct {
+	permissions *batchLoader
+}
+
+func newUserSource() *userSource {
+	return &userSource{permissions: newBatchLoader()}
+}
+
+func (us *userSource) setUsers(dbUsers []*table.User) []*User {
+	users := make([]*User, len(dbUsers))
+	for i, user := range dbUsers {
+		users[i] = &User{source: us, User: user}
+		us.permissions.add(&user.ID)
+	}
+	return users
+}
+
+// GetAllUsers loads all users.
+func GetAllUsers(tx *database.Tx) []*User {
+	return newUserSource().setUsers(getAllUsers(tx))
+}
+
+// GetUsersByIDs returns the users with the IDs.
+func GetUsersByIDs(tx *database.Tx, ids []int64) []*User {
+	return newUserSource().setUsers(getUsersByIDs(tx, ids))
+}
+
+func validateRole(values database.InputObject) error {
+	if role, ok := values.GetString("role"); ok && role != table.RoleAdmin && role != RoleUser {
+		return apperror.Validation("role", "role must be admin or user")
+	}
+	return nil
+}
+
+// AddUsers adds users and returns their IDs. The role defaults to user.
+func AddUsers(tx *database.Tx, adds []map[string]interface{}, user string) ([]int64, error) {
+	ids := make([]int64, len(adds))
+	for i, add := range adds {
+		values := database.InputObject(add)
+		name, _ := values.StringOrNull("name").(string)
+		if err := validateName(name); err != nil {
+			return nil, err
+		}
+		if err := validateRole(values); err != nil {
+			return nil, err
+		}
+		role, ok := values.GetString("role")
+		if !ok {
+			role = RoleUser
+		}
+		ids[i] = addUser(tx, name, role.(string), user)
+	}
+	return ids, nil
+}
+
+// UpdateUsers changes the roles of users and returns their IDs.
+func UpdateUsers(tx *database.Tx, updates []map[string]interface{}, user string) ([]int64, error) {
+	ids := make([]int64, len(updates))
+	for i, update := range updates {
+		values := database.InputObject(update)
+		if err := validateRole(values); err != nil {
+			return nil, err
+		}
+		ids[i] = values.RequireInt("id")
+		if err := updateUser(tx, ids[i], values.RequireInt("version"), values, user); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// AddAdmin gives the admin role to the user with the name, adding the user if necessary.
+func AddAdmin(tx *database.Tx, name string, user string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	users := getUserByName(tx, name)
+	if len(users) == 0 {
+		addUser(tx, name, table.RoleAdmin, user)
+		return nil
+	}
+	if users[0].Role == table.RoleAdmin {
+		return nil
+	}
+	return updateUser(tx, users[0].ID, int64(users[0].Version), database.InputObject{"role": table.RoleAdmin}, user)
+}
+
+// GrantAccountPermissions sets the permissions of users for accounts and returns the IDs of the account permissions.
+func GrantAccountPermissions(tx *database.Tx, grants []map[string]interface{}, user string) ([]int64, error) {
+	ids := make([]int64, len(grants))
+	for i, grant := range grants {
+		values := database.InputObject(grant)
+		permission, _ := values.GetString("permission")
+		switch permission {
+		case table.PermissionNone, table.PermissionRead, table.PermissionWrite:
+		default:
+			return nil, apperror.Validation("permission", "permission must be none, read or write")
+		}
+		userID, accountID := values.RequireInt("userId"), values.RequireInt("accountId")
+		if len(getUsersByIDs(tx, []int64{userID})) == 0 {
+			return nil, apperror.NotFound("app_user", userID)
+		}
+		if len(getAccountsByIDs(tx, []int64{accountID})) == 0 {
+			return nil, apperror.NotFound("account", accountID)
+		}
+		ids[i] = grantAccountPermission(tx, userID, accountID, permission.(string), user)
+	}
+	return ids, nil
+}
@@ -0,0 +1,239 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_User_GetAccountPermissions(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		permissions := []*table.AccountPermission{
+			{ID: 1, UserID: 42, AccountID: 3},
+			{ID: 2, UserID: 42, AccountID: 4},
+			{ID: 3, UserID: 96, AccountID: 3},
+		}
+		getPermissionsStub := mocka.Function(t, &getAccountPermissionsByUserIDs, permissions)
+		defer getPermissionsStub.Restore()
+		getUsersStub := mocka.Function(t, &getAllUsers, []*table.User{{ID: 42}, {ID: 96}, {ID: 7}})
+		defer getUsersStub.Restore()
+		users := GetAllUsers(tx)
+
+		assert.Equal(t, permissions[:2], users[0].GetAccountPermissions(tx))
+		assert.Equal(t, permissions[2:], users[1].GetAccountPermissions(tx))
+		assert.Nil(t, users[2].GetAccountPermissions(tx))
+		assert.Equal(t, 1, getPermissionsStub.CallCount())
+		assert.ElementsMatch(t, []int64{42, 96, 7}, getPermissionsStub.GetCall(0).Arguments()[1])
+	})
+}
+
+func Test_GetAllUsers(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		dbUsers := []*table.User{{ID: 42}}
+		getUsersStub := mocka.Function(t, &getAllUsers, dbUsers)
+		defer getUsersStub.Restore()
+
+		result := GetAllUsers(tx)
+
+		assert.Len(t, result, 1)
+		assert.Same(t, dbUsers[0], result[0].User)
+		assert.NotNil(t, result[0].source)
+		assert.Equal(t, []interface{}{tx}, getUsersStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_GetUsersByIDs(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		dbUsers := []*table.User{{ID: 42}}
+		getUsersStub := mocka.Function(t, &getUsersByIDs, dbUsers)
+		defer getUsersStub.Restore()
+
+		result := GetUsersByIDs(tx, []int64{42})
+
+		assert.Same(t, dbUsers[0], result[0].User)
+		assert.Equal(t, []interface{}{tx, []int64{42}}, getUsersStub.GetCall(0).Arguments())
+	})
+}
+
+func Test_AddUsers(t *testing.T) {
+	t.Run("adds users", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			addUserStub := mocka.Function(t, &addUser, int64(42))
+			addUserStub.OnCall(1).Return(int64(43))
+			defer addUserStub.Restore()
+			adds := []map[string]interface{}{{"name": "alice", "role": table.RoleAdmin}, {"name": "bob"}}
+
+			ids, err := AddUsers(tx, adds, "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, []int64{42, 43}, ids)
+			assert.Equal(t, []interface{}{tx, "alice", table.RoleAdmin, "somebody"}, addUserStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, "bob", RoleUser, "somebody"}, addUserStub.GetCall(1).Arguments())
+		})
+	})
+	tests := []struct {
+		name    string
+		add     map[string]interface{}
+		message string
+	}{
+		{"for missing name", map[string]interface{}{"role": RoleUser}, "name must not be empty"},
+		{"for invalid role", map[string]interface{}{"name": "alice", "role": "owner"}, "role must be admin or user"},
+	}
+	for _, test := range tests {
+		t.Run("returns error "+test.name, func(t *testing.T) {
+			addUserStub := mocka.Function(t, &addUser, int64(42))
+			defer addUserStub.Restore()
+
+			ids, err := AddUsers(nil, []map[string]interface{}{test.add}, "somebody")
+
+			assert.Nil(t, ids)
+			assert.EqualError(t, err, test.message)
+			assert.Equal(t, 0, addUserStub.CallCount())
+		})
+	}
+}
+
+func Test_UpdateUsers(t *testing.T) {
+	t.Run("updates roles", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			updateUserStub := mocka.Function(t, &updateUser, nil)
+			defer updateUserStub.Restore()
+			update := map[string]interface{}{"id": 42, "version": 1, "role": table.RoleAdmin}
+
+			ids, err := UpdateUsers(tx, []map[string]interface{}{update}, "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, []int64{42}, ids)
+			assert.Equal(t, []interface{}{tx, int64(42), int64(1), database.InputObject(update), "somebody"},
+				updateUserStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("returns error for invalid role", func(t *testing.T) {
+		updateUserStub := mocka.Function(t, &updateUser, nil)
+		defer updateUserStub.Restore()
+		update := map[string]interface{}{"id": 42, "version": 1, "role": "owner"}
+
+		ids, err := UpdateUsers(nil, []map[string]interface{}{update}, "somebody")
+
+		assert.Nil(t, ids)
+		assert.EqualError(t, err, "role must be admin or user")
+		assert.Equal(t, 0, updateUserStub.CallCount())
+	})
+	t.Run("returns update error", func(t *testing.T) {
+		expectedErr := apperror.RowDeleted("app_user", 42, 1)
+		updateUserStub := mocka.Function(t, &updateUser, expectedErr)
+		defer updateUserStub.Restore()
+		update := map[string]interface{}{"id": 42, "version": 1, "role": RoleUser}
+
+		ids, err := UpdateUsers(nil, []map[string]interface{}{update}, "somebody")
+
+		assert.Nil(t, ids)
+		assert.Same(t, expectedErr, err)
+	})
+}
+
+func Test_AddAdmin(t *testing.T) {
+	t.Run("adds new user", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			getUserStub := mocka.Function(t, &getUserByName, []*table.User{})
+			defer getUserStub.Restore()
+			addUserStub := mocka.Function(t, &addUser, int64(42))
+			defer addUserStub.Restore()
+
+			err := AddAdmin(tx, "alice", "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{tx, "alice"}, getUserStub.GetCall(0).Arguments())
+			assert.Equal(t, []interface{}{tx, "alice", table.RoleAdmin, "somebody"}, addUserStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("updates role of existing user", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			getUserStub := mocka.Function(t, &getUserByName, []*table.User{{ID: 42, Role: RoleUser, Version: 3}})
+			defer getUserStub.Restore()
+			updateUserStub := mocka.Function(t, &updateUser, nil)
+			defer updateUserStub.Restore()
+
+			err := AddAdmin(tx, "alice", "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{tx, int64(42), int64(3), database.InputObject{"role": table.RoleAdmin}, "somebody"},
+				updateUserStub.GetCall(0).Arguments())
+		})
+	})
+	t.Run("ignores existing admin", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			getUserStub := mocka.Function(t, &getUserByName, []*table.User{{ID: 42, Role: table.RoleAdmin}})
+			defer getUserStub.Restore()
+			addUserStub := mocka.Function(t, &addUser, int64(42))
+			defer addUserStub.Restore()
+			updateUserStub := mocka.Function(t, &updateUser, nil)
+			defer updateUserStub.Restore()
+
+			err := AddAdmin(tx, "alice", "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, 0, addUserStub.CallCount())
+			assert.Equal(t, 0, updateUserStub.CallCount())
+		})
+	})
+	t.Run("returns error for invalid name", func(t *testing.T) {
+		err := AddAdmin(nil, " alice", "somebody")
+
+		assert.EqualError(t, err, "name must not contain leading or trailing white space")
+	})
+}
+
+func Test_GrantAccountPermissions(t *testing.T) {
+	grant := map[string]interface{}{"userId": 42, "accountId": 3, "permission": table.PermissionRead}
+	t.Run("grants permission", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			getUsersStub := mocka.Function(t, &getUsersByIDs, []*table.User{{ID: 42}})
+			defer getUsersStub.Restore()
+			getAccountsStub := mocka.Function(t, &getAccountsByIDs, []*table.Account{{ID: 3}})
+			defer getAccountsStub.Restore()
+			grantStub := mocka.Function(t, &grantAccountPermission, int64(7))
+			defer grantStub.Restore()
+
+			ids, err := GrantAccountPermissions(tx, []map[string]interface{}{grant}, "somebody")
+
+			assert.Nil(t, err)
+			assert.Equal(t, []int64{7}, ids)
+			assert.Equal(t, []interface{}{tx, int64(42), int64(3), table.PermissionRead, "somebody"}, grantStub.GetCall(0).Arguments())
+		})
+	})
+	tests := []struct {
+		name     string
+		grant    map[string]interface{}
+		users    []*table.User
+		accounts []*table.Account
+		message  string
+	}{
+		{"for invalid permission", map[string]interface{}{"userId": 42, "accountId": 3, "permission": "owner"},
+			nil, nil, "permission must be none, read or write"},
+		{"for unknown user", grant, []*table.User{}, nil, "app user not found (42)"},
+		{"for unknown account", grant, []*table.User{{ID: 42}}, []*table.Account{}, "account not found (3)"},
+	}
+	for _, test := range tests {
+		t.Run("returns error "+test.name, func(t *testing.T) {
+			getUsersStub := mocka.Function(t, &getUsersByIDs, test.users)
+			defer getUsersStub.Restore()
+			getAccountsStub := mocka.Function(t, &getAccountsByIDs, test.accounts)
+			defer getAccountsStub.Restore()
+			grantStub := mocka.Function(t, &grantAccountPermission, int64(7))
+			defer grantStub.Restore()
+
+			ids, err := GrantAccountPermissions(nil, []map[string]interface{}{test.grant}, "somebody")
+
+			assert.Nil(t, ids)
+			assert.EqualError(t, err, test.message)
+			assert.Equal(t, 0, grantStub.CallCount())
+		})
+	}
+}
@@ -1,9 +1,9 @@
 package domain
 
 import (
-	"errors"
 	"testing"
 
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,23 +13,17 @@ func Test_validateName(t *testing.T) {
 		value string
 		err   error
 	}{
-		{"panics for empty name", "", errors.New("name must not be empty")},
-		{"panics for leading whitespace", " x", errors.New("name must not contain leading or trailing white space")},
-		{"panics for trailing whitespace", "x ", errors.New("name must not contain leading or trailing white space")},
-		{"returns for valid name", "x", nil},
+		{"returns error for empty name", "", apperror.Validation("name", "name must not be empty")},
+		{"returns error for leading whitespace", " x", apperror.Validation("name", "name must not contain leading or trailing white space")},
+		{"returns error for trailing whitespace", "x ", apperror.Validation("name", "name must not contain leading or trailing white space")},
+		{"returns nil for valid name", "x", nil},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			defer func() {
-				if err := recover(); err != nil {
-					assert.Equal(t, test.err, err)
-				} else if test.err != nil {
-					assert.Fail(t, "expected an error")
-				}
-			}()
+			err := validateName(test.value)
 
-			validateName(test.value)
+			assert.Equal(t, test.err, err)
 		})
 	}
 }
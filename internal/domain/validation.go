@@ -1,16 +1,18 @@
 package domain
 
 import (
-	"errors"
 	"strings"
 	"unicode"
+
+	"github.com/jonestimd/financesd/internal/apperror"
 )
 
-var validateName = func(name string) {
+var validateName = func(name string) error {
 	if len(strings.TrimSpace(name)) == 0 {
-		panic(errors.New("name must not be empty"))
+		return apperror.Validation("name", "name must not be empty")
 	}
 	if unicode.IsSpace(rune(name[0])) || unicode.IsSpace(rune(name[len(name)-1])) {
-		panic(errors.New("name must not contain leading or trailing white space"))
+		return apperror.Validation("name", "name must not contain leading or trailing white space")
 	}
+	return nil
 }
@@ -0,0 +1,164 @@
+package migration
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+// schemaFile contains the current schema, i.e. the result of applying all of the migrations
+const schemaFile = "schema.sql"
+
+var addCategory = database.AddCategory
+var addCurrency = database.AddCurrency
+var setBaseCurrencyID = database.SetBaseCurrencyID
+var addAdmin = domain.AddAdmin
+
+// Options selects the starter data for a new database.
+type Options struct {
+	// Categories adds a starter category tree
+	Categories bool
+	// Currency is the code of the base currency. The currency is not added if it is empty.
+	Currency string
+}
+
+type category struct {
+	code          string
+	description   string
+	amountType    string
+	income        bool
+	security      bool
+	assetExchange bool
+	children      []*category
+}
+
+func yesNo(value bool) *table.YesNo {
+	yn := table.YesNo(0)
+	yn.Set(value)
+	return &yn
+}
+
+func (c *category) model(parentID *int64) *table.Category {
+	model := &table.Category{
+		Code:          c.code,
+		AmountType:    "DEBIT_DEPOSIT",
+		ParentID:      parentID,
+		Security:      yesNo(c.security),
+		Income:        yesNo(c.income),
+		AssetExchange: yesNo(c.assetExchange),
+	}
+	if c.description != "" {
+		model.Description = &c.description
+	}
+	if c.amountType != "" {
+		model.AmountType = c.amountType
+	}
+	return model
+}
+
+// starterCategories are added to a new database when requested. The asset exchange categories are the ones that are
+// updated by 001_asset_exchange.sql.
+var starterCategories = []*category{
+	{code: "Income", income: true, children: []*category{
+		{code: "Salary", income: true},
+		{code: "Interest", income: true},
+		{code: "Dividend", income: true, security: true},
+		{code: "Other", income: true},
+	}},
+	{code: "Expense", children: []*category{
+		{code: "Housing"},
+		{code: "Utilities"},
+		{code: "Groceries"},
+		{code: "Transportation"},
+		{code: "Medical"},
+		{code: "Entertainment"},
+		{code: "Taxes"},
+		{code: "Fees"},
+	}},
+	{code: "Buy", description: "Purchase shares", security: true, assetExchange: true},
+	{code: "Sell", description: "Sell shares", income: true, security: true, assetExchange: true},
+	{code: "Reinvest", description: "Reinvest a distribution", security: true, assetExchange: true},
+	{code: "Shares In", description: "Shares received without payment", amountType: "ASSET_VALUE", security: true, assetExchange: true},
+	{code: "Shares Out", description: "Shares removed without payment", amountType: "ASSET_VALUE", income: true, security: true, assetExchange: true},
+}
+
+func addCategories(tx *database.Tx, categories []*category, parentID *int64, user string) {
+	for _, c := range categories {
+		id := addCategory(tx, c.model(parentID), user)
+		addCategories(tx, c.children, &id, user)
+	}
+}
+
+type currencyInfo struct {
+	name   string
+	symbol string
+	scale  int
+}
+
+// currencies contains the names of common currencies. Other currency codes are added using the code as the name.
+var currencies = map[string]currencyInfo{
+	"AUD": {"Australian Dollar", "$", 2},
+	"CAD": {"Canadian Dollar", "$", 2},
+	"CHF": {"Swiss Franc", "Fr", 2},
+	"EUR": {"Euro", "€", 2},
+	"GBP": {"Pound Sterling", "£", 2},
+	"JPY": {"Japanese Yen", "¥", 0},
+	"USD": {"US Dollar", "$", 2},
+}
+
+func newCurrency(code string) *table.Currency {
+	code = strings.ToUpper(code)
+	info, ok := currencies[code]
+	if !ok {
+		info = currencyInfo{name: code, scale: 2}
+	}
+	currency := &table.Currency{Code: code, Asset: table.Asset{Name: info.name, Type: "Currency", Scale: info.scale}}
+	if info.symbol != "" {
+		currency.Symbol = &info.symbol
+	}
+	return currency
+}
+
+// Init creates the schema for a new database, records all of the migrations as applied and adds the starter data.
+// The user is added as an admin so that the database can be used without granting any permissions. Returns an error
+// if the database has already been initialized.
+func Init(db *sql.DB, options Options, user string) error {
+	migrations, err := load()
+	if err != nil {
+		return err
+	}
+	statements, err := readStatements(schemaFile)
+	if err != nil {
+		return err
+	}
+	return inTx(db, func(tx *database.Tx) error {
+		if len(getSchemaVersions(tx)) > 0 {
+			return errors.New("the database has already been initialized")
+		}
+		execScript(tx, statements)
+		for _, migration := range migrations {
+			addSchemaVersion(tx, migration.Version, migration.Name)
+		}
+		if err := addAdmin(tx, user, user); err != nil {
+			return err
+		}
+		if options.Categories {
+			addCategories(tx, starterCategories, nil, user)
+		}
+		if options.Currency != "" {
+			setBaseCurrencyID(tx, addCurrency(tx, newCurrency(options.Currency), user), user)
+		}
+		return nil
+	})
+}
+
+// AddAdmin gives the admin role to the user with the name, adding the user if necessary.
+func AddAdmin(db *sql.DB, name string, user string) error {
+	return inTx(db, func(tx *database.Tx) error {
+		return addAdmin(tx, name, user)
+	})
+}
@@ -0,0 +1,182 @@
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_category_model(t *testing.T) {
+	parentID := int64(1)
+	c := &category{code: "Shares In", description: "Shares received", amountType: "ASSET_VALUE", security: true, assetExchange: true}
+
+	model := c.model(&parentID)
+
+	assert.Equal(t, "Shares In", model.Code)
+	assert.Equal(t, "Shares received", *model.Description)
+	assert.Equal(t, "ASSET_VALUE", model.AmountType)
+	assert.Same(t, &parentID, model.ParentID)
+	assert.True(t, model.Security.Get())
+	assert.False(t, model.Income.Get())
+	assert.True(t, model.AssetExchange.Get())
+	assert.Nil(t, (&category{code: "Fees"}).model(nil).Description)
+	assert.Equal(t, "DEBIT_DEPOSIT", (&category{code: "Fees"}).model(nil).AmountType)
+}
+
+func Test_newCurrency(t *testing.T) {
+	usd := newCurrency("usd")
+	jpy := newCurrency("JPY")
+	xyz := newCurrency("XYZ")
+
+	assert.Equal(t, "USD", usd.Code)
+	assert.Equal(t, "US Dollar", usd.Name)
+	assert.Equal(t, "Currency", usd.Type)
+	assert.Equal(t, 2, usd.Scale)
+	assert.Equal(t, "$", *usd.Symbol)
+	assert.Equal(t, 0, jpy.Scale)
+	assert.Equal(t, &table.Currency{Code: "XYZ", Asset: table.Asset{Name: "XYZ", Type: "Currency", Scale: 2}}, xyz)
+}
+
+func Test_Init(t *testing.T) {
+	defer setFiles(testFiles)()
+	t.Run("creates schema and records migrations", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{})
+		defer getSchemaVersionsStub.Restore()
+		execScriptStub := mocka.Function(t, &execScript)
+		defer execScriptStub.Restore()
+		addSchemaVersionStub := mocka.Function(t, &addSchemaVersion)
+		defer addSchemaVersionStub.Restore()
+		addCategoryStub := mocka.Function(t, &addCategory, int64(1))
+		defer addCategoryStub.Restore()
+		addCurrencyStub := mocka.Function(t, &addCurrency, int64(1))
+		defer addCurrencyStub.Restore()
+		addAdminStub := mocka.Function(t, &addAdmin, nil)
+		defer addAdminStub.Restore()
+
+		err := Init(db, Options{}, "somebody")
+
+		assert.Nil(t, err)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Equal(t, []interface{}{[]string{"create table company (id @autoID())", "create table payee (id @autoID())"}},
+			execScriptStub.GetCall(0).Arguments()[1:])
+		assert.Equal(t, 2, addSchemaVersionStub.CallCount())
+		assert.Equal(t, []interface{}{1, "company"}, addSchemaVersionStub.GetCall(0).Arguments()[1:])
+		assert.Equal(t, []interface{}{2, "payee"}, addSchemaVersionStub.GetCall(1).Arguments()[1:])
+		assert.Equal(t, 0, addCategoryStub.CallCount())
+		assert.Equal(t, 0, addCurrencyStub.CallCount())
+		assert.Equal(t, []interface{}{"somebody", "somebody"}, addAdminStub.GetCall(0).Arguments()[1:])
+	})
+	t.Run("adds starter data", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{})
+		defer getSchemaVersionsStub.Restore()
+		execScriptStub := mocka.Function(t, &execScript)
+		defer execScriptStub.Restore()
+		addSchemaVersionStub := mocka.Function(t, &addSchemaVersion)
+		defer addSchemaVersionStub.Restore()
+		addCategoryStub := mocka.Function(t, &addCategory, int64(1))
+		defer addCategoryStub.Restore()
+		addCurrencyStub := mocka.Function(t, &addCurrency, int64(42))
+		defer addCurrencyStub.Restore()
+		setBaseCurrencyIDStub := mocka.Function(t, &setBaseCurrencyID)
+		defer setBaseCurrencyIDStub.Restore()
+		addAdminStub := mocka.Function(t, &addAdmin, nil)
+		defer addAdminStub.Restore()
+		categoryCount := 0
+		for _, c := range starterCategories {
+			categoryCount += 1 + len(c.children)
+		}
+
+		err := Init(db, Options{Categories: true, Currency: "EUR"}, "somebody")
+
+		assert.Nil(t, err)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Equal(t, categoryCount, addCategoryStub.CallCount())
+		income := addCategoryStub.GetCall(0).Arguments()[1].(*table.Category)
+		salary := addCategoryStub.GetCall(1).Arguments()[1].(*table.Category)
+		assert.Equal(t, "Income", income.Code)
+		assert.Nil(t, income.ParentID)
+		assert.Equal(t, "Salary", salary.Code)
+		assert.Equal(t, int64(1), *salary.ParentID)
+		assert.Equal(t, "EUR", addCurrencyStub.GetCall(0).Arguments()[1].(*table.Currency).Code)
+		assert.Equal(t, []interface{}{int64(42), "somebody"}, setBaseCurrencyIDStub.GetCall(0).Arguments()[1:])
+	})
+	t.Run("returns error for invalid user name", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{})
+		defer getSchemaVersionsStub.Restore()
+		execScriptStub := mocka.Function(t, &execScript)
+		defer execScriptStub.Restore()
+		addSchemaVersionStub := mocka.Function(t, &addSchemaVersion)
+		defer addSchemaVersionStub.Restore()
+		expectedErr := errors.New("name must not be empty")
+		addAdminStub := mocka.Function(t, &addAdmin, expectedErr)
+		defer addAdminStub.Restore()
+
+		err := Init(db, Options{}, "")
+
+		assert.Same(t, expectedErr, err)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+	t.Run("returns error if already initialized", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{{Version: 1}})
+		defer getSchemaVersionsStub.Restore()
+		execScriptStub := mocka.Function(t, &execScript)
+		defer execScriptStub.Restore()
+
+		err := Init(db, Options{}, "somebody")
+
+		assert.EqualError(t, err, "the database has already been initialized")
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Equal(t, 0, execScriptStub.CallCount())
+	})
+}
+
+func Test_AddAdmin(t *testing.T) {
+	t.Run("commits transaction", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		addAdminStub := mocka.Function(t, &addAdmin, nil)
+		defer addAdminStub.Restore()
+
+		err := AddAdmin(db, "admin", "somebody")
+
+		assert.Nil(t, err)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Equal(t, []interface{}{"admin", "somebody"}, addAdminStub.GetCall(0).Arguments()[1:])
+	})
+	t.Run("rolls back on error", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		expectedErr := errors.New("invalid name")
+		addAdminStub := mocka.Function(t, &addAdmin, expectedErr)
+		defer addAdminStub.Restore()
+
+		err := AddAdmin(db, "", "somebody")
+
+		assert.Same(t, expectedErr, err)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func Test_schemaFile(t *testing.T) {
+	statements, err := readStatements(schemaFile)
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, statements)
+}
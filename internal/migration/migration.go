@@ -0,0 +1,203 @@
+// Package migration applies the schema changes in the migrations directory to the database. The migrations are
+// embedded in the binary and the applied versions are recorded in the schema_version table.
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/migrations"
+)
+
+var files fs.FS = migrations.Files
+var getSchemaVersions = database.GetSchemaVersions
+var execScript = database.ExecScript
+var addSchemaVersion = database.AddSchemaVersion
+var checkSchema = database.CheckSchema
+
+// Migration is a versioned change to the database schema.
+type Migration struct {
+	Version int
+	Name    string
+	// ApplyDate is nil if the migration has not been applied
+	ApplyDate *time.Time
+	file      string
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+// statements are separated by a semicolon at the end of a line
+var statementSeparator = regexp.MustCompile(`;[ \t]*(?:\r?\n|$)`)
+
+var blankPattern = regexp.MustCompile(`^\s*$`)
+
+// load returns the embedded migrations ordered by version.
+func load() ([]*Migration, error) {
+	names, err := fs.Glob(files, "[0-9]*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var result []*Migration
+	versions := make(map[int]string)
+	for _, name := range names {
+		match := fileNamePattern.FindStringSubmatch(name)
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		version, _ := strconv.Atoi(match[1])
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version: %s, %s", other, name)
+		}
+		versions[version] = name
+		result = append(result, &Migration{Version: version, Name: match[2], file: name})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// readStatements returns the SQL statements in the file.
+func readStatements(file string) ([]string, error) {
+	script, err := fs.ReadFile(files, file)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, statement := range statementSeparator.Split(string(script), -1) {
+		if !blankPattern.MatchString(statement) {
+			result = append(result, statement)
+		}
+	}
+	return result, nil
+}
+
+// inTx runs fn in a database transaction. The transaction is committed if fn doesn't panic or return an error.
+func inTx(db *sql.DB, fn func(tx *database.Tx) error) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	if err := fn(database.NewTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Status returns all of the migrations with the apply dates of the ones that have been applied.
+func Status(db *sql.DB) ([]*Migration, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+	err = inTx(db, func(tx *database.Tx) error {
+		applied := make(map[int]time.Time)
+		for _, version := range getSchemaVersions(tx) {
+			applied[version.Version] = version.ApplyDate
+		}
+		for _, migration := range migrations {
+			if date, ok := applied[migration.Version]; ok {
+				migration.ApplyDate = &date
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+// Pending returns the migrations that have not been applied.
+func Pending(db *sql.DB) ([]*Migration, error) {
+	migrations, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Migration
+	for _, migration := range migrations {
+		if migration.ApplyDate == nil {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// CheckSchema returns an error if the database is missing any of the columns used by the application.
+func CheckSchema(db *sql.DB) error {
+	return inTx(db, checkSchema)
+}
+
+// Up applies the pending migrations in order of version. Each migration is applied in a separate transaction
+// and the migrations stop at the first failure. Returns the migrations that were applied. MySQL commits DDL
+// statements implicitly, so a failed migration may be partially applied.
+func Up(db *sql.DB) ([]*Migration, error) {
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+	var applied []*Migration
+	for _, migration := range pending {
+		statements, err := readStatements(migration.file)
+		if err != nil {
+			return applied, err
+		}
+		err = inTx(db, func(tx *database.Tx) error {
+			execScript(tx, statements)
+			addSchemaVersion(tx, migration.Version, migration.Name)
+			return nil
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %s failed: %v", migration, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Baseline records the pending migrations up to and including the version as applied without running them, e.g. for
+// a database that was changed by hand. Returns the migrations that were recorded.
+func Baseline(db *sql.DB, version int) ([]*Migration, error) {
+	migrations, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	var baseline []*Migration
+	for _, migration := range migrations {
+		if migration.Version == version {
+			found = true
+		}
+		if migration.Version <= version && migration.ApplyDate == nil {
+			baseline = append(baseline, migration)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown migration version: %d", version)
+	}
+	err = inTx(db, func(tx *database.Tx) error {
+		for _, migration := range baseline {
+			addSchemaVersion(tx, migration.Version, migration.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
@@ -0,0 +1,268 @@
+package migration
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFiles = fstest.MapFS{
+	"schema.sql":      {Data: []byte("create table company (id @autoID());\ncreate table payee (id @autoID());\n")},
+	"002_payee.sql":   {Data: []byte("create table payee (id bigint);\n\ncreate index payee_ix on payee (id);\n")},
+	"001_company.sql": {Data: []byte("create table company (\n  id bigint,\n  name varchar(10)\n);")},
+}
+
+func setFiles(fs fstest.MapFS) func() {
+	saved := files
+	files = fs
+	return func() { files = saved }
+}
+
+func newTestDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mockDB, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	return db, mockDB
+}
+
+func Test_Migration_String(t *testing.T) {
+	assert.Equal(t, "001_asset_exchange", (&Migration{Version: 1, Name: "asset_exchange"}).String())
+}
+
+func Test_load(t *testing.T) {
+	t.Run("returns embedded migrations in order", func(t *testing.T) {
+		migrations, err := load()
+
+		assert.Nil(t, err)
+		assert.NotEmpty(t, migrations)
+		for i, migration := range migrations {
+			assert.Equal(t, i+1, migration.Version)
+		}
+		assert.Equal(t, "asset_exchange", migrations[0].Name)
+	})
+	t.Run("returns error for invalid file name", func(t *testing.T) {
+		defer setFiles(fstest.MapFS{"1-payee.sql": {}})()
+
+		_, err := load()
+
+		assert.EqualError(t, err, "invalid migration file name: 1-payee.sql")
+	})
+	t.Run("returns error for duplicate version", func(t *testing.T) {
+		defer setFiles(fstest.MapFS{"1_company.sql": {}, "001_payee.sql": {}})()
+
+		_, err := load()
+
+		assert.EqualError(t, err, "duplicate migration version: 001_payee.sql, 1_company.sql")
+	})
+}
+
+func Test_readStatements(t *testing.T) {
+	defer setFiles(testFiles)()
+
+	company, err := readStatements("001_company.sql")
+	assert.Nil(t, err)
+	payee, err := readStatements("002_payee.sql")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"create table company (\n  id bigint,\n  name varchar(10)\n)"}, company)
+	assert.Equal(t, []string{"create table payee (id bigint)", "\ncreate index payee_ix on payee (id)"}, payee)
+}
+
+func Test_Status(t *testing.T) {
+	defer setFiles(testFiles)()
+	t.Run("returns migrations with apply date", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		applyDate := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{{Version: 1, ApplyDate: applyDate}})
+		defer getSchemaVersionsStub.Restore()
+
+		migrations, err := Status(db)
+
+		assert.Nil(t, err)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Equal(t, []*Migration{
+			{Version: 1, Name: "company", ApplyDate: &applyDate, file: "001_company.sql"},
+			{Version: 2, Name: "payee", file: "002_payee.sql"},
+		}, migrations)
+	})
+	t.Run("returns query error", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		originalGetSchemaVersions := getSchemaVersions
+		defer func() { getSchemaVersions = originalGetSchemaVersions }()
+		getSchemaVersions = func(*database.Tx) []*table.SchemaVersion {
+			panic(errors.New("access denied"))
+		}
+
+		_, err := Status(db)
+
+		assert.EqualError(t, err, "access denied")
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func Test_Pending(t *testing.T) {
+	defer setFiles(testFiles)()
+	db, mockDB := newTestDB(t)
+	mockDB.ExpectBegin()
+	mockDB.ExpectCommit()
+	getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{{Version: 1}})
+	defer getSchemaVersionsStub.Restore()
+
+	pending, err := Pending(db)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*Migration{{Version: 2, Name: "payee", file: "002_payee.sql"}}, pending)
+}
+
+func Test_CheckSchema(t *testing.T) {
+	t.Run("returns nil if schema is current", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		checkSchemaStub := mocka.Function(t, &checkSchema, nil)
+		defer checkSchemaStub.Restore()
+
+		err := CheckSchema(db)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, checkSchemaStub.CallCount())
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+	t.Run("returns error for missing columns", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		checkSchemaStub := mocka.Function(t, &checkSchema, errors.New("columns missing from the database: payee.name"))
+		defer checkSchemaStub.Restore()
+
+		err := CheckSchema(db)
+
+		assert.EqualError(t, err, "columns missing from the database: payee.name")
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+	})
+}
+
+func Test_Up(t *testing.T) {
+	defer setFiles(testFiles)()
+	t.Run("applies pending migrations", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		for i := 0; i < 3; i++ {
+			mockDB.ExpectBegin()
+			mockDB.ExpectCommit()
+		}
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{})
+		defer getSchemaVersionsStub.Restore()
+		execScriptStub := mocka.Function(t, &execScript)
+		defer execScriptStub.Restore()
+		addSchemaVersionStub := mocka.Function(t, &addSchemaVersion)
+		defer addSchemaVersionStub.Restore()
+
+		applied, err := Up(db)
+
+		assert.Nil(t, err)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Equal(t, []*Migration{
+			{Version: 1, Name: "company", file: "001_company.sql"},
+			{Version: 2, Name: "payee", file: "002_payee.sql"},
+		}, applied)
+		assert.Equal(t, 2, execScriptStub.CallCount())
+		assert.Equal(t, []interface{}{[]string{"create table payee (id bigint)", "\ncreate index payee_ix on payee (id)"}},
+			execScriptStub.GetCall(1).Arguments()[1:])
+		assert.Equal(t, []interface{}{2, "payee"}, addSchemaVersionStub.GetCall(1).Arguments()[1:])
+	})
+	t.Run("stops at failed migration", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectRollback()
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{})
+		defer getSchemaVersionsStub.Restore()
+		originalExecScript := execScript
+		defer func() { execScript = originalExecScript }()
+		calls := 0
+		execScript = func(*database.Tx, []string) {
+			calls++
+			panic(errors.New("table exists"))
+		}
+
+		applied, err := Up(db)
+
+		assert.EqualError(t, err, "migration 001_company failed: table exists")
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Empty(t, applied)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func Test_Baseline(t *testing.T) {
+	defer setFiles(testFiles)()
+	t.Run("records pending migrations up to version", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{})
+		defer getSchemaVersionsStub.Restore()
+		execScriptStub := mocka.Function(t, &execScript)
+		defer execScriptStub.Restore()
+		addSchemaVersionStub := mocka.Function(t, &addSchemaVersion)
+		defer addSchemaVersionStub.Restore()
+
+		baseline, err := Baseline(db, 1)
+
+		assert.Nil(t, err)
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Equal(t, []*Migration{{Version: 1, Name: "company", file: "001_company.sql"}}, baseline)
+		assert.Equal(t, 1, addSchemaVersionStub.CallCount())
+		assert.Equal(t, []interface{}{1, "company"}, addSchemaVersionStub.GetCall(0).Arguments()[1:])
+		assert.Equal(t, 0, execScriptStub.CallCount())
+	})
+	t.Run("skips applied migrations", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{{Version: 1}})
+		defer getSchemaVersionsStub.Restore()
+		addSchemaVersionStub := mocka.Function(t, &addSchemaVersion)
+		defer addSchemaVersionStub.Restore()
+
+		baseline, err := Baseline(db, 2)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []*Migration{{Version: 2, Name: "payee", file: "002_payee.sql"}}, baseline)
+		assert.Equal(t, []interface{}{2, "payee"}, addSchemaVersionStub.GetCall(0).Arguments()[1:])
+	})
+	t.Run("returns error for unknown version", func(t *testing.T) {
+		db, mockDB := newTestDB(t)
+		mockDB.ExpectBegin()
+		mockDB.ExpectCommit()
+		getSchemaVersionsStub := mocka.Function(t, &getSchemaVersions, []*table.SchemaVersion{})
+		defer getSchemaVersionsStub.Restore()
+		addSchemaVersionStub := mocka.Function(t, &addSchemaVersion)
+		defer addSchemaVersionStub.Restore()
+
+		baseline, err := Baseline(db, 3)
+
+		assert.EqualError(t, err, "unknown migration version: 3")
+		assert.Nil(t, mockDB.ExpectationsWereMet())
+		assert.Nil(t, baseline)
+		assert.Equal(t, 0, addSchemaVersionStub.CallCount())
+	})
+}
@@ -1,9 +1,8 @@
 package schema
 
 import (
-	"database/sql"
-
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/domain"
 )
 
@@ -21,8 +20,14 @@ var accountSchema = graphql.NewObject(graphql.ObjectConfig{
 		"type":             &graphql.Field{Type: graphql.String}, // TODO enum?
 		"closed":           &graphql.Field{Type: yesNoType},
 		"currencyId":       &graphql.Field{Type: graphql.Int},
+		"currency":         &graphql.Field{Type: currencySchema, Resolve: resolveCurrency},
 		"transactionCount": &graphql.Field{Type: graphql.Int},
-		"balance":          &graphql.Field{Type: graphql.String},
+		"balance": &graphql.Field{
+			Type:    graphql.String,
+			Args:    graphql.FieldConfigArgument{"currency": {Type: graphql.Int, Description: "ID of the currency for converting the balance."}},
+			Resolve: resolveBalance,
+		},
+		"attachments": &graphql.Field{Type: graphql.NewList(attachmentSchema), Resolve: resolveAttachments},
 	}),
 })
 
@@ -33,25 +38,28 @@ var accountQueryFields = &graphql.Field{
 		"name": {Type: graphql.String, Description: "unique account name"},
 	},
 	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		permissions := getPermissions(p)
 		if idArg, ok := p.Args["id"]; ok {
 			id := idArg.(int)
-			return getAccountByID(tx, int64(id)), nil
+			return permissions.FilterAccounts(getAccountByID(tx, int64(id))), nil
 		}
 		if nameArg, ok := p.Args["name"]; ok {
 			name, _ := nameArg.(string)
-			return getAccountsByName(tx, name), nil
+			return permissions.FilterAccounts(getAccountsByName(tx, name)), nil
 		}
-		return getAllAccounts(tx), nil
+		return permissions.FilterAccounts(getAllAccounts(tx)), nil
 	},
 }
 
 type accountModel interface {
-	GetCompany(tx *sql.Tx) (*domain.Company, error)
+	GetCompany(tx *database.Tx) *domain.Company
 }
 
+var _ accountModel = (*domain.Account)(nil)
+
 func resolveCompany(p graphql.ResolveParams) (interface{}, error) {
 	account := p.Source.(accountModel)
-	tx := p.Context.Value(DbContextKey).(*sql.Tx)
-	return account.GetCompany(tx)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return account.GetCompany(tx), nil
 }
@@ -1,14 +1,14 @@
 package schema
 
 import (
-	"database/sql"
-	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
 	"github.com/jonestimd/financesd/internal/domain"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,7 +16,7 @@ func Test_accountQueryFields_Resolve(t *testing.T) {
 	companyID := int64(99)
 	accountID := int64(1)
 	accounts := []*domain.Account{domain.NewAccount(accountID, &companyID)}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		getAll := mocka.Function(t, &getAllAccounts, accounts)
 		byID := mocka.Function(t, &getAccountByID, accounts)
 		byName := mocka.Function(t, &getAccountsByName, accounts)
@@ -57,27 +57,41 @@ func Test_accountQueryFields_Resolve(t *testing.T) {
 	})
 }
 
+func Test_accountQueryFields_Resolve_filtersAccounts(t *testing.T) {
+	accounts := []*domain.Account{domain.NewAccount(1, nil), domain.NewAccount(2, nil)}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		getAll := mocka.Function(t, &getAllAccounts, accounts)
+		defer getAll.Restore()
+		params := newResolveParams(tx, accountQuery, newField("", "id")).
+			setPermissions(domain.NewPermissions(false, map[int64]string{2: table.PermissionRead}))
+
+		result, err := accountQueryFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, accounts[1:], result)
+	})
+}
+
 type mockAccountModel struct {
 	company *domain.Company
-	err     error
-	tx      *sql.Tx
+	tx      *database.Tx
 }
 
-func (a *mockAccountModel) GetCompany(tx *sql.Tx) (*domain.Company, error) {
+func (a *mockAccountModel) GetCompany(tx *database.Tx) *domain.Company {
 	a.tx = tx
-	return a.company, a.err
+	return a.company
 }
 
 func Test_resolveCompany(t *testing.T) {
 	companyID := int64(99)
 	company := domain.NewCompany(companyID, "")
-	mockAccount := &mockAccountModel{company: company, err: errors.New("test error")}
-	sqltest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *sql.Tx) {
+	mockAccount := &mockAccountModel{company: company}
+	dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
 		params := newResolveParams(tx, "", newField("", "id"), newField("", "name")).setSource(mockAccount)
 
 		result, err := resolveCompany(params.ResolveParams)
 
-		assert.Same(t, mockAccount.err, err)
+		assert.Nil(t, err)
 		assert.Same(t, mockAccount.company, result)
 		assert.Same(t, tx, mockAccount.tx)
 	})
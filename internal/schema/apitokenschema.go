@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+)
+
+var apiTokenSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "apiToken",
+	Description: "a personal token for authenticating API requests",
+	Fields: addAudit(graphql.Fields{
+		"id":         &graphql.Field{Type: nonNullInt},
+		"name":       &graphql.Field{Type: nonNullString},
+		"expiryDate": &graphql.Field{Type: dateType, Description: "The token can't be used on or after this date."},
+	}),
+})
+
+var newAPITokenSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "newApiToken",
+	Description: "a newly created API token",
+	Fields: graphql.Fields{
+		"token":    &graphql.Field{Type: nonNullString, Description: "The bearer token. It can't be retrieved again."},
+		"apiToken": &graphql.Field{Type: graphql.NewNonNull(apiTokenSchema)},
+	},
+})
+
+var apiTokenQueryFields = &graphql.Field{
+	Type:        nonNullList(apiTokenSchema),
+	Description: "API tokens of the current user.",
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		return getAPITokens(tx, user), nil
+	},
+}
+
+var createAPITokenFields = &graphql.Field{
+	Type:        newAPITokenSchema,
+	Description: "Create an API token for the current user.",
+	Args: graphql.FieldConfigArgument{
+		"name":       {Type: nonNullString, Description: "Unique name of the token."},
+		"expiryDate": {Type: dateType, Description: "Date when the token expires."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		return createAPIToken(tx, p.Args["name"].(string), p.Args["expiryDate"], user)
+	},
+}
+
+var deleteAPITokensFields = &graphql.Field{
+	Type:        nonNullInt,
+	Description: "Delete API tokens of the current user. Returns the number of deleted tokens.",
+	Args: graphql.FieldConfigArgument{
+		"ids": {Type: graphql.NewNonNull(intList), Description: "IDs of the tokens to delete."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		ids := make([]int64, 0)
+		for _, id := range p.Args["ids"].([]interface{}) {
+			ids = append(ids, int64(id.(int)))
+		}
+		return deleteAPITokens(tx, ids, user)
+	},
+}
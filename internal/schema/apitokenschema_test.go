@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_apiTokenQueryFields_Resolve(t *testing.T) {
+	tokens := []*table.APIToken{{ID: 42}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		getTokensStub := mocka.Function(t, &getAPITokens, tokens)
+		defer getTokensStub.Restore()
+		params := newResolveParams(tx, apiTokenQuery, newField("", "id"))
+
+		result, err := apiTokenQueryFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, tokens, result)
+		assert.Equal(t, []interface{}{tx, "somebody"}, getTokensStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_createAPITokenFields_Resolve(t *testing.T) {
+	token := &domain.NewAPIToken{Token: "fin_x", APIToken: &table.APIToken{ID: 42}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		createStub := mocka.Function(t, &createAPIToken, token, nil)
+		defer createStub.Restore()
+		params := newResolveParams(tx, createAPITokenMutation, newField("", "token")).addArg("name", "laptop")
+
+		result, err := createAPITokenFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Same(t, token, result)
+		assert.Equal(t, []interface{}{tx, "laptop", nil, "somebody"}, createStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_deleteAPITokensFields_Resolve(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		deleteStub := mocka.Function(t, &deleteAPITokens, int64(2), nil)
+		defer deleteStub.Restore()
+		params := newResolveParams(tx, deleteAPITokensMutation).addArg("ids", []interface{}{1, 2})
+
+		result, err := deleteAPITokensFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), result)
+		assert.Equal(t, []interface{}{tx, []int64{1, 2}, "somebody"}, deleteStub.GetFirstCall().Arguments())
+	})
+}
@@ -0,0 +1,35 @@
+package schema
+
+import (
+	"errors"
+
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+)
+
+var attachmentSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "attachment",
+	Description: "a file attached to a transaction or an account",
+	Fields: addAudit(graphql.Fields{
+		"id":            &graphql.Field{Type: nonNullInt},
+		"transactionId": &graphql.Field{Type: graphql.Int},
+		"accountId":     &graphql.Field{Type: graphql.Int},
+		"fileName":      &graphql.Field{Type: nonNullString},
+		"contentType":   &graphql.Field{Type: nonNullString},
+		"size":          &graphql.Field{Type: nonNullInt, Description: "size of the file in bytes"},
+		"hash":          &graphql.Field{Type: nonNullString, Description: "SHA-256 hash of the file content"},
+	}),
+})
+
+type attachmentsModel interface {
+	GetAttachments(tx *database.Tx) []*table.Attachment
+}
+
+func resolveAttachments(p graphql.ResolveParams) (interface{}, error) {
+	if model, ok := p.Source.(attachmentsModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return model.GetAttachments(tx), nil
+	}
+	return nil, errors.New("invalid source")
+}
@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockAttachmentsModel struct {
+	tx          *database.Tx
+	attachments []*table.Attachment
+}
+
+func (m *mockAttachmentsModel) GetAttachments(tx *database.Tx) []*table.Attachment {
+	m.tx = tx
+	return m.attachments
+}
+
+func Test_resolveAttachments(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		t.Run("returns attachments", func(t *testing.T) {
+			source := &mockAttachmentsModel{attachments: []*table.Attachment{{ID: 42}}}
+			params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(source)
+
+			result, err := getTxSchema().Fields()["attachments"].Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, source.attachments, result)
+			assert.Same(t, tx, source.tx)
+		})
+		t.Run("returns error for invalid source", func(t *testing.T) {
+			params := newResolveParams(tx, accountQuery, newField("", "id"))
+
+			_, err := accountSchema.Fields()["attachments"].Resolve(params.ResolveParams)
+
+			assert.Equal(t, "invalid source", err.Error())
+		})
+	})
+}
@@ -1,31 +1,95 @@
 package schema
 
 import (
-	"database/sql"
-
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
 )
 
 var categorySchema = graphql.NewObject(graphql.ObjectConfig{
 	Name:        "category",
 	Description: "the type of a transaction",
 	Fields: addAudit(graphql.Fields{
-		"id":               &graphql.Field{Type: graphql.Int},
-		"code":             &graphql.Field{Type: graphql.String},
-		"description":      &graphql.Field{Type: graphql.String},
-		"amountType":       &graphql.Field{Type: graphql.String},
-		"parentId":         &graphql.Field{Type: graphql.Int},
-		"security":         &graphql.Field{Type: yesNoType},
-		"income":           &graphql.Field{Type: yesNoType},
-		"assetExchange":    &graphql.Field{Type: yesNoType},
-		"transactionCount": &graphql.Field{Type: graphql.Int},
+		"id":            &graphql.Field{Type: graphql.Int},
+		"code":          &graphql.Field{Type: graphql.String},
+		"description":   &graphql.Field{Type: graphql.String},
+		"amountType":    &graphql.Field{Type: graphql.String},
+		"parentId":      &graphql.Field{Type: graphql.Int},
+		"security":      &graphql.Field{Type: yesNoType},
+		"income":        &graphql.Field{Type: yesNoType},
+		"assetExchange": &graphql.Field{Type: yesNoType},
+		"transactionCount": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Number of transactions with details in the category in the readable accounts.",
+			Resolve:     resolveCategoryTransactionCount,
+		},
+		"amount": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Total of the transaction details with the category in the readable accounts.",
+			Args:        convertArgs,
+			Resolve:     resolveCategoryAmount,
+		},
 	}),
 })
 
-var categoryQueryFields = &graphql.Field{
-	Type: graphql.NewList(categorySchema),
-	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
-		return getAllCategories(tx), nil
-	},
+func categoryQueryFields() *graphql.Field {
+	categorySchema.AddFieldConfig("parent", &graphql.Field{Type: categorySchema, Resolve: resolveCategoryParent})
+	categorySchema.AddFieldConfig("children", &graphql.Field{Type: newList(categorySchema), Resolve: resolveCategoryChildren})
+	categorySchema.AddFieldConfig("details", &graphql.Field{
+		Type:        txDetailList,
+		Description: "Transaction details with the category in the readable accounts, ordered by transaction date.",
+		Args:        getPageArgs(),
+		Resolve:     resolveCategoryDetails,
+	})
+	return &graphql.Field{
+		Type: graphql.NewList(categorySchema),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			tx := p.Context.Value(DbContextKey).(*database.Tx)
+			return getAllCategories(tx), nil
+		},
+	}
+}
+
+type categoryModel interface {
+	GetParent(tx *database.Tx) *domain.Category
+	GetChildren(tx *database.Tx) []*domain.Category
+	GetDetails(tx *database.Tx, filter database.PageFilter, accountIDs []int64) []*domain.TransactionDetail
+	GetTransactionCount(tx *database.Tx, accountIDs []int64) int64
+	GetAmount(tx *database.Tx, currencyID *int64, accountIDs []int64) (string, error)
+}
+
+var _ categoryModel = (*domain.Category)(nil)
+
+func resolveCategoryParent(p graphql.ResolveParams) (interface{}, error) {
+	category := p.Source.(categoryModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return category.GetParent(tx), nil
+}
+
+func resolveCategoryChildren(p graphql.ResolveParams) (interface{}, error) {
+	category := p.Source.(categoryModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return category.GetChildren(tx), nil
+}
+
+func resolveCategoryDetails(p graphql.ResolveParams) (interface{}, error) {
+	category := p.Source.(categoryModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	filter, err := getPageFilter(p)
+	if err != nil {
+		return nil, err
+	}
+	return category.GetDetails(tx, filter, getPermissions(p).ReadableAccountIDs()), nil
+}
+
+func resolveCategoryTransactionCount(p graphql.ResolveParams) (interface{}, error) {
+	category := p.Source.(categoryModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return category.GetTransactionCount(tx, getPermissions(p).ReadableAccountIDs()), nil
+}
+
+func resolveCategoryAmount(p graphql.ResolveParams) (interface{}, error) {
+	category := p.Source.(categoryModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return category.GetAmount(tx, getCurrencyArg(p), getPermissions(p).ReadableAccountIDs())
 }
@@ -1,27 +1,148 @@
 package schema
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/jonestimd/financesd/internal/domain"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_categoryQueryFields_Resolve(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
-		categories := []*table.Category{{ID: 42}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		categories := []*domain.Category{{Category: &table.Category{ID: 42}}}
 		getAll := mocka.Function(t, &getAllCategories, categories)
 		defer getAll.Restore()
 		params := newResolveParams(tx, categoryQuery, newField("", "id"), newField("", "code"))
 
-		result, err := categoryQueryFields.Resolve(params.ResolveParams)
+		result, err := categoryQueryFields().Resolve(params.ResolveParams)
 
 		assert.Nil(t, err)
 		assert.Equal(t, categories, result)
 		assert.Equal(t, []interface{}{tx}, getAll.GetFirstCall().Arguments())
 	})
 }
+
+type mockCategoryModel struct {
+	mockDetailsModel
+	parent     *domain.Category
+	children   []*domain.Category
+	currencyID *int64
+	amount     string
+	amountErr  error
+}
+
+func (m *mockCategoryModel) GetAmount(tx *database.Tx, currencyID *int64, accountIDs []int64) (string, error) {
+	m.tx = tx
+	m.currencyID = currencyID
+	m.accountIDs = accountIDs
+	return m.amount, m.amountErr
+}
+
+func (m *mockCategoryModel) GetParent(tx *database.Tx) *domain.Category {
+	m.tx = tx
+	return m.parent
+}
+
+func (m *mockCategoryModel) GetChildren(tx *database.Tx) []*domain.Category {
+	m.tx = tx
+	return m.children
+}
+
+func Test_resolveCategoryParent(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		category := &mockCategoryModel{parent: &domain.Category{Category: &table.Category{ID: 1}}}
+		params := newResolveParams(tx, categoryQuery).setSource(category)
+
+		result, err := resolveCategoryParent(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Same(t, category.parent, result)
+		assert.Same(t, tx, category.tx)
+	})
+}
+
+func Test_resolveCategoryChildren(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		category := &mockCategoryModel{children: []*domain.Category{{Category: &table.Category{ID: 2}}}}
+		params := newResolveParams(tx, categoryQuery).setSource(category)
+
+		result, err := resolveCategoryChildren(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, category.children, result)
+		assert.Same(t, tx, category.tx)
+	})
+}
+
+func Test_resolveCategoryDetails(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		category := &mockCategoryModel{}
+		category.details = []*domain.TransactionDetail{domain.NewTransactionDetail(42, 96)}
+		params := newResolveParams(tx, categoryQuery).setSource(category).addArg("limit", 10)
+
+		result, err := resolveCategoryDetails(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, category.details, result)
+		assert.Same(t, tx, category.tx)
+		assert.Equal(t, database.PageFilter{Limit: 10}, category.filter)
+		assert.Nil(t, category.accountIDs)
+	})
+}
+
+func Test_resolveCategoryAmount(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		t.Run("returns converted amount in readable accounts", func(t *testing.T) {
+			category := &mockCategoryModel{amount: "12.34"}
+			permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+			params := newResolveParams(tx, categoryQuery).setSource(category).setPermissions(permissions).addArg("currency", 42)
+
+			result, err := resolveCategoryAmount(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, "12.34", result)
+			assert.Same(t, tx, category.tx)
+			assert.Equal(t, int64(42), *category.currencyID)
+			assert.Equal(t, []int64{1}, category.accountIDs)
+		})
+		t.Run("returns amount in default currency", func(t *testing.T) {
+			category := &mockCategoryModel{amount: "12.34"}
+			params := newResolveParams(tx, categoryQuery).setSource(category)
+
+			_, err := resolveCategoryAmount(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Nil(t, category.currencyID)
+		})
+		t.Run("returns conversion error", func(t *testing.T) {
+			expectedErr := apperror.NotFound("currency", int64(42))
+			category := &mockCategoryModel{amountErr: expectedErr}
+			params := newResolveParams(tx, categoryQuery).setSource(category).addArg("currency", 42)
+
+			_, err := resolveCategoryAmount(params.ResolveParams)
+
+			assert.Same(t, expectedErr, err)
+		})
+	})
+}
+
+func Test_resolveCategoryTransactionCount(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		category := &mockCategoryModel{mockDetailsModel: mockDetailsModel{count: 3}}
+		permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+		params := newResolveParams(tx, categoryQuery).setSource(category).setPermissions(permissions)
+
+		result, err := resolveCategoryTransactionCount(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), result)
+		assert.Same(t, tx, category.tx)
+		assert.Equal(t, []int64{1}, category.accountIDs)
+	})
+}
@@ -1,9 +1,8 @@
 package schema
 
 import (
-	"database/sql"
-
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/domain"
 )
 
@@ -28,7 +27,7 @@ func companyQueryFields() *graphql.Field {
 			"name": {Type: graphql.String, Description: "unique company name"},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			tx := p.Context.Value(DbContextKey).(*sql.Tx)
+			tx := p.Context.Value(DbContextKey).(*database.Tx)
 			if idArg, ok := p.Args["id"]; ok {
 				id := idArg.(int)
 				return getCompanyByID(tx, int64(id)), nil
@@ -43,13 +42,15 @@ func companyQueryFields() *graphql.Field {
 }
 
 type companyModel interface {
-	GetAccounts(tx *sql.Tx) ([]*domain.Account, error)
+	GetAccounts(tx *database.Tx) []*domain.Account
 }
 
+var _ companyModel = (*domain.Company)(nil)
+
 func resolveAccounts(p graphql.ResolveParams) (interface{}, error) {
 	company := p.Source.(companyModel)
-	tx := p.Context.Value(DbContextKey).(*sql.Tx)
-	return company.GetAccounts(tx)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return getPermissions(p).FilterAccounts(company.GetAccounts(tx)), nil
 }
 
 var companyInput = graphql.NewInputObject(graphql.InputObjectConfig{
@@ -70,17 +71,26 @@ var updateCompaniesFields = &graphql.Field{
 		"delete": {Type: idVersionList, Description: "IDs of companies to delete."},
 	},
 	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if err := getPermissions(p).RequireAdmin(); err != nil {
+			return nil, err
+		}
 		companies := make([]*domain.Company, 0)
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
 		user := p.Context.Value(UserKey).(string)
 		if ids, ok := p.Args["delete"]; ok {
-			deleteCompanies(tx, asMaps(ids))
+			deleteCompanies(tx, asMaps(ids), user)
 		}
 		if updates, ok := p.Args["update"]; ok {
-			companies = updateCompanies(tx, updates, user)
+			var err error
+			if companies, err = updateCompanies(tx, updates, user); err != nil {
+				return nil, err
+			}
 		}
 		if names, ok := p.Args["add"]; ok {
-			added := addCompanies(tx, asStrings(names), user)
+			added, err := addCompanies(tx, asStrings(names), user)
+			if err != nil {
+				return nil, err
+			}
 			companies = append(companies, added...)
 		}
 		return companies, nil
@@ -1,20 +1,21 @@
 package schema
 
 import (
-	"database/sql"
-	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
 	"github.com/jonestimd/financesd/internal/domain"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_companyQueryFields_Resolve(t *testing.T) {
 	companies := []*domain.Company{domain.NewCompany(1, "")}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		getAll := mocka.Function(t, &getAllCompanies, companies)
 		byID := mocka.Function(t, &getCompanyByID, companies)
 		byName := mocka.Function(t, &getCompanyByName, companies)
@@ -57,35 +58,49 @@ func Test_companyQueryFields_Resolve(t *testing.T) {
 
 type mockCompanyModel struct {
 	accounts []*domain.Account
-	err      error
-	tx       *sql.Tx
+	tx       *database.Tx
 }
 
-func (c *mockCompanyModel) GetAccounts(tx *sql.Tx) ([]*domain.Account, error) {
+func (c *mockCompanyModel) GetAccounts(tx *database.Tx) []*domain.Account {
 	c.tx = tx
-	return c.accounts, c.err
+	return c.accounts
 }
 
 func Test_resolveAccounts(t *testing.T) {
 	companyID := int64(42)
-	accounts := []*domain.Account{domain.NewAccount(123, &companyID)}
-	mockCompany := &mockCompanyModel{accounts: accounts, err: errors.New("test error")}
-	sqltest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *sql.Tx) {
-		params := newResolveParams(tx, companyQuery, newField("", "id"), newField("", "name")).setSource(mockCompany)
-
-		result, err := resolveAccounts(params.ResolveParams)
-
-		assert.Same(t, mockCompany.err, err)
-		assert.Equal(t, mockCompany.accounts, result)
-		assert.Same(t, tx, mockCompany.tx)
+	accounts := []*domain.Account{domain.NewAccount(123, &companyID), domain.NewAccount(456, &companyID)}
+	t.Run("returns accounts", func(t *testing.T) {
+		mockCompany := &mockCompanyModel{accounts: accounts}
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, companyQuery, newField("", "id"), newField("", "name")).setSource(mockCompany)
+
+			result, err := resolveAccounts(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, mockCompany.accounts, result)
+			assert.Same(t, tx, mockCompany.tx)
+		})
+	})
+	t.Run("returns readable accounts", func(t *testing.T) {
+		mockCompany := &mockCompanyModel{accounts: accounts}
+		dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, companyQuery, newField("", "id"), newField("", "name")).
+				setSource(mockCompany).
+				setPermissions(domain.NewPermissions(false, map[int64]string{456: table.PermissionRead}))
+
+			result, err := resolveAccounts(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, accounts[1:], result)
+		})
 	})
 }
 
 func Test_updateCompany_Resolve_add(t *testing.T) {
 	names := []interface{}{"The Company"}
 	companies := []*domain.Company{domain.NewCompany(42, names[0].(string))}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
-		mockAddCompanies := mocka.Function(t, &addCompanies, companies)
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		mockAddCompanies := mocka.Function(t, &addCompanies, companies, nil)
 		defer mockAddCompanies.Restore()
 		params := newResolveParams(tx, companyQuery, newField("", "id"), newField("", "name")).addArg("add", names)
 
@@ -97,10 +112,23 @@ func Test_updateCompany_Resolve_add(t *testing.T) {
 	})
 }
 
+func Test_updateCompany_Resolve_requiresAdmin(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		params := newResolveParams(tx, companyQuery, newField("", "id")).
+			addArg("add", []interface{}{"The Company"}).
+			setPermissions(domain.NewPermissions(false, nil))
+
+		result, err := updateCompaniesFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, result)
+		assert.Equal(t, apperror.Forbidden("admin role required"), err)
+	})
+}
+
 func Test_updateCompany_Resolve_delete(t *testing.T) {
 	ids := []map[string]interface{}{{"id": 1, "version": 2}, {"id": 3, "version": 4}}
 	companies := []*domain.Company{}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		count := int64(2)
 		mockDeleteCompanies := mocka.Function(t, &deleteCompanies, count)
 		defer mockDeleteCompanies.Restore()
@@ -110,7 +138,7 @@ func Test_updateCompany_Resolve_delete(t *testing.T) {
 
 		assert.Nil(t, err)
 		assert.Equal(t, companies, result)
-		assert.Equal(t, []interface{}{tx, ids}, mockDeleteCompanies.GetFirstCall().Arguments())
+		assert.Equal(t, []interface{}{tx, ids, "somebody"}, mockDeleteCompanies.GetFirstCall().Arguments())
 	})
 }
 
@@ -119,8 +147,8 @@ func Test_updateCompany_Resolve_update(t *testing.T) {
 	name := "new name"
 	args := []interface{}{map[string]interface{}{"id": id, "name": name}}
 	companies := []*domain.Company{}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
-		mockUpdateCompanies := mocka.Function(t, &updateCompanies, companies)
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		mockUpdateCompanies := mocka.Function(t, &updateCompanies, companies, nil)
 		defer mockUpdateCompanies.Restore()
 		params := newResolveParams(tx, companyQuery, newField("", "id")).addArg("update", args)
 
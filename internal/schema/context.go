@@ -1,5 +1,11 @@
 package schema
 
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
 type reqContextKey string
 
 // DbContextKey is the GraphQL request context key for the database connection.
@@ -7,3 +13,40 @@ const DbContextKey = reqContextKey("db")
 
 // UserKey is the GraphQL request context key for the user.
 const UserKey = reqContextKey("user")
+
+// PermissionsKey is the GraphQL request context key for the user's account permissions.
+const PermissionsKey = reqContextKey("permissions")
+
+// AttachmentStoreKey is the GraphQL request context key for the attachment file store.
+const AttachmentStoreKey = reqContextKey("attachmentStore")
+
+// AfterCommitKey is the GraphQL request context key for a *[]func() that is run after the database transaction
+// is committed.
+const AfterCommitKey = reqContextKey("afterCommit")
+
+// FileStore removes attachment files.
+type FileStore interface {
+	Remove(hash string) error
+}
+
+// afterCommit registers a function to run after the request's database transaction is committed.
+func afterCommit(p graphql.ResolveParams, fn func()) {
+	if callbacks, ok := p.Context.Value(AfterCommitKey).(*[]func()); ok {
+		*callbacks = append(*callbacks, fn)
+	}
+}
+
+func getPermissions(p graphql.ResolveParams) *domain.Permissions {
+	return p.Context.Value(PermissionsKey).(*domain.Permissions)
+}
+
+// returns the IDs of the input objects
+func inputIDs(args ...[]map[string]interface{}) []int64 {
+	ids := make([]int64, 0)
+	for _, maps := range args {
+		for _, m := range maps {
+			ids = append(ids, database.InputObject(m).RequireInt("id"))
+		}
+	}
+	return ids
+}
@@ -0,0 +1,207 @@
+package schema
+
+import (
+	"errors"
+
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+var currencySchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "currency",
+	Description: "a monetary asset",
+	Fields: graphql.Fields{
+		"id":         &graphql.Field{Type: graphql.Int, Resolve: nestedResolver("Asset", "ID")},
+		"code":       &graphql.Field{Type: graphql.String},
+		"name":       &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "Name")},
+		"scale":      &graphql.Field{Type: graphql.Int, Resolve: nestedResolver("Asset", "Scale")},
+		"symbol":     &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "Symbol")},
+		"version":    &graphql.Field{Type: graphql.Int, Resolve: nestedResolver("Asset", "Version")},
+		"changeUser": &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "ChangeUser")},
+		"changeDate": &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "ChangeDate")},
+	},
+})
+
+var assetSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "asset",
+	Description: "a currency or security",
+	Fields: addAudit(graphql.Fields{
+		"id":     &graphql.Field{Type: graphql.Int},
+		"name":   &graphql.Field{Type: graphql.String},
+		"type":   &graphql.Field{Type: graphql.String},
+		"scale":  &graphql.Field{Type: graphql.Int},
+		"symbol": &graphql.Field{Type: graphql.String},
+	}),
+})
+
+var currencyQueryFields = &graphql.Field{
+	Type: graphql.NewList(currencySchema),
+	Args: graphql.FieldConfigArgument{
+		"id": {Type: graphql.Int, Description: "currency ID"},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		if id, ok := p.Args["id"]; ok {
+			return getCurrencyByID(tx, int64(id.(int))), nil
+		}
+		return getAllCurrencies(tx), nil
+	},
+}
+
+var baseCurrencyQueryFields = &graphql.Field{
+	Type:        currencySchema,
+	Description: "The currency used for converting between currencies that don't have a direct exchange rate.",
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		if currency := getBaseCurrency(tx); currency != nil {
+			return currency, nil
+		}
+		return nil, nil
+	},
+}
+
+var setBaseCurrencyFields = &graphql.Field{
+	Type:        currencySchema,
+	Description: "Set the base currency.",
+	Args: graphql.FieldConfigArgument{
+		"currencyId": {Type: nonNullInt, Description: "ID of the new base currency."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if err := getPermissions(p).RequireAdmin(); err != nil {
+			return nil, err
+		}
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		return setBaseCurrency(tx, int64(p.Args["currencyId"].(int)), user)
+	},
+}
+
+var exchangeRateSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "exchangeRate",
+	Description: "the value of one unit of a currency in another currency",
+	Fields: addAudit(graphql.Fields{
+		"id":             &graphql.Field{Type: nonNullInt},
+		"fromCurrencyId": &graphql.Field{Type: nonNullInt},
+		"toCurrencyId":   &graphql.Field{Type: nonNullInt},
+		"date":           &graphql.Field{Type: nonNullDate},
+		"rate":           &graphql.Field{Type: nonNullFloat},
+	}),
+})
+
+var exchangeRateList = nonNullList(exchangeRateSchema)
+
+var exchangeRateQueryFields = &graphql.Field{
+	Type: exchangeRateList,
+	Args: graphql.FieldConfigArgument{
+		"currencyId": {Type: graphql.Int, Description: "currency ID (from or to)"},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		var currencyID interface{}
+		if id, ok := p.Args["currencyId"]; ok {
+			currencyID = int64(id.(int))
+		}
+		return getExchangeRates(tx, currencyID), nil
+	},
+}
+
+var addExchangeRateInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "addExchangeRateInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"fromCurrencyId": {Type: nonNullInt},
+		"toCurrencyId":   {Type: nonNullInt},
+		"date":           {Type: nonNullDate},
+		"rate":           {Type: nonNullFloat, Description: "Amount of **toCurrency** for one unit of **fromCurrency**."},
+	},
+})
+
+var updateExchangeRateInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "updateExchangeRateInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id":      {Type: nonNullInt},
+		"version": {Type: nonNullInt},
+		"date":    {Type: dateType},
+		"rate":    {Type: graphql.Float},
+	},
+})
+
+var updateExchangeRatesFields = &graphql.Field{
+	Type:        exchangeRateList,
+	Description: "Add, update and/or delete exchange rates.",
+	Args: graphql.FieldConfigArgument{
+		"add":    {Type: newList(addExchangeRateInput), Description: "Exchange rates to add."},
+		"update": {Type: newList(updateExchangeRateInput), Description: "Changes to be made to existing exchange rates."},
+		"delete": {Type: idVersionList, Description: "IDs of exchange rates to delete."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if err := getPermissions(p).RequireAdmin(); err != nil {
+			return nil, err
+		}
+		rates := []*table.ExchangeRate{}
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		if ids, ok := p.Args["delete"]; ok {
+			deleteExchangeRates(tx, asMaps(ids), user)
+		}
+		ids := make([]int64, 0)
+		if updates, ok := p.Args["update"]; ok {
+			var err error
+			if ids, err = updateExchangeRates(tx, asMaps(updates), user); err != nil {
+				return nil, err
+			}
+		}
+		if adds, ok := p.Args["add"]; ok {
+			addIDs, err := addExchangeRates(tx, asMaps(adds), user)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, addIDs...)
+		}
+		if len(ids) > 0 {
+			rates = getExchangeRatesByIDs(tx, ids)
+		}
+		return rates, nil
+	},
+}
+
+// convertArgs are the arguments of an aggregate amount that can be converted to another currency.
+var convertArgs = graphql.FieldConfigArgument{
+	"currency": {Type: graphql.Int, Description: "ID of the currency for converting the amount. Defaults to the base currency."},
+}
+
+// getCurrencyArg returns the currency argument of the field or nil if it wasn't specified.
+func getCurrencyArg(p graphql.ResolveParams) *int64 {
+	if currencyID, ok := p.Args["currency"].(int); ok {
+		id := int64(currencyID)
+		return &id
+	}
+	return nil
+}
+
+type currencyModel interface {
+	GetCurrency(tx *database.Tx) *table.Currency
+	GetBalance(tx *database.Tx, currencyID int64) (string, error)
+}
+
+var _ currencyModel = (*domain.Account)(nil)
+
+func resolveCurrency(p graphql.ResolveParams) (interface{}, error) {
+	if account, ok := p.Source.(currencyModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return account.GetCurrency(tx), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveBalance(p graphql.ResolveParams) (interface{}, error) {
+	if currencyID, ok := p.Args["currency"]; ok {
+		if account, ok := p.Source.(currencyModel); ok {
+			tx := p.Context.Value(DbContextKey).(*database.Tx)
+			return account.GetBalance(tx, int64(currencyID.(int)))
+		}
+		return nil, errors.New("invalid source")
+	}
+	return defaultResolveFn(p)
+}
@@ -0,0 +1,238 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_currencyQueryFields_Resolve(t *testing.T) {
+	currencies := []*table.Currency{{Code: "USD"}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		getAll := mocka.Function(t, &getAllCurrencies, currencies)
+		defer getAll.Restore()
+		byID := mocka.Function(t, &getCurrencyByID, currencies)
+		defer byID.Restore()
+		tests := []struct {
+			name     string
+			argName  string
+			argValue interface{}
+			stub     *mocka.Stub
+			stubArgs []interface{}
+		}{
+			{name: "returns all currencies", stub: getAll, stubArgs: []interface{}{tx}},
+			{name: "returns currency with ID", argName: "id", argValue: 42, stub: byID, stubArgs: []interface{}{tx, int64(42)}},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				params := newResolveParams(tx, currencyQuery, newField("", "id")).addArg(test.argName, test.argValue)
+
+				result, err := currencyQueryFields.Resolve(params.ResolveParams)
+
+				assert.Nil(t, err)
+				assert.Equal(t, currencies, result)
+				assert.Equal(t, test.stubArgs, test.stub.GetFirstCall().Arguments())
+			})
+		}
+	})
+}
+
+func Test_baseCurrencyQueryFields_Resolve(t *testing.T) {
+	currency := &table.Currency{Code: "USD"}
+	tests := []struct {
+		name     string
+		currency *table.Currency
+		result   interface{}
+	}{
+		{"returns nil if not set", nil, nil},
+		{"returns currency", currency, currency},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+				getBaseStub := mocka.Function(t, &getBaseCurrency, test.currency)
+				defer getBaseStub.Restore()
+				params := newResolveParams(tx, baseCurrencyQuery, newField("", "id"))
+
+				result, err := baseCurrencyQueryFields.Resolve(params.ResolveParams)
+
+				assert.Nil(t, err)
+				assert.Equal(t, test.result, result)
+			})
+		})
+	}
+}
+
+func Test_setBaseCurrencyFields_Resolve(t *testing.T) {
+	currency := &table.Currency{Code: "USD"}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		setBaseStub := mocka.Function(t, &setBaseCurrency, currency, nil)
+		defer setBaseStub.Restore()
+		params := newResolveParams(tx, setBaseCurrencyMutation, newField("", "id")).addArg("currencyId", 42)
+
+		result, err := setBaseCurrencyFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Same(t, currency, result)
+		assert.Equal(t, []interface{}{tx, int64(42), "somebody"}, setBaseStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_exchangeRateQueryFields_Resolve(t *testing.T) {
+	rates := []*table.ExchangeRate{{ID: 1}}
+	tests := []struct {
+		name       string
+		argValue   interface{}
+		currencyID interface{}
+	}{
+		{"returns all rates", nil, nil},
+		{"returns rates for currency", 42, int64(42)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+				getRatesStub := mocka.Function(t, &getExchangeRates, rates)
+				defer getRatesStub.Restore()
+				params := newResolveParams(tx, exchangeRateQuery, newField("", "id"))
+				if test.argValue != nil {
+					params.addArg("currencyId", test.argValue)
+				}
+
+				result, err := exchangeRateQueryFields.Resolve(params.ResolveParams)
+
+				assert.Nil(t, err)
+				assert.Equal(t, rates, result)
+				assert.Equal(t, []interface{}{tx, test.currencyID}, getRatesStub.GetFirstCall().Arguments())
+			})
+		})
+	}
+}
+
+func Test_updateExchangeRatesFields_Resolve(t *testing.T) {
+	t.Run("deletes rates", func(t *testing.T) {
+		ids := []map[string]interface{}{{"id": 1, "version": 2}}
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			deleteStub := mocka.Function(t, &deleteExchangeRates, int64(1))
+			defer deleteStub.Restore()
+			params := newResolveParams(tx, updateExchangeRatesMutation, newField("", "id")).addArrayArg("delete", ids)
+
+			result, err := updateExchangeRatesFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, []*table.ExchangeRate{}, result)
+			assert.Equal(t, []interface{}{tx, ids, "somebody"}, deleteStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("adds and updates rates", func(t *testing.T) {
+		adds := []map[string]interface{}{{"fromCurrencyId": 1, "toCurrencyId": 2, "rate": 1.5}}
+		updates := []map[string]interface{}{{"id": 3, "version": 0, "rate": 1.4}}
+		rates := []*table.ExchangeRate{{ID: 3}, {ID: 4}}
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			addStub := mocka.Function(t, &addExchangeRates, []int64{4}, nil)
+			defer addStub.Restore()
+			updateStub := mocka.Function(t, &updateExchangeRates, []int64{3}, nil)
+			defer updateStub.Restore()
+			getRatesStub := mocka.Function(t, &getExchangeRatesByIDs, rates)
+			defer getRatesStub.Restore()
+			params := newResolveParams(tx, updateExchangeRatesMutation, newField("", "id")).
+				addArrayArg("add", adds).
+				addArrayArg("update", updates)
+
+			result, err := updateExchangeRatesFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, rates, result)
+			assert.Equal(t, []interface{}{tx, adds, "somebody"}, addStub.GetFirstCall().Arguments())
+			assert.Equal(t, []interface{}{tx, updates, "somebody"}, updateStub.GetFirstCall().Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{3, 4}}, getRatesStub.GetFirstCall().Arguments())
+		})
+	})
+}
+
+type mockCurrencyModel struct {
+	currency   *table.Currency
+	balance    string
+	balanceErr error
+	currencyID int64
+	tx         *database.Tx
+}
+
+func (a *mockCurrencyModel) GetCurrency(tx *database.Tx) *table.Currency {
+	a.tx = tx
+	return a.currency
+}
+
+func (a *mockCurrencyModel) GetBalance(tx *database.Tx, currencyID int64) (string, error) {
+	a.tx = tx
+	a.currencyID = currencyID
+	return a.balance, a.balanceErr
+}
+
+func Test_resolveCurrency(t *testing.T) {
+	dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+		t.Run("returns currency", func(t *testing.T) {
+			account := &mockCurrencyModel{currency: &table.Currency{Code: "USD"}}
+			params := newResolveParams(tx, accountQuery, newField("", "id")).setSource(account)
+
+			result, err := resolveCurrency(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Same(t, account.currency, result)
+			assert.Same(t, tx, account.tx)
+		})
+		t.Run("returns error for invalid source", func(t *testing.T) {
+			params := newResolveParams(tx, accountQuery, newField("", "id"))
+
+			_, err := resolveCurrency(params.ResolveParams)
+
+			assert.Equal(t, "invalid source", err.Error())
+		})
+	})
+}
+
+func Test_resolveBalance(t *testing.T) {
+	dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+		t.Run("returns converted balance", func(t *testing.T) {
+			account := &mockCurrencyModel{balance: "12.34"}
+			params := newResolveParams(tx, accountQuery, newField("", "id")).setSource(account).addArg("currency", 42)
+
+			result, err := resolveBalance(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, "12.34", result)
+			assert.Equal(t, int64(42), account.currencyID)
+		})
+		t.Run("returns conversion error", func(t *testing.T) {
+			expectedErr := apperror.NotFound("currency", int64(42))
+			account := &mockCurrencyModel{balanceErr: expectedErr}
+			params := newResolveParams(tx, accountQuery, newField("", "id")).setSource(account).addArg("currency", 42)
+
+			_, err := resolveBalance(params.ResolveParams)
+
+			assert.Same(t, expectedErr, err)
+		})
+		t.Run("returns default value without currency", func(t *testing.T) {
+			defaultResolveStub := mocka.Function(t, &defaultResolveFn, "56.78", nil)
+			defer defaultResolveStub.Restore()
+			params := newResolveParams(tx, accountQuery, newField("", "id")).setSource(&mockCurrencyModel{})
+
+			result, err := resolveBalance(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, "56.78", result)
+		})
+		t.Run("returns error for invalid source", func(t *testing.T) {
+			params := newResolveParams(tx, accountQuery, newField("", "id")).addArg("currency", 42)
+
+			_, err := resolveBalance(params.ResolveParams)
+
+			assert.Equal(t, "invalid source", err.Error())
+		})
+	})
+}
@@ -1,6 +1,9 @@
 package schema
 
 import (
+	"os"
+
+	"github.com/graphql-go/graphql"
 	"github.com/jonestimd/financesd/internal/database"
 	"github.com/jonestimd/financesd/internal/domain"
 )
@@ -16,17 +19,56 @@ var getAllAccounts = domain.GetAllAccounts
 var getAccountByID = domain.GetAccountByID
 var getAccountsByName = domain.GetAccountsByName
 
-var getAllCategories = database.GetAllCategories
+var getAllUsers = domain.GetAllUsers
+var getUsersByIDs = domain.GetUsersByIDs
+var addUsers = domain.AddUsers
+var updateUsers = domain.UpdateUsers
+var grantAccountPermissions = domain.GrantAccountPermissions
+var getAccountPermissionsByIDs = database.GetAccountPermissionsByIDs
+var revokeAccountPermissions = database.RevokeAccountPermissions
+
+var getAPITokens = database.GetAPITokens
+var createAPIToken = domain.CreateAPIToken
+var deleteAPITokens = database.DeleteAPITokens
+
+var getAllCurrencies = database.GetAllCurrencies
+var getCurrencyByID = database.GetCurrencyByID
+var getBaseCurrency = domain.GetBaseCurrency
+var setBaseCurrency = domain.SetBaseCurrency
+var getExchangeRates = database.GetExchangeRates
+var getExchangeRatesByIDs = database.GetExchangeRatesByIDs
+var addExchangeRates = domain.AddExchangeRates
+var updateExchangeRates = domain.UpdateExchangeRates
+var deleteExchangeRates = database.DeleteExchangeRates
+
+var getAllCategories = domain.GetAllCategories
 
-var getAllGroups = database.GetAllGroups
+var getAllGroups = domain.GetAllGroups
 
-var getAllPayees = database.GetAllPayees
+var getAllPayees = domain.GetAllPayees
 
-var getAllSecurities = database.GetAllSecurities
-var getSecurityByID = database.GetSecurityByID
-var getSecurityBySymbol = database.GetSecurityBySymbol
+var getAllSecurities = domain.GetAllSecurities
+var getSecurityByID = domain.GetSecurityByID
+var getSecurityBySymbol = domain.GetSecurityBySymbol
 var getAccountTransactions = domain.GetTransactions
 var getTransactionsByIDs = domain.GetTransactionsByIDs
 var insertTransactions = domain.InsertTransactions
 var updateTransactions = domain.UpdateTransactions
 var deleteTransactions = domain.DeleteTransactions
+var getTrash = domain.GetTrash
+var restoreTransactions = domain.RestoreTransactions
+var purgeTrash = domain.PurgeTrash
+var bulkUpdateDetails = domain.BulkUpdateDetails
+
+var getImportItems = database.GetImportItems
+var addImportItems = domain.AddImportItems
+var acceptImportItems = domain.AcceptImportItems
+var matchImportItems = domain.MatchImportItems
+var deleteImportItems = database.DeleteImportItems
+
+var getHistory = domain.GetHistory
+var undoChangeSet = domain.UndoChangeSet
+
+var defaultResolveFn = graphql.DefaultResolveFn
+
+var readFile = os.ReadFile
@@ -1,26 +1,61 @@
 package schema
 
 import (
-	"database/sql"
-
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
 )
 
 var groupSchema = graphql.NewObject(graphql.ObjectConfig{
 	Name:        "group",
 	Description: "alternate categorization for transaction details",
 	Fields: addAudit(graphql.Fields{
-		"id":               &graphql.Field{Type: graphql.Int},
-		"name":             &graphql.Field{Type: graphql.String},
-		"description":      &graphql.Field{Type: graphql.String},
-		"transactionCount": &graphql.Field{Type: graphql.Int},
+		"id":          &graphql.Field{Type: graphql.Int},
+		"name":        &graphql.Field{Type: graphql.String},
+		"description": &graphql.Field{Type: graphql.String},
+		"transactionCount": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Number of transactions with details in the group in the readable accounts.",
+			Resolve:     resolveGroupTransactionCount,
+		},
 	}),
 })
 
-var groupQueryFields = &graphql.Field{
-	Type: graphql.NewList(groupSchema),
-	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
-		return getAllGroups(tx), nil
-	},
+func groupQueryFields() *graphql.Field {
+	groupSchema.AddFieldConfig("details", &graphql.Field{
+		Type:        txDetailList,
+		Description: "Transaction details in the group in the readable accounts, ordered by transaction date.",
+		Args:        getPageArgs(),
+		Resolve:     resolveGroupDetails,
+	})
+	return &graphql.Field{
+		Type: graphql.NewList(groupSchema),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			tx := p.Context.Value(DbContextKey).(*database.Tx)
+			return getAllGroups(tx), nil
+		},
+	}
+}
+
+type groupModel interface {
+	GetDetails(tx *database.Tx, filter database.PageFilter, accountIDs []int64) []*domain.TransactionDetail
+	GetTransactionCount(tx *database.Tx, accountIDs []int64) int64
+}
+
+var _ groupModel = (*domain.Group)(nil)
+
+func resolveGroupDetails(p graphql.ResolveParams) (interface{}, error) {
+	group := p.Source.(groupModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	filter, err := getPageFilter(p)
+	if err != nil {
+		return nil, err
+	}
+	return group.GetDetails(tx, filter, getPermissions(p).ReadableAccountIDs()), nil
+}
+
+func resolveGroupTransactionCount(p graphql.ResolveParams) (interface{}, error) {
+	group := p.Source.(groupModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return group.GetTransactionCount(tx, getPermissions(p).ReadableAccountIDs()), nil
 }
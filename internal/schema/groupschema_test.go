@@ -1,27 +1,93 @@
 package schema
 
 import (
-	"database/sql"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/jonestimd/financesd/internal/domain"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_groupQueryFields_Resolve(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
-		groups := []*table.Group{{ID: 42}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		groups := []*domain.Group{{Group: &table.Group{ID: 1}}}
 		getAll := mocka.Function(t, &getAllGroups, groups)
 		defer getAll.Restore()
 		params := newResolveParams(tx, groupQuery, newField("", "id"), newField("", "name"))
 
-		result, err := groupQueryFields.Resolve(params.ResolveParams)
+		result, err := groupQueryFields().Resolve(params.ResolveParams)
 
 		assert.Nil(t, err)
 		assert.Equal(t, groups, result)
 		assert.Equal(t, []interface{}{tx}, getAll.GetFirstCall().Arguments())
 	})
 }
+
+type mockDetailsModel struct {
+	tx         *database.Tx
+	filter     database.PageFilter
+	accountIDs []int64
+	details    []*domain.TransactionDetail
+	count      int64
+}
+
+func (m *mockDetailsModel) GetDetails(tx *database.Tx, filter database.PageFilter, accountIDs []int64) []*domain.TransactionDetail {
+	m.tx = tx
+	m.filter = filter
+	m.accountIDs = accountIDs
+	return m.details
+}
+
+func (m *mockDetailsModel) GetTransactionCount(tx *database.Tx, accountIDs []int64) int64 {
+	m.tx = tx
+	m.accountIDs = accountIDs
+	return m.count
+}
+
+func Test_resolveGroupDetails(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		t.Run("returns page of details in readable accounts", func(t *testing.T) {
+			group := &mockDetailsModel{details: []*domain.TransactionDetail{domain.NewTransactionDetail(42, 96)}}
+			permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionWrite})
+			params := newResolveParams(tx, groupQuery).setSource(group).setPermissions(permissions).
+				addArg("endDate", "2020-12-31").addArg("offset", 5)
+
+			result, err := resolveGroupDetails(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, group.details, result)
+			assert.Same(t, tx, group.tx)
+			assert.Equal(t, database.PageFilter{EndDate: "2020-12-31", Offset: 5}, group.filter)
+			assert.Equal(t, []int64{1}, group.accountIDs)
+		})
+		t.Run("returns error for negative offset", func(t *testing.T) {
+			group := &mockDetailsModel{}
+			params := newResolveParams(tx, groupQuery).setSource(group).addArg("offset", -1)
+
+			_, err := resolveGroupDetails(params.ResolveParams)
+
+			assert.Equal(t, apperror.Validation("offset", "must not be negative"), err)
+			assert.Nil(t, group.tx)
+		})
+	})
+}
+
+func Test_resolveGroupTransactionCount(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		group := &mockDetailsModel{count: 3}
+		permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+		params := newResolveParams(tx, groupQuery).setSource(group).setPermissions(permissions)
+
+		result, err := resolveGroupTransactionCount(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), result)
+		assert.Same(t, tx, group.tx)
+		assert.Equal(t, []int64{1}, group.accountIDs)
+	})
+}
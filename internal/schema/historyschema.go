@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+var historyEntityType = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "historyEntity",
+	Description: "the type of entity for change history",
+	Values: graphql.EnumValueConfigMap{
+		"attachment":        {Value: "attachment"},
+		"company":           {Value: "company"},
+		"exchangeRate":      {Value: "exchange_rate"},
+		"importItem":        {Value: "import_item"},
+		"payee":             {Value: "payee"},
+		"setting":           {Value: "setting"},
+		"transaction":       {Value: "transaction"},
+		"transactionDetail": {Value: "transaction_detail"},
+	},
+})
+
+var fieldChangeSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "fieldChange",
+	Description: "the previous and new value of a field",
+	Fields: graphql.Fields{
+		"field":  &graphql.Field{Type: nonNullString},
+		"before": &graphql.Field{Type: graphql.String, Description: "null if the row was inserted or the field was empty"},
+		"after":  &graphql.Field{Type: graphql.String, Description: "null if the row was deleted or the field was cleared"},
+	},
+})
+
+var changeHistorySchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "changeHistory",
+	Description: "a version of an entity",
+	Fields: addAudit(graphql.Fields{
+		"id":          &graphql.Field{Type: nonNullInt},
+		"action":      &graphql.Field{Type: nonNullString, Description: "insert, update or delete"},
+		"beforeImage": &graphql.Field{Type: graphql.String, Description: "JSON object containing the columns before the change"},
+		"afterImage":  &graphql.Field{Type: graphql.String, Description: "JSON object containing the columns after the change"},
+		"changeSetId": &graphql.Field{Type: graphql.String, Description: "ID of the mutation request that made the change"},
+		"changes": &graphql.Field{
+			Type:        nonNullList(fieldChangeSchema),
+			Description: "the fields that were changed",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(*domain.ChangeHistory).Changes(), nil
+			},
+		},
+	}),
+})
+
+var historyQueryFields = &graphql.Field{
+	Type:        nonNullList(changeHistorySchema),
+	Description: "Versions of an entity, oldest first.",
+	Args: graphql.FieldConfigArgument{
+		"entity": {Type: graphql.NewNonNull(historyEntityType)},
+		"id":     {Type: graphql.NewNonNull(graphql.ID), Description: "ID of the entity (name for setting)."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		tableName := p.Args["entity"].(string)
+		history := getHistory(tx, tableName, p.Args["id"].(string))
+		if err := getPermissions(p).RequireHistoryRead(tx, tableName, history); err != nil {
+			return nil, err
+		}
+		return history, nil
+	},
+}
+
+var undoFields = &graphql.Field{
+	Type:        nonNullList(changeHistorySchema),
+	Description: "Undo the changes made by a mutation request. Fails if any of the changed rows have been modified since. Returns the changes that were reverted.",
+	Args: graphql.FieldConfigArgument{
+		"changeSetId": {Type: nonNullString, Description: "ID of the change set."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		return undoChangeSet(tx, p.Args["changeSetId"].(string), user, getPermissions(p))
+	},
+}
@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_changeHistorySchema_changes(t *testing.T) {
+	before, after := `{"name":"old"}`, `{"name":"new"}`
+	history := domain.NewChangeHistory(&table.ChangeHistory{BeforeImage: &before, AfterImage: &after})
+	params := newResolveParams(nil, historyQuery).setSource(history)
+
+	result, err := changeHistorySchema.Fields()["changes"].Resolve(params.ResolveParams)
+
+	assert.Nil(t, err)
+	oldName, newName := "old", "new"
+	assert.Equal(t, []*domain.FieldChange{{Field: "name", Before: &oldName, After: &newName}}, result)
+}
+
+func Test_historyQueryFields_Resolve(t *testing.T) {
+	after := `{"id":42,"account_id":1}`
+	history := []*domain.ChangeHistory{domain.NewChangeHistory(&table.ChangeHistory{ID: 1, AfterImage: &after})}
+	t.Run("returns history", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			getHistoryStub := mocka.Function(t, &getHistory, history)
+			defer getHistoryStub.Restore()
+			params := newResolveParams(tx, historyQuery, newField("", "id")).
+				addArg("entity", "transaction").addArg("id", "42").
+				setPermissions(domain.NewPermissions(false, map[int64]string{1: table.PermissionRead}))
+
+			result, err := historyQueryFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, history, result)
+			assert.Equal(t, []interface{}{tx, "transaction", "42"}, getHistoryStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("requires account permission", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			getHistoryStub := mocka.Function(t, &getHistory, history)
+			defer getHistoryStub.Restore()
+			params := newResolveParams(tx, historyQuery, newField("", "id")).
+				addArg("entity", "transaction").addArg("id", "42").
+				setPermissions(domain.NewPermissions(false, nil))
+
+			result, err := historyQueryFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.AccountForbidden(1, "readable"), err)
+		})
+	})
+}
+
+func Test_undoFields_Resolve(t *testing.T) {
+	history := []*domain.ChangeHistory{domain.NewChangeHistory(&table.ChangeHistory{ID: 1})}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		undoStub := mocka.Function(t, &undoChangeSet, history, nil)
+		defer undoStub.Restore()
+		params := newResolveParams(tx, undoMutation, newField("", "id")).addArg("changeSetId", "123:4")
+
+		result, err := undoFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, history, result)
+		assert.Equal(t, []interface{}{tx, "123:4", "somebody", getPermissions(params.ResolveParams)}, undoStub.GetFirstCall().Arguments())
+	})
+}
@@ -0,0 +1,145 @@
+package schema
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+var importItemSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "importItem",
+	Description: "an imported transaction waiting for review",
+	Fields: addAudit(graphql.Fields{
+		"id":                        &graphql.Field{Type: nonNullInt},
+		"accountId":                 &graphql.Field{Type: nonNullInt},
+		"date":                      &graphql.Field{Type: nonNullDate},
+		"referenceNumber":           &graphql.Field{Type: graphql.String},
+		"payeeName":                 &graphql.Field{Type: graphql.String},
+		"memo":                      &graphql.Field{Type: graphql.String},
+		"amount":                    &graphql.Field{Type: nonNullFloat},
+		"payeeId":                   &graphql.Field{Type: graphql.Int, Description: "suggested payee"},
+		"transactionCategoryId":     &graphql.Field{Type: graphql.Int, Description: "suggested category"},
+		"matchedTransactionId":      &graphql.Field{Type: graphql.Int, Description: "suggested matching transaction"},
+		"matchedTransactionVersion": &graphql.Field{Type: graphql.Int, Description: "version of the suggested matching transaction"},
+	}),
+})
+
+var importItemList = nonNullList(importItemSchema)
+
+var importItemQueryFields = &graphql.Field{
+	Type:        importItemList,
+	Description: "Imported transactions waiting for review.",
+	Args: graphql.FieldConfigArgument{
+		"accountId": {Type: nonNullInt, Description: "account ID"},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		accountID := int64(p.Args["accountId"].(int))
+		if err := getPermissions(p).RequireRead(accountID); err != nil {
+			return nil, err
+		}
+		return getImportItems(tx, accountID), nil
+	},
+}
+
+var importItemInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "importItemInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"date":            {Type: nonNullDate},
+		"referenceNumber": {Type: graphql.String},
+		"payee":           {Type: graphql.String, Description: "Name of the payee."},
+		"memo":            {Type: graphql.String},
+		"amount":          {Type: nonNullFloat},
+	},
+})
+
+var addImportItemsFields = &graphql.Field{
+	Type:        importItemList,
+	Description: "Add imported transactions to the staging area for review.",
+	Args: graphql.FieldConfigArgument{
+		"accountId": {Type: nonNullInt, Description: "ID of account for the imported transactions."},
+		"items":     {Type: graphql.NewNonNull(newList(importItemInput)), Description: "Imported transactions."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		accountID := database.InputObject(p.Args).RequireInt("accountId")
+		if err := getPermissions(p).RequireWrite(accountID); err != nil {
+			return nil, err
+		}
+		return addImportItems(tx, accountID, asMaps(p.Args["items"]), user), nil
+	},
+}
+
+var acceptImportInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "acceptImportInput",
+	Description: "Omit **payeeId** or **transactionCategoryId** to use the suggested value.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id":                    {Type: nonNullInt},
+		"version":               {Type: nonNullInt},
+		"payeeId":               {Type: graphql.Int},
+		"transactionCategoryId": {Type: graphql.Int},
+	},
+})
+
+var matchImportInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "matchImportInput",
+	Description: "Omit **transactionId** to use the suggested transaction. **transactionVersion** is required with **transactionId** and defaults to the version of the suggested transaction.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id":                 {Type: nonNullInt},
+		"version":            {Type: nonNullInt},
+		"transactionId":      {Type: graphql.Int},
+		"transactionVersion": {Type: graphql.Int},
+	},
+})
+
+var reviewImportItemsFields = &graphql.Field{
+	Type:        txList,
+	Description: "Accept, match and/or discard imported transactions. Returns the accepted and matched transactions.",
+	Args: graphql.FieldConfigArgument{
+		"accept":  {Type: newList(acceptImportInput), Description: "Import items to add as new transactions."},
+		"match":   {Type: newList(matchImportInput), Description: "Import items to link to existing transactions."},
+		"discard": {Type: idVersionList, Description: "IDs of import items to discard."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		transactions := []*domain.Transaction{}
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		var discards, accepts, matches []map[string]interface{}
+		if arg, ok := p.Args["discard"]; ok {
+			discards = asMaps(arg)
+		}
+		if arg, ok := p.Args["accept"]; ok {
+			accepts = asMaps(arg)
+		}
+		if arg, ok := p.Args["match"]; ok {
+			matches = asMaps(arg)
+		}
+		if err := getPermissions(p).RequireImportWrite(tx, inputIDs(discards, accepts, matches)); err != nil {
+			return nil, err
+		}
+		if discards != nil {
+			if err := deleteImportItems(tx, discards, user); err != nil {
+				return nil, err
+			}
+		}
+		ids := make([]int64, 0)
+		if accepts != nil {
+			var err error
+			if ids, err = acceptImportItems(tx, accepts, user); err != nil {
+				return nil, err
+			}
+		}
+		if matches != nil {
+			matchIDs, err := matchImportItems(tx, matches, user)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, matchIDs...)
+		}
+		if len(ids) > 0 {
+			transactions = getTransactionsByIDs(tx, ids)
+		}
+		return transactions, nil
+	},
+}
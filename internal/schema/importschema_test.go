@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_importItemQueryFields_Resolve(t *testing.T) {
+	items := []*table.ImportItem{{ID: 42}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		getImportItemsStub := mocka.Function(t, &getImportItems, items)
+		defer getImportItemsStub.Restore()
+		params := newResolveParams(tx, importItemQuery, newField("", "id")).addArg("accountId", 96)
+
+		result, err := importItemQueryFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, items, result)
+		assert.Equal(t, []interface{}{tx, int64(96)}, getImportItemsStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_importItemQueryFields_Resolve_requiresRead(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		params := newResolveParams(tx, importItemQuery, newField("", "id")).
+			addArg("accountId", 96).
+			setPermissions(domain.NewPermissions(false, nil))
+
+		result, err := importItemQueryFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, result)
+		assert.Equal(t, apperror.AccountForbidden(96, "readable"), err)
+	})
+}
+
+func Test_addImportItemsFields_Resolve_requiresWrite(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		params := newResolveParams(tx, addImportItemsMutation, newField("", "id")).
+			addArg("accountId", 96).
+			addArrayArg("items", []map[string]interface{}{{"date": "2020-12-25", "amount": 12.34}}).
+			setPermissions(domain.NewPermissions(false, map[int64]string{96: table.PermissionRead}))
+
+		result, err := addImportItemsFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, result)
+		assert.Equal(t, apperror.AccountForbidden(96, "writable"), err)
+	})
+}
+
+func Test_addImportItemsFields_Resolve(t *testing.T) {
+	args := []map[string]interface{}{{"date": "2020-12-25", "amount": 12.34}}
+	items := []*table.ImportItem{{ID: 42}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		addImportItemsStub := mocka.Function(t, &addImportItems, items)
+		defer addImportItemsStub.Restore()
+		params := newResolveParams(tx, addImportItemsMutation, newField("", "id")).
+			addArg("accountId", 96).
+			addArrayArg("items", args)
+
+		result, err := addImportItemsFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, items, result)
+		assert.Equal(t, []interface{}{tx, int64(96), args, "somebody"}, addImportItemsStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_reviewImportItemsFields_Resolve(t *testing.T) {
+	t.Run("discards items", func(t *testing.T) {
+		args := []map[string]interface{}{{"id": 42, "version": 1}}
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			deleteImportItemsStub := mocka.Function(t, &deleteImportItems, nil)
+			defer deleteImportItemsStub.Restore()
+			params := newResolveParams(tx, reviewImportItemsMutation, newField("", "id")).addArrayArg("discard", args)
+
+			result, err := reviewImportItemsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, []*domain.Transaction{}, result)
+			assert.Equal(t, []interface{}{tx, args, "somebody"}, deleteImportItemsStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("accepts and matches items", func(t *testing.T) {
+		accepts := []map[string]interface{}{{"id": 42, "version": 1}}
+		matches := []map[string]interface{}{{"id": 96, "version": 1}}
+		transactions := []*domain.Transaction{domain.NewTransaction(123), domain.NewTransaction(321)}
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			acceptImportItemsStub := mocka.Function(t, &acceptImportItems, []int64{123}, nil)
+			defer acceptImportItemsStub.Restore()
+			matchImportItemsStub := mocka.Function(t, &matchImportItems, []int64{321}, nil)
+			defer matchImportItemsStub.Restore()
+			getTransactionsStub := mocka.Function(t, &getTransactionsByIDs, transactions)
+			defer getTransactionsStub.Restore()
+			params := newResolveParams(tx, reviewImportItemsMutation, newField("", "id")).
+				addArrayArg("accept", accepts).
+				addArrayArg("match", matches)
+
+			result, err := reviewImportItemsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, transactions, result)
+			assert.Equal(t, []interface{}{tx, accepts, "somebody"}, acceptImportItemsStub.GetFirstCall().Arguments())
+			assert.Equal(t, []interface{}{tx, matches, "somebody"}, matchImportItemsStub.GetFirstCall().Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{123, 321}}, getTransactionsStub.GetFirstCall().Arguments())
+		})
+	})
+}
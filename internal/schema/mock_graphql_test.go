@@ -2,10 +2,11 @@ package schema
 
 import (
 	"context"
-	"database/sql"
 
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/language/ast"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
 )
 
 type mockDefinition struct {
@@ -71,10 +72,11 @@ func newResolveInfo(queryName string, querySelection ...ast.Selection) graphql.R
 	}
 }
 
-func newResolveParams(tx *sql.Tx, queryName string, querySelection ...ast.Selection) *resolveParamsBuilder {
+func newResolveParams(tx *database.Tx, queryName string, querySelection ...ast.Selection) *resolveParamsBuilder {
 	info := newResolveInfo(queryName, querySelection...)
 	ctx := context.WithValue(context.Background(), DbContextKey, tx)
 	ctx = context.WithValue(ctx, UserKey, "somebody")
+	ctx = context.WithValue(ctx, PermissionsKey, domain.NewPermissions(true, nil))
 	return &resolveParamsBuilder{graphql.ResolveParams{Info: info, Context: ctx}}
 }
 
@@ -116,6 +118,11 @@ func asInterfaces(maps []map[string]interface{}, nested ...string) []interface{}
 	return values
 }
 
+func (b *resolveParamsBuilder) setPermissions(permissions *domain.Permissions) *resolveParamsBuilder {
+	b.Context = context.WithValue(b.Context, PermissionsKey, permissions)
+	return b
+}
+
 func (b *resolveParamsBuilder) setSource(source interface{}) *resolveParamsBuilder {
 	b.Source = source
 	return b
@@ -1,25 +1,73 @@
 package schema
 
 import (
-	"database/sql"
-
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
 )
 
 var payeeSchema = graphql.NewObject(graphql.ObjectConfig{
 	Name:        "payee",
 	Description: "the other party in a transaction",
 	Fields: addAudit(graphql.Fields{
-		"id":               &graphql.Field{Type: graphql.Int},
-		"name":             &graphql.Field{Type: graphql.String},
-		"transactionCount": &graphql.Field{Type: graphql.Int},
+		"id":   &graphql.Field{Type: graphql.Int},
+		"name": &graphql.Field{Type: graphql.String},
+		"transactionCount": &graphql.Field{
+			Type:        graphql.Int,
+			Description: "Number of transactions with the payee in the readable accounts.",
+			Resolve:     resolvePayeeTransactionCount,
+		},
+		"amount": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Total of the transactions with the payee in the readable accounts.",
+			Args:        convertArgs,
+			Resolve:     resolvePayeeAmount,
+		},
 	}),
 })
 
-var payeeQueryFields = &graphql.Field{
-	Type: graphql.NewList(payeeSchema),
-	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
-		return getAllPayees(tx), nil
-	},
+func payeeQueryFields() *graphql.Field {
+	payeeSchema.AddFieldConfig("transactions", &graphql.Field{
+		Type:        txList,
+		Description: "Transactions with the payee in the readable accounts, ordered by date.",
+		Args:        getPageArgs(),
+		Resolve:     resolvePayeeTransactions,
+	})
+	return &graphql.Field{
+		Type: graphql.NewList(payeeSchema),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			tx := p.Context.Value(DbContextKey).(*database.Tx)
+			return getAllPayees(tx), nil
+		},
+	}
+}
+
+type payeeModel interface {
+	GetTransactions(tx *database.Tx, filter database.PageFilter, accountIDs []int64) []*domain.Transaction
+	GetTransactionCount(tx *database.Tx, accountIDs []int64) int64
+	GetAmount(tx *database.Tx, currencyID *int64, accountIDs []int64) (string, error)
+}
+
+var _ payeeModel = (*domain.Payee)(nil)
+
+func resolvePayeeTransactions(p graphql.ResolveParams) (interface{}, error) {
+	payee := p.Source.(payeeModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	filter, err := getPageFilter(p)
+	if err != nil {
+		return nil, err
+	}
+	return payee.GetTransactions(tx, filter, getPermissions(p).ReadableAccountIDs()), nil
+}
+
+func resolvePayeeTransactionCount(p graphql.ResolveParams) (interface{}, error) {
+	payee := p.Source.(payeeModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return payee.GetTransactionCount(tx, getPermissions(p).ReadableAccountIDs()), nil
+}
+
+func resolvePayeeAmount(p graphql.ResolveParams) (interface{}, error) {
+	payee := p.Source.(payeeModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return payee.GetAmount(tx, getCurrencyArg(p), getPermissions(p).ReadableAccountIDs())
 }
@@ -1,27 +1,131 @@
 package schema
 
 import (
-	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/jonestimd/financesd/internal/domain"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_payeeQueryFields_Resolve(t *testing.T) {
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
-		payees := []*table.Payee{{ID: 1}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		payees := []*domain.Payee{{Payee: &table.Payee{ID: 1}}}
 		getAll := mocka.Function(t, &getAllPayees, payees)
 		defer getAll.Restore()
 		params := newResolveParams(tx, payeeQuery, newField("", "id"), newField("", "name"))
 
-		result, err := payeeQueryFields.Resolve(params.ResolveParams)
+		result, err := payeeQueryFields().Resolve(params.ResolveParams)
 
 		assert.Nil(t, err)
 		assert.Equal(t, payees, result)
 		assert.Equal(t, []interface{}{tx}, getAll.GetFirstCall().Arguments())
 	})
 }
+
+type mockPayeeModel struct {
+	tx           *database.Tx
+	filter       database.PageFilter
+	accountIDs   []int64
+	transactions []*domain.Transaction
+	count        int64
+	currencyID   *int64
+	amount       string
+	amountErr    error
+}
+
+func (m *mockPayeeModel) GetAmount(tx *database.Tx, currencyID *int64, accountIDs []int64) (string, error) {
+	m.tx = tx
+	m.currencyID = currencyID
+	m.accountIDs = accountIDs
+	return m.amount, m.amountErr
+}
+
+func (m *mockPayeeModel) GetTransactionCount(tx *database.Tx, accountIDs []int64) int64 {
+	m.tx = tx
+	m.accountIDs = accountIDs
+	return m.count
+}
+
+func (m *mockPayeeModel) GetTransactions(tx *database.Tx, filter database.PageFilter, accountIDs []int64) []*domain.Transaction {
+	m.tx = tx
+	m.filter = filter
+	m.accountIDs = accountIDs
+	return m.transactions
+}
+
+func Test_resolvePayeeTransactions(t *testing.T) {
+	startDate := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		t.Run("returns page of transactions in readable accounts", func(t *testing.T) {
+			payee := &mockPayeeModel{transactions: []*domain.Transaction{domain.NewTransaction(42)}}
+			permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+			params := newResolveParams(tx, payeeQuery).setSource(payee).setPermissions(permissions).
+				addArg("startDate", startDate).addArg("limit", 10).addArg("offset", 20)
+
+			result, err := resolvePayeeTransactions(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, payee.transactions, result)
+			assert.Same(t, tx, payee.tx)
+			assert.Equal(t, database.PageFilter{StartDate: startDate, Limit: 10, Offset: 20}, payee.filter)
+			assert.Equal(t, []int64{1}, payee.accountIDs)
+		})
+		t.Run("returns transactions in all accounts for admin", func(t *testing.T) {
+			payee := &mockPayeeModel{}
+			params := newResolveParams(tx, payeeQuery).setSource(payee).setPermissions(domain.NewPermissions(true, nil))
+
+			_, err := resolvePayeeTransactions(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, database.PageFilter{}, payee.filter)
+			assert.Nil(t, payee.accountIDs)
+		})
+		t.Run("returns error for negative limit", func(t *testing.T) {
+			payee := &mockPayeeModel{}
+			params := newResolveParams(tx, payeeQuery).setSource(payee).addArg("limit", -1)
+
+			_, err := resolvePayeeTransactions(params.ResolveParams)
+
+			assert.Equal(t, apperror.Validation("limit", "must not be negative"), err)
+			assert.Nil(t, payee.tx)
+		})
+	})
+}
+
+func Test_resolvePayeeTransactionCount(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		payee := &mockPayeeModel{count: 3}
+		permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+		params := newResolveParams(tx, payeeQuery).setSource(payee).setPermissions(permissions)
+
+		result, err := resolvePayeeTransactionCount(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), result)
+		assert.Same(t, tx, payee.tx)
+		assert.Equal(t, []int64{1}, payee.accountIDs)
+	})
+}
+
+func Test_resolvePayeeAmount(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		payee := &mockPayeeModel{amount: "12.34"}
+		permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+		params := newResolveParams(tx, payeeQuery).setSource(payee).setPermissions(permissions).addArg("currency", 42)
+
+		result, err := resolvePayeeAmount(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "12.34", result)
+		assert.Same(t, tx, payee.tx)
+		assert.Equal(t, int64(42), *payee.currencyID)
+		assert.Equal(t, []int64{1}, payee.accountIDs)
+	})
+}
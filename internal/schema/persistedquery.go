@@ -0,0 +1,95 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+)
+
+// PersistedQueries contains queries by the hex encoded SHA-256 hash of their text. The queries are loaded from a
+// manifest that is generated from the web client. In strict mode, only the queries in the manifest are allowed.
+// Otherwise, clients can register other queries by sending them with their hash (i.e. automatic persisted queries).
+type PersistedQueries struct {
+	strict  bool
+	lock    sync.RWMutex
+	queries map[string]string
+}
+
+// QueryHash returns the hex encoded SHA-256 hash of the query.
+func QueryHash(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
+
+// LoadPersistedQueries reads a JSON manifest that maps hashes to queries. A missing manifest is only an error in strict
+// mode.
+func LoadPersistedQueries(path string, strict bool) (*PersistedQueries, error) {
+	queries := make(map[string]string)
+	data, err := readFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !strict {
+			return &PersistedQueries{queries: queries}, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return nil, fmt.Errorf("invalid persisted query manifest %s: %v", path, err)
+	}
+	for hash, query := range queries {
+		if QueryHash(query) != hash {
+			return nil, fmt.Errorf("persisted query hash doesn't match the query: %s", hash)
+		}
+	}
+	return &PersistedQueries{strict: strict, queries: queries}, nil
+}
+
+// Len returns the number of persisted queries.
+func (pq *PersistedQueries) Len() int {
+	pq.lock.RLock()
+	defer pq.lock.RUnlock()
+	return len(pq.queries)
+}
+
+// Resolve returns the query of a request. hash is the hash sent by the client, which is blank if the request doesn't
+// use a persisted query. If the request only contains the hash, then the query is looked up. If the request contains
+// the query and its hash, then the query is registered. Returns an error if the query is unknown or, in strict mode,
+// isn't in the manifest.
+func (pq *PersistedQueries) Resolve(hash string, query string) (string, error) {
+	hash = strings.ToLower(hash)
+	if query == "" {
+		if hash == "" {
+			return query, nil
+		}
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+		if query, ok := pq.queries[hash]; ok {
+			return query, nil
+		}
+		if pq.strict {
+			return "", apperror.PersistedQueryNotAllowed(hash)
+		}
+		return "", apperror.PersistedQueryNotFound(hash)
+	}
+	queryHash := QueryHash(query)
+	if hash != "" && hash != queryHash {
+		return "", apperror.Validation("extensions.persistedQuery.sha256Hash", "hash doesn't match the query")
+	}
+	if pq.strict {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+		if _, ok := pq.queries[queryHash]; !ok {
+			return "", apperror.PersistedQueryNotAllowed(queryHash)
+		}
+	} else if hash != "" {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+		pq.queries[hash] = query
+	}
+	return query, nil
+}
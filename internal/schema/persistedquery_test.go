@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+const accountsQuery = "{ accounts { id } }"
+
+func Test_QueryHash(t *testing.T) {
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", QueryHash(""))
+	assert.Len(t, QueryHash(accountsQuery), 64)
+}
+
+func Test_LoadPersistedQueries(t *testing.T) {
+	hash := QueryHash(accountsQuery)
+	tests := []struct {
+		name    string
+		data    string
+		readErr error
+		strict  bool
+		queries map[string]string
+		err     string
+	}{
+		{"loads manifest", `{"` + hash + `": "` + accountsQuery + `"}`, nil, true, map[string]string{hash: accountsQuery}, ""},
+		{"ignores missing manifest", "", os.ErrNotExist, false, map[string]string{}, ""},
+		{"returns error for missing manifest in strict mode", "", os.ErrNotExist, true, nil, "file does not exist"},
+		{"returns read error", "", errors.New("permission denied"), false, nil, "permission denied"},
+		{"returns error for invalid JSON", "[", nil, false, nil,
+			"invalid persisted query manifest queries.json: unexpected end of JSON input"},
+		{"returns error for wrong hash", `{"abc": "` + accountsQuery + `"}`, nil, false, nil,
+			"persisted query hash doesn't match the query: abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockReadFile := mocka.Function(t, &readFile, []byte(test.data), test.readErr)
+			defer mockReadFile.Restore()
+
+			queries, err := LoadPersistedQueries("queries.json", test.strict)
+
+			assert.Equal(t, []interface{}{"queries.json"}, mockReadFile.GetCall(0).Arguments())
+			if test.err == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, test.queries, queries.queries)
+				assert.Equal(t, len(test.queries), queries.Len())
+			} else {
+				assert.EqualError(t, err, test.err)
+				assert.Nil(t, queries)
+			}
+		})
+	}
+}
+
+func Test_PersistedQueries_Resolve(t *testing.T) {
+	hash := QueryHash(accountsQuery)
+	otherQuery := "{ payees { id } }"
+	otherHash := QueryHash(otherQuery)
+	tests := []struct {
+		name    string
+		strict  bool
+		hash    string
+		query   string
+		result  string
+		err     error
+		queries map[string]string
+	}{
+		{"returns query without hash", false, "", otherQuery, otherQuery, nil, map[string]string{hash: accountsQuery}},
+		{"returns blank query", true, "", "", "", nil, map[string]string{hash: accountsQuery}},
+		{"returns persisted query", false, hash, "", accountsQuery, nil, map[string]string{hash: accountsQuery}},
+		{"ignores case of hash", false, strings.ToUpper(hash), "", accountsQuery, nil, map[string]string{hash: accountsQuery}},
+		{"returns not found for unknown hash", false, otherHash, "", "", apperror.PersistedQueryNotFound(otherHash),
+			map[string]string{hash: accountsQuery}},
+		{"registers query", false, otherHash, otherQuery, otherQuery, nil, map[string]string{hash: accountsQuery, otherHash: otherQuery}},
+		{"returns error for wrong hash", false, hash, otherQuery, "",
+			apperror.Validation("extensions.persistedQuery.sha256Hash", "hash doesn't match the query"), map[string]string{hash: accountsQuery}},
+		{"allows manifest query in strict mode", true, "", accountsQuery, accountsQuery, nil, map[string]string{hash: accountsQuery}},
+		{"rejects other query in strict mode", true, "", otherQuery, "", apperror.PersistedQueryNotAllowed(otherHash),
+			map[string]string{hash: accountsQuery}},
+		{"doesn't register query in strict mode", true, otherHash, otherQuery, "", apperror.PersistedQueryNotAllowed(otherHash),
+			map[string]string{hash: accountsQuery}},
+		{"rejects unknown hash in strict mode", true, otherHash, "", "", apperror.PersistedQueryNotAllowed(otherHash),
+			map[string]string{hash: accountsQuery}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pq := &PersistedQueries{strict: test.strict, queries: map[string]string{hash: accountsQuery}}
+
+			result, err := pq.Resolve(test.hash, test.query)
+
+			assert.Equal(t, test.result, result)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.queries, pq.queries)
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/jonestimd/financesd/internal/apperror"
+)
+
+// defaultListSize is the expected number of items in a list field that isn't in expectedListSizes.
+const defaultListSize = 10
+
+// expectedListSizes contains the expected number of items in list fields, by type and field name. The limit argument
+// of a field is used instead if it is specified.
+var expectedListSizes = map[string]int{
+	"Queries.transactions":    1000,
+	"Queries.trash":           100,
+	"Queries.payees":          500,
+	"Queries.categories":      200,
+	"Queries.history":         100,
+	"Queries.importItems":     100,
+	"payee.transactions":      100,
+	"category.details":        100,
+	"category.children":       5,
+	"group.details":           100,
+	"transaction.details":     2,
+	"transaction.attachments": 1,
+}
+
+// QueryLimits are the maximum depth and estimated cost of a GraphQL operation. A limit of 0 is not checked.
+type QueryLimits struct {
+	MaxDepth int
+	MaxCost  int
+}
+
+// Check returns an error if the depth or cost of the operation exceeds the limits.
+func (l QueryLimits) Check(cost *QueryCost) error {
+	if l.MaxDepth > 0 && cost.Depth > l.MaxDepth {
+		return apperror.QueryLimit("depth", cost.Depth, l.MaxDepth)
+	}
+	if l.MaxCost > 0 && cost.Cost > l.MaxCost {
+		return apperror.QueryLimit("cost", cost.Cost, l.MaxCost)
+	}
+	return nil
+}
+
+// QueryCost is the depth and estimated cost of a GraphQL operation. The cost is the number of objects that will be
+// resolved, i.e. each object or list field counts the expected number of items multiplied by the sizes of the lists
+// that contain it. Scalar and introspection fields are not counted.
+type QueryCost struct {
+	Depth int
+	Cost  int
+}
+
+func (c *QueryCost) String() string {
+	return fmt.Sprintf("depth %d, cost %d", c.Depth, c.Cost)
+}
+
+// GetQueryCost computes the depth and cost of the operation. Returns nil if the query can't be parsed or doesn't
+// contain the operation, which are reported when the query is executed.
+func GetQueryCost(schema *graphql.Schema, query string, operationName string, variables map[string]interface{}) *QueryCost {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query)})})
+	if err != nil {
+		return nil
+	}
+	coster := &queryCoster{fragments: make(map[string]*ast.FragmentDefinition), variables: variables, schema: schema}
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || definition.Name != nil && definition.Name.Value == operationName {
+				operation = definition
+			}
+		case *ast.FragmentDefinition:
+			coster.fragments[definition.Name.Value] = definition
+		}
+	}
+	if operation == nil {
+		return nil
+	}
+	var root *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	}
+	if root == nil {
+		return nil
+	}
+	depth, cost := coster.selectionCost(root, operation.SelectionSet, 1, make(map[string]bool))
+	return &QueryCost{Depth: depth, Cost: cost}
+}
+
+type queryCoster struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// fieldsType is implemented by objects and interfaces.
+type fieldsType interface {
+	graphql.Type
+	Fields() graphql.FieldDefinitionMap
+}
+
+// selectionCost returns the depth and cost of the selections. The multiplier is the number of times that the parent
+// type will be resolved. Fragments in visiting are skipped to avoid infinite recursion on fragment cycles.
+func (c *queryCoster) selectionCost(parent graphql.Type, selectionSet *ast.SelectionSet, multiplier int, visiting map[string]bool) (int, int) {
+	if selectionSet == nil {
+		return 0, 0
+	}
+	depth, cost := 0, 0
+	for _, selection := range selectionSet.Selections {
+		selectionDepth, selectionCost := 0, 0
+		switch selection := selection.(type) {
+		case *ast.Field:
+			selectionDepth, selectionCost = c.fieldCost(parent, selection, multiplier, visiting)
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if selection.TypeCondition != nil {
+				fragmentType = c.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			selectionDepth, selectionCost = c.selectionCost(fragmentType, selection.SelectionSet, multiplier, visiting)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			if fragment, ok := c.fragments[name]; ok && !visiting[name] {
+				visiting[name] = true
+				fragmentType := c.schema.Type(fragment.TypeCondition.Name.Value)
+				selectionDepth, selectionCost = c.selectionCost(fragmentType, fragment.SelectionSet, multiplier, visiting)
+				delete(visiting, name)
+			}
+		}
+		if selectionDepth > depth {
+			depth = selectionDepth
+		}
+		cost = addCost(cost, selectionCost)
+	}
+	return depth, cost
+}
+
+func (c *queryCoster) fieldCost(parent graphql.Type, field *ast.Field, multiplier int, visiting map[string]bool) (int, int) {
+	parentFields, ok := parent.(fieldsType)
+	if !ok || strings.HasPrefix(field.Name.Value, "__") {
+		return 0, 0
+	}
+	definition := parentFields.Fields()[field.Name.Value]
+	if definition == nil {
+		return 0, 0
+	}
+	fieldType := definition.Type
+	if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+		fieldType = nonNull.OfType
+	}
+	if _, ok := fieldType.(*graphql.List); ok {
+		multiplier = multiplyCost(multiplier, c.listSize(parent.Name()+"."+field.Name.Value, field))
+	}
+	if namedType, ok := graphql.GetNamed(fieldType).(graphql.Type); ok && graphql.IsCompositeType(namedType) {
+		depth, cost := c.selectionCost(namedType, field.SelectionSet, multiplier, visiting)
+		return depth + 1, addCost(multiplier, cost)
+	}
+	return 1, 0
+}
+
+// listSize returns the limit argument of the field or the expected size of the list.
+func (c *queryCoster) listSize(name string, field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value == "limit" {
+			if limit := c.intValue(argument.Value); limit > 0 {
+				return limit
+			}
+		}
+	}
+	if size, ok := expectedListSizes[name]; ok {
+		return size
+	}
+	return defaultListSize
+}
+
+// intValue returns the value of an integer literal or variable. Returns 0 if the value is not an integer.
+func (c *queryCoster) intValue(value ast.Value) int {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		var result int
+		fmt.Sscan(value.Value, &result)
+		return result
+	case *ast.Variable:
+		switch variable := c.variables[value.Name.Value].(type) {
+		case int:
+			return variable
+		case float64:
+			return int(variable)
+		}
+	}
+	return 0
+}
+
+const maxCost = math.MaxInt32
+
+func addCost(a int, b int) int {
+	if a > maxCost-b {
+		return maxCost
+	}
+	return a + b
+}
+
+func multiplyCost(a int, b int) int {
+	if b != 0 && a > maxCost/b {
+		return maxCost
+	}
+	return a * b
+}
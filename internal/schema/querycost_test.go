@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_QueryLimits_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits QueryLimits
+		err    error
+	}{
+		{"returns nil within limits", QueryLimits{MaxDepth: 5, MaxCost: 100}, nil},
+		{"returns nil for no limits", QueryLimits{}, nil},
+		{"returns error for depth", QueryLimits{MaxDepth: 4, MaxCost: 100}, apperror.QueryLimit("depth", 5, 4)},
+		{"returns error for cost", QueryLimits{MaxDepth: 5, MaxCost: 99}, apperror.QueryLimit("cost", 100, 99)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.limits.Check(&QueryCost{Depth: 5, Cost: 100})
+
+			assert.Equal(t, test.err, err)
+		})
+	}
+}
+
+func Test_GetQueryCost(t *testing.T) {
+	schema, err := New()
+	assert.Nil(t, err)
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		variables     map[string]interface{}
+		cost          *QueryCost
+	}{
+		{"returns nil for parse error", "{ accounts {", "", nil, nil},
+		{"returns nil for unknown operation", "query accounts { accounts { id } }", "payees", nil, nil},
+		{"uses default list size", "{ accounts { id name } }", "", nil, &QueryCost{Depth: 2, Cost: 10}},
+		{"multiplies nested fields by list sizes",
+			"{ transactions(accountId: 1) { id payee { name } details { amount category { code } } } }", "", nil,
+			&QueryCost{Depth: 4, Cost: 6000}},
+		{"uses limit argument", "{ payees { transactions(limit: 5) { id } } }", "", nil, &QueryCost{Depth: 3, Cost: 3000}},
+		{"uses limit variable", "query payees($limit: Int) { payees { transactions(limit: $limit) { id } } }", "payees",
+			map[string]interface{}{"limit": float64(20)}, &QueryCost{Depth: 3, Cost: 10500}},
+		{"follows fragments", "{ payees { ...payeeFields } } fragment payeeFields on payee { id transactions { id } }", "", nil,
+			&QueryCost{Depth: 3, Cost: 50500}},
+		{"follows inline fragments", "{ payees { ... on payee { transactions { id } } } }", "", nil, &QueryCost{Depth: 3, Cost: 50500}},
+		{"ignores fragment cycle", "{ payees { ...a } } fragment a on payee { id ...a }", "", nil, &QueryCost{Depth: 2, Cost: 500}},
+		{"ignores introspection", "{ __schema { types { name } } accounts { id } }", "", nil, &QueryCost{Depth: 2, Cost: 10}},
+		{"saturates cost", "{ payees { transactions(limit: 100000) { details(limit: 100000) { id } } } }", "", nil,
+			&QueryCost{Depth: 4, Cost: maxCost}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cost := GetQueryCost(&schema, test.query, test.operationName, test.variables)
+
+			assert.Equal(t, test.cost, cost)
+		})
+	}
+}
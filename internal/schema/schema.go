@@ -12,30 +12,82 @@ const payeeQuery = "payees"
 // const assetsQuery = "assets"
 const securityQuery = "securities"
 const categoryQuery = "categories"
+const currencyQuery = "currencies"
+const baseCurrencyQuery = "baseCurrency"
+const setBaseCurrencyMutation = "setBaseCurrency"
+const exchangeRateQuery = "exchangeRates"
+const updateExchangeRatesMutation = "updateExchangeRates"
 const groupQuery = "groups"
 const transactionQuery = "transactions"
 const updateTxMutation = "updateTransactions"
+const bulkUpdateDetailsMutation = "bulkUpdateDetails"
+const trashQuery = "trash"
+const restoreMutation = "restore"
+const purgeTrashMutation = "purgeTrash"
+const importItemQuery = "importItems"
+const addImportItemsMutation = "addImportItems"
+const reviewImportItemsMutation = "reviewImportItems"
+const userQuery = "users"
+const updateUsersMutation = "updateUsers"
+const grantAccountPermissionsMutation = "grantAccountPermissions"
+const revokeAccountPermissionsMutation = "revokeAccountPermissions"
+const apiTokenQuery = "apiTokens"
+const createAPITokenMutation = "createApiToken"
+const deleteAPITokensMutation = "deleteApiTokens"
+const historyQuery = "history"
+const undoMutation = "undo"
+const transactionsChangedSubscription = "transactionsChanged"
+const accountsChangedSubscription = "accountsChanged"
+const referenceDataChangedSubscription = "referenceDataChanged"
 
 var queries = graphql.Fields{
-	accountQuery:     accountQueryFields,
-	companyQuery:     companyQueryFields(),
-	payeeQuery:       payeeQueryFields,
-	securityQuery:    securityQueryFields,
-	categoryQuery:    categoryQueryFields,
-	groupQuery:       groupQueryFields,
-	transactionQuery: transactionQueryFields,
+	accountQuery:      accountQueryFields,
+	companyQuery:      companyQueryFields(),
+	payeeQuery:        payeeQueryFields(),
+	securityQuery:     securityQueryFields,
+	categoryQuery:     categoryQueryFields(),
+	groupQuery:        groupQueryFields(),
+	transactionQuery:  transactionQueryFields,
+	trashQuery:        trashQueryFields,
+	importItemQuery:   importItemQueryFields,
+	currencyQuery:     currencyQueryFields,
+	baseCurrencyQuery: baseCurrencyQueryFields,
+	exchangeRateQuery: exchangeRateQueryFields,
+	userQuery:         userQueryFields,
+	apiTokenQuery:     apiTokenQueryFields,
+	historyQuery:      historyQueryFields,
 }
 
 var mutations = graphql.Fields{
-	updateCompaniesMutation: updateCompaniesFields,
-	updateTxMutation:        updateTxFields,
+	updateCompaniesMutation:          updateCompaniesFields,
+	updateTxMutation:                 updateTxFields,
+	bulkUpdateDetailsMutation:        bulkUpdateDetailsFields,
+	restoreMutation:                  restoreFields,
+	purgeTrashMutation:               purgeTrashFields,
+	addImportItemsMutation:           addImportItemsFields,
+	reviewImportItemsMutation:        reviewImportItemsFields,
+	setBaseCurrencyMutation:          setBaseCurrencyFields,
+	updateExchangeRatesMutation:      updateExchangeRatesFields,
+	updateUsersMutation:              updateUsersFields,
+	grantAccountPermissionsMutation:  grantAccountPermissionsFields,
+	revokeAccountPermissionsMutation: revokeAccountPermissionsFields,
+	createAPITokenMutation:           createAPITokenFields,
+	deleteAPITokensMutation:          deleteAPITokensFields,
+	undoMutation:                     undoFields,
+}
+
+var subscriptions = graphql.Fields{
+	transactionsChangedSubscription:  transactionsChangedFields,
+	accountsChangedSubscription:      accountsChangedFields,
+	referenceDataChangedSubscription: referenceDataChangedFields,
 }
 
 // New creates the GraphQL schema.
 func New() (graphql.Schema, error) {
 	schemaConfig := graphql.SchemaConfig{
-		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "Queries", Fields: queries}),
-		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutations", Fields: mutations}),
+		Query:        graphql.NewObject(graphql.ObjectConfig{Name: "Queries", Fields: queries}),
+		Mutation:     graphql.NewObject(graphql.ObjectConfig{Name: "Mutations", Fields: mutations}),
+		Subscription: graphql.NewObject(graphql.ObjectConfig{Name: "Subscriptions", Fields: subscriptions}),
 	}
 	return graphql.NewSchema(schemaConfig)
 }
@@ -1,9 +1,12 @@
 package schema
 
 import (
-	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
 )
 
 // Schema
@@ -17,12 +20,12 @@ var securitySchema = graphql.NewObject(graphql.ObjectConfig{
 		"name":             &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "Name")},
 		"scale":            &graphql.Field{Type: graphql.Int, Resolve: nestedResolver("Asset", "Scale")},
 		"symbol":           &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "Symbol")},
-		"shares":           &graphql.Field{Type: graphql.Float},
-		"firstAcquired":    &graphql.Field{Type: dateType},
-		"costBasis":        &graphql.Field{Type: graphql.Float},
-		"dividends":        &graphql.Field{Type: graphql.Float},
+		"shares":           &graphql.Field{Type: graphql.Float, Resolve: resolveShares},
+		"firstAcquired":    &graphql.Field{Type: dateType, Resolve: resolveFirstAcquired},
+		"costBasis":        &graphql.Field{Type: graphql.String, Args: convertArgs, Resolve: resolveCostBasis},
+		"dividends":        &graphql.Field{Type: graphql.String, Args: convertArgs, Resolve: resolveDividends},
 		"version":          &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "Version")},
-		"transactionCount": &graphql.Field{Type: graphql.Int},
+		"transactionCount": &graphql.Field{Type: graphql.Int, Resolve: resolveSecurityTransactionCount},
 		"changeUser":       &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "ChangeUser")},
 		"changeDate":       &graphql.Field{Type: graphql.String, Resolve: nestedResolver("Asset", "ChangeDate")},
 	},
@@ -35,7 +38,7 @@ var securityQueryFields = &graphql.Field{
 		"symbol": {Type: graphql.String, Description: "unique security symbol"},
 	},
 	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
 		if id, ok := p.Args["id"]; ok {
 			intID := id.(int)
 			return getSecurityByID(tx, int64(intID)), nil
@@ -45,3 +48,53 @@ var securityQueryFields = &graphql.Field{
 		return getAllSecurities(tx), nil
 	},
 }
+
+type securityModel interface {
+	GetShares(tx *database.Tx, accountIDs []int64) float64
+	GetFirstAcquired(tx *database.Tx, accountIDs []int64) *time.Time
+	GetTransactionCount(tx *database.Tx, accountIDs []int64) int64
+	GetCostBasis(tx *database.Tx, currencyID *int64, accountIDs []int64) (*string, error)
+	GetDividends(tx *database.Tx, currencyID *int64, accountIDs []int64) (*string, error)
+}
+
+var _ securityModel = (*domain.Security)(nil)
+
+func resolveShares(p graphql.ResolveParams) (interface{}, error) {
+	if security, ok := p.Source.(securityModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return security.GetShares(tx, getPermissions(p).ReadableAccountIDs()), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveFirstAcquired(p graphql.ResolveParams) (interface{}, error) {
+	if security, ok := p.Source.(securityModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return security.GetFirstAcquired(tx, getPermissions(p).ReadableAccountIDs()), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveSecurityTransactionCount(p graphql.ResolveParams) (interface{}, error) {
+	if security, ok := p.Source.(securityModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return security.GetTransactionCount(tx, getPermissions(p).ReadableAccountIDs()), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveCostBasis(p graphql.ResolveParams) (interface{}, error) {
+	if security, ok := p.Source.(securityModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return security.GetCostBasis(tx, getCurrencyArg(p), getPermissions(p).ReadableAccountIDs())
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveDividends(p graphql.ResolveParams) (interface{}, error) {
+	if security, ok := p.Source.(securityModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return security.GetDividends(tx, getCurrencyArg(p), getPermissions(p).ReadableAccountIDs())
+	}
+	return nil, errors.New("invalid source")
+}
@@ -1,7 +1,6 @@
 package schema
 
 import (
-	"database/sql"
 	"fmt"
 	"testing"
 	"time"
@@ -9,23 +8,18 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
 	"github.com/jonestimd/financesd/internal/database/table"
-	"github.com/jonestimd/financesd/internal/sqltest"
+	"github.com/jonestimd/financesd/internal/domain"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_securitySchema_Fields(t *testing.T) {
 	symbol := "S1"
 	now := time.Now()
-	cost := float64(12.34)
-	dividends := float64(23.45)
 	security := &table.Security{
-		Type:             "Stock",
-		Shares:           96,
-		FirstAcquired:    &now,
-		CostBasis:        &cost,
-		Dividends:        &dividends,
-		TransactionCount: 69,
+		Type: "Stock",
 		Asset: table.Asset{
 			ID:      1,
 			Name:    "the asset",
@@ -36,7 +30,7 @@ func Test_securitySchema_Fields(t *testing.T) {
 			Audited: table.Audited{ChangeUser: "me", ChangeDate: &now},
 		},
 	}
-	params := graphql.ResolveParams{Source: security}
+	params := graphql.ResolveParams{Source: &domain.Security{Security: security}}
 	tests := []struct {
 		field string
 		value interface{}
@@ -62,7 +56,7 @@ func Test_securitySchema_Fields(t *testing.T) {
 
 func Test_securityQueryFields_Resolve_all(t *testing.T) {
 	symbol := "S1"
-	securities := []*table.Security{{AssetID: 42}}
+	securities := []*domain.Security{{Security: &table.Security{AssetID: 42}}}
 	getAll := mocka.Function(t, &getAllSecurities, securities)
 	getByID := mocka.Function(t, &getSecurityByID, securities)
 	getBySymbol := mocka.Function(t, &getSecurityBySymbol, securities)
@@ -71,7 +65,7 @@ func Test_securityQueryFields_Resolve_all(t *testing.T) {
 		getByID.Restore()
 		getBySymbol.Restore()
 	}()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		tests := []struct {
 			name     string
 			argName  string
@@ -103,3 +97,119 @@ func Test_securityQueryFields_Resolve_all(t *testing.T) {
 		}
 	})
 }
+
+type mockSecurityModel struct {
+	tx               *database.Tx
+	currencyID       *int64
+	accountIDs       []int64
+	shares           float64
+	firstAcquired    *time.Time
+	transactionCount int64
+	costBasis        *string
+	dividends        *string
+}
+
+func (m *mockSecurityModel) GetShares(tx *database.Tx, accountIDs []int64) float64 {
+	m.tx = tx
+	m.accountIDs = accountIDs
+	return m.shares
+}
+
+func (m *mockSecurityModel) GetFirstAcquired(tx *database.Tx, accountIDs []int64) *time.Time {
+	m.tx = tx
+	m.accountIDs = accountIDs
+	return m.firstAcquired
+}
+
+func (m *mockSecurityModel) GetTransactionCount(tx *database.Tx, accountIDs []int64) int64 {
+	m.tx = tx
+	m.accountIDs = accountIDs
+	return m.transactionCount
+}
+
+func (m *mockSecurityModel) GetCostBasis(tx *database.Tx, currencyID *int64, accountIDs []int64) (*string, error) {
+	m.tx = tx
+	m.currencyID = currencyID
+	m.accountIDs = accountIDs
+	return m.costBasis, nil
+}
+
+func (m *mockSecurityModel) GetDividends(tx *database.Tx, currencyID *int64, accountIDs []int64) (*string, error) {
+	m.tx = tx
+	m.currencyID = currencyID
+	m.accountIDs = accountIDs
+	return m.dividends, nil
+}
+
+func Test_resolveSecuritySummary(t *testing.T) {
+	firstAcquired := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	security := &mockSecurityModel{shares: 12.5, firstAcquired: &firstAcquired, transactionCount: 3}
+	dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+		tests := []struct {
+			name     string
+			resolver graphql.FieldResolveFn
+			expected interface{}
+		}{
+			{"shares", resolveShares, 12.5},
+			{"firstAcquired", resolveFirstAcquired, &firstAcquired},
+			{"transactionCount", resolveSecurityTransactionCount, int64(3)},
+		}
+		for _, test := range tests {
+			t.Run(test.name+" returns value for readable accounts", func(t *testing.T) {
+				permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+				params := newResolveParams(tx, securityQuery).setSource(security).setPermissions(permissions)
+
+				result, err := test.resolver(params.ResolveParams)
+
+				assert.Nil(t, err)
+				assert.Equal(t, test.expected, result)
+				assert.Same(t, tx, security.tx)
+				assert.Equal(t, []int64{1}, security.accountIDs)
+			})
+			t.Run(test.name+" returns error for invalid source", func(t *testing.T) {
+				params := newResolveParams(tx, securityQuery).setSource("")
+
+				_, err := test.resolver(params.ResolveParams)
+
+				assert.EqualError(t, err, "invalid source")
+			})
+		}
+	})
+}
+
+func Test_resolveSecurityAmounts(t *testing.T) {
+	costBasis, dividends := "12.34", "56.78"
+	dbtest.TestInTx(t, func(_ sqlmock.Sqlmock, tx *database.Tx) {
+		tests := []struct {
+			name     string
+			resolver graphql.FieldResolveFn
+			expected *string
+		}{
+			{"costBasis", resolveCostBasis, &costBasis},
+			{"dividends", resolveDividends, &dividends},
+		}
+		for _, test := range tests {
+			t.Run(test.name+" returns converted amount", func(t *testing.T) {
+				security := &mockSecurityModel{costBasis: &costBasis, dividends: &dividends}
+				permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+				params := newResolveParams(tx, securityQuery).setSource(security).setPermissions(permissions).
+					addArg("currency", 42)
+
+				result, err := test.resolver(params.ResolveParams)
+
+				assert.Nil(t, err)
+				assert.Same(t, test.expected, result)
+				assert.Same(t, tx, security.tx)
+				assert.Equal(t, int64(42), *security.currencyID)
+				assert.Equal(t, []int64{1}, security.accountIDs)
+			})
+			t.Run(test.name+" returns error for invalid source", func(t *testing.T) {
+				params := newResolveParams(tx, securityQuery).setSource("")
+
+				_, err := test.resolver(params.ResolveParams)
+
+				assert.EqualError(t, err, "invalid source")
+			})
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+// ChangeEventKey is the key of the *domain.ChangeEvent in the root object of a subscription operation. The event is
+// missing when the subscription is started, which validates the arguments without returning any changes.
+const ChangeEventKey = "changeEvent"
+
+var rowChangeSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "rowChange",
+	Description: "a row that was inserted, updated or deleted",
+	Fields: graphql.Fields{
+		"entity":  &graphql.Field{Type: nonNullString, Description: "table name of the entity (e.g. transaction_detail)"},
+		"id":      &graphql.Field{Type: nonNullInt},
+		"version": &graphql.Field{Type: graphql.Int, Description: "version after the change, null for unversioned entities"},
+		"action":  &graphql.Field{Type: nonNullString, Description: "insert, update or delete"},
+	},
+})
+
+var changeEventSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "changeEvent",
+	Description: "the rows that were changed by a committed request",
+	Fields: graphql.Fields{
+		"changeSetId": &graphql.Field{Type: nonNullString, Description: "ID of the request that made the changes"},
+		"user":        &graphql.Field{Type: nonNullString, Description: "user that made the changes"},
+		"changes":     &graphql.Field{Type: nonNullList(rowChangeSchema)},
+	},
+})
+
+// getChangeEvent returns the event being published or nil if the subscription is being started.
+func getChangeEvent(p graphql.ResolveParams) *domain.ChangeEvent {
+	if root, ok := p.Source.(map[string]interface{}); ok {
+		if event, ok := root[ChangeEventKey].(*domain.ChangeEvent); ok {
+			return event
+		}
+	}
+	return nil
+}
+
+var transactionsChangedFields = &graphql.Field{
+	Type:        changeEventSchema,
+	Description: "Changes to the transactions, transaction details and attachments of an account.",
+	Args: graphql.FieldConfigArgument{
+		"accountId": {Type: graphql.NewNonNull(graphql.Int), Description: "account ID"},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		accountID := int64(p.Args["accountId"].(int))
+		if err := getPermissions(p).RequireRead(accountID); err != nil {
+			return nil, err
+		}
+		if event := getChangeEvent(p); event != nil {
+			return event.ForAccount(accountID), nil
+		}
+		return nil, nil
+	},
+}
+
+var accountsChangedFields = &graphql.Field{
+	Type:        changeEventSchema,
+	Description: "Changes to companies, accounts and the transactions of the accounts.",
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if event := getChangeEvent(p); event != nil {
+			return event.ForAccounts(getPermissions(p)), nil
+		}
+		return nil, nil
+	},
+}
+
+var referenceDataChangedFields = &graphql.Field{
+	Type:        changeEventSchema,
+	Description: "Changes to payees, categories, groups, assets and securities.",
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if event := getChangeEvent(p); event != nil {
+			return event.ForReferenceData(), nil
+		}
+		return nil, nil
+	},
+}
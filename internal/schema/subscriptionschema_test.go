@@ -0,0 +1,90 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_transactionsChangedFields_Resolve(t *testing.T) {
+	event := &domain.ChangeEvent{ChangeSetID: "123:4", User: "somebody", Changes: []*domain.RowChange{{Entity: "payee", ID: 7}}}
+	t.Run("returns nil without event", func(t *testing.T) {
+		params := newResolveParams(nil, transactionsChangedSubscription).addArg("accountId", 1)
+
+		result, err := transactionsChangedFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Nil(t, result)
+	})
+	t.Run("filters event", func(t *testing.T) {
+		params := newResolveParams(nil, transactionsChangedSubscription).addArg("accountId", 1).
+			setSource(map[string]interface{}{ChangeEventKey: event})
+
+		result, err := transactionsChangedFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, event.ForAccount(1), result)
+	})
+	t.Run("requires account permission", func(t *testing.T) {
+		params := newResolveParams(nil, transactionsChangedSubscription).addArg("accountId", 1).
+			setPermissions(domain.NewPermissions(false, map[int64]string{2: table.PermissionRead}))
+
+		result, err := transactionsChangedFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, result)
+		assert.Equal(t, apperror.AccountForbidden(1, "readable"), err)
+	})
+}
+
+func Test_accountsChangedFields_Resolve(t *testing.T) {
+	event := &domain.ChangeEvent{ChangeSetID: "123:4", User: "somebody", Changes: []*domain.RowChange{
+		{Entity: "company", ID: 3},
+		{Entity: "payee", ID: 7},
+	}}
+	tests := []struct {
+		name   string
+		source interface{}
+		result interface{}
+	}{
+		{"returns nil without event", map[string]interface{}{}, nil},
+		{"filters event", map[string]interface{}{ChangeEventKey: event}, event.ForAccounts(domain.NewPermissions(true, nil))},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := newResolveParams(nil, accountsChangedSubscription).setSource(test.source)
+
+			result, err := accountsChangedFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func Test_referenceDataChangedFields_Resolve(t *testing.T) {
+	event := &domain.ChangeEvent{ChangeSetID: "123:4", User: "somebody", Changes: []*domain.RowChange{
+		{Entity: "company", ID: 3},
+		{Entity: "payee", ID: 7},
+	}}
+	tests := []struct {
+		name   string
+		source interface{}
+		result interface{}
+	}{
+		{"returns nil without event", map[string]interface{}{}, nil},
+		{"filters event", map[string]interface{}{ChangeEventKey: event}, event.ForReferenceData()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := newResolveParams(nil, referenceDataChangedSubscription).setSource(test.source)
+
+			result, err := referenceDataChangedFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package schema
 
 import (
-	"database/sql"
 	"errors"
+	"log"
 
 	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
 	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
 	"github.com/jonestimd/financesd/internal/domain"
 )
 
@@ -24,6 +26,9 @@ func getDetailFields() graphql.Fields {
 func getDetailSchema(name string, relatedField string, fieldType graphql.Output, resolve graphql.FieldResolveFn) *graphql.Object {
 	detailFields := getDetailFields()
 	detailFields[relatedField] = &graphql.Field{Type: fieldType, Resolve: resolve}
+	detailFields["category"] = &graphql.Field{Type: categorySchema, Resolve: resolveDetailCategory}
+	detailFields["group"] = &graphql.Field{Type: groupSchema, Resolve: resolveDetailGroup}
+	detailFields["exchangeAsset"] = &graphql.Field{Type: assetSchema, Resolve: resolveExchangeAsset}
 	return graphql.NewObject(graphql.ObjectConfig{
 		Name:        name,
 		Description: "a detail of a financial transaction",
@@ -45,10 +50,15 @@ func getTxFields() graphql.Fields {
 }
 
 func getTxSchemaConfig(name string) graphql.ObjectConfig {
+	fields := getTxFields()
+	fields["trashDate"] = &graphql.Field{Type: graphql.DateTime, Description: "When the transaction was deleted. Null if not in the trash."}
+	fields["payee"] = &graphql.Field{Type: payeeSchema, Resolve: resolvePayee}
+	fields["account"] = &graphql.Field{Type: accountSchema, Resolve: resolveTxAccount}
+	fields["security"] = &graphql.Field{Type: securitySchema, Resolve: resolveSecurity}
 	return graphql.ObjectConfig{
 		Description: "a financial transaction",
 		Name:        name,
-		Fields:      addAudit(getTxFields()),
+		Fields:      addAudit(fields),
 	}
 }
 
@@ -58,10 +68,38 @@ func getTxSchema() *graphql.Object {
 	detailSchema := getDetailSchema("transactionDetail", "relatedDetail", relatedDetailSchema, resolveRelatedDetail)
 	txSchema := graphql.NewObject(getTxSchemaConfig("transaction"))
 	txSchema.AddFieldConfig("details", &graphql.Field{Type: graphql.NewList(detailSchema), Resolve: resolveDetails})
+	txSchema.AddFieldConfig("attachments", &graphql.Field{Type: graphql.NewList(attachmentSchema), Resolve: resolveAttachments})
+	detailSchema.AddFieldConfig("transaction", &graphql.Field{Type: txSchema, Resolve: resolveDetailTransaction})
 	return txSchema
 }
 
-var txList = newList(getTxSchema())
+var transactionSchema = getTxSchema()
+var txList = newList(transactionSchema)
+var txDetailList = transactionSchema.Fields()["details"].Type
+
+// getPageArgs returns the arguments for selecting a page of the transactions or details of each parent object.
+func getPageArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"startDate": {Type: dateType, Description: "Earliest transaction date (inclusive)."},
+		"endDate":   {Type: dateType, Description: "Latest transaction date (inclusive)."},
+		"limit":     {Type: graphql.Int, Description: "Maximum number of items for each parent. Not limited if omitted."},
+		"offset":    {Type: graphql.Int, DefaultValue: 0, Description: "Number of items to skip for each parent."},
+	}
+}
+
+// getPageFilter converts the page arguments to a database filter.
+func getPageFilter(p graphql.ResolveParams) (database.PageFilter, error) {
+	filter := database.PageFilter{StartDate: p.Args["startDate"], EndDate: p.Args["endDate"]}
+	filter.Limit, _ = p.Args["limit"].(int)
+	filter.Offset, _ = p.Args["offset"].(int)
+	if filter.Limit < 0 {
+		return filter, apperror.Validation("limit", "must not be negative")
+	}
+	if filter.Offset < 0 {
+		return filter, apperror.Validation("offset", "must not be negative")
+	}
+	return filter, nil
+}
 
 var transactionQueryFields = &graphql.Field{
 	Type: txList,
@@ -69,45 +107,137 @@ var transactionQueryFields = &graphql.Field{
 		"accountId": {Type: graphql.NewNonNull(graphql.Int), Description: "account ID"},
 	},
 	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
-		accountID := p.Args["accountId"].(int)
-		return getAccountTransactions(tx, int64(accountID)), nil
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		accountID := int64(p.Args["accountId"].(int))
+		if err := getPermissions(p).RequireRead(accountID); err != nil {
+			return nil, err
+		}
+		return getAccountTransactions(tx, accountID), nil
 	},
 }
 
 type txModel interface {
-	GetDetails(tx *sql.Tx) []*domain.TransactionDetail
+	GetDetails(tx *database.Tx) []*domain.TransactionDetail
 }
 
 var _ txModel = (*domain.Transaction)(nil)
 
 func resolveDetails(p graphql.ResolveParams) (interface{}, error) {
 	if transaction, ok := p.Source.(txModel); ok {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
 		return transaction.GetDetails(tx), nil
 	}
 	return nil, errors.New("invalid source")
 }
 
+type txReferenceModel interface {
+	GetPayee(tx *database.Tx) *domain.Payee
+	GetAccount(tx *database.Tx) *domain.Account
+	GetSecurity(tx *database.Tx) *domain.Security
+}
+
+var _ txReferenceModel = (*domain.Transaction)(nil)
+
+func resolvePayee(p graphql.ResolveParams) (interface{}, error) {
+	if transaction, ok := p.Source.(txReferenceModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return transaction.GetPayee(tx), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+// resolveTxAccount returns null if the user can't read the account, e.g. the account of a transfer.
+func resolveTxAccount(p graphql.ResolveParams) (interface{}, error) {
+	if transaction, ok := p.Source.(txReferenceModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		if account := transaction.GetAccount(tx); account != nil && getPermissions(p).CanRead(account.ID) {
+			return account, nil
+		}
+		return nil, nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveSecurity(p graphql.ResolveParams) (interface{}, error) {
+	if transaction, ok := p.Source.(txReferenceModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return transaction.GetSecurity(tx), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
 type detailModel interface {
-	GetRelatedDetail(tx *sql.Tx) *domain.TransactionDetail
-	GetRelatedTransaction(tx *sql.Tx) *domain.Transaction
+	GetRelatedDetail(tx *database.Tx) *domain.TransactionDetail
+	GetRelatedTransaction(tx *database.Tx) *domain.Transaction
+	GetTransaction(tx *database.Tx) *domain.Transaction
 }
 
 var _ detailModel = (*domain.TransactionDetail)(nil)
 
+// resolveRelatedDetail returns null if the user can't read the account of the related detail's transaction.
 func resolveRelatedDetail(p graphql.ResolveParams) (interface{}, error) {
 	if detail, ok := p.Source.(detailModel); ok {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
-		return detail.GetRelatedDetail(tx), nil
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		if related := detail.GetRelatedDetail(tx); related != nil && readableTransaction(p, related.GetRelatedTransaction(tx)) != nil {
+			return related, nil
+		}
+		return nil, nil
 	}
 	return nil, errors.New("invalid source")
 }
 
+// readableTransaction returns nil if the user can't read the account of the transaction.
+func readableTransaction(p graphql.ResolveParams, transaction *domain.Transaction) interface{} {
+	if transaction != nil && getPermissions(p).CanRead(transaction.AccountID) {
+		return transaction
+	}
+	return nil
+}
+
 func resolveRelatedTransaction(p graphql.ResolveParams) (interface{}, error) {
 	if detail, ok := p.Source.(detailModel); ok {
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
-		return detail.GetRelatedTransaction(tx), nil
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return readableTransaction(p, detail.GetRelatedTransaction(tx)), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveDetailTransaction(p graphql.ResolveParams) (interface{}, error) {
+	if detail, ok := p.Source.(detailModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return readableTransaction(p, detail.GetTransaction(tx)), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+type detailReferenceModel interface {
+	GetCategory(tx *database.Tx) *domain.Category
+	GetGroup(tx *database.Tx) *domain.Group
+	GetExchangeAsset(tx *database.Tx) *table.Asset
+}
+
+var _ detailReferenceModel = (*domain.TransactionDetail)(nil)
+
+func resolveDetailCategory(p graphql.ResolveParams) (interface{}, error) {
+	if detail, ok := p.Source.(detailReferenceModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return detail.GetCategory(tx), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveDetailGroup(p graphql.ResolveParams) (interface{}, error) {
+	if detail, ok := p.Source.(detailReferenceModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return detail.GetGroup(tx), nil
+	}
+	return nil, errors.New("invalid source")
+}
+
+func resolveExchangeAsset(p graphql.ResolveParams) (interface{}, error) {
+	if detail, ok := p.Source.(detailReferenceModel); ok {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return detail.GetExchangeAsset(tx), nil
 	}
 	return nil, errors.New("invalid source")
 }
@@ -115,7 +245,15 @@ func resolveRelatedTransaction(p graphql.ResolveParams) (interface{}, error) {
 func getDetailInput(action string) *graphql.InputObjectFieldConfig {
 	fields := graphql.InputObjectConfigFieldMap{
 		"transferAccountId": &graphql.InputObjectFieldConfig{Type: graphql.Int},
-		"version":           &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"transferAmount": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Float,
+			Description: "Amount for the other account of a transfer. Defaults to the negated **amount** converted using **exchangeRate**.",
+		},
+		"exchangeRate": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Float,
+			Description: "Rate for converting **amount** to the currency of the transfer account. When updating, the current rate is kept if neither **transferAmount** nor **exchangeRate** is specified.",
+		},
+		"version": &graphql.InputObjectFieldConfig{Type: graphql.Int},
 	}
 	for name, field := range getDetailFields() {
 		fieldType := field.Type
@@ -161,22 +299,50 @@ var updateTxFields = &graphql.Field{
 		"accountId": {Type: nonNullInt, Description: "ID of account for transactions."},
 		"add":       {Type: newList(getTxInput("add")), Description: "Transactions to add."},
 		"update":    {Type: newList(getTxInput("update")), Description: "Changes to be made to existing transactions."},
-		"delete":    {Type: idVersionList, Description: "IDs of transactions to delete."},
+		"delete":    {Type: idVersionList, Description: "IDs of transactions to move to the trash, along with their transfer transactions."},
 	},
 	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 		transactions := []*domain.Transaction{}
-		tx := p.Context.Value(DbContextKey).(*sql.Tx)
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
 		user := p.Context.Value(UserKey).(string)
-		if ids, ok := p.Args["delete"]; ok {
-			deleteTransactions(tx, asMaps(ids))
+		var deletes, updates, inserts []map[string]interface{}
+		if arg, ok := p.Args["delete"]; ok {
+			deletes = asMaps(arg)
+		}
+		if arg, ok := p.Args["update"]; ok {
+			updates = asMaps(arg, "details")
+		}
+		if arg, ok := p.Args["add"]; ok {
+			inserts = asMaps(arg, "details")
+		}
+		permissions := getPermissions(p)
+		if inserts != nil {
+			if err := permissions.RequireWrite(database.InputObject(p.Args).RequireInt("accountId")); err != nil {
+				return nil, err
+			}
+		}
+		if err := permissions.RequireTransactionWrite(tx, inputIDs(deletes, updates), append(updates, inserts...)); err != nil {
+			return nil, err
+		}
+		if deletes != nil {
+			if err := deleteTransactions(tx, deletes, user); err != nil {
+				return nil, err
+			}
 		}
 		ids := make([]int64, 0)
-		if updates, ok := p.Args["update"]; ok {
-			ids = updateTransactions(tx, asMaps(updates, "details"), user)
+		if updates != nil {
+			var err error
+			if ids, err = updateTransactions(tx, updates, user); err != nil {
+				return nil, err
+			}
 		}
-		if inserts, ok := p.Args["add"]; ok {
+		if inserts != nil {
 			accountID := database.InputObject(p.Args).RequireInt("accountId")
-			ids = append(ids, insertTransactions(tx, accountID, asMaps(inserts, "details"), user)...)
+			insertIDs, err := insertTransactions(tx, accountID, inserts, user)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, insertIDs...)
 		}
 		if len(ids) > 0 {
 			transactions = getTransactionsByIDs(tx, ids)
@@ -184,3 +350,125 @@ var updateTxFields = &graphql.Field{
 		return transactions, nil
 	},
 }
+
+var trashQueryFields = &graphql.Field{
+	Type:        txList,
+	Description: "Deleted transactions, most recent first.",
+	Args: graphql.FieldConfigArgument{
+		"accountId": {Type: graphql.Int, Description: "account ID"},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		permissions := getPermissions(p)
+		var accountID interface{}
+		if id, ok := p.Args["accountId"]; ok {
+			accountID = int64(id.(int))
+			if err := permissions.RequireRead(accountID.(int64)); err != nil {
+				return nil, err
+			}
+		}
+		transactions := make([]*domain.Transaction, 0)
+		for _, transaction := range getTrash(tx, accountID) {
+			if permissions.CanRead(transaction.AccountID) {
+				transactions = append(transactions, transaction)
+			}
+		}
+		return transactions, nil
+	},
+}
+
+var restoreFields = &graphql.Field{
+	Type:        txList,
+	Description: "Move transactions out of the trash, along with their transfer transactions. Returns the restored transactions.",
+	Args: graphql.FieldConfigArgument{
+		"ids": {Type: graphql.NewNonNull(intList), Description: "IDs of the transactions to restore."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		ids := make([]int64, 0)
+		for _, id := range p.Args["ids"].([]interface{}) {
+			ids = append(ids, int64(id.(int)))
+		}
+		if err := getPermissions(p).RequireTransactionWrite(tx, ids, nil); err != nil {
+			return nil, err
+		}
+		return restoreTransactions(tx, ids, user)
+	},
+}
+
+var purgeTrashFields = &graphql.Field{
+	Type:        nonNullInt,
+	Description: "Permanently delete transactions that have been in the trash longer than the retention period, along with attachment files that are no longer used. Returns the number of deleted transactions.",
+	Args: graphql.FieldConfigArgument{
+		"retentionDays": {Type: graphql.Int, DefaultValue: 30, Description: "Number of days to keep deleted transactions."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if err := getPermissions(p).RequireAdmin(); err != nil {
+			return nil, err
+		}
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		count, hashes := purgeTrash(tx, p.Args["retentionDays"].(int), user)
+		if store, ok := p.Context.Value(AttachmentStoreKey).(FileStore); ok && len(hashes) > 0 {
+			// only remove the files if the delete is committed
+			afterCommit(p, func() {
+				for _, hash := range hashes {
+					if err := store.Remove(hash); err != nil {
+						log.Printf("Error removing attachment file %s: %v", hash, err)
+					}
+				}
+			})
+		}
+		return count, nil
+	},
+}
+
+var detailFilterInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "detailFilterInput",
+	Description: "Selects transaction details. Transfer details are not included.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"accountId":             {Type: graphql.Int},
+		"startDate":             {Type: dateType, Description: "Earliest transaction date (inclusive)."},
+		"endDate":               {Type: dateType, Description: "Latest transaction date (inclusive)."},
+		"payeeId":               {Type: graphql.Int},
+		"transactionCategoryId": {Type: graphql.Int, Description: "Current category of the details."},
+		"memo":                  {Type: graphql.String, Description: "Text contained in the transaction or detail memo."},
+	},
+})
+
+var detailChangeInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "detailChangeInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"transactionCategoryId": {Type: graphql.Int},
+		"transactionGroupId":    {Type: graphql.Int},
+		"memo":                  {Type: graphql.String},
+	},
+})
+
+var bulkUpdateDetailsFields = &graphql.Field{
+	Type:        nonNullInt,
+	Description: "Change the category, group and/or memo of all details matching the filter. Returns the number of affected details.",
+	Args: graphql.FieldConfigArgument{
+		"filter": {Type: graphql.NewNonNull(detailFilterInput), Description: "Details to update."},
+		"change": {Type: graphql.NewNonNull(detailChangeInput), Description: "Values to set on the details."},
+		"dryRun": {Type: graphql.Boolean, DefaultValue: false, Description: "Count the matching details without updating them."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		filter := p.Args["filter"].(map[string]interface{})
+		change := p.Args["change"].(map[string]interface{})
+		dryRun, _ := p.Args["dryRun"].(bool)
+		if permissions := getPermissions(p); !permissions.IsAdmin() {
+			if accountID, ok := database.InputObject(filter).IntOrNull("accountId").(int64); ok {
+				if err := permissions.RequireWrite(accountID); err != nil {
+					return nil, err
+				}
+			} else {
+				filter["accountIds"] = permissions.WritableAccountIDs()
+			}
+		}
+		return bulkUpdateDetails(tx, filter, change, dryRun, user)
+	},
+}
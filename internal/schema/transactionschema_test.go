@@ -1,13 +1,18 @@
 package schema
 
 import (
-	"database/sql"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/MonsantoCo/mocka/v2"
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
 	"github.com/jonestimd/financesd/internal/domain"
-	"github.com/jonestimd/financesd/internal/sqltest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,7 +20,7 @@ func Test_transactionQueryFields_Resolve_returnsRows(t *testing.T) {
 	transactions := []*domain.Transaction{domain.NewTransaction(42)}
 	getTransactions := mocka.Function(t, &getAccountTransactions, transactions)
 	defer getTransactions.Restore()
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		accountID := 123
 		params := newResolveParams(tx, transactionQuery, newField("", "id"), newField("", "memo")).addArg("accountId", accountID)
 
@@ -27,9 +32,22 @@ func Test_transactionQueryFields_Resolve_returnsRows(t *testing.T) {
 	})
 }
 
+func Test_transactionQueryFields_Resolve_requiresRead(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		params := newResolveParams(tx, transactionQuery, newField("", "id")).
+			addArg("accountId", 123).
+			setPermissions(domain.NewPermissions(false, map[int64]string{123: table.PermissionNone}))
+
+		result, err := transactionQueryFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, result)
+		assert.Equal(t, apperror.AccountForbidden(123, "readable"), err)
+	})
+}
+
 func Test_resolveDetails(t *testing.T) {
 	txID := int64(42)
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		t.Run("returns details", func(t *testing.T) {
 			details := []*domain.TransactionDetail{domain.NewTransactionDetail(96, txID)}
 			domainTx := domain.NewTransaction(txID)
@@ -55,10 +73,11 @@ func Test_resolveRelatedDetail(t *testing.T) {
 	detailID := int64(96)
 	relatedID := int64(69)
 	resolver := findSchemaField(getTxSchema(), "details", "relatedDetail").Resolve
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		t.Run("returns detail", func(t *testing.T) {
 			detail := domain.NewTransactionDetail(detailID, 42)
 			relatedDetail := domain.NewTransactionDetail(relatedID, 24)
+			relatedDetail.SetRelatedTransaction(domain.NewTransaction(24))
 			detail.SetRelatedDetail(relatedDetail)
 			params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(detail)
 
@@ -67,6 +86,30 @@ func Test_resolveRelatedDetail(t *testing.T) {
 			assert.Nil(t, err)
 			assert.Equal(t, relatedDetail, result)
 		})
+		t.Run("returns nil for transfer into unreadable account", func(t *testing.T) {
+			detail := domain.NewTransactionDetail(detailID, 42)
+			relatedDetail := domain.NewTransactionDetail(relatedID, 24)
+			relatedTx := domain.NewTransaction(24)
+			relatedTx.AccountID = 2
+			relatedDetail.SetRelatedTransaction(relatedTx)
+			detail.SetRelatedDetail(relatedDetail)
+			params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(detail).
+				setPermissions(domain.NewPermissions(false, map[int64]string{1: table.PermissionRead, 2: table.PermissionNone}))
+
+			result, err := resolver(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Nil(t, result)
+		})
+		t.Run("returns nil for detail that is not a transfer", func(t *testing.T) {
+			detail := domain.NewTransactionDetail(detailID, 42)
+			params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(detail)
+
+			result, err := resolver(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Nil(t, result)
+		})
 		t.Run("returns error for invalid source", func(t *testing.T) {
 			params := newResolveParams(tx, transactionQuery, newField("", "id"))
 
@@ -81,7 +124,7 @@ func Test_resolveRelatedTransaction(t *testing.T) {
 	detailID := int64(96)
 	txID := int64(69)
 	resolver := findSchemaField(getTxSchema(), "details", "relatedDetail", "transaction").Resolve
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
 		t.Run("returns transaction", func(t *testing.T) {
 			detail := domain.NewTransactionDetail(detailID, txID)
 			relatedTx := domain.NewTransaction(24)
@@ -93,6 +136,71 @@ func Test_resolveRelatedTransaction(t *testing.T) {
 			assert.Nil(t, err)
 			assert.Equal(t, relatedTx, result)
 		})
+		t.Run("returns nil for transfer into unreadable account", func(t *testing.T) {
+			detail := domain.NewTransactionDetail(detailID, txID)
+			relatedTx := domain.NewTransaction(24)
+			relatedTx.AccountID = 2
+			detail.SetRelatedTransaction(relatedTx)
+			params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(detail).
+				setPermissions(domain.NewPermissions(false, map[int64]string{1: table.PermissionRead, 2: table.PermissionNone}))
+
+			result, err := resolver(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Nil(t, result)
+		})
+		t.Run("returns error for invalid source", func(t *testing.T) {
+			params := newResolveParams(tx, transactionQuery, newField("", "id"))
+
+			_, err := resolver(params.ResolveParams)
+
+			assert.Equal(t, "invalid source", err.Error())
+		})
+	})
+}
+
+type mockDetailModel struct {
+	tx          *database.Tx
+	transaction *domain.Transaction
+}
+
+func (m *mockDetailModel) GetRelatedDetail(tx *database.Tx) *domain.TransactionDetail {
+	return nil
+}
+
+func (m *mockDetailModel) GetRelatedTransaction(tx *database.Tx) *domain.Transaction {
+	return nil
+}
+
+func (m *mockDetailModel) GetTransaction(tx *database.Tx) *domain.Transaction {
+	m.tx = tx
+	return m.transaction
+}
+
+func Test_resolveDetailTransaction(t *testing.T) {
+	resolver := findSchemaField(getTxSchema(), "details", "transaction").Resolve
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		t.Run("returns transaction", func(t *testing.T) {
+			detail := &mockDetailModel{transaction: domain.NewTransaction(42)}
+			params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(detail)
+
+			result, err := resolver(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Same(t, detail.transaction, result)
+			assert.Same(t, tx, detail.tx)
+		})
+		t.Run("returns nil for unreadable account", func(t *testing.T) {
+			detail := &mockDetailModel{transaction: domain.NewTransaction(42)}
+			detail.transaction.AccountID = 2
+			params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(detail).
+				setPermissions(domain.NewPermissions(false, map[int64]string{1: table.PermissionRead}))
+
+			result, err := resolver(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Nil(t, result)
+		})
 		t.Run("returns error for invalid source", func(t *testing.T) {
 			params := newResolveParams(tx, transactionQuery, newField("", "id"))
 
@@ -103,19 +211,119 @@ func Test_resolveRelatedTransaction(t *testing.T) {
 	})
 }
 
+type mockReferenceModel struct {
+	tx       *database.Tx
+	payee    *domain.Payee
+	account  *domain.Account
+	security *domain.Security
+	category *domain.Category
+	group    *domain.Group
+	asset    *table.Asset
+}
+
+func (m *mockReferenceModel) GetPayee(tx *database.Tx) *domain.Payee {
+	m.tx = tx
+	return m.payee
+}
+
+func (m *mockReferenceModel) GetAccount(tx *database.Tx) *domain.Account {
+	m.tx = tx
+	return m.account
+}
+
+func (m *mockReferenceModel) GetSecurity(tx *database.Tx) *domain.Security {
+	m.tx = tx
+	return m.security
+}
+
+func (m *mockReferenceModel) GetCategory(tx *database.Tx) *domain.Category {
+	m.tx = tx
+	return m.category
+}
+
+func (m *mockReferenceModel) GetGroup(tx *database.Tx) *domain.Group {
+	m.tx = tx
+	return m.group
+}
+
+func (m *mockReferenceModel) GetExchangeAsset(tx *database.Tx) *table.Asset {
+	m.tx = tx
+	return m.asset
+}
+
+func Test_resolveReferences(t *testing.T) {
+	model := &mockReferenceModel{
+		payee:    &domain.Payee{Payee: &table.Payee{ID: 1}},
+		account:  domain.NewAccount(2, nil),
+		security: &domain.Security{Security: &table.Security{Asset: table.Asset{ID: 3}}},
+		category: &domain.Category{Category: &table.Category{ID: 4}},
+		group:    &domain.Group{Group: &table.Group{ID: 5}},
+		asset:    &table.Asset{ID: 6},
+	}
+	tests := []struct {
+		name     string
+		path     []string
+		expected interface{}
+	}{
+		{"transaction.payee", []string{"payee"}, model.payee},
+		{"transaction.account", []string{"account"}, model.account},
+		{"transaction.security", []string{"security"}, model.security},
+		{"detail.category", []string{"details", "category"}, model.category},
+		{"detail.group", []string{"details", "group"}, model.group},
+		{"detail.exchangeAsset", []string{"details", "exchangeAsset"}, model.asset},
+		{"relatedTransaction.payee", []string{"details", "relatedDetail", "transaction", "payee"}, model.payee},
+		{"relatedDetail.category", []string{"details", "relatedDetail", "category"}, model.category},
+	}
+	for _, test := range tests {
+		resolver := findSchemaField(getTxSchema(), test.path...).Resolve
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			t.Run("returns "+test.name, func(t *testing.T) {
+				params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(model)
+
+				result, err := resolver(params.ResolveParams)
+
+				assert.Nil(t, err)
+				assert.Same(t, test.expected, result)
+				assert.Same(t, tx, model.tx)
+			})
+			t.Run("returns error for invalid source for "+test.name, func(t *testing.T) {
+				params := newResolveParams(tx, transactionQuery, newField("", "id"))
+
+				_, err := resolver(params.ResolveParams)
+
+				assert.Equal(t, "invalid source", err.Error())
+			})
+		})
+	}
+}
+
+func Test_resolveTxAccount_unreadableAccount(t *testing.T) {
+	resolver := findSchemaField(getTxSchema(), "details", "relatedDetail", "transaction", "account").Resolve
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		model := &mockReferenceModel{account: domain.NewAccount(2, nil)}
+		params := newResolveParams(tx, transactionQuery, newField("", "id")).setSource(model).
+			setPermissions(domain.NewPermissions(false, map[int64]string{1: table.PermissionWrite}))
+
+		result, err := resolver(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Nil(t, result)
+	})
+}
+
 func Test_updateTransactions_Resolve_delete(t *testing.T) {
 	id := 42
 	args := []map[string]interface{}{{"id": id, "version": 1}}
 	transactions := []*domain.Transaction{}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
-		deleteTransactionsStub := mocka.Function(t, &deleteTransactions)
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		deleteTransactionsStub := mocka.Function(t, &deleteTransactions, nil)
 		defer deleteTransactionsStub.Restore()
 		params := newResolveParams(tx, transactionQuery, newField("", "id")).addArrayArg("delete", args)
 
 		result, err := updateTxFields.Resolve(params.ResolveParams)
 
 		assert.Equal(t, transactions, result)
-		assert.Equal(t, []interface{}{tx, args}, deleteTransactionsStub.GetFirstCall().Arguments())
+		assert.Equal(t, []interface{}{tx, args, "somebody"}, deleteTransactionsStub.GetFirstCall().Arguments())
 		assert.Nil(t, err)
 	})
 }
@@ -126,8 +334,8 @@ func Test_updateTransactions_Resolve_update(t *testing.T) {
 	args := []map[string]interface{}{{"id": id, "name": name, "details": []map[string]interface{}{{"id": 96, "version": 1}}}}
 	updateIDs := []int64{42}
 	transactions := []*domain.Transaction{domain.NewTransaction(int64(id))}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
-		mockUpdateTransactions := mocka.Function(t, &updateTransactions, updateIDs)
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		mockUpdateTransactions := mocka.Function(t, &updateTransactions, updateIDs, nil)
 		defer mockUpdateTransactions.Restore()
 		mockGetTransactions := mocka.Function(t, &getTransactionsByIDs, transactions)
 		defer mockGetTransactions.Restore()
@@ -142,14 +350,32 @@ func Test_updateTransactions_Resolve_update(t *testing.T) {
 	})
 }
 
+func Test_updateTransactions_Resolve_returnsError(t *testing.T) {
+	args := []map[string]interface{}{{"id": 42, "version": 1}}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		conflict := apperror.VersionConflict("transaction", 42, 1)
+		mockUpdateTransactions := mocka.Function(t, &updateTransactions, nil, conflict)
+		defer mockUpdateTransactions.Restore()
+		mockGetTransactions := mocka.Function(t, &getTransactionsByIDs, nil)
+		defer mockGetTransactions.Restore()
+		params := newResolveParams(tx, transactionQuery, newField("", "id")).addArrayArg("update", args, "details")
+
+		result, err := updateTxFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, result)
+		assert.Same(t, conflict, err)
+		assert.Equal(t, 0, mockGetTransactions.CallCount())
+	})
+}
+
 func Test_updateTransactions_Resolve_insert(t *testing.T) {
 	name := "new name"
 	accountID := 96
 	args := []map[string]interface{}{{"date": "2020-12-25", "name": name, "details": []map[string]interface{}{{"amount": 96}}}}
 	newIDs := []int64{42}
 	transactions := []*domain.Transaction{domain.NewTransaction(newIDs[0])}
-	sqltest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *sql.Tx) {
-		mockInsertTransactions := mocka.Function(t, &insertTransactions, newIDs)
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		mockInsertTransactions := mocka.Function(t, &insertTransactions, newIDs, nil)
 		defer mockInsertTransactions.Restore()
 		mockGetTransactions := mocka.Function(t, &getTransactionsByIDs, transactions)
 		defer mockGetTransactions.Restore()
@@ -165,3 +391,225 @@ func Test_updateTransactions_Resolve_insert(t *testing.T) {
 		assert.Equal(t, []interface{}{tx, newIDs}, mockGetTransactions.GetCall(0).Arguments())
 	})
 }
+
+func Test_updateTransactions_Resolve_requiresWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		details []map[string]interface{}
+		message string
+	}{
+		{"for account", []map[string]interface{}{{"amount": 96}}, "account not writable (96)"},
+		{"for transfer account", []map[string]interface{}{{"amount": 96, "transferAccountId": 69}}, "account not writable (69)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := []map[string]interface{}{{"date": "2020-12-25", "details": test.details}}
+			dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+				mockInsertTransactions := mocka.Function(t, &insertTransactions, []int64{42}, nil)
+				defer mockInsertTransactions.Restore()
+				writable := map[int64]string{96: table.PermissionRead}
+				if test.details[0]["transferAccountId"] != nil {
+					writable[96] = table.PermissionWrite
+				}
+				params := newResolveParams(tx, transactionQuery, newField("", "id")).
+					addArg("accountId", 96).
+					addArrayArg("add", args, "details").
+					setPermissions(domain.NewPermissions(false, writable))
+
+				result, err := updateTxFields.Resolve(params.ResolveParams)
+
+				assert.Nil(t, result)
+				assert.EqualError(t, err, test.message)
+				assert.IsType(t, &apperror.ForbiddenError{}, err)
+				assert.Equal(t, 0, mockInsertTransactions.CallCount())
+			})
+		})
+	}
+}
+
+func Test_bulkUpdateDetailsFields_Resolve_limitsAccounts(t *testing.T) {
+	change := map[string]interface{}{"transactionCategoryId": 42}
+	permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionWrite, 2: table.PermissionRead})
+	t.Run("sets writable accounts", func(t *testing.T) {
+		filter := map[string]interface{}{"memo": "x"}
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			bulkUpdateStub := mocka.Function(t, &bulkUpdateDetails, int64(3), nil)
+			defer bulkUpdateStub.Restore()
+			params := newResolveParams(tx, bulkUpdateDetailsMutation).
+				addArg("filter", filter).
+				addArg("change", change).
+				addArg("dryRun", false).
+				setPermissions(permissions)
+
+			_, err := bulkUpdateDetailsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			expectedFilter := map[string]interface{}{"memo": "x", "accountIds": []int64{1}}
+			assert.Equal(t, []interface{}{tx, expectedFilter, change, false, "somebody"}, bulkUpdateStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("requires writable account", func(t *testing.T) {
+		filter := map[string]interface{}{"accountId": 2}
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, bulkUpdateDetailsMutation).
+				addArg("filter", filter).
+				addArg("change", change).
+				setPermissions(permissions)
+
+			result, err := bulkUpdateDetailsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.AccountForbidden(2, "writable"), err)
+		})
+	})
+}
+
+func Test_bulkUpdateDetailsFields_Resolve(t *testing.T) {
+	filter := map[string]interface{}{"accountId": 96}
+	change := map[string]interface{}{"transactionCategoryId": 42}
+	tests := []struct {
+		name   string
+		dryRun interface{}
+	}{
+		{"updates details", false},
+		{"counts details", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+				bulkUpdateStub := mocka.Function(t, &bulkUpdateDetails, int64(3), nil)
+				defer bulkUpdateStub.Restore()
+				params := newResolveParams(tx, bulkUpdateDetailsMutation).
+					addArg("filter", filter).
+					addArg("change", change).
+					addArg("dryRun", test.dryRun)
+
+				result, err := bulkUpdateDetailsFields.Resolve(params.ResolveParams)
+
+				assert.Nil(t, err)
+				assert.Equal(t, int64(3), result)
+				assert.Equal(t, []interface{}{tx, filter, change, test.dryRun, "somebody"}, bulkUpdateStub.GetFirstCall().Arguments())
+			})
+		})
+	}
+}
+
+func Test_transactionSchema_trashDate(t *testing.T) {
+	trashDate := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	field := transactionSchema.Fields()["trashDate"]
+
+	assert.Same(t, graphql.DateTime, field.Type)
+	assert.Equal(t, "2021-03-04T05:06:07Z", graphql.DateTime.Serialize(&trashDate))
+}
+
+func Test_trashQueryFields_Resolve(t *testing.T) {
+	transactions := []*domain.Transaction{
+		{Transaction: &table.Transaction{ID: 1, AccountID: 1}},
+		{Transaction: &table.Transaction{ID: 2, AccountID: 2}},
+	}
+	permissions := domain.NewPermissions(false, map[int64]string{1: table.PermissionRead})
+	t.Run("returns readable transactions", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			getTrashStub := mocka.Function(t, &getTrash, transactions)
+			defer getTrashStub.Restore()
+			params := newResolveParams(tx, trashQuery, newField("", "id")).setPermissions(permissions)
+
+			result, err := trashQueryFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, transactions[:1], result)
+			assert.Equal(t, []interface{}{tx, nil}, getTrashStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("returns transactions for account", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			getTrashStub := mocka.Function(t, &getTrash, transactions[:1])
+			defer getTrashStub.Restore()
+			params := newResolveParams(tx, trashQuery, newField("", "id")).addArg("accountId", 1).setPermissions(permissions)
+
+			result, err := trashQueryFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, transactions[:1], result)
+			assert.Equal(t, []interface{}{tx, int64(1)}, getTrashStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("requires account permission", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, trashQuery, newField("", "id")).addArg("accountId", 2).setPermissions(permissions)
+
+			result, err := trashQueryFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.AccountForbidden(2, "readable"), err)
+		})
+	})
+}
+
+func Test_restoreFields_Resolve(t *testing.T) {
+	transactions := []*domain.Transaction{domain.NewTransaction(42)}
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		restoreStub := mocka.Function(t, &restoreTransactions, transactions, nil)
+		defer restoreStub.Restore()
+		params := newResolveParams(tx, restoreMutation, newField("", "id")).addArg("ids", []interface{}{42})
+
+		result, err := restoreFields.Resolve(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, transactions, result)
+		assert.Equal(t, []interface{}{tx, []int64{42}, "somebody"}, restoreStub.GetFirstCall().Arguments())
+	})
+}
+
+func Test_purgeTrashFields_Resolve(t *testing.T) {
+	t.Run("purges trash", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			purgeStub := mocka.Function(t, &purgeTrash, 3, []string{"abc"})
+			defer purgeStub.Restore()
+			params := newResolveParams(tx, purgeTrashMutation).addArg("retentionDays", 7)
+
+			result, err := purgeTrashFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, 3, result)
+			assert.Equal(t, []interface{}{tx, 7, "somebody"}, purgeStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("removes unused files after commit", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			purgeStub := mocka.Function(t, &purgeTrash, 3, []string{"abc", "def"})
+			defer purgeStub.Restore()
+			store := &mockFileStore{}
+			callbacks := make([]func(), 0)
+			params := newResolveParams(tx, purgeTrashMutation).addArg("retentionDays", 7)
+			params.Context = context.WithValue(params.Context, AttachmentStoreKey, store)
+			params.Context = context.WithValue(params.Context, AfterCommitKey, &callbacks)
+
+			purgeTrashFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, store.removed)
+			assert.Len(t, callbacks, 1)
+			callbacks[0]()
+			assert.Equal(t, []string{"abc", "def"}, store.removed)
+		})
+	})
+	t.Run("requires admin", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, purgeTrashMutation).addArg("retentionDays", 7).setPermissions(domain.NewPermissions(false, nil))
+
+			result, err := purgeTrashFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.Forbidden("admin role required"), err)
+		})
+	})
+}
+
+type mockFileStore struct {
+	removed []string
+}
+
+func (s *mockFileStore) Remove(hash string) error {
+	s.removed = append(s.removed, hash)
+	return nil
+}
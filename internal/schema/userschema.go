@@ -0,0 +1,161 @@
+package schema
+
+import (
+	"github.com/graphql-go/graphql"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+var accountPermissionSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "accountPermission",
+	Description: "access to an account granted to a user",
+	Fields: addAudit(graphql.Fields{
+		"id":         &graphql.Field{Type: nonNullInt},
+		"userId":     &graphql.Field{Type: nonNullInt},
+		"accountId":  &graphql.Field{Type: nonNullInt},
+		"permission": &graphql.Field{Type: nonNullString, Description: "none, read or write"},
+	}),
+})
+
+var accountPermissionList = nonNullList(accountPermissionSchema)
+
+var userSchema = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "user",
+	Description: "a person allowed to access the application",
+	Fields: addAudit(graphql.Fields{
+		"id":          &graphql.Field{Type: nonNullInt},
+		"name":        &graphql.Field{Type: nonNullString},
+		"role":        &graphql.Field{Type: nonNullString, Description: "Admins can access all accounts. Other users can only access the accounts they have been granted."},
+		"permissions": &graphql.Field{Type: accountPermissionList, Resolve: resolveAccountPermissions},
+	}),
+})
+
+var userList = nonNullList(userSchema)
+
+var userQueryFields = &graphql.Field{
+	Type:        userList,
+	Description: "Users and their account permissions. Requires the admin role.",
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if err := getPermissions(p).RequireAdmin(); err != nil {
+			return nil, err
+		}
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		return getAllUsers(tx), nil
+	},
+}
+
+type userModel interface {
+	GetAccountPermissions(tx *database.Tx) []*table.AccountPermission
+}
+
+var _ userModel = (*domain.User)(nil)
+
+func resolveAccountPermissions(p graphql.ResolveParams) (interface{}, error) {
+	user := p.Source.(userModel)
+	tx := p.Context.Value(DbContextKey).(*database.Tx)
+	return user.GetAccountPermissions(tx), nil
+}
+
+var addUserInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "addUserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name": {Type: nonNullString, Description: "The name used to sign in."},
+		"role": {Type: graphql.String, Description: "admin or user (default)"},
+	},
+})
+
+var updateUserInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "updateUserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"id":      {Type: nonNullInt},
+		"version": {Type: nonNullInt},
+		"role":    {Type: graphql.String, Description: "admin or user"},
+	},
+})
+
+var updateUsersFields = &graphql.Field{
+	Type:        userList,
+	Description: "Add users and/or change their roles. Requires the admin role.",
+	Args: graphql.FieldConfigArgument{
+		"add":    {Type: newList(addUserInput), Description: "Users to add."},
+		"update": {Type: newList(updateUserInput), Description: "Changes to be made to existing users."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if err := getPermissions(p).RequireAdmin(); err != nil {
+			return nil, err
+		}
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		ids := make([]int64, 0)
+		if updates, ok := p.Args["update"]; ok {
+			var err error
+			if ids, err = updateUsers(tx, asMaps(updates), user); err != nil {
+				return nil, err
+			}
+		}
+		if adds, ok := p.Args["add"]; ok {
+			addIDs, err := addUsers(tx, asMaps(adds), user)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, addIDs...)
+		}
+		if len(ids) == 0 {
+			return []*domain.User{}, nil
+		}
+		return getUsersByIDs(tx, ids), nil
+	},
+}
+
+var grantInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "grantAccountPermissionInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"userId":     {Type: nonNullInt},
+		"accountId":  {Type: nonNullInt},
+		"permission": {Type: nonNullString, Description: "none, read or write"},
+	},
+})
+
+var grantAccountPermissionsFields = &graphql.Field{
+	Type:        accountPermissionList,
+	Description: "Set the permissions of users for accounts. Requires the admin role.",
+	Args: graphql.FieldConfigArgument{
+		"grants": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(grantInput)))},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if err := getPermissions(p).RequireAdmin(); err != nil {
+			return nil, err
+		}
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		ids, err := grantAccountPermissions(tx, asMaps(p.Args["grants"]), user)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return []*table.AccountPermission{}, nil
+		}
+		return getAccountPermissionsByIDs(tx, ids), nil
+	},
+}
+
+var revokeAccountPermissionsFields = &graphql.Field{
+	Type:        nonNullInt,
+	Description: "Delete account permissions. Returns the number of deleted permissions. Requires the admin role.",
+	Args: graphql.FieldConfigArgument{
+		"ids": {Type: graphql.NewNonNull(intList), Description: "IDs of the account permissions to delete."},
+	},
+	Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+		if err := getPermissions(p).RequireAdmin(); err != nil {
+			return nil, err
+		}
+		tx := p.Context.Value(DbContextKey).(*database.Tx)
+		user := p.Context.Value(UserKey).(string)
+		ids := make([]int64, 0)
+		for _, id := range p.Args["ids"].([]interface{}) {
+			ids = append(ids, int64(id.(int)))
+		}
+		return revokeAccountPermissions(tx, ids, user)
+	},
+}
@@ -0,0 +1,209 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/jonestimd/financesd/internal/apperror"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/database/dbtest"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_userQueryFields_Resolve(t *testing.T) {
+	t.Run("returns users", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			users := []*domain.User{{User: &table.User{ID: 42}}}
+			getUsersStub := mocka.Function(t, &getAllUsers, users)
+			defer getUsersStub.Restore()
+			params := newResolveParams(tx, userQuery, newField("", "id"))
+
+			result, err := userQueryFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, users, result)
+			assert.Equal(t, []interface{}{tx}, getUsersStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("requires admin", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, userQuery, newField("", "id")).setPermissions(domain.NewPermissions(false, nil))
+
+			result, err := userQueryFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.Forbidden("admin role required"), err)
+		})
+	})
+}
+
+type mockUserModel struct {
+	permissions []*table.AccountPermission
+	tx          *database.Tx
+}
+
+func (u *mockUserModel) GetAccountPermissions(tx *database.Tx) []*table.AccountPermission {
+	u.tx = tx
+	return u.permissions
+}
+
+func Test_resolveAccountPermissions(t *testing.T) {
+	dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+		mockUser := &mockUserModel{permissions: []*table.AccountPermission{{ID: 1}}}
+		params := newResolveParams(tx, userQuery, newField("", "permissions")).setSource(mockUser)
+
+		result, err := resolveAccountPermissions(params.ResolveParams)
+
+		assert.Nil(t, err)
+		assert.Equal(t, mockUser.permissions, result)
+		assert.Same(t, tx, mockUser.tx)
+	})
+}
+
+func Test_updateUsersFields_Resolve(t *testing.T) {
+	adds := []map[string]interface{}{{"name": "alice"}}
+	updates := []map[string]interface{}{{"id": 42, "version": 1, "role": table.RoleAdmin}}
+	t.Run("adds and updates users", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			users := []*domain.User{{User: &table.User{ID: 42}}, {User: &table.User{ID: 43}}}
+			addUsersStub := mocka.Function(t, &addUsers, []int64{43}, nil)
+			defer addUsersStub.Restore()
+			updateUsersStub := mocka.Function(t, &updateUsers, []int64{42}, nil)
+			defer updateUsersStub.Restore()
+			getUsersStub := mocka.Function(t, &getUsersByIDs, users)
+			defer getUsersStub.Restore()
+			params := newResolveParams(tx, updateUsersMutation, newField("", "id")).
+				addArrayArg("add", adds).addArrayArg("update", updates)
+
+			result, err := updateUsersFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, users, result)
+			assert.Equal(t, []interface{}{tx, adds, "somebody"}, addUsersStub.GetFirstCall().Arguments())
+			assert.Equal(t, []interface{}{tx, updates, "somebody"}, updateUsersStub.GetFirstCall().Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{42, 43}}, getUsersStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("returns empty list for no changes", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, updateUsersMutation, newField("", "id"))
+
+			result, err := updateUsersFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, []*domain.User{}, result)
+		})
+	})
+	t.Run("returns add error", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			expectedErr := apperror.Validation("name", "name must not be empty")
+			addUsersStub := mocka.Function(t, &addUsers, nil, expectedErr)
+			defer addUsersStub.Restore()
+			params := newResolveParams(tx, updateUsersMutation, newField("", "id")).addArrayArg("add", adds)
+
+			result, err := updateUsersFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Same(t, expectedErr, err)
+		})
+	})
+	t.Run("returns update error", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			expectedErr := apperror.Validation("role", "role must be admin or user")
+			updateUsersStub := mocka.Function(t, &updateUsers, nil, expectedErr)
+			defer updateUsersStub.Restore()
+			params := newResolveParams(tx, updateUsersMutation, newField("", "id")).addArrayArg("update", updates)
+
+			result, err := updateUsersFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Same(t, expectedErr, err)
+		})
+	})
+	t.Run("requires admin", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, updateUsersMutation, newField("", "id")).
+				addArrayArg("add", adds).setPermissions(domain.NewPermissions(false, nil))
+
+			result, err := updateUsersFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.Forbidden("admin role required"), err)
+		})
+	})
+}
+
+func Test_grantAccountPermissionsFields_Resolve(t *testing.T) {
+	grants := []map[string]interface{}{{"userId": 42, "accountId": 3, "permission": table.PermissionRead}}
+	t.Run("returns granted permissions", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			permissions := []*table.AccountPermission{{ID: 7}}
+			grantStub := mocka.Function(t, &grantAccountPermissions, []int64{7}, nil)
+			defer grantStub.Restore()
+			getPermissionsStub := mocka.Function(t, &getAccountPermissionsByIDs, permissions)
+			defer getPermissionsStub.Restore()
+			params := newResolveParams(tx, grantAccountPermissionsMutation, newField("", "id")).addArrayArg("grants", grants)
+
+			result, err := grantAccountPermissionsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, permissions, result)
+			assert.Equal(t, []interface{}{tx, grants, "somebody"}, grantStub.GetFirstCall().Arguments())
+			assert.Equal(t, []interface{}{tx, []int64{7}}, getPermissionsStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("returns error", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			expectedErr := apperror.NotFound("account", 3)
+			grantStub := mocka.Function(t, &grantAccountPermissions, nil, expectedErr)
+			defer grantStub.Restore()
+			params := newResolveParams(tx, grantAccountPermissionsMutation, newField("", "id")).addArrayArg("grants", grants)
+
+			result, err := grantAccountPermissionsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Same(t, expectedErr, err)
+		})
+	})
+	t.Run("requires admin", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, grantAccountPermissionsMutation, newField("", "id")).
+				addArrayArg("grants", grants).setPermissions(domain.NewPermissions(false, nil))
+
+			result, err := grantAccountPermissionsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.Forbidden("admin role required"), err)
+		})
+	})
+}
+
+func Test_revokeAccountPermissionsFields_Resolve(t *testing.T) {
+	t.Run("deletes permissions", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			revokeStub := mocka.Function(t, &revokeAccountPermissions, int64(2), nil)
+			defer revokeStub.Restore()
+			params := newResolveParams(tx, revokeAccountPermissionsMutation).addArg("ids", []interface{}{1, 2})
+
+			result, err := revokeAccountPermissionsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), result)
+			assert.Equal(t, []interface{}{tx, []int64{1, 2}, "somebody"}, revokeStub.GetFirstCall().Arguments())
+		})
+	})
+	t.Run("requires admin", func(t *testing.T) {
+		dbtest.TestInTx(t, func(mock sqlmock.Sqlmock, tx *database.Tx) {
+			params := newResolveParams(tx, revokeAccountPermissionsMutation).
+				addArg("ids", []interface{}{1}).setPermissions(domain.NewPermissions(false, nil))
+
+			result, err := revokeAccountPermissionsFields.Resolve(params.ResolveParams)
+
+			assert.Nil(t, result)
+			assert.Equal(t, apperror.Forbidden("admin role required"), err)
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"log"
+	"sync"
+
+	"github.com/jonestimd/financesd/internal/domain"
+)
+
+// number of events that can be queued for a subscriber
+const subscriberBufferSize = 16
+
+// Broker publishes change events to subscribers. Subscribers that aren't keeping up are dropped.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan *domain.ChangeEvent]struct{}
+}
+
+// NewBroker creates a broker without any subscribers.
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan *domain.ChangeEvent]struct{})}
+}
+
+// Subscribe returns a channel that receives the published events and a function that ends the subscription and closes
+// the channel. The channel is also closed if the subscriber's queue fills up.
+func (b *Broker) Subscribe() (<-chan *domain.ChangeEvent, func()) {
+	events := make(chan *domain.ChangeEvent, subscriberBufferSize)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[events] = struct{}{}
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subscribers[events]; ok {
+				delete(b.subscribers, events)
+				close(events)
+			}
+		})
+	}
+}
+
+// Publish sends the event to the subscribers without waiting. A subscriber with a full queue is removed and its
+// channel is closed, so that it can't miss events without knowing it.
+func (b *Broker) Publish(event *domain.ChangeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			log.Printf("Subscriber queue full, ending subscription at change event %s", event.ChangeSetID)
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
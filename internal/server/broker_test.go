@@ -0,0 +1,54 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Broker_Publish(t *testing.T) {
+	t.Run("sends event to subscribers", func(t *testing.T) {
+		broker := NewBroker()
+		events1, cancel1 := broker.Subscribe()
+		defer cancel1()
+		events2, cancel2 := broker.Subscribe()
+		defer cancel2()
+		event := &domain.ChangeEvent{ChangeSetID: "123:4"}
+
+		broker.Publish(event)
+
+		assert.Same(t, event, <-events1)
+		assert.Same(t, event, <-events2)
+	})
+	t.Run("ends subscription for full queue", func(t *testing.T) {
+		broker := NewBroker()
+		events, cancel := broker.Subscribe()
+		defer cancel()
+
+		for i := 0; i <= subscriberBufferSize; i++ {
+			broker.Publish(&domain.ChangeEvent{})
+		}
+
+		assert.Len(t, events, subscriberBufferSize)
+		assert.Len(t, broker.subscribers, 0)
+		count := 0
+		for range events {
+			count++
+		}
+		assert.Equal(t, subscriberBufferSize, count)
+	})
+}
+
+func Test_Broker_Subscribe_cancel(t *testing.T) {
+	broker := NewBroker()
+	events, cancel := broker.Subscribe()
+
+	cancel()
+	cancel()
+	broker.Publish(&domain.ChangeEvent{})
+
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.Len(t, broker.subscribers, 0)
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/jonestimd/financesd/internal/auth"
+	"github.com/jonestimd/financesd/internal/database"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/jonestimd/financesd/internal/schema"
+)
+
+// SubscriptionProtocol is the WebSocket sub-protocol used for GraphQL subscriptions (subscriptions-transport-ws).
+const SubscriptionProtocol = "graphql-ws"
+
+// message types of the graphql-ws protocol
+const (
+	msgConnectionInit      = "connection_init"
+	msgConnectionAck       = "connection_ack"
+	msgConnectionError     = "connection_error"
+	msgConnectionTerminate = "connection_terminate"
+	msgKeepAlive           = "ka"
+	msgStart               = "start"
+	msgStop                = "stop"
+	msgData                = "data"
+	msgError               = "error"
+	msgComplete            = "complete"
+)
+
+var keepAliveInterval = 30 * time.Second
+
+var getPermissions = domain.GetPermissions
+
+var upgrader = websocket.Upgrader{Subprotocols: []string{SubscriptionProtocol}}
+
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type startPayload struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// SubscriptionHandler runs GraphQL subscriptions over WebSocket connections. The result of a subscription is sent each
+// time a change event is published, unless none of the changes match the subscription.
+type SubscriptionHandler struct {
+	db     *sql.DB
+	schema *graphql.Schema
+	broker *Broker
+}
+
+// NewSubscriptionHandler creates a handler for subscriptions to the events published by the broker. Requests must be
+// authenticated before they are passed to the handler.
+func NewSubscriptionHandler(db *sql.DB, schema *graphql.Schema, broker *Broker) *SubscriptionHandler {
+	return &SubscriptionHandler{db: db, schema: schema, broker: broker}
+}
+
+func (h *SubscriptionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user := auth.User(r.Context())
+	if user == "" {
+		http.Error(w, "Unknown user", http.StatusBadRequest)
+		return
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already sent an error response
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	conn := &subscriptionConn{SubscriptionHandler: h, ws: ws, user: user, subscriptions: make(map[string]*subscription), done: make(chan struct{})}
+	if ws.Subprotocol() != SubscriptionProtocol {
+		conn.close(websocket.CloseProtocolError, "unsupported sub-protocol")
+		return
+	}
+	conn.run(r.Context())
+}
+
+// subscriptionConn contains the state of a WebSocket connection.
+type subscriptionConn struct {
+	*SubscriptionHandler
+	ws            *websocket.Conn
+	user          string
+	writeLock     sync.Mutex
+	lock          sync.Mutex
+	subscriptions map[string]*subscription
+	done          chan struct{}
+}
+
+// subscription contains the state of a started subscription.
+type subscription struct {
+	cancel func()
+}
+
+func (c *subscriptionConn) send(id string, msgType string, payload interface{}) {
+	msg := message{ID: id, Type: msgType}
+	if payload != nil {
+		msg.Payload, _ = json.Marshal(payload)
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if err := c.ws.WriteJSON(msg); err != nil {
+		log.Printf("Error sending subscription message to %s: %v", c.user, err)
+	}
+}
+
+func (c *subscriptionConn) sendError(id string, err error) {
+	c.send(id, msgError, []gqlerrors.FormattedError{gqlerrors.FormatError(err)})
+}
+
+// close ends the subscriptions and closes the connection.
+func (c *subscriptionConn) close(code int, text string) {
+	c.lock.Lock()
+	for _, sub := range c.subscriptions {
+		sub.cancel()
+	}
+	c.subscriptions = make(map[string]*subscription)
+	c.lock.Unlock()
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	c.ws.Close()
+}
+
+// run reads messages from the client until the connection is closed.
+func (c *subscriptionConn) run(ctx context.Context) {
+	defer close(c.done)
+	initialized := false
+	for {
+		var msg message
+		if err := c.ws.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("Error reading subscription message from %s: %v", c.user, err)
+			}
+			c.close(websocket.CloseNormalClosure, "")
+			return
+		}
+		switch msg.Type {
+		case msgConnectionInit:
+			if !initialized {
+				initialized = true
+				c.send("", msgConnectionAck, nil)
+				go c.keepAlive()
+			}
+		case msgStart:
+			if !initialized {
+				c.send("", msgConnectionError, map[string]string{"message": "connection not initialized"})
+				c.close(websocket.ClosePolicyViolation, "connection not initialized")
+				return
+			}
+			c.start(ctx, msg)
+		case msgStop:
+			c.stop(msg.ID)
+		case msgConnectionTerminate:
+			c.close(websocket.CloseNormalClosure, "")
+			return
+		default:
+			c.sendError(msg.ID, fmt.Errorf("unknown message type: %s", msg.Type))
+		}
+	}
+}
+
+// keepAlive sends keep alive messages until the connection is closed.
+func (c *subscriptionConn) keepAlive() {
+	c.send("", msgKeepAlive, nil)
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.send("", msgKeepAlive, nil)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// loadPermissions returns the current permissions of the user.
+func (c *subscriptionConn) loadPermissions(ctx context.Context) (permissions *domain.Permissions, err error) {
+	sqlTx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer sqlTx.Rollback()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return getPermissions(database.NewTx(sqlTx), c.user), nil
+}
+
+// start validates the subscription by running it without an event and then runs it for each published event.
+func (c *subscriptionConn) start(ctx context.Context, msg message) {
+	var payload startPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		c.sendError(msg.ID, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+	if msg.ID == "" {
+		c.sendError(msg.ID, fmt.Errorf("missing subscription ID"))
+		return
+	}
+	if !isSubscription(payload.Query, payload.OperationName) {
+		c.sendError(msg.ID, fmt.Errorf("only subscriptions are supported"))
+		return
+	}
+	permissions, err := c.loadPermissions(ctx)
+	if err != nil {
+		log.Printf("Error loading permissions for %s: %v", c.user, err)
+		c.sendError(msg.ID, fmt.Errorf("error loading permissions"))
+		return
+	}
+	ctx = context.WithValue(ctx, schema.UserKey, c.user)
+	params := graphql.Params{
+		Schema:         *c.schema,
+		RequestString:  payload.Query,
+		VariableValues: payload.Variables,
+		OperationName:  payload.OperationName,
+		Context:        context.WithValue(ctx, schema.PermissionsKey, permissions),
+	}
+	if result := graphql.Do(params); result.HasErrors() {
+		c.send(msg.ID, msgError, result.Errors)
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.subscriptions[msg.ID]; ok {
+		c.sendError(msg.ID, fmt.Errorf("subscription already started: %s", msg.ID))
+		return
+	}
+	events, cancel := c.broker.Subscribe()
+	sub := &subscription{cancel: cancel}
+	c.subscriptions[msg.ID] = sub
+	log.Printf("Subscription %s started by %s", msg.ID, c.user)
+	go func() {
+		for event := range events {
+			// the permissions may have changed since the subscription was started
+			permissions, err := c.loadPermissions(ctx)
+			if err != nil {
+				log.Printf("Error loading permissions for %s: %v", c.user, err)
+				c.end(msg.ID, sub, fmt.Errorf("error loading permissions"))
+				return
+			}
+			params.Context = context.WithValue(ctx, schema.PermissionsKey, permissions)
+			params.RootObject = map[string]interface{}{schema.ChangeEventKey: event}
+			if result := graphql.Do(params); result.HasErrors() || hasData(result) {
+				c.send(msg.ID, msgData, result)
+			}
+		}
+		// the broker closes the channel if the subscription isn't keeping up
+		c.end(msg.ID, sub, fmt.Errorf("change events were dropped, the subscription must be restarted"))
+	}()
+}
+
+// end removes a subscription that hasn't been stopped by the client and sends the error to the client.
+func (c *subscriptionConn) end(id string, sub *subscription, err error) {
+	c.lock.Lock()
+	ok := c.subscriptions[id] == sub
+	if ok {
+		delete(c.subscriptions, id)
+	}
+	c.lock.Unlock()
+	if ok {
+		sub.cancel()
+		c.sendError(id, err)
+	}
+}
+
+// stop ends a subscription.
+func (c *subscriptionConn) stop(id string) {
+	c.lock.Lock()
+	sub, ok := c.subscriptions[id]
+	delete(c.subscriptions, id)
+	c.lock.Unlock()
+	if ok {
+		sub.cancel()
+		c.send(id, msgComplete, nil)
+	}
+}
+
+// isSubscription returns false if the operation of the request is a query or mutation. Invalid requests are left for
+// the executor to report.
+func isSubscription(query string, operationName string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return true
+	}
+	for _, node := range doc.Definitions {
+		if operation, ok := node.(*ast.OperationDefinition); ok {
+			if operationName == "" || operation.Name != nil && operation.Name.Value == operationName {
+				return operation.Operation == ast.OperationTypeSubscription
+			}
+		}
+	}
+	return true
+}
+
+// hasData returns true if any of the subscription fields have a value.
+func hasData(result *graphql.Result) bool {
+	if data, ok := result.Data.(map[string]interface{}); ok {
+		for _, value := range data {
+			if value != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
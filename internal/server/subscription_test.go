@@ -0,0 +1,258 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MonsantoCo/mocka/v2"
+	"github.com/gorilla/websocket"
+	"github.com/jonestimd/financesd/internal/auth"
+	"github.com/jonestimd/financesd/internal/database/table"
+	"github.com/jonestimd/financesd/internal/domain"
+	"github.com/jonestimd/financesd/internal/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+const referenceDataSubscription = "subscription { referenceDataChanged { changeSetId changes { entity id action } } }"
+
+type subscriptionTest struct {
+	t      *testing.T
+	mock   sqlmock.Sqlmock
+	broker *Broker
+	server *httptest.Server
+	ws     *websocket.Conn
+}
+
+func newSubscriptionTest(t *testing.T, user string, protocols ...string) *subscriptionTest {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	graphqlSchema, err := schema.New()
+	assert.Nil(t, err)
+	broker := NewBroker()
+	handler := NewSubscriptionHandler(db, &graphqlSchema, broker)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+	}))
+	st := &subscriptionTest{t: t, mock: mock, broker: broker, server: server}
+	if user != "" {
+		dialer := websocket.Dialer{Subprotocols: protocols}
+		st.ws, _, err = dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		assert.Nil(t, err)
+	}
+	return st
+}
+
+func (st *subscriptionTest) close() {
+	if st.ws != nil {
+		st.ws.Close()
+	}
+	st.server.Close()
+}
+
+func (st *subscriptionTest) send(id string, msgType string, payload interface{}) {
+	msg := message{ID: id, Type: msgType}
+	if payload != nil {
+		msg.Payload, _ = json.Marshal(payload)
+	}
+	assert.Nil(st.t, st.ws.WriteJSON(msg))
+}
+
+func (st *subscriptionTest) read() (msg message, payload interface{}) {
+	assert.Nil(st.t, st.ws.ReadJSON(&msg))
+	if msg.Payload != nil {
+		json.Unmarshal(msg.Payload, &payload)
+	}
+	return
+}
+
+func (st *subscriptionTest) init() {
+	st.send("", msgConnectionInit, nil)
+	msg, _ := st.read()
+	assert.Equal(st.t, msgConnectionAck, msg.Type)
+	msg, _ = st.read()
+	assert.Equal(st.t, msgKeepAlive, msg.Type)
+}
+
+// start expects a transaction for loading the permissions.
+func (st *subscriptionTest) start(id string, query string) {
+	st.expectPermissions()
+	st.send(id, msgStart, map[string]interface{}{"query": query})
+}
+
+// expectPermissions expects a transaction for reloading the permissions when an event is published.
+func (st *subscriptionTest) expectPermissions() {
+	st.mock.ExpectBegin()
+	st.mock.ExpectRollback()
+}
+
+func (st *subscriptionTest) subscriberCount() int {
+	st.broker.mu.Lock()
+	defer st.broker.mu.Unlock()
+	return len(st.broker.subscribers)
+}
+
+func Test_SubscriptionHandler_requiresUser(t *testing.T) {
+	st := newSubscriptionTest(t, "")
+	defer st.close()
+
+	response, err := http.Get(st.server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
+
+func Test_SubscriptionHandler_requiresProtocol(t *testing.T) {
+	st := newSubscriptionTest(t, "somebody")
+	defer st.close()
+
+	_, _, err := st.ws.ReadMessage()
+
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseProtocolError))
+}
+
+func Test_SubscriptionHandler_requiresInit(t *testing.T) {
+	st := newSubscriptionTest(t, "somebody", SubscriptionProtocol)
+	defer st.close()
+
+	st.send("1", msgStart, map[string]interface{}{"query": referenceDataSubscription})
+
+	msg, payload := st.read()
+	assert.Equal(t, msgConnectionError, msg.Type)
+	assert.Equal(t, map[string]interface{}{"message": "connection not initialized"}, payload)
+	_, _, err := st.ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation))
+}
+
+func Test_SubscriptionHandler_sendsMatchingEvents(t *testing.T) {
+	getPermissionsStub := mocka.Function(t, &getPermissions, domain.NewPermissions(true, nil))
+	defer getPermissionsStub.Restore()
+	st := newSubscriptionTest(t, "somebody", SubscriptionProtocol)
+	defer st.close()
+	st.init()
+
+	st.start("1", referenceDataSubscription)
+	assert.Eventually(t, func() bool { return st.subscriberCount() == 1 }, time.Second, time.Millisecond)
+	st.expectPermissions()
+	st.expectPermissions()
+	st.broker.Publish(&domain.ChangeEvent{ChangeSetID: "123:4", Changes: []*domain.RowChange{{Entity: "transaction", ID: 42}}})
+	st.broker.Publish(&domain.ChangeEvent{ChangeSetID: "123:5", Changes: []*domain.RowChange{{Entity: "payee", ID: 7, Action: table.HistoryInsert}}})
+
+	msg, payload := st.read()
+	assert.Equal(t, message{ID: "1", Type: msgData, Payload: msg.Payload}, msg)
+	assert.Equal(t, map[string]interface{}{"data": map[string]interface{}{
+		"referenceDataChanged": map[string]interface{}{
+			"changeSetId": "123:5",
+			"changes":     []interface{}{map[string]interface{}{"entity": "payee", "id": float64(7), "action": "insert"}},
+		},
+	}}, payload)
+	assert.Equal(t, 3, getPermissionsStub.CallCount())
+	assert.Equal(t, "somebody", getPermissionsStub.GetCall(0).Arguments()[1])
+	st.send("1", msgStop, nil)
+	msg, _ = st.read()
+	assert.Equal(t, message{ID: "1", Type: msgComplete}, msg)
+	assert.Equal(t, 0, st.subscriberCount())
+	assert.Nil(t, st.mock.ExpectationsWereMet())
+}
+
+func Test_SubscriptionHandler_checksPermissionsForEvents(t *testing.T) {
+	getPermissionsStub := mocka.Function(t, &getPermissions, domain.NewPermissions(false, map[int64]string{1: table.PermissionRead}))
+	defer getPermissionsStub.Restore()
+	getPermissionsStub.OnSecondCall().Return(domain.NewPermissions(false, map[int64]string{2: table.PermissionRead}))
+	st := newSubscriptionTest(t, "somebody", SubscriptionProtocol)
+	defer st.close()
+	st.init()
+
+	st.start("1", "subscription { transactionsChanged(accountId: 1) { changeSetId } }")
+	assert.Eventually(t, func() bool { return st.subscriberCount() == 1 }, time.Second, time.Millisecond)
+	st.expectPermissions()
+	st.broker.Publish(&domain.ChangeEvent{ChangeSetID: "123:4", Changes: []*domain.RowChange{{Entity: "transaction", ID: 42}}})
+
+	msg, payload := st.read()
+	assert.Equal(t, message{ID: "1", Type: msgData, Payload: msg.Payload}, msg)
+	errors := payload.(map[string]interface{})["errors"].([]interface{})
+	assert.Equal(t, "account not readable (1)", errors[0].(map[string]interface{})["message"])
+	assert.Equal(t, 2, getPermissionsStub.CallCount())
+	assert.Nil(t, st.mock.ExpectationsWereMet())
+}
+
+func Test_SubscriptionHandler_endsSubscriptionDroppedByBroker(t *testing.T) {
+	getPermissionsStub := mocka.Function(t, &getPermissions, domain.NewPermissions(true, nil))
+	defer getPermissionsStub.Restore()
+	st := newSubscriptionTest(t, "somebody", SubscriptionProtocol)
+	defer st.close()
+	st.init()
+
+	st.start("1", referenceDataSubscription)
+	assert.Eventually(t, func() bool { return st.subscriberCount() == 1 }, time.Second, time.Millisecond)
+	st.broker.mu.Lock()
+	for events := range st.broker.subscribers {
+		delete(st.broker.subscribers, events)
+		close(events)
+	}
+	st.broker.mu.Unlock()
+
+	msg, payload := st.read()
+	assert.Equal(t, msgError, msg.Type)
+	assert.Equal(t, "1", msg.ID)
+	assert.Equal(t, "change events were dropped, the subscription must be restarted",
+		payload.([]interface{})[0].(map[string]interface{})["message"])
+	st.send("1", msgStop, nil)
+	st.send("", msgConnectionTerminate, nil)
+	_, _, err := st.ws.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+}
+
+func Test_SubscriptionHandler_rejectsSubscription(t *testing.T) {
+	readable := domain.NewPermissions(false, map[int64]string{2: table.PermissionRead})
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"rejects query", "{ accounts { id } }", "only subscriptions are supported"},
+		{"rejects unknown field", "subscription { unknown }", `Cannot query field "unknown" on type "Subscriptions".`},
+		{"rejects unreadable account", "subscription { transactionsChanged(accountId: 1) { changeSetId } }", "account not readable (1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			getPermissionsStub := mocka.Function(t, &getPermissions, readable)
+			defer getPermissionsStub.Restore()
+			st := newSubscriptionTest(t, "somebody", SubscriptionProtocol)
+			defer st.close()
+			st.init()
+
+			st.start("1", test.query)
+
+			msg, payload := st.read()
+			assert.Equal(t, msgError, msg.Type)
+			assert.Equal(t, "1", msg.ID)
+			assert.Equal(t, test.message, payload.([]interface{})[0].(map[string]interface{})["message"])
+			assert.Equal(t, 0, st.subscriberCount())
+		})
+	}
+}
+
+func Test_isSubscription(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		result        bool
+	}{
+		{"returns true for subscription", referenceDataSubscription, "", true},
+		{"returns false for query", "{ accounts { id } }", "", false},
+		{"returns false for mutation", "mutation { undo(changeSetId: \"1\") { id } }", "", false},
+		{"uses operation name", "query a { accounts { id } } subscription b { accountsChanged { user } }", "b", true},
+		{"returns true for parse error", "subscription {", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.result, isSubscription(test.query, test.operationName))
+		})
+	}
+}
@@ -50,7 +50,7 @@ func MockRows(columns ...string) *sqlmock.Rows {
 	return sqlmock.NewRows(columns)
 }
 
-// UpdateArgs creates an array of expected arguments for running an update query.
-func UpdateArgs(tx *sql.Tx, query string, args ...interface{}) []interface{} {
+// UpdateArgs creates an array of expected arguments for running an update query in the transaction.
+func UpdateArgs(tx interface{}, query string, args ...interface{}) []interface{} {
 	return []interface{}{tx, query, args}
 }
@@ -1,3 +1,4 @@
+-- Databases that already have the asset_exchange column can skip this migration with: financesd migrate baseline 1
 alter table transaction_category add column asset_exchange char(1) not null default 'N';
 
 update transaction_category set asset_exchange = 'Y'
@@ -0,0 +1,17 @@
+create table import_item (
+    id @autoID(),
+    account_id bigint not null,
+    date date not null,
+    reference_number varchar(50),
+    payee_name varchar(200),
+    memo varchar(2000),
+    amount decimal(19,2) not null,
+    transaction_id bigint,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint import_item_account_fk foreign key (account_id) references account (id),
+    constraint import_item_transaction_fk foreign key (transaction_id) references transaction (id) on delete set null
+);
+
+create index import_item_pending_ix on import_item (account_id, transaction_id);
@@ -0,0 +1,21 @@
+create table exchange_rate (
+    id @autoID(),
+    from_currency_id bigint not null,
+    to_currency_id bigint not null,
+    date date not null,
+    rate decimal(19,10) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint exchange_rate_from_fk foreign key (from_currency_id) references currency (asset_id),
+    constraint exchange_rate_to_fk foreign key (to_currency_id) references currency (asset_id),
+    constraint exchange_rate_ak unique (from_currency_id, to_currency_id, date)
+);
+
+create table setting (
+    name varchar(50) not null primary key,
+    value varchar(200),
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0
+);
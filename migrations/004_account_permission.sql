@@ -0,0 +1,24 @@
+create table app_user (
+    id @autoID(),
+    name varchar(60) not null,
+    role varchar(10) not null default 'user',
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint app_user_ak unique (name),
+    constraint app_user_role_ck check (role in ('admin', 'user'))
+);
+
+create table account_permission (
+    id @autoID(),
+    user_id bigint not null,
+    account_id bigint not null,
+    permission varchar(10) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint account_permission_ak unique (user_id, account_id),
+    constraint account_permission_user_fk foreign key (user_id) references app_user (id) on delete cascade,
+    constraint account_permission_account_fk foreign key (account_id) references account (id) on delete cascade,
+    constraint account_permission_ck check (permission in ('read', 'write', 'none'))
+);
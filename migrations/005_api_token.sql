@@ -0,0 +1,13 @@
+create table api_token (
+    id @autoID(),
+    user_id bigint not null,
+    name varchar(100) not null,
+    token_hash char(64) not null,
+    expiry_date date,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint api_token_hash_ak unique (token_hash),
+    constraint api_token_name_ak unique (user_id, name),
+    constraint api_token_user_fk foreign key (user_id) references app_user (id) on delete cascade
+);
@@ -0,0 +1,14 @@
+create table change_history (
+    id @autoID(),
+    entity varchar(50) not null,
+    entity_key varchar(100) not null,
+    action varchar(10) not null,
+    version int,
+    before_image json,
+    after_image json,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    constraint change_history_action_ck check (action in ('insert', 'update', 'delete'))
+);
+
+create index change_history_entity_ix on change_history (entity, entity_key, id);
@@ -0,0 +1,8 @@
+create table change_set (
+    id varchar(40) not null primary key,
+    change_user varchar(60) not null,
+    change_date timestamp not null
+);
+
+alter table change_history add column change_set_id varchar(40);
+alter table change_history add constraint change_history_set_fk foreign key (change_set_id) references change_set (id);
@@ -0,0 +1,12 @@
+alter table transaction add column trash_date timestamp null;
+
+create index transaction_trash_ix on transaction (trash_date);
+
+-- The view can't be altered portably, so it is recreated from the definition in schema.sql at this version. A later
+-- migration that changes the view must recreate it the same way, and the definition here must not be changed.
+drop view tx;
+
+create view tx as
+select id, account_id, date, reference_number, payee_id, security_id, memo, cleared
+from transaction
+where trash_date is null;
@@ -0,0 +1,17 @@
+create table attachment (
+    id @autoID(),
+    transaction_id bigint,
+    account_id bigint,
+    file_name varchar(255) not null,
+    content_type varchar(100) not null,
+    size bigint not null,
+    hash char(64) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint attachment_transaction_fk foreign key (transaction_id) references transaction (id),
+    constraint attachment_account_fk foreign key (account_id) references account (id),
+    constraint attachment_owner_ck check ((transaction_id is null) <> (account_id is null))
+);
+
+create index attachment_hash_ix on attachment (hash);
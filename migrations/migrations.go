@@ -0,0 +1,10 @@
+// Package migrations contains the database schema changes. The file name of each migration is the version
+// followed by an underscore and a description, e.g. 001_asset_exchange.sql.
+package migrations
+
+import "embed"
+
+// Files contains the migration scripts.
+//
+//go:embed *.sql
+var Files embed.FS
@@ -0,0 +1,288 @@
+-- The schema for a new database, including all of the numbered migrations.
+-- Written in MySQL syntax using the same macros as the queries, e.g. autoID for a generated primary key.
+
+create table company (
+    id @autoID(),
+    name varchar(200) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint company_ak unique (name)
+);
+
+create table asset (
+    id @autoID(),
+    name varchar(200) not null,
+    type varchar(20) not null,
+    scale int not null default 2,
+    symbol varchar(20),
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint asset_ak unique (name)
+);
+
+create table currency (
+    asset_id bigint not null primary key,
+    code varchar(3) not null,
+    constraint currency_asset_fk foreign key (asset_id) references asset (id),
+    constraint currency_ak unique (code)
+);
+
+create table security (
+    asset_id bigint not null primary key,
+    type varchar(50) not null,
+    constraint security_asset_fk foreign key (asset_id) references asset (id)
+);
+
+create table stock_split (
+    id @autoID(),
+    security_id bigint not null,
+    date date not null,
+    shares_in decimal(19,6) not null,
+    shares_out decimal(19,6) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint stock_split_security_fk foreign key (security_id) references security (asset_id),
+    constraint stock_split_ak unique (security_id, date)
+);
+
+create table account (
+    id @autoID(),
+    company_id bigint,
+    name varchar(200) not null,
+    description varchar(200),
+    account_no varchar(50),
+    type varchar(20) not null,
+    closed char(1) not null default 'N',
+    currency_id bigint not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint account_company_fk foreign key (company_id) references company (id),
+    constraint account_currency_fk foreign key (currency_id) references currency (asset_id),
+    constraint account_ak unique (company_id, name)
+);
+
+create table payee (
+    id @autoID(),
+    name varchar(200) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint payee_ak unique (name)
+);
+
+create table transaction_category (
+    id @autoID(),
+    code varchar(50) not null,
+    description varchar(200),
+    amount_type varchar(20) not null default 'DEBIT_DEPOSIT',
+    parent_id bigint,
+    security char(1) not null default 'N',
+    income char(1) not null default 'N',
+    asset_exchange char(1) not null default 'N',
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint transaction_category_parent_fk foreign key (parent_id) references transaction_category (id),
+    constraint transaction_category_ak unique (parent_id, code),
+    constraint transaction_category_amount_type_ck check (amount_type in ('DEBIT_DEPOSIT', 'ASSET_VALUE'))
+);
+
+create table transaction_group (
+    id @autoID(),
+    name varchar(200) not null,
+    description varchar(200),
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint transaction_group_ak unique (name)
+);
+
+create table transaction (
+    id @autoID(),
+    account_id bigint not null,
+    date date not null,
+    reference_number varchar(50),
+    payee_id bigint,
+    security_id bigint,
+    memo varchar(2000),
+    cleared char(1),
+    trash_date timestamp null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint transaction_account_fk foreign key (account_id) references account (id),
+    constraint transaction_payee_fk foreign key (payee_id) references payee (id),
+    constraint transaction_security_fk foreign key (security_id) references security (asset_id)
+);
+
+create index transaction_trash_ix on transaction (trash_date);
+
+create table transaction_detail (
+    id @autoID(),
+    transaction_id bigint not null,
+    transaction_category_id bigint,
+    transaction_group_id bigint,
+    memo varchar(2000),
+    amount decimal(19,2) not null,
+    asset_quantity decimal(19,6),
+    exchange_asset_id bigint,
+    related_detail_id bigint,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint transaction_detail_transaction_fk foreign key (transaction_id) references transaction (id),
+    constraint transaction_detail_category_fk foreign key (transaction_category_id) references transaction_category (id),
+    constraint transaction_detail_group_fk foreign key (transaction_group_id) references transaction_group (id),
+    constraint transaction_detail_asset_fk foreign key (exchange_asset_id) references asset (id),
+    -- the details of a transfer reference each other, so deleting one side clears the other side's reference
+    constraint transaction_detail_related_fk foreign key (related_detail_id) references transaction_detail (id) on delete set null
+);
+
+create table security_lot (
+    id @autoID(),
+    purchase_tx_detail_id bigint not null,
+    sale_tx_detail_id bigint not null,
+    purchase_shares decimal(19,6) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint security_lot_purchase_fk foreign key (purchase_tx_detail_id) references transaction_detail (id) on delete cascade,
+    constraint security_lot_sale_fk foreign key (sale_tx_detail_id) references transaction_detail (id) on delete cascade,
+    constraint security_lot_ak unique (purchase_tx_detail_id, sale_tx_detail_id)
+);
+
+-- transactions that are not in the trash. Changes to the view must be made by a migration that drops and recreates it
+-- (see 008_transaction_trash.sql).
+create view tx as
+select id, account_id, date, reference_number, payee_id, security_id, memo, cleared
+from transaction
+where trash_date is null;
+
+create view tx_detail as
+select id, transaction_id tx_id, transaction_category_id, transaction_group_id, memo, amount, asset_quantity,
+    exchange_asset_id, related_detail_id
+from transaction_detail;
+
+create table import_item (
+    id @autoID(),
+    account_id bigint not null,
+    date date not null,
+    reference_number varchar(50),
+    payee_name varchar(200),
+    memo varchar(2000),
+    amount decimal(19,2) not null,
+    transaction_id bigint,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint import_item_account_fk foreign key (account_id) references account (id),
+    constraint import_item_transaction_fk foreign key (transaction_id) references transaction (id) on delete set null
+);
+
+create index import_item_pending_ix on import_item (account_id, transaction_id);
+
+create table exchange_rate (
+    id @autoID(),
+    from_currency_id bigint not null,
+    to_currency_id bigint not null,
+    date date not null,
+    rate decimal(19,10) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint exchange_rate_from_fk foreign key (from_currency_id) references currency (asset_id),
+    constraint exchange_rate_to_fk foreign key (to_currency_id) references currency (asset_id),
+    constraint exchange_rate_ak unique (from_currency_id, to_currency_id, date)
+);
+
+create table setting (
+    name varchar(50) not null primary key,
+    value varchar(200),
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0
+);
+
+create table app_user (
+    id @autoID(),
+    name varchar(60) not null,
+    role varchar(10) not null default 'user',
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint app_user_ak unique (name),
+    constraint app_user_role_ck check (role in ('admin', 'user'))
+);
+
+create table account_permission (
+    id @autoID(),
+    user_id bigint not null,
+    account_id bigint not null,
+    permission varchar(10) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint account_permission_ak unique (user_id, account_id),
+    constraint account_permission_user_fk foreign key (user_id) references app_user (id) on delete cascade,
+    constraint account_permission_account_fk foreign key (account_id) references account (id) on delete cascade,
+    constraint account_permission_ck check (permission in ('read', 'write', 'none'))
+);
+
+create table api_token (
+    id @autoID(),
+    user_id bigint not null,
+    name varchar(100) not null,
+    token_hash char(64) not null,
+    expiry_date date,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint api_token_hash_ak unique (token_hash),
+    constraint api_token_name_ak unique (user_id, name),
+    constraint api_token_user_fk foreign key (user_id) references app_user (id) on delete cascade
+);
+
+create table change_set (
+    id varchar(40) not null primary key,
+    change_user varchar(60) not null,
+    change_date timestamp not null
+);
+
+create table change_history (
+    id @autoID(),
+    entity varchar(50) not null,
+    entity_key varchar(100) not null,
+    action varchar(10) not null,
+    version int,
+    before_image json,
+    after_image json,
+    change_set_id varchar(40),
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    constraint change_history_set_fk foreign key (change_set_id) references change_set (id),
+    constraint change_history_action_ck check (action in ('insert', 'update', 'delete'))
+);
+
+create index change_history_entity_ix on change_history (entity, entity_key, id);
+
+create table attachment (
+    id @autoID(),
+    transaction_id bigint,
+    account_id bigint,
+    file_name varchar(255) not null,
+    content_type varchar(100) not null,
+    size bigint not null,
+    hash char(64) not null,
+    change_user varchar(60) not null,
+    change_date timestamp not null,
+    version int not null default 0,
+    constraint attachment_transaction_fk foreign key (transaction_id) references transaction (id),
+    constraint attachment_account_fk foreign key (account_id) references account (id),
+    constraint attachment_owner_ck check ((transaction_id is null) <> (account_id is null))
+);
+
+create index attachment_hash_ix on attachment (hash);
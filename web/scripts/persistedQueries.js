@@ -0,0 +1,16 @@
+// Writes the manifest of persisted queries that is loaded by the server, i.e. the queries of the stores keyed by the
+// hex encoded SHA-256 hash of their text.
+const crypto = require('crypto');
+const fs = require('fs');
+const path = require('path');
+
+require('@babel/register')({extensions: ['.ts']});
+const queries = require('../src/lib/store/queries');
+
+const manifest = {};
+for (const query of Object.values(queries)) {
+    manifest[crypto.createHash('sha256').update(query).digest('hex')] = query;
+}
+const file = path.resolve(__dirname, '..', 'dist', 'persisted-queries.json');
+fs.writeFileSync(file, JSON.stringify(manifest, null, 2));
+console.log(`Wrote ${Object.keys(manifest).length} queries to ${file}`);
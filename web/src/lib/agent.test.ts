@@ -1,4 +1,5 @@
 import * as agent from './agent';
+import {TextEncoder} from 'util';
 
 const mockFetch = fetch as jest.MockedFunction<typeof fetch>;
 
@@ -54,5 +55,57 @@ describe('agent', () => {
             expect(result).toBe(responseBody);
             expect(fetch).toBeCalledWith('/finances/api/v1/graphql', {method: 'POST', body: JSON.stringify({query, variables})});
         });
+        describe('with persisted query', () => {
+            const hash = '0001ff';
+            const extensions = {persistedQuery: {version: 1, sha256Hash: hash}};
+            const digest = jest.fn();
+            beforeAll(() => {
+                Object.defineProperty(global, 'crypto', {value: {subtle: {digest}}, configurable: true});
+                Object.defineProperty(global, 'TextEncoder', {value: TextEncoder, configurable: true});
+            });
+            afterAll(() => {
+                delete (global as {crypto?: unknown}).crypto;
+            });
+            beforeEach(() => {
+                digest.mockResolvedValue(new Uint8Array([0, 1, 255]).buffer);
+            });
+            it('posts hash of query', async () => {
+                const query = 'persisted query';
+                const variables = {a: 123};
+                mockFetch.mockResolvedValue(mockResponse({json: responseBody}));
+
+                const result = await agent.graphql(query, variables);
+
+                expect(result).toBe(responseBody);
+                expect(fetch).toBeCalledTimes(1);
+                expect(fetch).toBeCalledWith('/finances/api/v1/graphql', {method: 'POST', body: JSON.stringify({variables, extensions})});
+                expect(digest).toBeCalledWith('SHA-256', new TextEncoder().encode(query));
+            });
+            it('reuses hash of query', async () => {
+                const query = 'cached query';
+                mockFetch.mockResolvedValue(mockResponse({json: responseBody}));
+
+                await agent.graphql(query);
+                await agent.graphql(query);
+
+                expect(digest).toBeCalledTimes(1);
+                expect(fetch).toBeCalledTimes(2);
+            });
+            it('posts query if hash is not found', async () => {
+                const query = 'new query';
+                const notFound = {errors: [{message: 'PersistedQueryNotFound', extensions: {code: 'PERSISTED_QUERY_NOT_FOUND'}}]};
+                mockFetch.mockResolvedValueOnce(mockResponse({json: notFound}));
+                mockFetch.mockResolvedValueOnce(mockResponse({json: responseBody}));
+
+                const result = await agent.graphql(query);
+
+                expect(result).toBe(responseBody);
+                expect(fetch).toBeCalledTimes(2);
+                expect(fetch).toHaveBeenNthCalledWith(2, '/finances/api/v1/graphql', {
+                    method: 'POST',
+                    body: JSON.stringify({query, variables: {}, extensions}),
+                });
+            });
+        });
     });
 });
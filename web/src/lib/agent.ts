@@ -1,4 +1,3 @@
-
 export async function post<T>(url: string, body: BodyInit): Promise<T> {
     const response = await fetch(url, {method: 'POST', body});
     if (!response.ok) throw new Error(`Fetch failed: ${response.status} - ${response.statusText}`);
@@ -13,6 +12,7 @@ interface IGraphqlLocation {
 interface IGraphqlError {
     message: string;
     locations: IGraphqlLocation[];
+    extensions?: {code?: string};
 }
 
 export interface IGraphqlResponse<T> {
@@ -20,7 +20,37 @@ export interface IGraphqlResponse<T> {
     errors?: IGraphqlError[];
 }
 
+const graphqlUrl = '/finances/api/v1/graphql';
+const queryHashes = new Map<string, string>();
+
+/**
+ * @return the hex encoded SHA-256 hash of the query or undefined if the browser doesn't support hashing (i.e. the page
+ * wasn't loaded from a secure origin).
+ */
+async function hashQuery(query: string): Promise<string | undefined> {
+    if (typeof crypto === 'undefined' || !crypto.subtle) return undefined;
+    let hash = queryHashes.get(query);
+    if (!hash) {
+        const digest = await crypto.subtle.digest('SHA-256', new TextEncoder().encode(query));
+        hash = Array.from(new Uint8Array(digest), (b) => b.toString(16).padStart(2, '0')).join('');
+        queryHashes.set(query, hash);
+    }
+    return hash;
+}
+
+function isNotFound({errors}: IGraphqlResponse<unknown>) {
+    return errors?.some((error) => error.extensions?.code === 'PERSISTED_QUERY_NOT_FOUND') ?? false;
+}
+
+/**
+ * Send the hash of the query instead of the query text. If the server doesn't know the hash, then send the query with
+ * its hash so that the server can register it.
+ */
 export async function graphql<T>(query: string, variables: unknown = {}): Promise<IGraphqlResponse<T>> {
-    const body = JSON.stringify({query, variables});
-    return post('/finances/api/v1/graphql', body);
+    const sha256Hash = await hashQuery(query);
+    if (!sha256Hash) return post(graphqlUrl, JSON.stringify({query, variables}));
+    const extensions = {persistedQuery: {version: 1, sha256Hash}};
+    const response = await post<IGraphqlResponse<T>>(graphqlUrl, JSON.stringify({variables, extensions}));
+    if (!isNotFound(response)) return response;
+    return post(graphqlUrl, JSON.stringify({query, variables, extensions}));
 }
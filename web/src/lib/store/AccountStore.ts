@@ -5,25 +5,9 @@ import {IMessageStore} from './MessageStore';
 import {computed, makeObservable, ObservableMap} from 'mobx';
 import Loader from './Loader';
 import AlertStore from './AlertStore';
+import {accountsQuery as query, updateCompaniesQuery} from './queries';
 
-const accountFields = `
-fragment accountFields on account {
-    id name type accountNo description closed companyId version transactionCount balance
-}`;
-
-const companyFields = 'fragment companyFields on company {id name version}';
-
-export const query = `${companyFields}
-${accountFields}
-{
-    accounts {...accountFields}
-    companies {...companyFields}
-}`;
-
-export const updateCompaniesQuery = `${companyFields}
-mutation update($add: [String!], $delete: [idVersion!], $update: [companyInput!]) {
-    companies: updateCompanies(add: $add, delete: $delete, update: $update) {...companyFields}
-}`;
+export {query, updateCompaniesQuery};
 
 interface IAccountsResponse {
     accounts: IAccount[];
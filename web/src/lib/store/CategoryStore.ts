@@ -4,8 +4,9 @@ import {IMessageStore} from './MessageStore';
 import {computed, makeObservable, ObservableMap} from 'mobx';
 import AlertStore from './AlertStore';
 import Loader from './Loader';
+import {categoriesQuery as query} from './queries';
 
-export const query = '{categories {id code description amountType parentId security income version transactionCount}}';
+export {query};
 
 export const loadingCategories = 'Loading categories';
 
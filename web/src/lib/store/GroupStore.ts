@@ -4,10 +4,9 @@ import {IMessageStore} from './MessageStore';
 import {computed, makeObservable, ObservableMap} from 'mobx';
 import Loader from './Loader';
 import AlertStore from './AlertStore';
+import {groupsQuery as query} from './queries';
 
-export const query = `{
-    groups {id name description version transactionCount}
-}`;
+export {query};
 
 export const loadingGroups = 'Loading groups';
 
@@ -4,12 +4,9 @@ import {IMessageStore} from './MessageStore';
 import {computed, makeObservable, ObservableMap} from 'mobx';
 import Loader from './Loader';
 import AlertStore from './AlertStore';
+import {payeesQuery as query} from './queries';
 
-export const query = `{
-    payees {
-        id name version transactionCount
-    }
-}`;
+export {query};
 
 export const loadingPayees = 'Loading payees';
 
@@ -4,12 +4,9 @@ import {IMessageStore} from './MessageStore';
 import {computed, makeObservable, ObservableMap} from 'mobx';
 import Loader from './Loader';
 import AlertStore from './AlertStore';
+import {securitiesQuery as query} from './queries';
 
-export const query = `{
-    securities {
-        id name type scale symbol type version transactionCount shares firstAcquired costBasis dividends
-    }
-}`;
+export {query};
 
 export const loadingSecurities = 'Loading securities';
 
@@ -3,24 +3,9 @@ import {ObservableMap} from 'mobx';
 import {RootStore} from './RootStore';
 import TransactionTableModel from '../model/TransactionTableModel';
 import Loader from './Loader';
+import {transactionsQuery as query, updateTxMutation} from './queries';
 
-const transactionFields = `fragment transactionFields on transaction {
-    id version date referenceNumber payeeId securityId memo cleared
-    details {
-        id version transactionCategoryId transactionGroupId memo amount assetQuantity
-        relatedDetail {transaction {id accountId}}
-    }
-}`;
-
-export const query = `${transactionFields}
-query($accountId: Int!) {
-    transactions(accountId: $accountId) {...transactionFields}
-}`;
-
-export const updateTxMutation = `${transactionFields}
-mutation update($accountId: Int!, $deletes: [idVersion!], $adds: [addTransactionInput!], $updates: [updateTransactionInput!]) {
-    transactions: updateTransactions(accountId: $accountId, delete: $deletes, add: $adds, update: $updates) {...transactionFields}
-}`;
+export {query, updateTxMutation};
 
 export const loadingTransactions = 'Loading transactions';
 export const savingTransactions = 'Saving transactions';
@@ -0,0 +1,57 @@
+// The GraphQL operations of the stores. This module doesn't have any dependencies so that the build can generate the
+// manifest of persisted queries from it.
+
+const accountFields = `
+fragment accountFields on account {
+    id name type accountNo description closed companyId version transactionCount balance
+}`;
+
+const companyFields = 'fragment companyFields on company {id name version}';
+
+export const accountsQuery = `${companyFields}
+${accountFields}
+{
+    accounts {...accountFields}
+    companies {...companyFields}
+}`;
+
+export const updateCompaniesQuery = `${companyFields}
+mutation update($add: [String!], $delete: [idVersion!], $update: [companyInput!]) {
+    companies: updateCompanies(add: $add, delete: $delete, update: $update) {...companyFields}
+}`;
+
+export const categoriesQuery = '{categories {id code description amountType parentId security income version transactionCount}}';
+
+export const groupsQuery = `{
+    groups {id name description version transactionCount}
+}`;
+
+export const payeesQuery = `{
+    payees {
+        id name version transactionCount
+    }
+}`;
+
+export const securitiesQuery = `{
+    securities {
+        id name type scale symbol type version transactionCount shares firstAcquired costBasis dividends
+    }
+}`;
+
+const transactionFields = `fragment transactionFields on transaction {
+    id version date referenceNumber payeeId securityId memo cleared
+    details {
+        id version transactionCategoryId transactionGroupId memo amount assetQuantity
+        relatedDetail {transaction {id accountId}}
+    }
+}`;
+
+export const transactionsQuery = `${transactionFields}
+query($accountId: Int!) {
+    transactions(accountId: $accountId) {...transactionFields}
+}`;
+
+export const updateTxMutation = `${transactionFields}
+mutation update($accountId: Int!, $deletes: [idVersion!], $adds: [addTransactionInput!], $updates: [updateTransactionInput!]) {
+    transactions: updateTransactions(accountId: $accountId, delete: $deletes, add: $adds, update: $updates) {...transactionFields}
+}`;